@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/internal/util"
+)
+
+func CmdAnalyze(cli *Cli) error {
+	format := outputFormat(cli)
+	if format != "" && format != "json" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", must be: json", format))
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	bundles, err := p.Stack.Analyze()
+	if err != nil {
+		return util.NewReadableError(err, "Could not bundle the config: "+err.Error())
+	}
+
+	if format == "json" {
+		if err := printJSON(bundles); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	const top = 10
+	for i, bundle := range bundles {
+		if i > 0 {
+			fmt.Println()
+		}
+		color.New(color.FgWhite, color.Bold).Print(bundle.Name)
+		color.New(color.FgHiBlack).Println("  " + ui.FormatBytes(int64(bundle.Bytes)))
+
+		color.New(color.FgHiBlack).Println("  Inputs")
+		for _, input := range bundle.Inputs[:min(top, len(bundle.Inputs))] {
+			fmt.Printf("    %-10s %s\n", ui.FormatBytes(int64(input.Bytes)), input.Path)
+		}
+		if len(bundle.Packages) > 0 {
+			color.New(color.FgHiBlack).Println("  Dependencies")
+			for _, pkg := range bundle.Packages[:min(top, len(bundle.Packages))] {
+				fmt.Printf("    %-10s %s\n", ui.FormatBytes(int64(pkg.Bytes)), pkg.Name)
+			}
+		}
+		if len(bundle.Duplicates) > 0 {
+			color.New(color.FgYellow).Println("  Duplicates")
+			for _, duplicate := range bundle.Duplicates {
+				fmt.Println("    " + duplicate.Name)
+				for _, path := range duplicate.Paths {
+					color.New(color.FgHiBlack).Println("      " + path)
+				}
+			}
+		}
+	}
+	return nil
+}
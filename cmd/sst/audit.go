@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+func CmdAudit(cli *Cli) error {
+	format := outputFormat(cli)
+	if format != "" && format != "json" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", must be: json", format))
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	entries, err := provider.ListAudit(p.Backend(), p.App().Name, p.App().Stage)
+	if err != nil {
+		if err == provider.ErrAuditNotSupported {
+			return util.NewReadableError(err, fmt.Sprintf("Audit logs are not supported by the \"%s\" home provider", p.App().Home))
+		}
+		return util.NewReadableError(err, "Could not read the audit log")
+	}
+
+	if format == "json" {
+		if err := printJSON(entries); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	for _, entry := range entries {
+		color.New(color.FgHiBlack).Print(entry.Time.Local().Format(time.DateTime) + "  ")
+		color.New(color.FgWhite, color.Bold).Printf("%-13s", entry.Operation)
+		fmt.Print("  " + entry.Principal)
+		if entry.User != "" {
+			color.New(color.FgHiBlack).Print("  " + entry.User + "@" + entry.Host)
+		}
+		if entry.Detail != "" {
+			color.New(color.FgHiBlack).Print("  " + entry.Detail)
+		}
+		fmt.Println()
+	}
+	return nil
+}
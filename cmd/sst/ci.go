@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/manifoldco/promptui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/global"
+)
+
+// ErrNeedsInput is returned when the CLI would prompt but it can't, it exits
+// with a different code so a pipeline can tell it apart from a failure.
+var ErrNeedsInput = fmt.Errorf("input required in non-interactive mode")
+
+const exitNeedsInput = 2
+
+// CI is true if the CLI is running in CI or with --ci, it never prompts then.
+func (c *Cli) CI() bool {
+	return c.Bool("ci") || global.CI() != ""
+}
+
+// needsInput is the error for a prompt that can't be shown, hint is the flag
+// to pass in instead.
+func needsInput(hint string) error {
+	return util.NewReadableError(ErrNeedsInput, "Running non-interactively, "+hint)
+}
+
+// confirm asks a yes or no question. With --yes it's always yes, and in CI
+// it fails instead of waiting for an answer. The prompt is rendered on stderr
+// so it doesn't end up in the output when it's redirected.
+func confirm(cli *Cli, label string) (bool, error) {
+	if cli.Bool("yes") {
+		return true, nil
+	}
+	if cli.CI() {
+		return false, needsInput("pass in --yes to confirm: " + label)
+	}
+	prompt := promptui.Select{
+		Label:        "‏‏‎ ‎" + label,
+		HideSelected: true,
+		Items:        []string{"Yes", "No"},
+		HideHelp:     true,
+		Stdout:       os.Stderr,
+	}
+	_, answer, err := prompt.Run()
+	if err != nil {
+		return false, util.NewReadableError(err, "")
+	}
+	return answer == "Yes", nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+)
+
+// the values of an argument or a flag that are completed from the app
+const (
+	CompleteStage    = "stage"
+	CompleteFunction = "function"
+	CompleteSecret   = "secret"
+	CompleteURN      = "urn"
+)
+
+var completionScripts = map[string]string{
+	"bash": `_sst() {
+  local IFS=$'\n'
+  COMPREPLY=($(sst __complete "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _sst sst
+`,
+	"zsh": `#compdef sst
+_sst() {
+  local -a completions
+  completions=(${(f)"$(sst __complete "${(@)words[2,$CURRENT]}" 2>/dev/null)"})
+  compadd -- $completions
+}
+compdef _sst sst
+`,
+	"fish": `function __sst_complete
+    set -l args (commandline -opc)[2..-1] (commandline -ct)
+    sst __complete $args 2>/dev/null
+end
+complete -c sst -f -a '(__sst_complete)'
+`,
+}
+
+func CmdCompletion(cli *Cli) error {
+	shell := cli.Positional(0)
+	script, ok := completionScripts[shell]
+	if !ok {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown shell \"%s\", must be one of: bash, zsh, fish", shell))
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// complete prints the completions for the last of the words, the ones
+// before it are the command line so far. It's run by the shell on every tab
+// so it never prompts or fails.
+func complete(words []string) {
+	os.Remove(logFile.Name())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, candidate := range completeWords(words, newCompleter()) {
+		fmt.Println(candidate)
+	}
+}
+
+type completeSource interface {
+	values(kind string, stage string) []string
+}
+
+func completeWords(words []string, source completeSource) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+	previous := words[:len(words)-1]
+	// bash splits --stage=prod into --stage, =, and prod
+	if len(previous) > 1 && previous[len(previous)-1] == "=" {
+		previous = previous[:len(previous)-1]
+	} else if current == "=" && len(previous) > 0 {
+		current = ""
+	}
+
+	cmd := &Root
+	flags := map[string]Flag{}
+	addFlags := func(c *Command) {
+		for _, f := range c.Flags {
+			flags[f.Name] = f
+		}
+	}
+	addFlags(cmd)
+	stage := ""
+	positional := 0
+	for i := 0; i < len(previous); i++ {
+		word := previous[i]
+		if strings.HasPrefix(word, "--") {
+			name, value, hasValue := strings.Cut(strings.TrimPrefix(word, "--"), "=")
+			f, ok := flags[name]
+			if ok && f.Type == "string" && !hasValue && i+1 < len(previous) {
+				i++
+				value = previous[i]
+			}
+			if name == "stage" {
+				stage = value
+			}
+			continue
+		}
+		if child := findChild(cmd, word); child != nil {
+			cmd = child
+			addFlags(cmd)
+			continue
+		}
+		positional++
+	}
+
+	var candidates []string
+	prefix := current
+	switch {
+	case strings.HasPrefix(current, "--") && strings.Contains(current, "="):
+		name, value, _ := strings.Cut(strings.TrimPrefix(current, "--"), "=")
+		if f, ok := flags[name]; ok {
+			for _, candidate := range source.values(f.Complete, stage) {
+				candidates = append(candidates, "--"+name+"="+candidate)
+			}
+		}
+		prefix = "--" + name + "=" + value
+	case strings.HasPrefix(current, "-"):
+		for name := range flags {
+			candidates = append(candidates, "--"+name)
+		}
+	case len(previous) > 0 && isStringFlag(flags, previous[len(previous)-1]):
+		f := flags[strings.TrimPrefix(previous[len(previous)-1], "--")]
+		candidates = source.values(f.Complete, stage)
+	case len(cmd.Children) > 0:
+		for _, child := range cmd.Children {
+			if !child.Hidden {
+				candidates = append(candidates, child.Name)
+			}
+		}
+	case positional < len(cmd.Args):
+		candidates = source.values(cmd.Args[positional].Complete, stage)
+	}
+
+	result := []string{}
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) && !seen[candidate] {
+			seen[candidate] = true
+			result = append(result, candidate)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func findChild(cmd *Command, name string) *Command {
+	for _, child := range cmd.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+func isStringFlag(flags map[string]Flag, word string) bool {
+	if !strings.HasPrefix(word, "--") || strings.Contains(word, "=") {
+		return false
+	}
+	f, ok := flags[strings.TrimPrefix(word, "--")]
+	return ok && f.Type == "string"
+}
+
+// completer completes from what's saved in the .sst directory. It never
+// evaluates the config or reaches the home provider, the shell runs it on
+// every tab.
+type completer struct {
+	cfgPath string
+}
+
+func newCompleter() *completer {
+	cfgPath, _ := project.Discover()
+	return &completer{cfgPath: cfgPath}
+}
+
+func (c *completer) stage(stage string) string {
+	if stage == "" {
+		stage = project.LoadBranchStage(c.cfgPath)
+	}
+	if stage == "" {
+		stage = project.LoadPersonalStage(c.cfgPath)
+	}
+	return stage
+}
+
+func (c *completer) values(kind string, stage string) []string {
+	if c.cfgPath == "" || kind == "" {
+		return nil
+	}
+	stage = c.stage(stage)
+	if kind == CompleteStage {
+		result := project.CompletionStages(c.cfgPath)
+		for _, local := range []string{project.LoadPersonalStage(c.cfgPath), project.LoadBranchStage(c.cfgPath)} {
+			if local != "" {
+				result = append(result, local)
+			}
+		}
+		return result
+	}
+	completions := project.LoadCompletions(c.cfgPath, stage)
+	if completions == nil {
+		return nil
+	}
+	switch kind {
+	case CompleteFunction:
+		return completions.Functions
+	case CompleteURN:
+		return completions.URNs
+	case CompleteSecret:
+		return completions.Secrets
+	}
+	return nil
+}
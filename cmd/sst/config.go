@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/global"
+)
+
+// applyUserConfig applies the defaults of the user config that aren't set
+// with a flag or in the environment. The ones for an app are read when it's
+// loaded.
+func applyUserConfig(cli *Cli) error {
+	config, err := global.LoadUserConfig()
+	if err != nil {
+		// sst config still works so the file can be fixed
+		if len(cli.path) > 1 && cli.path[1].Name == "config" {
+			return nil
+		}
+		return util.NewReadableError(err, err.Error())
+	}
+	if config.Proxy != "" && os.Getenv("HTTPS_PROXY") == "" && os.Getenv("HTTP_PROXY") == "" {
+		os.Setenv("HTTPS_PROXY", config.Proxy)
+		os.Setenv("HTTP_PROXY", config.Proxy)
+	}
+	switch config.Color {
+	case "always":
+		color.NoColor = false
+	case "never":
+		color.NoColor = true
+	}
+	return nil
+}
+
+func userConfigError(key string, err error) error {
+	if errors.Is(err, global.ErrUnknownConfigKey) {
+		return util.NewReadableError(err, fmt.Sprintf("Unknown config key \"%s\", must be one of: %s", key, strings.Join(global.UserConfigKeys, ", ")))
+	}
+	return util.NewReadableError(err, err.Error())
+}
+
+func CmdConfigList(cli *Cli) error {
+	config, err := global.ReadUserConfig()
+	if err != nil {
+		return util.NewReadableError(err, err.Error())
+	}
+	values := map[string]string{}
+	for _, key := range global.UserConfigKeys {
+		value, _ := config.Get(key)
+		if value != "" {
+			values[key] = value
+		}
+	}
+	if ui.Output() == ui.OutputJSON {
+		ui.Result(values)
+		return nil
+	}
+	color.New(color.FgHiBlack).Println(global.UserConfigPath())
+	for _, key := range global.UserConfigKeys {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		color.New(color.FgWhite, color.Bold).Printf("%-13s", key)
+		fmt.Println(value)
+	}
+	return nil
+}
+
+func CmdConfigGet(cli *Cli) error {
+	key := cli.Positional(0)
+	config, err := global.ReadUserConfig()
+	if err != nil {
+		return util.NewReadableError(err, err.Error())
+	}
+	value, err := config.Get(key)
+	if err != nil {
+		return userConfigError(key, err)
+	}
+	if ui.Output() == ui.OutputJSON {
+		ui.Result(map[string]string{key: value})
+		return nil
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func CmdConfigSet(cli *Cli) error {
+	key := cli.Positional(0)
+	value := cli.Positional(1)
+	if value == "" {
+		return util.NewReadableError(nil, fmt.Sprintf("Pass in the value of \"%s\", or use `sst config unset` to remove it", key))
+	}
+	return updateUserConfig(key, value, fmt.Sprintf("Set \"%s\" to \"%s\"", key, value))
+}
+
+func CmdConfigUnset(cli *Cli) error {
+	key := cli.Positional(0)
+	return updateUserConfig(key, "", fmt.Sprintf("Unset \"%s\"", key))
+}
+
+func updateUserConfig(key string, value string, message string) error {
+	config, err := global.ReadUserConfig()
+	if err != nil {
+		return util.NewReadableError(err, err.Error())
+	}
+	if err := config.Set(key, value); err != nil {
+		return userConfigError(key, err)
+	}
+	if err := config.Save(); err != nil {
+		return util.NewReadableError(err, "Could not write "+global.UserConfigPath())
+	}
+	ui.Success(message)
+	return nil
+}
@@ -1,33 +1,29 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
-	"strings"
 	"sync"
 
 	"github.com/sst/ion/cmd/sst/ui"
 	"github.com/sst/ion/internal/util"
 	"github.com/sst/ion/pkg/project"
 	"github.com/sst/ion/pkg/server"
+	"github.com/sst/ion/pkg/server/dev/aws"
 )
 
 func CmdDev(cli *Cli) error {
-	var args []string
-	for _, arg := range cli.arguments {
-		args = append(args, strings.Fields(arg)...)
-	}
+	args := cli.Command()
 	slog.Info("args", "args", args, "length", len(args))
 	hasTarget := len(args) > 0
 
 	cfgPath, err := project.Discover()
 	if err != nil {
-		return util.NewReadableError(err, "Could not find sst.config.ts")
+		return util.NewReadableError(err, "Could not find an sst.config.ts or sst.config.js")
 	}
 
 	stage, err := getStage(cli, cfgPath)
@@ -35,6 +31,10 @@ func CmdDev(cli *Cli) error {
 		return util.NewReadableError(err, "Could not find stage")
 	}
 
+	if kind := cli.String("trigger"); kind != "" {
+		return triggerDev(cfgPath, stage, kind, args)
+	}
+
 	deployComplete := make(chan *project.CompleteEvent)
 	runOnce := false
 	var wg sync.WaitGroup
@@ -63,21 +63,7 @@ func CmdDev(cli *Cli) error {
 				args[1:]...,
 			)
 
-			for dir, receiver := range complete.Receivers {
-				dir = filepath.Join(cfgPath, "..", dir)
-				if !strings.HasPrefix(dir, cwd) {
-					continue
-				}
-				for key, value := range receiver.Environment {
-					cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
-				}
-				for _, resource := range receiver.Links {
-					value := complete.Links[resource]
-					jsonValue, _ := json.Marshal(value)
-					envVar := fmt.Sprintf("SST_RESOURCE_%s=%s", resource, jsonValue)
-					cmd.Env = append(cmd.Env, envVar)
-				}
-			}
+			cmd.Env = linkEnv(cfgPath, cwd, complete.Receivers, complete.Links)
 			cmd.Env = append(cmd.Env,
 				os.Environ()...,
 			)
@@ -126,11 +112,22 @@ func CmdDev(cli *Cli) error {
 
 	state := &server.State{}
 	// fmt.Print("\033[H\033[2J")
+	filter, err := aws.NewLogFilter(cli.String("filter"), cli.String("level"))
+	if err != nil {
+		return util.NewReadableError(err, err.Error())
+	}
 	u := ui.New(ui.ProgressModeDev)
+	u.FilterLogs(filter)
 	defer u.Destroy()
 	err = server.Connect(cli.Context, server.ConnectInput{
-		CfgPath: cfgPath,
-		Stage:   stage,
+		CfgPath:       cfgPath,
+		Stage:         stage,
+		Inspect:       cli.Bool("inspect"),
+		Attach:        cli.Bool("attach"),
+		AllowReserved: cli.Bool("allow-reserved"),
+		LogLevel:      cli.String("log-level"),
+		LogFormat:     cli.String("log-format"),
+		LogFile:       cli.String("log-file"),
 		OnEvent: func(event server.Event) {
 			if !hasTarget || !runOnce || true {
 				defer u.Trigger(&event.StackEvent)
@@ -185,3 +182,27 @@ func CmdDev(cli *Cli) error {
 
 	return nil
 }
+
+func triggerDev(cfgPath string, stage string, kind string, names []string) error {
+	if kind != "cron" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown trigger \"%s\", must be: cron", kind))
+	}
+	if len(names) == 0 {
+		return util.NewReadableError(nil, "Pass in the name of the cron to trigger")
+	}
+	for _, name := range names {
+		err := server.Trigger(cfgPath, stage, kind, name)
+		if err == server.ErrServerNotRunning {
+			return util.NewReadableError(err, "sst dev is not running for this stage, start it first")
+		}
+		if err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+		if ui.Output() == ui.OutputJSON {
+			ui.Success("Triggered " + name)
+			continue
+		}
+		fmt.Println("Triggered " + name)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/pkg/project"
+)
+
+func CmdDiff(cli *Cli) error {
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	asJSON := ui.Output() == ui.OutputJSON
+	diff := project.NewDiff()
+	var failures []project.Error
+	u := ui.New(ui.ProgressModeDeploy)
+	u.Header(version, p.App().Name, p.App().Stage)
+	err = p.Stack.Run(cli.Context, &project.StackInput{
+		Command: "preview",
+		OnEvent: func(event *project.StackEvent) {
+			diff.Add(event)
+			if event.CompleteEvent != nil {
+				failures = event.CompleteEvent.Errors
+			}
+			// the changes are printed once the preview is done
+			if !asJSON && (event.ResourcePreEvent != nil || event.ResOutputsEvent != nil || event.CompleteEvent != nil) {
+				return
+			}
+			u.Trigger(event)
+		},
+	})
+	u.Destroy()
+	if !asJSON {
+		for _, failure := range failures {
+			color.New(color.FgRed, color.Bold).Print("✕  ")
+			fmt.Println(failure.Message)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	components := diff.Components()
+	if asJSON {
+		return printJSON(components)
+	}
+	if len(components) == 0 {
+		color.New(color.FgGreen, color.Bold).Print(ui.IconCheck)
+		color.New(color.FgWhite, color.Bold).Println("  No changes")
+		return nil
+	}
+	for _, component := range components {
+		printComponentDiff(component)
+	}
+	return nil
+}
+
+var diffSymbols = map[string]string{
+	project.DiffOpCreate:  "+",
+	project.DiffOpUpdate:  "~",
+	project.DiffOpReplace: "±",
+	project.DiffOpDelete:  "-",
+}
+
+var diffColors = map[string]color.Attribute{
+	project.DiffOpCreate:  color.FgGreen,
+	project.DiffOpUpdate:  color.FgYellow,
+	project.DiffOpReplace: color.FgMagenta,
+	project.DiffOpDelete:  color.FgRed,
+}
+
+func printComponentDiff(component project.ComponentDiff) {
+	fmt.Println()
+	if component.Name == "" {
+		color.New(color.FgWhite, color.Bold).Println("App")
+	} else {
+		color.New(color.FgWhite, color.Bold).Print(component.Name + " ")
+		color.New(color.FgHiBlack).Println(component.Type)
+	}
+	for _, resource := range component.Resources {
+		color.New(diffColors[resource.Op], color.Bold).Print("  " + diffSymbols[resource.Op] + " ")
+		fmt.Print(resource.Name + " ")
+		color.New(color.FgHiBlack).Println(resource.Type)
+		for _, property := range resource.Properties {
+			color.New(color.FgHiBlack).Print("      " + property.Path + ": ")
+			fmt.Println(formatDiffValue(property.Old) + " → " + formatDiffValue(property.New))
+		}
+	}
+}
+
+func formatDiffValue(value interface{}) string {
+	if value == nil {
+		return "∅"
+	}
+	if str, ok := value.(string); ok {
+		return str
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
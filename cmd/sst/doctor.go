@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+)
+
+var ErrDoctorFailed = fmt.Errorf("doctor checks failed")
+
+func CmdDoctor(cli *Cli) error {
+	format := outputFormat(cli)
+	if format != "" && format != "json" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", must be: json", format))
+	}
+
+	// not initProject, that fixes the platform and fails on the providers
+	// before they can be checked
+	cfgPath, err := project.Discover()
+	if err != nil {
+		return util.NewReadableError(err, "Could not find an sst.config.ts or sst.config.js")
+	}
+	stage, err := getStage(cli, cfgPath)
+	if err != nil {
+		return util.NewReadableError(err, "Could not find stage")
+	}
+	p, err := project.New(&project.ProjectConfig{
+		Version: version,
+		Stage:   stage,
+		Config:  cfgPath,
+		Output:  projectOutput(),
+	})
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	checks := p.Doctor(cli.Context)
+	failed := 0
+	for _, check := range checks {
+		if check.Status == project.DoctorFail {
+			failed++
+		}
+	}
+	if format == "json" {
+		if err := printJSON(checks); err != nil {
+			return err
+		}
+	} else {
+		printDoctorChecks(checks)
+	}
+	if failed > 0 {
+		return util.NewReadableError(ErrDoctorFailed, fmt.Sprintf("%d check(s) failed", failed))
+	}
+	return nil
+}
+
+func printDoctorChecks(checks []project.DoctorCheck) {
+	for _, check := range checks {
+		switch check.Status {
+		case project.DoctorOk:
+			color.New(color.FgGreen, color.Bold).Print("✓  ")
+		case project.DoctorWarn:
+			color.New(color.FgYellow, color.Bold).Print("!  ")
+		default:
+			color.New(color.FgRed, color.Bold).Print("✕  ")
+		}
+		color.New(color.FgWhite, color.Bold).Printf("%-18s", check.Name)
+		fmt.Println(check.Message)
+		if check.Fix != "" {
+			color.New(color.FgHiBlack).Println("   " + check.Fix)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+func CmdEnv(cli *Cli) error {
+	args := cli.Command()
+	if len(args) == 0 {
+		return util.NewReadableError(nil, "Pass in the command to run, like `sst env -- npm test`")
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	cwd, _ := os.Getwd()
+	env, err := stageEnv(p, cwd)
+	if err != nil {
+		return err
+	}
+	return runWithEnv(p, args, cwd, env)
+}
+
+// stageEnv is the environment of a command run in dir with the links of the
+// stage, see linkEnv.
+func stageEnv(p *project.Project, dir string) ([]string, error) {
+	links, err := provider.GetLinks(p.Backend(), p.App().Name, p.App().Stage)
+	if err != nil {
+		return nil, util.NewReadableError(err, "Could not read the links of the stage")
+	}
+	// the receivers are only known once sst dev ran
+	metadata, err := p.Stack.Metadata()
+	if err != nil {
+		return nil, util.NewReadableError(err, "Could not read the metadata of the stage")
+	}
+	env := append(linkEnv(p.PathConfig(), dir, metadata.Receivers, links), os.Environ()...)
+	env = append(env,
+		"SST_APP="+p.App().Name,
+		"SST_STAGE="+p.App().Stage,
+	)
+	return env, nil
+}
+
+// runWithEnv runs the command in dir and exits with its exit code if it
+// fails.
+func runWithEnv(p *project.Project, args []string, dir string, env []string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		p.Cleanup()
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return util.NewReadableError(err, err.Error())
+	}
+	return nil
+}
+
+// linkEnv is the environment of a command run in cwd. It gets the links and
+// environment of the receivers in cwd, like the site it's run from, and
+// every link if there are none. The environment of the process goes after
+// it so it wins, like in sst dev.
+func linkEnv(cfgPath string, cwd string, receivers project.Receivers, links map[string]interface{}) []string {
+	env := map[string]string{}
+	matched := false
+	for dir, receiver := range receivers {
+		dir = filepath.Join(cfgPath, "..", dir)
+		rel, err := filepath.Rel(cwd, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		matched = true
+		for key, value := range receiver.Environment {
+			env[key] = value
+		}
+		for _, name := range receiver.Links {
+			value, _ := json.Marshal(links[name])
+			env["SST_RESOURCE_"+name] = string(value)
+		}
+	}
+	if !matched {
+		for name, value := range links {
+			value, _ := json.Marshal(value)
+			env["SST_RESOURCE_"+name] = string(value)
+		}
+	}
+	result := []string{}
+	for key, value := range env {
+		result = append(result, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(result)
+	return result
+}
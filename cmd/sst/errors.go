@@ -9,9 +9,13 @@ import (
 func TransformError(err error) error {
 	mapping := map[error]string{
 		project.ErrInvalidStageName: "The stage name is invalid. It can only contain alphanumeric characters and hyphens.",
+		project.ErrInvalidAppName:   "The app name is invalid. It can only contain alphanumeric characters and hyphens.",
 		project.ErrV2Config:         "You are using sst ion and this looks like an sst v2 config",
 		project.ErrStageNotFound:    "Stage not found",
+		project.ErrLayoutNewer:      "The .sst directory was created by a newer version of sst, upgrade sst or remove the .sst directory",
+		project.ErrDevenvNotBuilt:   "This project uses devenv, run `devenv shell` once to build the profile",
 		provider.ErrLockExists:      "",
+		provider.ErrStageExists:     "A stage with that name already exists",
 	}
 
 	match, ok := mapping[err]
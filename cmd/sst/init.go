@@ -3,22 +3,26 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
 	"slices"
 	"strings"
 	"time"
 
-	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/manifoldco/promptui"
+	"github.com/sst/ion/cmd/sst/ui"
 	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/platform"
 	"github.com/sst/ion/pkg/project"
 )
 
 func CmdInit(cli *Cli) error {
-	if _, err := os.Stat("sst.config.ts"); err == nil {
+	if project.FindConfig(".") != "" {
 		color.New(color.FgRed, color.Bold).Print("×")
 		color.New(color.FgWhite, color.Bold).Println(" SST project already exists")
 		return nil
@@ -45,12 +49,188 @@ func CmdInit(cli *Cli) error {
 	}
 	fmt.Print("\033[?25h")
 
+	var err error
+	var remote *project.Template
+	template := cli.String("template")
+	if template != "" {
+		if builtInTemplate(template) {
+			remote = builtInEntry(template)
+		} else {
+			remote, err = findTemplate(cli, template)
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		template, err = detectTemplate()
+		if err != nil {
+			return err
+		}
+		ok, err := confirm(cli, "Continue")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		// nothing to drop into, so it can start from one in the gallery,
+		// without a prompt it stays vanilla unless --template is passed in
+		if template == "vanilla" && !cli.CI() {
+			remote, err = selectTemplate(cli)
+			if err != nil {
+				return err
+			}
+			if remote != nil {
+				template = remote.Name
+			}
+		}
+	}
+
+	color.New(color.FgGreen, color.Bold).Print("✓ ")
+	color.New(color.FgWhite).Println(" Template: ", template)
+	fmt.Println()
+
+	home := cli.String("home")
+	if remote != nil && remote.Home != "" {
+		if home != "" && home != remote.Home {
+			return util.NewReadableError(nil, fmt.Sprintf("The %s template only works with the \"%s\" home", remote.Name, remote.Home))
+		}
+		home = remote.Home
+	}
+	if home != "" && home != "aws" && home != "cloudflare" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown home \"%s\", must be one of: aws, cloudflare", home))
+	}
+	if home == "" {
+		home = "aws"
+		if template == "vanilla" && !cli.CI() {
+			p := promptui.Select{
+				Label:        "‏‏‎ ‎Where do you want to deploy your app? You can change this later",
+				HideSelected: true,
+				Items:        []string{"aws", "cloudflare"},
+				HideHelp:     true,
+			}
+			_, home, err = p.Run()
+			if err != nil {
+				return util.NewReadableError(err, "")
+			}
+		}
+	}
+
+	color.New(color.FgGreen, color.Bold).Print("✓ ")
+	color.New(color.FgWhite).Println(" Using: " + home)
+	fmt.Println()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	input := &project.CreateInput{
+		App:   cli.String("name"),
+		Home:  home,
+		Stage: cli.String("stage"),
+	}
+	if input.App == "" {
+		input.App = strings.ToLower(filepath.Base(cwd))
+	}
+	if remote != nil && remote.BuiltIn() && !builtInTemplate(remote.Name) {
+		return util.NewReadableError(nil, fmt.Sprintf("The %s template is not in this version of sst, run \"sst upgrade\" to get it", remote.Name))
+	}
+	var source fs.FS
+	if remote != nil && !remote.BuiltIn() {
+		spin := ui.NewSpinner()
+		spin.Suffix = "  Downloading template..."
+		spin.Start()
+		dir, err := project.FetchTemplate(cli.Context, *remote)
+		spin.Stop()
+		if err != nil {
+			return util.NewReadableError(err, fmt.Sprintf("Could not download the %s template: %v", remote.Name, err))
+		}
+		defer os.RemoveAll(dir)
+		source = os.DirFS(dir)
+	} else {
+		source, err = fs.Sub(platform.Templates, path.Join("templates", template))
+		if err != nil {
+			return err
+		}
+	}
+	err = project.CreateFrom(source, input)
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+
+	spin := ui.NewSpinner()
+	spin.Suffix = "  Installing providers..."
+	spin.Start()
+
+	cfgPath, err := project.Discover()
+	if err != nil {
+		return err
+	}
+	proj, err := project.New(&project.ProjectConfig{
+		Config:  cfgPath,
+		Stage:   "sst",
+		Version: version,
+		Output:  projectOutput(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := proj.CopyPlatform(version); err != nil {
+		return err
+	}
+	if input.Stage != "" {
+		if err := project.SetPersonalStage(cfgPath, input.Stage); err != nil {
+			return err
+		}
+	}
+
+	if err := proj.Install(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat("package-lock.json"); err == nil {
+		cmd = exec.Command("npm", "install")
+	}
+	if _, err := os.Stat("yarn.lock"); err == nil {
+		cmd = exec.Command("yarn", "install")
+	}
+	if _, err := os.Stat("pnpm-lock.yaml"); err == nil {
+		cmd = exec.Command("pnpm", "install")
+	}
+	if _, err := os.Stat("bun.lockb"); err == nil {
+		cmd = exec.Command("bun", "install")
+	}
+	// a template from the gallery brings its own package.json
+	if _, err := os.Stat("package.json"); err == nil && cmd == nil && remote != nil {
+		cmd = exec.Command("npm", "install")
+	}
+	if cmd != nil {
+		spin.Suffix = "  Installing dependencies..."
+		spin.Start()
+		slog.Info("installing deps", "args", cmd.Args)
+		cmd.Run()
+		spin.Stop()
+	}
+
+	spin.Stop()
+
+	color.New(color.FgGreen, color.Bold).Print("✓ ")
+	color.New(color.FgWhite).Println(" Success 🎉")
+	fmt.Println()
+	return nil
+}
+
+// detectTemplate picks the built in template for the project in the current
+// directory and prints what it will do.
+func detectTemplate() (string, error) {
 	var template string
 
 	hints := []string{}
 	files, err := os.ReadDir(".")
 	if err != nil {
-		return err
+		return "", err
 	}
 	for _, file := range files {
 		if file.IsDir() {
@@ -102,100 +282,76 @@ func CmdInit(cli *Cli) error {
 		break
 	}
 	fmt.Println()
+	return template, nil
+}
 
-	p := promptui.Select{
-		Label:        "‏‏‎ ‎Continue",
-		HideSelected: true,
-		Items:        []string{"Yes", "No"},
-		HideHelp:     true,
-	}
+func builtInTemplate(name string) bool {
+	_, err := fs.Stat(platform.Templates, path.Join("templates", name, "preset.json"))
+	return err == nil
+}
 
-	_, confirm, err := p.Run()
+// builtInEntry is the entry of a built in template in the registry, the
+// ones detected from the project, like js, don't have one.
+func builtInEntry(name string) *project.Template {
+	templates, err := project.BuiltInTemplates()
 	if err != nil {
-		return util.NewReadableError(err, "")
-	}
-	if confirm == "No" {
 		return nil
 	}
-
-	color.New(color.FgGreen, color.Bold).Print("✓ ")
-	color.New(color.FgWhite).Println(" Template: ", template)
-	fmt.Println()
-
-	home := "aws"
-	if template == "vanilla" {
-		p = promptui.Select{
-			Label:        "‏‏‎ ‎Where do you want to deploy your app? You can change this later",
-			HideSelected: true,
-			Items:        []string{"aws", "cloudflare"},
-			HideHelp:     true,
-		}
-		_, home, err = p.Run()
-		if err != nil {
-			return util.NewReadableError(err, "")
+	for _, template := range templates {
+		if template.Name == name {
+			return &template
 		}
 	}
+	return nil
+}
 
-	color.New(color.FgGreen, color.Bold).Print("✓ ")
-	color.New(color.FgWhite).Println(" Using: " + home)
-	fmt.Println()
-
-	err = project.Create(template, home)
-	if err != nil {
-		return err
-	}
-	var cmd *exec.Cmd
-
-	spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	spin.Suffix = "  Installing providers..."
-	spin.Start()
-
-	cfgPath, err := project.Discover()
+func findTemplate(cli *Cli, name string) (*project.Template, error) {
+	templates, err := project.ListTemplates(cli.Context, cli.String("registry"))
 	if err != nil {
-		return err
+		return nil, util.NewReadableError(err, "Could not read the template registry")
 	}
-	proj, err := project.New(&project.ProjectConfig{
-		Config:  cfgPath,
-		Stage:   "sst",
-		Version: version,
-	})
-	if err != nil {
-		return err
-	}
-	if err := proj.CopyPlatform(version); err != nil {
-		return err
+	names := []string{}
+	for _, template := range templates {
+		if template.Name == name {
+			return &template, nil
+		}
+		names = append(names, template.Name)
 	}
+	return nil, util.NewReadableError(nil, fmt.Sprintf("Template \"%s\" was not found, the templates are: %s", name, strings.Join(names, ", ")))
+}
 
-	if err := proj.Install(); err != nil {
-		return err
+// selectTemplate asks for a template from the gallery, it's nil for the
+// vanilla one.
+func selectTemplate(cli *Cli) (*project.Template, error) {
+	templates, err := project.ListTemplates(cli.Context, cli.String("registry"))
+	if err != nil {
+		return nil, util.NewReadableError(err, "Could not read the template registry")
 	}
-
-	if _, err := os.Stat("package-lock.json"); err == nil {
-		cmd = exec.Command("npm", "install")
+	if len(templates) == 0 {
+		return nil, nil
 	}
-	if _, err := os.Stat("yarn.lock"); err == nil {
-		cmd = exec.Command("yarn", "install")
+	items := []string{"vanilla - just the sst.config.ts"}
+	for _, template := range templates {
+		item := template.Name + " - " + template.Description
+		if !template.Official {
+			item += " (community)"
+		}
+		items = append(items, item)
 	}
-	if _, err := os.Stat("pnpm-lock.yaml"); err == nil {
-		cmd = exec.Command("pnpm", "install")
+	p := promptui.Select{
+		Label:        "‏‏‎ ‎Start from a template",
+		HideSelected: true,
+		Items:        items,
+		HideHelp:     true,
 	}
-	if _, err := os.Stat("bun.lockb"); err == nil {
-		cmd = exec.Command("bun", "install")
+	index, _, err := p.Run()
+	if err != nil {
+		return nil, util.NewReadableError(err, "")
 	}
-	if cmd != nil {
-		spin.Suffix = "  Installing dependencies..."
-		spin.Start()
-		slog.Info("installing deps", "args", cmd.Args)
-		cmd.Run()
-		spin.Stop()
+	if index == 0 {
+		return nil, nil
 	}
-
-	spin.Stop()
-
-	color.New(color.FgGreen, color.Bold).Print("✓ ")
-	color.New(color.FgWhite).Println(" Success 🎉")
-	fmt.Println()
-	return nil
+	return &templates[index-1], nil
 }
 
 func fileContains(filePath string, str string) bool {
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/fatih/color"
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/project/provider"
+	"github.com/sst/ion/pkg/runtime"
+)
+
+func CmdInvoke(cli *Cli) error {
+	functionID := cli.Positional(0)
+	payload, err := readPayload(cli.String("payload"))
+	if err != nil {
+		return err
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	awsProvider, ok := p.Providers["aws"].(*provider.AwsProvider)
+	if !ok {
+		return util.NewReadableError(nil, "Invoking functions needs the \"aws\" provider")
+	}
+
+	if cli.Bool("remote") {
+		return invokeRemote(cli, p, awsProvider, functionID, payload)
+	}
+
+	metadata, err := p.Stack.Metadata()
+	if err != nil {
+		return util.NewReadableError(err, "Could not read the metadata of the stage")
+	}
+	warp, ok := metadata.Warps[functionID]
+	if !ok {
+		return util.NewReadableError(nil, fmt.Sprintf("Function \"%s\" was not found. Run `sst dev` once so it can be invoked locally, or pass in --remote.%s", functionID, suggestFunctions(metadata.Warps)))
+	}
+
+	providerEnv, err := awsProvider.Env()
+	if err != nil {
+		return util.NewReadableError(err, "Could not get AWS credentials")
+	}
+	providerEnv["SST_APP"] = p.App().Name
+	providerEnv["SST_STAGE"] = p.App().Stage
+	env, err := runtime.LocalEnv(warp, metadata.Links, metadata.Receivers, providerEnv)
+	if err != nil {
+		return util.NewReadableError(err, err.Error())
+	}
+	settings, _ := warp.Settings()
+
+	fmt.Fprintln(os.Stderr, color.New(color.FgHiBlack).Sprintf("Invoking %s locally...", functionID))
+	output, err := runtime.Invoke(cli.Context, &runtime.InvokeInput{
+		Project: p,
+		Warp:    warp,
+		Links:   metadata.Links,
+		Env:     env,
+		Payload: payload,
+		Timeout: settings.Timeout,
+		Logs:    os.Stderr,
+	})
+	if err != nil {
+		return util.NewReadableError(err, fmt.Sprintf("Could not invoke %s: %v", functionID, err))
+	}
+	if output.Error != nil {
+		for _, line := range output.Error.Trace {
+			fmt.Fprintln(os.Stderr, color.New(color.FgHiBlack).Sprint(line))
+		}
+		return util.NewReadableError(nil, fmt.Sprintf("%s failed: %s", functionID, output.Error.ErrorMessage))
+	}
+	printResponse(output.Response)
+	return nil
+}
+
+func invokeRemote(cli *Cli, p *project.Project, awsProvider *provider.AwsProvider, functionID string, payload []byte) error {
+	functions, err := p.Stack.Functions()
+	if err != nil {
+		return util.NewReadableError(err, "Could not read the state of the stage")
+	}
+	function, ok := functions[functionID]
+	if !ok || function.Name == "" {
+		return util.NewReadableError(nil, fmt.Sprintf("Function \"%s\" is not deployed to the \"%s\" stage", functionID, p.App().Stage))
+	}
+
+	config := awsProvider.Config().Copy()
+	if function.Region != "" {
+		config.Region = function.Region
+	}
+	fmt.Fprintln(os.Stderr, color.New(color.FgHiBlack).Sprintf("Invoking %s...", function.Name))
+	result, err := lambda.NewFromConfig(config).Invoke(cli.Context, &lambda.InvokeInput{
+		FunctionName: aws.String(function.Name),
+		Payload:      payload,
+		LogType:      types.LogTypeTail,
+	})
+	if err != nil {
+		return util.NewReadableError(err, fmt.Sprintf("Could not invoke %s: %v", functionID, err))
+	}
+	if result.LogResult != nil {
+		logs, _ := base64.StdEncoding.DecodeString(*result.LogResult)
+		fmt.Fprint(os.Stderr, string(logs))
+	}
+	if result.FunctionError != nil {
+		var invokeError runtime.InvokeError
+		json.Unmarshal(result.Payload, &invokeError)
+		if invokeError.ErrorMessage == "" {
+			invokeError.ErrorMessage = string(result.Payload)
+		}
+		return util.NewReadableError(nil, fmt.Sprintf("%s failed: %s", functionID, invokeError.ErrorMessage))
+	}
+	printResponse(result.Payload)
+	return nil
+}
+
+// readPayload reads the event from a file, or stdin if it's "-", and
+// defaults to an empty object.
+func readPayload(path string) ([]byte, error) {
+	if path == "" {
+		return []byte("{}"), nil
+	}
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, util.NewReadableError(err, fmt.Sprintf("Could not read the payload from \"%s\"", path))
+	}
+	if !json.Valid(data) {
+		return nil, util.NewReadableError(nil, fmt.Sprintf("The payload in \"%s\" is not valid JSON", path))
+	}
+	return data, nil
+}
+
+func printResponse(response []byte) {
+	if ui.Output() == ui.OutputJSON {
+		if json.Valid(response) {
+			ui.Result(json.RawMessage(response))
+		} else {
+			ui.Result(string(response))
+		}
+		return
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, response, "", "  "); err == nil {
+		response = indented.Bytes()
+	}
+	fmt.Println(strings.TrimSpace(string(response)))
+}
+
+func suggestFunctions(warps project.Warps) string {
+	if len(warps) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(warps))
+	for name := range warps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "\nFunctions: " + strings.Join(names, ", ")
+}
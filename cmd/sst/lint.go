@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+)
+
+var ErrLintFindings = fmt.Errorf("lint findings")
+
+func CmdLint(cli *Cli) error {
+	format := outputFormat(cli)
+	if format != "" && format != "json" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", must be: json", format))
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	findings, err := p.Stack.Lint()
+	if err != nil {
+		return util.NewReadableError(err, "Could not bundle the config: "+err.Error())
+	}
+
+	if format == "json" {
+		if err := printJSON(findings); err != nil {
+			return err
+		}
+	} else {
+		printLintFindings(findings)
+	}
+	if len(findings) > 0 {
+		return util.NewReadableError(ErrLintFindings, fmt.Sprintf("Found %d problem(s) in the config", len(findings)))
+	}
+	return nil
+}
+
+func printLintFindings(findings []project.LintFinding) {
+	for _, finding := range findings {
+		color.New(color.FgYellow, color.Bold).Printf("%-17s", finding.Rule)
+		if finding.Location != "" {
+			color.New(color.FgHiBlack).Print(finding.Location + "  ")
+		}
+		fmt.Println(finding.Message)
+	}
+}
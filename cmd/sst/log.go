@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sst/ion/internal/util"
+)
+
+// logFileCount is how many of the logs of the previous runs are kept next to
+// sst.log, as sst.1.log and so on.
+const logFileCount = 5
+
+// tempLogFile is where the logs go until the working directory is known
+var tempLogFile = logFile.Name()
+
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// configureLog points the logs at the log file, and stderr with --verbose.
+// The logger is set up even if the flags are invalid so the error is logged.
+func configureLog(cli *Cli) error {
+	var err error
+	level := slog.LevelInfo
+	if name := cli.String("log-level"); name != "" {
+		parsed, ok := logLevels[strings.ToLower(name)]
+		if ok {
+			level = parsed
+		} else {
+			err = util.NewReadableError(nil, fmt.Sprintf("Unknown log level \"%s\", must be one of: debug, info, warn, error", name))
+		}
+	}
+	writers := []io.Writer{logFile}
+	if cli.Bool("verbose") {
+		writers = append(writers, os.Stderr)
+	}
+	writer := io.MultiWriter(writers...)
+	options := &slog.HandlerOptions{
+		Level: level,
+	}
+	switch format := cli.String("log-format"); format {
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(writer, options)))
+	default:
+		if format != "" && format != "text" {
+			err = util.NewReadableError(nil, fmt.Sprintf("Unknown log format \"%s\", must be one of: text, json", format))
+		}
+		slog.SetDefault(slog.New(slog.NewTextHandler(writer, options)))
+	}
+	return err
+}
+
+// moveLogFile moves what's been logged so far to path and logs there from
+// now on. The logs in the working directory are rotated, unless sst dev is
+// running and still writing to them, otherwise it's appended to like a
+// --log-file.
+func moveLogFile(cli *Cli, path string, rotate bool) error {
+	if path == logFile.Name() {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if rotate {
+		rotateLogFiles(path)
+		flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	}
+	next, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := logFile.Seek(0, 0); err != nil {
+		return err
+	}
+	if _, err := io.Copy(next, logFile); err != nil {
+		return err
+	}
+	previous := logFile
+	logFile = next
+	if err := configureLog(cli); err != nil {
+		return err
+	}
+	previous.Close()
+	if previous.Name() == tempLogFile {
+		os.Remove(previous.Name())
+	}
+	return nil
+}
+
+// rotateLogFiles moves sst.log to sst.1.log, sst.1.log to sst.2.log, and so
+// on, dropping the oldest one.
+func rotateLogFiles(path string) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	rotated := func(index int) string {
+		if index == 0 {
+			return path
+		}
+		return fmt.Sprintf("%s.%d%s", base, index, ext)
+	}
+	os.Remove(rotated(logFileCount))
+	for index := logFileCount - 1; index >= 0; index-- {
+		os.Rename(rotated(index), rotated(index+1))
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
+	"github.com/sst/ion/pkg/server/dev/aws"
+)
+
+func CmdLogs(cli *Cli) error {
+	functionID := cli.Positional(0)
+	since := 10 * time.Minute
+	if value := cli.String("since"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return util.NewReadableError(err, fmt.Sprintf("Invalid duration \"%s\", use something like 30s, 10m, or 2h", value))
+		}
+		since = parsed
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	awsProvider, ok := p.Providers["aws"].(*provider.AwsProvider)
+	if !ok {
+		return util.NewReadableError(nil, "Reading logs needs the \"aws\" provider")
+	}
+	functions, err := p.Stack.Functions()
+	if err != nil {
+		return util.NewReadableError(err, "Could not read the state of the stage")
+	}
+	function, ok := functions[functionID]
+	if !ok || function.LogGroup == "" {
+		names := make([]string, 0, len(functions))
+		for name := range functions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		suggestion := ""
+		if len(names) > 0 {
+			suggestion = "\nFunctions: " + strings.Join(names, ", ")
+		}
+		return util.NewReadableError(nil, fmt.Sprintf("Function \"%s\" is not deployed to the \"%s\" stage%s", functionID, p.App().Stage, suggestion))
+	}
+
+	config := awsProvider.Config().Copy()
+	if function.Region != "" {
+		config.Region = function.Region
+	}
+	asJSON := ui.Output() == ui.OutputJSON
+	follow := cli.Bool("follow")
+	reader := aws.NewLogReader(config, function.LogGroup, cli.String("filter"), time.Now().Add(-since))
+	if !asJSON {
+		fmt.Fprintln(os.Stderr, color.New(color.FgHiBlack).Sprintf("Reading %s", function.LogGroup))
+	}
+	for {
+		entries, err := reader.Read(cli.Context)
+		if err != nil && cli.Context.Err() != nil {
+			return nil
+		}
+		// the group doesn't exist until the function logs something
+		if errors.Is(err, aws.ErrLogGroupNotFound) && !follow {
+			return util.NewReadableError(err, fmt.Sprintf("%s has not logged anything yet", functionID))
+		}
+		if err != nil && !errors.Is(err, aws.ErrLogGroupNotFound) {
+			return util.NewReadableError(err, fmt.Sprintf("Could not read the logs of %s: %v", functionID, err))
+		}
+		for _, entry := range entries {
+			printLogEntry(entry, asJSON)
+		}
+		if !follow {
+			return nil
+		}
+		select {
+		case <-cli.Context.Done():
+			return nil
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+var logLevelColors = map[string]color.Attribute{
+	aws.LogLevelDebug: color.FgHiBlack,
+	aws.LogLevelInfo:  color.FgBlue,
+	aws.LogLevelWarn:  color.FgYellow,
+	aws.LogLevelError: color.FgRed,
+}
+
+func printLogEntry(entry aws.LogEntry, asJSON bool) {
+	if asJSON {
+		ui.WriteJSON("LogEntry", entry)
+		return
+	}
+	prefix := color.New(color.FgHiBlack).Sprint(entry.Timestamp.Local().Format("15:04:05.000"))
+	if entry.RequestID != "" {
+		prefix += " " + color.New(color.FgHiBlack).Sprint(entry.RequestID[:8])
+	}
+	if entry.Level != "" {
+		prefix += " " + color.New(logLevelColors[entry.Level], color.Bold).Sprintf("%-5s", strings.ToUpper(entry.Level))
+	}
+	for _, line := range strings.Split(entry.Message, "\n") {
+		fmt.Println(prefix + " " + line)
+	}
+}
@@ -3,20 +3,19 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	flag "github.com/spf13/pflag"
-	"io"
 	"log/slog"
 	"os"
 	"os/exec"
 	"os/signal"
 	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
-	"time"
 
-	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/joho/godotenv"
 	"github.com/sst/ion/cmd/sst/ui"
@@ -31,7 +30,7 @@ import (
 var version = "dev"
 
 var logFile = (func() *os.File {
-	logFile, err := os.CreateTemp("", "sst-*.log")
+	logFile, err := os.CreateTemp(os.Getenv("SST_TEMP_DIR"), "sst-*.log")
 	if err != nil {
 		panic(err)
 	}
@@ -39,6 +38,11 @@ var logFile = (func() *os.File {
 })()
 
 func main() {
+	// the shell runs this on every tab, it's not tracked
+	if len(os.Args) > 1 && os.Args[1] == "__complete" {
+		complete(os.Args[2:])
+		return
+	}
 	telemetry.SetVersion(version)
 	defer telemetry.Close()
 	telemetry.Track("cli.start", map[string]interface{}{
@@ -60,9 +64,15 @@ func main() {
 			if msg != "" {
 				ui.Error(readableErr.Error())
 			}
+		} else if ui.Output() != ui.OutputPretty {
+			// the logs are gone once a CI job is done
+			ui.Error("Unexpected error occurred: " + err.Error())
 		} else {
 			ui.Error("Unexpected error occurred. Please check the logs or run with --verbose for more details.")
 		}
+		if errors.Is(err, ErrNeedsInput) {
+			os.Exit(exitNeedsInput)
+		}
 		os.Exit(1)
 	}
 	telemetry.Track("cli.success", map[string]interface{}{})
@@ -110,16 +120,49 @@ func run() error {
 	cli := &Cli{
 		flags:     parsedFlags,
 		arguments: positionals,
+		dashed:    flag.CommandLine.ArgsLenAtDash() != -1,
 		path:      cmds,
 		Context:   ctx,
 		cancel:    cancel,
 	}
-	configureLog(cli)
+	if err := configureLog(cli); err != nil {
+		return err
+	}
+	if path := cli.String("log-file"); path != "" {
+		if err := moveLogFile(cli, path, false); err != nil {
+			return util.NewReadableError(err, "Could not create log file")
+		}
+	}
+	if err := applyUserConfig(cli); err != nil {
+		return err
+	}
+	if cli.Bool("json") {
+		ui.SetOutput(ui.OutputJSON)
+	} else if cli.CI() {
+		ui.SetOutput(ui.OutputPlain)
+	}
 	if cliParseError != nil {
 		return cli.PrintHelp()
 	}
 
-	spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+	// the toolchain declared in the project decides which pulumi and node
+	// are used, so it's resolved before checking for them. Without it the
+	// ones on the PATH are used.
+	if cfgPath, err := project.Discover(); err == nil {
+		if err := useToolchain(cfgPath); err != nil {
+			slog.Warn("failed to resolve toolchain", "err", err)
+			ui.Warn(TransformError(err).Error() + ". Using the tools on the PATH for now.")
+		}
+		target, err := pinnedVersion(cli, project.LoadPinnedVersion(cfgPath))
+		if err != nil {
+			return err
+		}
+		if target != "" {
+			return switchVersion(target)
+		}
+	}
+
+	spin := ui.NewSpinner()
 	spin.Suffix = "  First run, setting up environment..."
 	if global.NeedsPulumi() {
 		spin.Start()
@@ -184,8 +227,9 @@ var Root = Command{
 	},
 	Flags: []Flag{
 		{
-			Name: "stage",
-			Type: "string",
+			Name:     "stage",
+			Complete: CompleteStage,
+			Type:     "string",
 			Description: Description{
 				Short: "The stage to deploy to",
 				Long: strings.Join([]string{
@@ -204,6 +248,8 @@ var Root = Command{
 					":::tip",
 					"The stage that is stored in the `.sst/stage` file is called your personal stage.",
 					":::",
+					"",
+					"If the app sets `branchStage` in its config, the stage is derived from the current git branch instead, and your personal stage is only used for the branches that are mapped to it.",
 				}, "\n"),
 			},
 		},
@@ -223,6 +269,121 @@ var Root = Command{
 				}, "\n"),
 			},
 		},
+		{
+			Name: "log-level",
+			Type: "string",
+			Description: Description{
+				Short: "The level of the logs",
+				Long: strings.Join([]string{
+					"The level of the logs that are written, one of `debug`, `info`, `warn`, or `error`. Defaults to `info`.",
+					"",
+					"```bash frame=\"none\"",
+					"sst deploy --log-level=debug --verbose",
+					"```",
+				}, "\n"),
+			},
+		},
+		{
+			Name: "log-format",
+			Type: "string",
+			Description: Description{
+				Short: "The format of the logs",
+				Long: strings.Join([]string{
+					"The format of the logs, `text` or `json`. Defaults to `text`.",
+					"",
+					"With `json` each log is a JSON object on its own line, with its `time`, `level`, `msg`, and attributes.",
+				}, "\n"),
+			},
+		},
+		{
+			Name: "log-file",
+			Type: "string",
+			Description: Description{
+				Short: "Write the logs to a file",
+				Long: strings.Join([]string{
+					"Append the logs to this file.",
+					"",
+					"```bash frame=\"none\"",
+					"sst deploy --stage=production --log-file=deploy.log --log-format=json",
+					"```",
+					"",
+					"Otherwise they are written to `.sst/log/sst.log`. The logs of the last 5 runs are kept next to it as `sst.1.log`, `sst.2.log`, and so on.",
+				}, "\n"),
+			},
+		},
+		{
+			Name: "allow-reserved",
+			Type: "bool",
+			Description: Description{
+				Short: "Allow changing a reserved stage",
+				Long: strings.Join([]string{
+					"Allow deploying, removing, or renaming a stage that's in the `reservedStages` of the config.",
+					"",
+					"```bash frame=\"none\"",
+					"sst deploy --stage=production --allow-reserved",
+					"```",
+				}, "\n"),
+			},
+		},
+		{
+			Name: "ci",
+			Type: "bool",
+			Description: Description{
+				Short: "Run non-interactively",
+				Long: strings.Join([]string{
+					"Run without prompts, colors, or a spinner. This is turned on when the CLI detects it's running in CI, set `CI=false` to turn it off.",
+					"",
+					"```bash frame=\"none\"",
+					"sst deploy --stage=production --ci",
+					"```",
+					"",
+					"Anything that would prompt fails with exit code `2` instead, and tells you the flag to pass in. Like `--stage` if there's no stage, or `--yes` to confirm.",
+				}, "\n"),
+			},
+		},
+		{
+			Name: "yes",
+			Type: "bool",
+			Description: Description{
+				Short: "Answer yes to confirmations",
+				Long: strings.Join([]string{
+					"Answer yes to the confirmation prompts, they fail in CI otherwise.",
+					"",
+					"```bash frame=\"none\"",
+					"sst secret list --show-values --yes",
+					"```",
+				}, "\n"),
+			},
+		},
+		{
+			Name: "json",
+			Type: "bool",
+			Description: Description{
+				Short: "Print the output as JSON",
+				Long: strings.Join([]string{
+					"Print the output of any command as newline delimited JSON, one object per line.",
+					"",
+					"```bash frame=\"none\"",
+					"sst deploy --stage=production --json",
+					"```",
+					"",
+					"Each line has the `type` and the `event` itself.",
+					"",
+					"```json",
+					"{\"type\":\"Header\",\"event\":{\"app\":\"my-app\",\"mode\":\"deploy\",\"stage\":\"production\",\"version\":\"0.1.0\"}}",
+					"```",
+					"",
+					"- `Header`, the app and stage a command runs on.",
+					"- The events of a deploy, diff, remove, or `sst dev`, like `EngineEvent`, `StdOutEvent`, or `CompleteEvent`.",
+					"- `LogEntry`, a log of a function with `sst logs`.",
+					"- `Success`, the `message` of a command that's done.",
+					"- `Result`, what a command returns. Like the secrets with `sst secret list` or the changes with `sst diff`.",
+					"- `Error`, the `message` of a command that failed. It's the last line.",
+					"",
+					"Fields are only added to these, the ones that are there don't change. The commands with a `--format` use `json` with this.",
+				}, "\n"),
+			},
+		},
 		{
 			Name: "help",
 			Type: "bool",
@@ -253,8 +414,50 @@ var Root = Command{
 					"Initialize a new project in the current directory. This will create a `sst.config.ts` and `sst install` your providers.",
 					"",
 					"If this is run in a Next.js, Remix, or Astro project, it'll init SST in drop-in mode.",
+					"",
+					"Otherwise you can start from a template in the gallery, like a monorepo, a Next.js app, or an API with a queue.",
+					"",
+					"```bash frame=\"none\"",
+					"sst init --template monorepo --name my-app",
+					"```",
+					"",
+					"The templates are listed in a registry that's fetched when you run this. Use `--registry` or `SST_TEMPLATE_REGISTRY` to use your own, a URL or a path to a JSON file.",
 				}, "\n"),
 			},
+			Flags: []Flag{
+				{
+					Name: "template",
+					Type: "string",
+					Description: Description{
+						Short: "The template to start from",
+						Long:  "The template to start from, one of the built in ones or one from the registry.",
+					},
+				},
+				{
+					Name: "name",
+					Type: "string",
+					Description: Description{
+						Short: "The name of the app",
+						Long:  "The name of the app. Defaults to the name of the current directory.",
+					},
+				},
+				{
+					Name: "home",
+					Type: "string",
+					Description: Description{
+						Short: "Where the app is deployed, aws or cloudflare",
+						Long:  "Where the app is deployed and its state is stored, `aws` or `cloudflare`.",
+					},
+				},
+				{
+					Name: "registry",
+					Type: "string",
+					Description: Description{
+						Short: "The template registry to use",
+						Long:  "The template registry to use, a URL or a path to a JSON file.",
+					},
+				},
+			},
 			Run: CmdInit,
 		},
 		{
@@ -280,6 +483,14 @@ var Root = Command{
 					"sst dev \"next dev --turbo\"",
 					"```",
 					"",
+					"Or pass it in after `--`, the arguments are then passed to your command as is.",
+					"",
+					"```bash frame=\"none\"",
+					"sst dev -- next dev --turbo",
+					"```",
+					"",
+					"If the command isn't run from the directory of a site, it gets all the linked resources.",
+					"",
 					"Dev mode does a few things:",
 					"",
 					"1. Starts a local server",
@@ -302,6 +513,32 @@ var Root = Command{
 					"Starting multiple instances of `sst dev` in the same project only starts a single _server_. Meaning that the second instance connects to the existing one.",
 					"",
 					"This is different from SST v2, in that you needed to run `sst dev` and `sst bind` for your frontend.",
+					"",
+					"Crons run on your machine on their schedule while `sst dev` is running. To run one right away, trigger it from another terminal.",
+					"",
+					"```bash frame=\"none\"",
+					"sst dev --trigger cron MyCron",
+					"```",
+					"",
+					"To debug your Node.js functions, start it with `--inspect` and attach a debugger to the URL it prints for each function. This only applies if the `sst dev` server isn't already running.",
+					"",
+					"```bash frame=\"none\"",
+					"sst dev --inspect",
+					"```",
+					"",
+					"If someone else is running `sst dev` on the same stage, you'll be told who it is. Instead of deploying over their changes, you can attach to it. This shows the links of their last deploy and runs your command with them, without deploying or running the functions.",
+					"",
+					"```bash frame=\"none\"",
+					"sst dev --attach next dev",
+					"```",
+					"",
+					"The logs of the functions are shown as they run. The ones with `live: false` run in AWS, their logs are tailed from CloudWatch. Use `--filter` and `--level` to narrow them down.",
+					"",
+					"```bash frame=\"none\"",
+					"sst dev --filter MyApi --level warn",
+					"```",
+					"",
+					"The invocations of the functions are counted while `sst dev` is running. The ones that were invoked are summed up every 30 seconds, with their average and max duration, cold starts, and error rate. The server also serves them as JSON at `/metrics`.",
 				}, "\n"),
 			},
 			Args: []Argument{
@@ -312,6 +549,48 @@ var Root = Command{
 					},
 				},
 			},
+			Flags: []Flag{
+				{
+					Name: "trigger",
+					Type: "string",
+					Description: Description{
+						Short: "Fire a cron in the running sst dev",
+						Long:  "Fire something in the `sst dev` that's already running, instead of starting it. Pass in `cron` and the names of the crons to run them now.",
+					},
+				},
+				{
+					Name: "inspect",
+					Type: "bool",
+					Description: Description{
+						Short: "Run Node.js functions with the inspector enabled",
+						Long:  "Start the Node.js functions that run on your machine with `--inspect`, so a debugger can attach and stop on breakpoints. The inspector URL of each worker is printed as it starts.",
+					},
+				},
+				{
+					Name: "attach",
+					Type: "bool",
+					Description: Description{
+						Short: "Attach to the dev session running on the stage",
+						Long:  "Attach to the `sst dev` someone else is running on the stage, read-only. Your app is not deployed and your functions don't run on your machine, the links are the ones from their last deploy.",
+					},
+				},
+				{
+					Name: "filter",
+					Type: "string",
+					Description: Description{
+						Short: "Only show the function logs that match",
+						Long:  "Only show the function logs where the line or the name of the function matches the regular expression.",
+					},
+				},
+				{
+					Name: "level",
+					Type: "string",
+					Description: Description{
+						Short: "The lowest level of function logs to show",
+						Long:  "The lowest level of function logs to show, `debug`, `info`, `warn`, or `error`. The level of a line comes from CloudWatch, or a leading level or JSON `level` field for the ones that run locally.",
+					},
+				},
+			},
 			Examples: []Example{
 				{
 					Content: "sst dev",
@@ -348,6 +627,32 @@ var Root = Command{
 					"```",
 				}, "\n"),
 			},
+			Flags: []Flag{
+				{
+					Name: "strict",
+					Type: "bool",
+					Description: Description{
+						Short: "Fail if the build has warnings",
+						Long:  "Stops the deploy before it starts if bundling the config reported warnings, like an import that couldn't be resolved. Useful in CI.",
+					},
+				},
+				{
+					Name: "typecheck",
+					Type: "bool",
+					Description: Description{
+						Short: "Typecheck the config first",
+						Long:  "Runs `tsc` over your `sst.config.ts` and the files it imports before deploying, and stops if there are type errors. Set `typecheck` in your config to always do this.",
+					},
+				},
+				{
+					Name: "hotswap",
+					Type: "bool",
+					Description: Description{
+						Short: "Update only the code of functions if that's all that changed",
+						Long:  "If only the code of your Node.js functions changed since the last deploy from this machine, rebuilds them and uploads their code directly, skipping the full deploy. Falls back to a full deploy if anything else changed. Meant for development stages, the state isn't updated.",
+					},
+				},
+			},
 			Examples: []Example{
 				{
 					Content: "sst deploy --stage=production",
@@ -367,8 +672,11 @@ var Root = Command{
 				defer ui.Destroy()
 				ui.Header(version, p.App().Name, p.App().Stage)
 				err = p.Stack.Run(cli.Context, &project.StackInput{
-					Command: "up",
-					OnEvent: ui.Trigger,
+					Command:        "up",
+					OnEvent:        ui.Trigger,
+					FailOnWarnings: cli.Bool("strict"),
+					Typecheck:      cli.Bool("typecheck"),
+					Hotswap:        cli.Bool("hotswap"),
 				})
 				if err != nil {
 					return err
@@ -376,6 +684,84 @@ var Root = Command{
 				return nil
 			},
 		},
+		{
+			Name: "diff",
+			Description: Description{
+				Short: "See what a deploy would change",
+				Long: strings.Join([]string{
+					"Compare your app to what's deployed and list the changes a deploy would make, without making them.",
+					"",
+					"```bash frame=\"none\"",
+					"sst diff --stage=production",
+					"```",
+					"",
+					"The resources that would be created, updated, replaced, or removed are grouped by the component they are in. For the ones that change, the properties are listed with their old and new values. Secrets are not printed and values that are only known after the deploy are shown as `[unknown]`.",
+					"",
+					"Pass in `--json` to get the changes as JSON, for example to annotate a pull request in CI. They are in the `Result` line, grouped by component.",
+				}, "\n"),
+			},
+			Examples: []Example{
+				{
+					Content: "sst diff --stage=production",
+					Description: Description{
+						Short: "See what deploying to production would change",
+					},
+				},
+				{
+					Content: "sst diff --json > diff.json",
+					Description: Description{
+						Short: "Save the changes for a CI bot",
+					},
+				},
+			},
+			Run: CmdDiff,
+		},
+		{
+			Name: "outputs",
+			Description: Description{
+				Short: "Print the outputs of the stage",
+				Long: strings.Join([]string{
+					"Print the outputs your app returned in its last deploy to the stage, without deploying it.",
+					"",
+					"```bash frame=\"none\"",
+					"sst outputs --stage=production",
+					"```",
+					"",
+					"They are printed as JSON by default. Use `--format` to print them as `dotenv` or `export`, to load them in a script.",
+					"",
+					"```bash frame=\"none\"",
+					"eval \"$(sst outputs --format=export)\"",
+					"```",
+					"",
+					"For `dotenv` and `export`, the characters that aren't allowed in a variable name are replaced with `_` and the values that aren't strings are printed as JSON. Secret outputs are printed as `[secret]`.",
+				}, "\n"),
+			},
+			Flags: []Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: Description{
+						Short: "The output format, json, dotenv, or export",
+						Long:  "The output format, `json`, `dotenv`, or `export`. Defaults to `json`.",
+					},
+				},
+			},
+			Examples: []Example{
+				{
+					Content: "sst outputs --stage=production",
+					Description: Description{
+						Short: "Print the outputs of production as JSON",
+					},
+				},
+				{
+					Content: "sst outputs --format=dotenv > .env.outputs",
+					Description: Description{
+						Short: "Save the outputs to a dotenv file",
+					},
+				},
+			},
+			Run: CmdOutputs,
+		},
 		{
 			Name: "add",
 			Description: Description{
@@ -416,7 +802,9 @@ var Root = Command{
 			},
 			Run: func(cli *Cli) error {
 				pkg := cli.Positional(0)
-				fmt.Println("Adding provider", pkg+"...")
+				if ui.Output() != ui.OutputJSON {
+					fmt.Println("Adding provider", pkg+"...")
+				}
 				cfgPath, err := project.Discover()
 				if err != nil {
 					return err
@@ -429,6 +817,7 @@ var Root = Command{
 					Version: version,
 					Config:  cfgPath,
 					Stage:   stage,
+					Output:  projectOutput(),
 				})
 				if err != nil {
 					return err
@@ -448,6 +837,7 @@ var Root = Command{
 					Version: version,
 					Config:  cfgPath,
 					Stage:   stage,
+					Output:  projectOutput(),
 				})
 				if err != nil {
 					return err
@@ -491,12 +881,13 @@ var Root = Command{
 					Version: version,
 					Config:  cfgPath,
 					Stage:   stage,
+					Output:  projectOutput(),
 				})
 				if err != nil {
 					return err
 				}
 
-				spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+				spin := ui.NewSpinner()
 				defer spin.Stop()
 				spin.Suffix = "  Installing providers..."
 				spin.Start()
@@ -517,25 +908,84 @@ var Root = Command{
 			},
 		},
 		{
-			Name: "secret",
+			Name: "bundle-deps",
 			Description: Description{
-				Short: "Manage secrets",
-				Long:  "Manage the secrets in your app defined with `sst.Secret`.",
+				Short: "Cache the dependencies for offline use",
+				Long: strings.Join([]string{
+					"Installs the providers, downloads the Pulumi plugins they need, and saves them to a cache in the sst config directory.",
+					"",
+					"Once the cache is warm, installing the providers and deploying doesn't need the network. The cache is keyed by the version of sst and the providers, and can be copied to another machine with the rest of the config directory, like `~/.config/sst` on Linux.",
+					"",
+					"```bash frame=\"none\"",
+					"sst bundle-deps",
+					"```",
+				}, "\n"),
 			},
-			Children: []*Command{
-				{
-					Name: "set",
-					Description: Description{
-						Short: "Set a secret",
-						Long: strings.Join([]string{
-							"Set the value of the secret.",
-							"",
-							"The secrets are encrypted and stored in an S3 Bucket in your AWS account.",
-							"",
-							"For example, set the `sst.Secret` called `StripeSecret` to `123456789`.",
-							"",
-							"```bash frame=\"none\"",
-							"sst secret set StripeSecret dev_123456789",
+			Run: func(cli *Cli) error {
+				cfgPath, err := project.Discover()
+				if err != nil {
+					return err
+				}
+
+				stage, err := getStage(cli, cfgPath)
+				if err != nil {
+					return err
+				}
+
+				p, err := project.New(&project.ProjectConfig{
+					Version: version,
+					Config:  cfgPath,
+					Stage:   stage,
+					Output:  projectOutput(),
+				})
+				if err != nil {
+					return err
+				}
+
+				spin := ui.NewSpinner()
+				defer spin.Stop()
+				spin.Suffix = "  Bundling dependencies..."
+				spin.Start()
+				if !p.CheckPlatform(version) {
+					err := p.CopyPlatform(version)
+					if err != nil {
+						return err
+					}
+				}
+
+				err = p.BundleDeps(func(event *project.InstallEvent) {
+					if event.Status == "installing" {
+						spin.Suffix = "  Installing " + event.Package + "..."
+					}
+				})
+				if err != nil {
+					return util.NewReadableError(err, "Could not bundle the dependencies: "+err.Error())
+				}
+				spin.Stop()
+				ui.Success("Bundled dependencies for offline use")
+				return nil
+			},
+		},
+		{
+			Name: "secret",
+			Description: Description{
+				Short: "Manage secrets",
+				Long:  "Manage the secrets in your app defined with `sst.Secret`.",
+			},
+			Children: []*Command{
+				{
+					Name: "set",
+					Description: Description{
+						Short: "Set a secret",
+						Long: strings.Join([]string{
+							"Set the value of the secret.",
+							"",
+							"The secrets are encrypted and stored in an S3 Bucket in your AWS account.",
+							"",
+							"For example, set the `sst.Secret` called `StripeSecret` to `123456789`.",
+							"",
+							"```bash frame=\"none\"",
+							"sst secret set StripeSecret dev_123456789",
 							"```",
 							"",
 							"Optionally, set the secret in a specific stage.",
@@ -543,11 +993,37 @@ var Root = Command{
 							"```bash frame=\"none\"",
 							"sst secret set StripeSecret prod_123456789 --stage=production",
 							"```",
+							"",
+							"Secrets set in the `_fallback` stage are shared by every stage that doesn't set its own value. This is useful for ephemeral stages, like the ones for pull requests.",
+							"",
+							"```bash frame=\"none\"",
+							"sst secret set StripeSecret dev_123456789 --stage=_fallback",
+							"```",
+							"",
+							"Values that are awkward to quote in a shell, like certificates or keys, can be read from a file or from stdin. Multiline values are kept as is and binary values are stored base64 encoded.",
+							"",
+							"```bash frame=\"none\"",
+							"sst secret set Certificate --from-file cert.pem",
+							"cat key.der | sst secret set PrivateKey --stdin",
+							"```",
+							"",
+							"Secrets can be described, given an owner, and a rotation deadline. Deploys warn when they use a secret that is past its deadline. Leave out the value to only update these.",
+							"",
+							"```bash frame=\"none\"",
+							"sst secret set StripeSecret --description \"Stripe API key\" --owner payments --rotate-by 90d",
+							"```",
+							"",
+							"Short-lived secrets, like tokens handed out during an incident, can be set to expire. Once expired they are no longer passed to your app and deploys warn about them. Setting a new value clears the expiry.",
+							"",
+							"```bash frame=\"none\"",
+							"sst secret set IncidentToken abc123 --expires 4h",
+							"```",
 						}, "\n"),
 					},
 					Args: []Argument{
 						{
 							Name:     "name",
+							Complete: CompleteSecret,
 							Required: true,
 							Description: Description{
 								Short: "The name of the secret",
@@ -555,11 +1031,60 @@ var Root = Command{
 							},
 						},
 						{
-							Name:     "value",
-							Required: true,
+							Name: "value",
 							Description: Description{
 								Short: "The value of the secret",
-								Long:  "The value of the secret.",
+								Long:  "The value of the secret. Not needed with `--from-file` or `--stdin`.",
+							},
+						},
+					},
+					Flags: []Flag{
+						{
+							Name: "from-file",
+							Type: "string",
+							Description: Description{
+								Short: "Read the value from a file",
+								Long:  "Read the value from a file, it's stored exactly as is.",
+							},
+						},
+						{
+							Name: "stdin",
+							Type: "bool",
+							Description: Description{
+								Short: "Read the value from stdin",
+								Long:  "Read the value from stdin, it's stored exactly as is.",
+							},
+						},
+						{
+							Name: "description",
+							Type: "string",
+							Description: Description{
+								Short: "Describe what the secret is for",
+								Long:  "Describe what the secret is for.",
+							},
+						},
+						{
+							Name: "owner",
+							Type: "string",
+							Description: Description{
+								Short: "Who is responsible for the secret",
+								Long:  "Who is responsible for the secret, like a person or a team.",
+							},
+						},
+						{
+							Name: "rotate-by",
+							Type: "string",
+							Description: Description{
+								Short: "When the secret needs to be rotated",
+								Long:  "When the secret needs to be rotated. Either a date, `2025-01-31`, or an interval in days, `90d`, that restarts every time the value is set.",
+							},
+						},
+						{
+							Name: "expires",
+							Type: "string",
+							Description: Description{
+								Short: "When the secret expires",
+								Long:  "When the secret expires. Either a duration from now, `4h` or `7d`, or a date, `2025-01-31`.",
 							},
 						},
 					},
@@ -576,28 +1101,14 @@ var Root = Command{
 								Short: "Set the StripeSecret in production",
 							},
 						},
+						{
+							Content: "sst secret set Certificate --from-file cert.pem",
+							Description: Description{
+								Short: "Set the Certificate to the contents of cert.pem",
+							},
+						},
 					},
-					Run: func(cli *Cli) error {
-						key := cli.Positional(0)
-						value := cli.Positional(1)
-						p, err := initProject(cli)
-						if err != nil {
-							return err
-						}
-						defer p.Cleanup()
-						backend := p.Backend()
-						secrets, err := provider.GetSecrets(backend, p.App().Name, p.App().Stage)
-						if err != nil {
-							return util.NewReadableError(err, "Could not get secrets")
-						}
-						secrets[key] = value
-						err = provider.PutSecrets(backend, p.App().Name, p.App().Stage, secrets)
-						if err != nil {
-							return util.NewReadableError(err, "Could not set secret")
-						}
-						ui.Success(fmt.Sprintf("Set \"%s\" for stage \"%s\"", key, p.App().Stage))
-						return nil
-					},
+					Run: CmdSecretSet,
 				},
 				{
 					Name: "remove",
@@ -622,6 +1133,7 @@ var Root = Command{
 					Args: []Argument{
 						{
 							Name:     "name",
+							Complete: CompleteSecret,
 							Required: true,
 							Description: Description{
 								Short: "The name of the secret",
@@ -651,7 +1163,7 @@ var Root = Command{
 						}
 						defer p.Cleanup()
 						backend := p.Backend()
-						secrets, err := provider.GetSecrets(backend, p.App().Name, p.App().Stage)
+						secrets, err := provider.GetStageSecrets(backend, p.App().Name, p.App().Stage)
 						if err != nil {
 							return util.NewReadableError(err, "Could not get secrets")
 						}
@@ -666,6 +1178,17 @@ var Root = Command{
 						if err != nil {
 							return util.NewReadableError(err, "Could not set secret")
 						}
+						metadata, err := provider.GetStageSecretMetadata(backend, p.App().Name, p.App().Stage)
+						if err != nil {
+							return util.NewReadableError(err, "Could not get secret metadata")
+						}
+						if _, ok := metadata[key]; ok {
+							delete(metadata, key)
+							err = provider.PutSecretMetadata(backend, p.App().Name, p.App().Stage, metadata)
+							if err != nil {
+								return util.NewReadableError(err, "Could not remove secret metadata")
+							}
+						}
 						ui.Success(fmt.Sprintf("Removed \"%s\" for stage \"%s\"", key, p.App().Stage))
 						return nil
 					},
@@ -682,8 +1205,34 @@ var Root = Command{
 							"```bash frame=\"none\" frame=\"none\"",
 							"sst secret list --stage=production",
 							"```",
+							"",
+							"The values are redacted by default. Pass in `--show-values` to print them, you'll be asked to confirm first. The description, owner, and rotation deadline of each secret are printed next to it.",
+							"",
+							"Use `--format` to print the secrets as `json` or `dotenv`, this is useful when migrating secrets between stages.",
+							"",
+							"```bash frame=\"none\" frame=\"none\"",
+							"sst secret list --format=dotenv --show-values > .env.production",
+							"```",
 						}, "\n"),
 					},
+					Flags: []Flag{
+						{
+							Name: "format",
+							Type: "string",
+							Description: Description{
+								Short: "The output format, json or dotenv",
+								Long:  "The output format, `json` or `dotenv`.",
+							},
+						},
+						{
+							Name: "show-values",
+							Type: "bool",
+							Description: Description{
+								Short: "Print the secret values",
+								Long:  "Print the decrypted secret values instead of redacting them.",
+							},
+						},
+					},
 					Examples: []Example{
 						{
 							Content: "sst secret list --stage=production",
@@ -691,23 +1240,138 @@ var Root = Command{
 								Short: "List the secrets in production",
 							},
 						},
+						{
+							Content: "sst secret list --format=json --show-values",
+							Description: Description{
+								Short: "Export the secrets as JSON",
+							},
+						},
 					},
-					Run: func(cli *Cli) error {
-						p, err := initProject(cli)
-						if err != nil {
-							return err
-						}
-						defer p.Cleanup()
-
-						backend := p.Backend()
-						secrets, err := provider.GetSecrets(backend, p.App().Name, p.App().Stage)
-						if err != nil {
-							return util.NewReadableError(err, "Could not get secrets")
-						}
-						for key, value := range secrets {
-							fmt.Println(key, "=", value)
-						}
-						return nil
+					Run: CmdSecretList,
+				},
+				{
+					Name: "diff",
+					Description: Description{
+						Short: "Compare secrets between stages",
+						Long: strings.Join([]string{
+							"Compare the secrets of two stages and report the ones that are missing in either.",
+							"",
+							"```bash frame=\"none\" frame=\"none\"",
+							"sst secret diff staging production",
+							"```",
+							"",
+							"Pass in `--values` to also report secrets that are set to different values. Only a short hash of each value is printed, keyed with the passphrase of the first stage so it can't be used to guess the value.",
+							"",
+							"It exits with an error if the stages differ, so you can run it in CI before deploying.",
+						}, "\n"),
+					},
+					Args: []Argument{
+						{
+							Name:     "stageA",
+							Complete: CompleteStage,
+							Required: true,
+							Description: Description{
+								Short: "The stage to compare",
+								Long:  "The stage to compare.",
+							},
+						},
+						{
+							Name:     "stageB",
+							Complete: CompleteStage,
+							Required: true,
+							Description: Description{
+								Short: "The stage to compare against",
+								Long:  "The stage to compare against.",
+							},
+						},
+					},
+					Flags: []Flag{
+						{
+							Name: "values",
+							Type: "bool",
+							Description: Description{
+								Short: "Compare value hashes",
+								Long:  "Also compare the hashes of the secret values.",
+							},
+						},
+					},
+					Examples: []Example{
+						{
+							Content: "sst secret diff staging production",
+							Description: Description{
+								Short: "Find secrets missing in staging or production",
+							},
+						},
+						{
+							Content: "sst secret diff staging production --values",
+							Description: Description{
+								Short: "Also find secrets with different values",
+							},
+						},
+					},
+					Run: CmdSecretDiff,
+				},
+				{
+					Name: "sync",
+					Description: Description{
+						Short: "Sync secrets to another service",
+						Long:  "Push the secrets of a stage to another service so they stay in lockstep with SST.",
+					},
+					Children: []*Command{
+						{
+							Name: "github",
+							Description: Description{
+								Short: "Sync secrets to GitHub Actions",
+								Long: strings.Join([]string{
+									"Push the secrets of a stage to the secrets of a GitHub Actions environment.",
+									"",
+									"```bash frame=\"none\"",
+									"sst secret sync github --repo=acme/website --stage=production",
+									"```",
+									"",
+									"The environment is named after the stage and is created if it doesn't exist. Use `--environment` to sync to a different one.",
+									"",
+									"This needs a token in `GITHUB_TOKEN` that can manage the environments and secrets of the repository. GitHub uppercases the names, so `StripeSecret` is available as `secrets.STRIPESECRET` in your workflows.",
+									"",
+									"Secrets that are set in the environment but not in SST are kept. Pass in `--prune` to remove them.",
+								}, "\n"),
+							},
+							Flags: []Flag{
+								{
+									Name: "repo",
+									Type: "string",
+									Description: Description{
+										Short: "The repository, org/name",
+										Long:  "The GitHub repository to sync to, as `org/name`.",
+									},
+								},
+								{
+									Name: "environment",
+									Type: "string",
+									Description: Description{
+										Short: "The GitHub environment, defaults to the stage",
+										Long:  "The GitHub Actions environment to sync to. Defaults to the name of the stage.",
+									},
+								},
+								{
+									Name: "prune",
+									Type: "bool",
+									Description: Description{
+										Short: "Remove secrets that are not in SST",
+										Long:  "Remove the secrets in the environment that are not set in SST.",
+									},
+								},
+							},
+							Examples: []Example{
+								{
+									Content: "sst secret sync github --repo=acme/website --stage=production",
+									Description: Description{
+										Short: "Sync the production secrets",
+									},
+								},
+							},
+							Run: CmdSecretSyncGithub,
+						},
 					},
 				},
 			},
@@ -724,170 +1388,832 @@ var Root = Command{
 				},
 			},
 			Description: Description{
-				Short: "Run a command with linked resources",
+				Short: "Run a command with linked resources",
+				Long: strings.Join([]string{
+					"Run a command with all the resources linked to the environment.",
+					"",
+					"For example, you can run a Node script and use the [JS SDK](/docs/reference/sdk/) to access *all* the linked resources in your app.",
+					"",
+					"```js title=\"sst.config.ts\"",
+					"const myMainBucket = new sst.aws.Bucket(\"MyMainBucket\");",
+					"const myAdminBucket = new sst.aws.Bucket(\"MyAdminBucket\");",
+					"",
+					"new sst.aws.Nextjs(\"MyMainWeb\", {",
+					"  link: [myMainBucket]",
+					"});",
+					"",
+					"new sst.aws.Nextjs(\"MyAdminWeb\", {",
+					"  link: [myAdminBucket]",
+					"});",
+					"```",
+					"",
+					"Now if you run a script.",
+					"",
+					"```bash frame=\"none\" frame=\"none\"",
+					"sst shell node my-script.js",
+					"```",
+					"",
+					"It'll have access to all the buckets from above.",
+					"",
+					"```js title=\"my-script.js\"",
+					"import { Resource } from \"sst\";",
+					"",
+					"console.log(Resource.MyMainBucket.name, Resource.MyAdminBucket.name);",
+					"```",
+					"",
+					"If no command is passed in, it opens a shell session with the linked resources.",
+					"",
+					"```bash frame=\"none\" frame=\"none\"",
+					"sst shell",
+					"```",
+					"",
+					"This is useful if you want to run multiple commands, all while accessing the linked resources. It uses the shell in your `$SHELL`.",
+					"",
+					"The shell gets the same configuration your deployed code does. If it's run from the directory of a site, only the links and environment of that site are set. The AWS credentials and region the stage is deployed with are set as well, so the `aws` CLI talks to the same account.",
+					"",
+					"Pass in `--secrets` to also set the secrets of the stage as `SST_SECRET_*` variables.",
+					"",
+					"```bash frame=\"none\"",
+					"sst shell --secrets --stage=production",
+					"```",
+					"",
+					"It exits with the exit code of the command it runs.",
+				}, "\n"),
+			},
+			Flags: []Flag{
+				{
+					Name: "secrets",
+					Type: "bool",
+					Description: Description{
+						Short: "Also set the secrets of the stage",
+						Long:  "Also set the secret values of the stage as `SST_SECRET_*` environment variables.",
+					},
+				},
+			},
+			Examples: []Example{
+				{
+					Content: "sst shell",
+					Description: Description{
+						Short: "Open a shell session",
+					},
+				},
+				{
+					Content: "sst shell -- aws s3 ls",
+					Description: Description{
+						Short: "Run a command against the account of the stage",
+					},
+				},
+			},
+			Run: CmdShell,
+		},
+		{
+			Name: "run",
+			Args: []Argument{
+				{
+					Name:     "command",
+					Required: true,
+					Description: Description{
+						Short: "The command to run",
+						Long:  "The command to run, pass it in after `--`.",
+					},
+				},
+			},
+			Description: Description{
+				Short: "Run a one-off command against the stage",
+				Long: strings.Join([]string{
+					"Run a single command with the linked resources and the AWS credentials of the stage, like a migration or a seed script.",
+					"",
+					"```bash frame=\"none\"",
+					"sst run --stage=production -- npm run migrate",
+					"```",
+					"",
+					"Use `--dir` to run it in another directory. If it's the directory of a site, the command only gets the links and environment of that site, like `sst env` does.",
+					"",
+					"```bash frame=\"none\"",
+					"sst run --dir packages/core -- npx drizzle-kit migrate",
+					"```",
+					"",
+					"It doesn't prompt for anything, so it can run in CI. It exits with the exit code of the command.",
+				}, "\n"),
+			},
+			Flags: []Flag{
+				{
+					Name: "dir",
+					Type: "string",
+					Description: Description{
+						Short: "The directory to run the command in",
+						Long:  "The directory to run the command in, relative to the current one.",
+					},
+				},
+			},
+			Examples: []Example{
+				{
+					Content: "sst run --stage=production -- npm run migrate",
+					Description: Description{
+						Short: "Run the migrations against production",
+					},
+				},
+				{
+					Content: "sst run --dir packages/web -- npm run seed",
+					Description: Description{
+						Short: "Run a script with the links of a site",
+					},
+				},
+			},
+			Run: CmdRun,
+		},
+		{
+			Name: "env",
+			Args: []Argument{
+				{
+					Name:     "command",
+					Required: true,
+					Description: Description{
+						Short: "The command to run",
+						Long:  "The command to run, pass it in after `--`.",
+					},
+				},
+			},
+			Description: Description{
+				Short: "Run a command with the links of the stage",
+				Long: strings.Join([]string{
+					"Run a command with the linked resources of the stage in its environment, without starting `sst dev`.",
+					"",
+					"```bash frame=\"none\"",
+					"sst env -- npm test",
+					"```",
+					"",
+					"The resources are read from the last deploy of the stage and passed in as `SST_RESOURCE_*` variables, so tests, scripts, and ORMs can use the [JS SDK](/docs/reference/sdk/) to access them.",
+					"",
+					"If it's run from the directory of a site, it only gets the links and environment of that site. This needs `sst dev` to have run once on the stage. Otherwise it gets all the linked resources. Like in `sst dev`, the variables already set in your shell take precedence.",
+					"",
+					"The command exits with the exit code of the command it runs.",
+				}, "\n"),
+			},
+			Examples: []Example{
+				{
+					Content: "sst env -- npm test",
+					Description: Description{
+						Short: "Run the tests with the linked resources",
+					},
+				},
+				{
+					Content: "sst env --stage production -- npx drizzle-kit migrate",
+					Description: Description{
+						Short: "Run the migrations against production",
+					},
+				},
+			},
+			Run: CmdEnv,
+		},
+		{
+			Name: "invoke",
+			Description: Description{
+				Short: "Invoke a function",
+				Long: strings.Join([]string{
+					"Invoke a function of your app and print its response.",
+					"",
+					"By default, it builds the function and runs it on your machine, with its linked resources, the same way `sst dev` does. The function needs to have been run in `sst dev` once.",
+					"",
+					"```bash frame=\"none\"",
+					"sst invoke MyFunction --payload event.json",
+					"```",
+					"",
+					"Pass in `--remote` to invoke the deployed function instead.",
+					"",
+					"```bash frame=\"none\"",
+					"sst invoke MyFunction --payload event.json --remote --stage production",
+					"```",
+					"",
+					"The logs of the function are printed to stderr and the response to stdout.",
+				}, "\n"),
+			},
+			Args: []Argument{
+				{
+					Name:     "function",
+					Complete: CompleteFunction,
+					Required: true,
+					Description: Description{
+						Short: "The name of the function",
+						Long:  "The name of the function component.",
+					},
+				},
+			},
+			Flags: []Flag{
+				{
+					Name: "payload",
+					Type: "string",
+					Description: Description{
+						Short: "A JSON file with the event, - for stdin",
+						Long:  "A JSON file with the event to invoke the function with. Use `-` to read it from stdin. Defaults to `{}`.",
+					},
+				},
+				{
+					Name: "remote",
+					Type: "bool",
+					Description: Description{
+						Short: "Invoke the deployed function",
+						Long:  "Invoke the deployed function instead of running it locally.",
+					},
+				},
+			},
+			Examples: []Example{
+				{
+					Content: "sst invoke MyFunction --payload event.json",
+					Description: Description{
+						Short: "Invoke MyFunction locally with an event",
+					},
+				},
+			},
+			Run: CmdInvoke,
+		},
+		{
+			Name: "logs",
+			Description: Description{
+				Short: "Read the logs of a deployed function",
+				Long: strings.Join([]string{
+					"Read the CloudWatch logs of a function deployed to the stage. The log group is found from the state, so you only need the name of the function.",
+					"",
+					"```bash frame=\"none\"",
+					"sst logs MyFunction --stage production",
+					"```",
+					"",
+					"It prints the logs of the last 10 minutes. Use `--since` to go further back and `--follow` to keep printing them as they come in.",
+					"",
+					"```bash frame=\"none\"",
+					"sst logs MyFunction --since 2h --follow",
+					"```",
+					"",
+					"Use `--filter` to only get the logs that match a [CloudWatch filter pattern](https://docs.aws.amazon.com/AmazonCloudWatch/latest/logs/FilterAndPatternSyntax.html), and `--json` to get each log as a `LogEntry` line.",
+				}, "\n"),
+			},
+			Args: []Argument{
+				{
+					Name:     "function",
+					Complete: CompleteFunction,
+					Required: true,
+					Description: Description{
+						Short: "The name of the function",
+						Long:  "The name of the function component.",
+					},
+				},
+			},
+			Flags: []Flag{
+				{
+					Name: "since",
+					Type: "string",
+					Description: Description{
+						Short: "How far back to read, like 30m",
+						Long:  "How far back to read the logs, like `30s`, `10m`, or `2h`. Defaults to `10m`.",
+					},
+				},
+				{
+					Name: "follow",
+					Type: "bool",
+					Description: Description{
+						Short: "Keep printing new logs",
+						Long:  "Keep printing the logs as they come in, until it's stopped.",
+					},
+				},
+				{
+					Name: "filter",
+					Type: "string",
+					Description: Description{
+						Short: "A CloudWatch filter pattern",
+						Long:  "Only print the logs that match the CloudWatch filter pattern, like `ERROR`.",
+					},
+				},
+			},
+			Examples: []Example{
+				{
+					Content: "sst logs MyFunction --follow",
+					Description: Description{
+						Short: "Tail the logs of MyFunction",
+					},
+				},
+				{
+					Content: "sst logs MyFunction --since 1h --filter ERROR --json",
+					Description: Description{
+						Short: "Get the errors of the last hour as JSON",
+					},
+				},
+			},
+			Run: CmdLogs,
+		},
+		{
+			Name: "remove",
+			Description: Description{
+				Short: "Remove your application",
+				Long: strings.Join([]string{
+					"Removes your application. By default, it removes your personal stage.",
+					"",
+					":::tip",
+					"The resources in your app are removed based on the `removal` setting in your `sst.config.ts`.",
+					":::",
+					"",
+					"Optionally, remove your app from a specific stage.",
+					"",
+					"```bash frame=\"none\" frame=\"none\"",
+					"sst remove --stage=production",
+					"```",
+				}, "\n"),
+			},
+			Run: func(cli *Cli) error {
+				p, err := initProject(cli)
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+				ui := ui.New(ui.ProgressModeRemove)
+				defer ui.Destroy()
+				ui.Header(version, p.App().Name, p.App().Stage)
+				err = p.Stack.Run(cli.Context, &project.StackInput{
+					Command: "destroy",
+					OnEvent: ui.Trigger,
+				})
+				if err != nil {
+					return err
+				}
+				return nil
+			},
+		},
+		{
+			Name: "unlock",
+			Description: Description{
+				Short: "Clear any locks on the app state",
+				Long: strings.Join([]string{
+					"When you run `sst deploy`, it acquires a lock on your state file to prevent concurrent deploys.",
+					"",
+					"However, if something unexpectedly kills the `sst deploy` process, or if you manage to run `sst deploy` concurrently, the lock might not be released.",
+					"",
+					"This should not usually happen, but it can prevent you from deploying. You can run `sst cancel` to release the lock.",
+				}, "\n"),
+			},
+			Run: func(cli *Cli) error {
+				p, err := initProject(cli)
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				err = p.Stack.Cancel()
+				if err != nil {
+					return util.NewReadableError(err, "")
+				}
+				if ui.Output() == ui.OutputJSON {
+					ui.Success(fmt.Sprintf("Unlocked the app state for: %s / %s", p.App().Name, p.App().Stage))
+					return nil
+				}
+				color.New(color.FgGreen, color.Bold).Print("✓ ")
+				color.New(color.FgWhite).Print(" Unlocked the app state for: ")
+				color.New(color.FgWhite, color.Bold).Println(p.App().Name, "/", p.App().Stage)
+				return nil
+			},
+		},
+		{
+			Name: "rotate-passphrase",
+			Description: Description{
+				Short: "Rotate the passphrase for the stage",
+				Long: strings.Join([]string{
+					"Generates a new passphrase for the stage and re-encrypts your state, secrets, and links with it.",
+					"",
+					"Run this if the passphrase has been leaked. The old passphrase is restored if any step fails.",
+					"",
+					"If the passphrase is derived from a `kms` or `age` key, this also moves a stage that still uses its stored passphrase over to the key. The stored passphrase is deleted once the new one is verified.",
+					"",
+					"```bash frame=\"none\"",
+					"sst rotate-passphrase --stage=production",
+					"```",
+				}, "\n"),
+			},
+			Run: func(cli *Cli) error {
+				p, err := initProject(cli)
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				spin := ui.NewSpinner()
+				defer spin.Stop()
+				spin.Suffix = "  Rotating passphrase..."
+				spin.Start()
+				err = p.Stack.RotatePassphrase(cli.Context)
+				if err != nil {
+					return util.NewReadableError(err, "Could not rotate passphrase: "+err.Error())
+				}
+				spin.Stop()
+				ui.Success(fmt.Sprintf("Rotated passphrase for stage \"%s\"", p.App().Stage))
+				return nil
+			},
+		},
+		{
+			Name: "rename-stage",
+			Description: Description{
+				Short: "Rename a deployed stage",
+				Long: strings.Join([]string{
+					"Renames the stage without redeploying it. The resources in the state are moved to the new stage, along with its secrets, links, and metadata.",
+					"",
+					"```bash frame=\"none\"",
+					"sst rename-stage staging --stage=preview",
+					"```",
+					"",
+					"Only the state is moved, the deployed resources aren't touched. Their physical names are based on the old stage, so the next deploy of the new stage replaces the resources whose names change, like functions and buckets that aren't named explicitly. Run `sst diff` after renaming to see which ones.",
+					"",
+					"Both stages are locked while renaming and the old stage is only removed once the new one is verified.",
+				}, "\n"),
+			},
+			Args: []Argument{
+				{
+					Name:     "name",
+					Required: true,
+					Description: Description{
+						Short: "The new name of the stage",
+					},
+				},
+			},
+			Run: func(cli *Cli) error {
+				p, err := initProject(cli)
+				if err != nil {
+					return err
+				}
+				defer p.Cleanup()
+
+				next := cli.Positional(0)
+				spin := ui.NewSpinner()
+				defer spin.Stop()
+				spin.Suffix = "  Renaming stage..."
+				spin.Start()
+				err = p.RenameStage(cli.Context, p.App().Stage, next)
+				if err != nil {
+					if err == provider.ErrStateNotFound {
+						return util.NewReadableError(err, fmt.Sprintf("Stage \"%s\" has not been deployed", p.App().Stage))
+					}
+					return err
+				}
+				spin.Stop()
+				ui.Success(fmt.Sprintf("Renamed stage \"%s\" to \"%s\"", p.App().Stage, next))
+				return nil
+			},
+		},
+		{
+			Name: "rename-app",
+			Description: Description{
+				Short: "Rename the app across all its stages",
+				Long: strings.Join([]string{
+					"Renames the app without redeploying it. Every stage of the app is moved to the new name, along with its secrets, links, and metadata.",
+					"",
+					"```bash frame=\"none\"",
+					"sst rename-app my-new-app",
+					"```",
+					"",
+					"Use `--dry-run` to see every key and resource URN that will change.",
+					"",
+					"```bash frame=\"none\"",
+					"sst rename-app my-new-app --dry-run",
+					"```",
+					"",
+					"Either every stage is renamed or none are. Once it's done, update the `name` in your `sst.config.ts` to match.",
+				}, "\n"),
+			},
+			Args: []Argument{
+				{
+					Name:     "name",
+					Required: true,
+					Description: Description{
+						Short: "The new name of the app",
+					},
+				},
+			},
+			Flags: []Flag{
+				{
+					Name: "dry-run",
+					Type: "bool",
+					Description: Description{
+						Short: "Show what would change without renaming",
+						Long:  "Show every key and URN that would change without renaming anything.",
+					},
+				},
+				{
+					Name: "format",
+					Type: "string",
+					Description: Description{
+						Short: "The output format of the dry run, json",
+						Long:  "The output format of the dry run, `json`.",
+					},
+				},
+			},
+			Run: CmdRenameApp,
+		},
+		{
+			Name: "search",
+			Description: Description{
+				Short: "Search stages by tag",
+				Long: strings.Join([]string{
+					"Search every stage in your home provider, across apps, by the tags written on deploy.",
+					"",
+					"Each deploy tags the stage with `sst:app`, `sst:stage`, `sst:version`, and the `git:branch`, `git:commit`, and `git:remote` it was deployed from. The remote is stored as `host/owner/repo`, without any credentials in it. Along with any `tags` set in your config.",
+					"",
+					"```bash frame=\"none\"",
+					"sst search sst:app=my-app git:branch=main",
+					"```",
+					"",
+					"Pass in just the key to match any value.",
+					"",
+					"```bash frame=\"none\"",
+					"sst search team",
+					"```",
+				}, "\n"),
+			},
+			Args: []Argument{
+				{
+					Name: "filter",
+					Description: Description{
+						Short: "Tags to match, as key=value",
+						Long:  "Tags to match, as `key=value` or just `key`.",
+					},
+				},
+			},
+			Flags: []Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: Description{
+						Short: "The output format, json",
+						Long:  "The output format, `json`.",
+					},
+				},
+			},
+			Examples: []Example{
+				{
+					Content: "sst search sst:app=my-app git:branch=main",
+					Description: Description{
+						Short: "Find all stages of my-app deployed from main",
+					},
+				},
+			},
+			Run: CmdSearch,
+		},
+		{
+			Name: "audit",
+			Description: Description{
+				Short: "Show who accessed secrets and state",
+				Long: strings.Join([]string{
+					"Show the audit log of a stage.",
+					"",
+					"Every time the secrets of a stage are read or written, or its state is pulled or pushed, an entry is appended to the audit log in your home provider. It records when it happened, the operation, the identity used to access the home provider, and the local user and host.",
+					"",
+					"```bash frame=\"none\"",
+					"sst audit --stage production",
+					"```",
+				}, "\n"),
+			},
+			Flags: []Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: Description{
+						Short: "The output format, json",
+						Long:  "The output format, `json`.",
+					},
+				},
+			},
+			Examples: []Example{
+				{
+					Content: "sst audit --stage production --format json",
+					Description: Description{
+						Short: "Print the audit log of production as JSON",
+					},
+				},
+			},
+			Run: CmdAudit,
+		},
+		{
+			Name: "analyze",
+			Description: Description{
+				Short: "Show what went into the bundles",
+				Long: strings.Join([]string{
+					"Show what went into the config bundle and the function bundles.",
+					"",
+					"Lists the files and the dependencies that take up the most space in each bundle, and the packages that were bundled more than once from different copies.",
+					"",
+					"The config is bundled again, the functions are read from the last time they were built with `sst dev` or `sst deploy`.",
+					"",
+					"```bash frame=\"none\"",
+					"sst analyze",
+					"```",
+				}, "\n"),
+			},
+			Flags: []Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: Description{
+						Short: "The output format, json",
+						Long:  "The output format, `json`.",
+					},
+				},
+			},
+			Run: CmdAnalyze,
+		},
+		{
+			Name: "lint",
+			Description: Description{
+				Short: "Check the config for common problems",
 				Long: strings.Join([]string{
-					"Run a command with all the resources linked to the environment.",
-					"",
-					"For example, you can run a Node script and use the [JS SDK](/docs/reference/sdk/) to access *all* the linked resources in your app.",
-					"",
-					"```js title=\"sst.config.ts\"",
-					"const myMainBucket = new sst.aws.Bucket(\"MyMainBucket\");",
-					"const myAdminBucket = new sst.aws.Bucket(\"MyAdminBucket\");",
-					"",
-					"new sst.aws.Nextjs(\"MyMainWeb\", {",
-					"  link: [myMainBucket]",
-					"});",
-					"",
-					"new sst.aws.Nextjs(\"MyAdminWeb\", {",
-					"  link: [myAdminBucket]",
-					"});",
-					"```",
-					"",
-					"Now if you run a script.",
-					"",
-					"```bash frame=\"none\" frame=\"none\"",
-					"sst shell node my-script.js",
-					"```",
+					"Check your config for common problems without deploying it.",
 					"",
-					"It'll have access to all the buckets from above.",
+					"- A provider that's configured but never used.",
+					"- The `aws` provider without a region.",
+					"- Two components with the same name.",
+					"- Secrets that are hardcoded instead of set with `sst secret`.",
 					"",
-					"```js title=\"my-script.js\"",
-					"import { Resource } from \"sst\";",
+					"The same checks run before every deploy and are shown as warnings. This command exits with an error if there are any, so it can be used in CI.",
 					"",
-					"console.log(Resource.MyMainBucket.name, Resource.MyAdminBucket.name);",
+					"```bash frame=\"none\"",
+					"sst lint",
 					"```",
+				}, "\n"),
+			},
+			Flags: []Flag{
+				{
+					Name: "format",
+					Type: "string",
+					Description: Description{
+						Short: "The output format, json",
+						Long:  "The output format, `json`.",
+					},
+				},
+			},
+			Run: CmdLint,
+		},
+		{
+			Name: "doctor",
+			Description: Description{
+				Short: "Check the environment the app runs in",
+				Long: strings.Join([]string{
+					"Check the environment for the problems that stop your app from running, and print how to fix them.",
 					"",
-					"If no command is passed in, it opens a shell session with the linked resources.",
+					"- The version of Node.",
+					"- The credentials of each provider in the config.",
+					"- That the home provider can be reached.",
+					"- A lock left behind by a deploy that didn't finish.",
+					"- Operations in the state that were interrupted.",
+					"- The platform code in the `.sst/` directory.",
 					"",
-					"```bash frame=\"none\" frame=\"none\"",
-					"sst shell",
+					"```bash frame=\"none\"",
+					"sst doctor --stage production",
 					"```",
 					"",
-					"This is useful if you want to run multiple commands, all while accessing the linked resources.",
+					"It doesn't change anything and exits with an error if any of the checks fail.",
 				}, "\n"),
 			},
-			Examples: []Example{
+			Flags: []Flag{
 				{
-					Content: "sst shell",
+					Name: "format",
+					Type: "string",
 					Description: Description{
-						Short: "Open a shell session",
+						Short: "The output format, json",
+						Long:  "The output format, `json`.",
 					},
 				},
 			},
-			Run: func(cli *Cli) error {
-				p, err := initProject(cli)
-				if err != nil {
-					return err
-				}
-				defer p.Cleanup()
-
-				backend := p.Backend()
-				links, err := provider.GetLinks(backend, p.App().Name, p.App().Stage)
-				if err != nil {
-					return err
-				}
-				var args []string
-				for _, arg := range cli.arguments {
-					args = append(args, strings.Fields(arg)...)
-				}
-				if len(args) == 0 {
-					args = append(args, "sh")
-				}
-				cmd := exec.Command(
-					args[0],
-					args[1:]...,
-				)
-				cmd.Env = append(cmd.Env,
-					os.Environ()...,
-				)
-				cmd.Env = append(cmd.Env,
-					fmt.Sprintf("PS1=%s/%s> ", p.App().Name, p.App().Stage),
-				)
-
-				for resource, value := range links {
-					jsonValue, err := json.Marshal(value)
-					if err != nil {
-						return err
-					}
-
-					envVar := fmt.Sprintf("SST_RESOURCE_%s=%s", resource, jsonValue)
-					cmd.Env = append(cmd.Env, envVar)
-				}
-				cmd.Stdout = os.Stdout
-				cmd.Stderr = os.Stderr
-				cmd.Stdin = os.Stdin
-				err = cmd.Run()
-				if err != nil {
-					return util.NewReadableError(err, err.Error())
-				}
-				return nil
-			},
+			Run: CmdDoctor,
 		},
 		{
-			Name: "remove",
+			Name: "config",
 			Description: Description{
-				Short: "Remove your application",
+				Short: "Manage the defaults of the CLI",
 				Long: strings.Join([]string{
-					"Removes your application. By default, it removes your personal stage.",
+					"Manage the defaults of the CLI for all your apps. They are stored in `~/.config/sst/config.toml`, or the file in `SST_CONFIG`.",
 					"",
-					":::tip",
-					"The resources in your app are removed based on the `removal` setting in your `sst.config.ts`.",
-					":::",
+					"```toml title=\"~/.config/sst/config.toml\"",
+					"stage = \"frank\"",
+					"parallelism = 4",
+					"telemetry = false",
+					"proxy = \"http://proxy.internal:3128\"",
+					"color = \"never\"",
+					"```",
 					"",
-					"Optionally, remove your app from a specific stage.",
+					"- `stage` is the personal stage of the apps that don't have one yet, instead of your username.",
+					"- `parallelism` is how many functions are bundled at once.",
+					"- `telemetry` set to `false` turns off telemetry.",
+					"- `proxy` is used for the requests of the CLI and the commands it runs, unless `HTTPS_PROXY` or `HTTP_PROXY` are set.",
+					"- `color` is one of `auto`, `always`, or `never`.",
 					"",
-					"```bash frame=\"none\" frame=\"none\"",
-					"sst remove --stage=production",
-					"```",
+					"The flags, environment variables, and the config of your app take precedence over these. Like `--stage`, `SST_BUILD_CONCURRENCY`, or `buildConcurrency`.",
 				}, "\n"),
 			},
-			Run: func(cli *Cli) error {
-				p, err := initProject(cli)
-				if err != nil {
-					return err
-				}
-				defer p.Cleanup()
-				ui := ui.New(ui.ProgressModeRemove)
-				defer ui.Destroy()
-				ui.Header(version, p.App().Name, p.App().Stage)
-				err = p.Stack.Run(cli.Context, &project.StackInput{
-					Command: "destroy",
-					OnEvent: ui.Trigger,
-				})
-				if err != nil {
-					return err
-				}
-				return nil
+			Children: []*Command{
+				{
+					Name: "list",
+					Description: Description{
+						Short: "List the defaults",
+						Long:  "List the defaults that are set.",
+					},
+					Run: CmdConfigList,
+				},
+				{
+					Name: "get",
+					Description: Description{
+						Short: "Get a default",
+						Long:  "Print the value of a default, it's empty if it's not set.",
+					},
+					Args: []Argument{
+						{
+							Name:     "key",
+							Required: true,
+							Description: Description{
+								Short: "The key of the default",
+								Long:  "The key of the default, one of `stage`, `parallelism`, `telemetry`, `proxy`, or `color`.",
+							},
+						},
+					},
+					Run: CmdConfigGet,
+				},
+				{
+					Name: "set",
+					Description: Description{
+						Short: "Set a default",
+						Long: strings.Join([]string{
+							"Set a default.",
+							"",
+							"```bash frame=\"none\"",
+							"sst config set parallelism 4",
+							"```",
+						}, "\n"),
+					},
+					Args: []Argument{
+						{
+							Name:     "key",
+							Required: true,
+							Description: Description{
+								Short: "The key of the default",
+								Long:  "The key of the default, one of `stage`, `parallelism`, `telemetry`, `proxy`, or `color`.",
+							},
+						},
+						{
+							Name:     "value",
+							Required: true,
+							Description: Description{
+								Short: "The value of the default",
+								Long:  "The value of the default.",
+							},
+						},
+					},
+					Run: CmdConfigSet,
+				},
+				{
+					Name: "unset",
+					Description: Description{
+						Short: "Remove a default",
+						Long:  "Remove a default, the CLI uses its own default instead.",
+					},
+					Args: []Argument{
+						{
+							Name:     "key",
+							Required: true,
+							Description: Description{
+								Short: "The key of the default",
+								Long:  "The key of the default to remove.",
+							},
+						},
+					},
+					Run: CmdConfigUnset,
+				},
 			},
 		},
 		{
-			Name: "unlock",
+			Name: "completion",
 			Description: Description{
-				Short: "Clear any locks on the app state",
+				Short: "Print the shell completion script",
 				Long: strings.Join([]string{
-					"When you run `sst deploy`, it acquires a lock on your state file to prevent concurrent deploys.",
+					"Print the completion script for your shell, one of `bash`, `zsh`, or `fish`.",
 					"",
-					"However, if something unexpectedly kills the `sst deploy` process, or if you manage to run `sst deploy` concurrently, the lock might not be released.",
+					"```bash frame=\"none\"",
+					"sst completion zsh > \"${fpath[1]}/_sst\"",
+					"```",
 					"",
-					"This should not usually happen, but it can prevent you from deploying. You can run `sst cancel` to release the lock.",
+					"Or load it when the shell starts.",
+					"",
+					"```bash frame=\"none\"",
+					"echo 'source <(sst completion bash)' >> ~/.bashrc",
+					"sst completion fish > ~/.config/fish/completions/sst.fish",
+					"```",
+					"",
+					"Besides the commands and flags, it completes the stages, the functions for `sst logs` and `sst invoke`, and the secrets of your app. They come from the last deploy of each stage from your machine, so completing never evaluates your config or reaches your home provider.",
 				}, "\n"),
 			},
-			Run: func(cli *Cli) error {
-				p, err := initProject(cli)
-				if err != nil {
-					return err
-				}
-				defer p.Cleanup()
-
-				err = p.Stack.Cancel()
-				if err != nil {
-					return util.NewReadableError(err, "")
-				}
-				color.New(color.FgGreen, color.Bold).Print("✓ ")
-				color.New(color.FgWhite).Print(" Unlocked the app state for: ")
-				color.New(color.FgWhite, color.Bold).Println(p.App().Name, "/", p.App().Stage)
-				return nil
+			Args: []Argument{
+				{
+					Name:     "shell",
+					Required: true,
+					Description: Description{
+						Short: "The shell to complete",
+						Long:  "The shell to print the script for, one of `bash`, `zsh`, or `fish`.",
+					},
+				},
 			},
+			Run: CmdCompletion,
 		},
 		{
 			Name: "version",
@@ -896,6 +2222,10 @@ var Root = Command{
 				Long:  `Prints the current version of the CLI.`,
 			},
 			Run: func(cli *Cli) error {
+				if ui.Output() == ui.OutputJSON {
+					ui.Result(map[string]string{"version": version})
+					return nil
+				}
 				fmt.Println(version)
 				return nil
 			},
@@ -929,6 +2259,10 @@ var Root = Command{
 					return err
 				}
 				newVersion = strings.TrimPrefix(newVersion, "v")
+				if ui.Output() == ui.OutputJSON {
+					ui.Result(map[string]string{"from": version, "to": newVersion})
+					return nil
+				}
 
 				color.New(color.FgGreen, color.Bold).Print(ui.IconCheck)
 				if newVersion == version {
@@ -1008,8 +2342,9 @@ var Root = Command{
 			},
 			Flags: []Flag{
 				{
-					Type: "string",
-					Name: "parent",
+					Type:     "string",
+					Name:     "parent",
+					Complete: CompleteURN,
 					Description: Description{
 						Short: "The parent resource",
 					},
@@ -1037,6 +2372,7 @@ var Root = Command{
 					return err
 				}
 
+				ui.Success(fmt.Sprintf("Imported %s", name))
 				return nil
 			},
 		},
@@ -1054,6 +2390,8 @@ var Root = Command{
 				if err != nil {
 					return err
 				}
+				s.Inspect = cli.Bool("inspect")
+				s.Attach = cli.Bool("attach")
 
 				err = s.Start(cli.Context)
 				if err != nil {
@@ -1106,12 +2444,23 @@ var Root = Command{
 				Short: "Manage state of your deployment",
 			},
 			Children: []*Command{
+				{
+					Name: "list",
+					Description: Description{
+						Short: "List the resources in the state",
+						Long:  "List the resources in the state of the stage, with their type and ID.",
+					},
+					Run: CmdStateList,
+				},
 				{
 					Name: "edit",
 					Description: Description{
 						Short: "Edit the state of your deployment",
 					},
 					Run: func(cli *Cli) error {
+						if cli.CI() {
+							return needsInput("the state can only be edited in a terminal")
+						}
 						p, err := initProject(cli)
 						if err != nil {
 							return err
@@ -1152,6 +2501,9 @@ var Root = Command{
 
 func (c *Command) registerFlags(parsed map[string]interface{}) {
 	for _, f := range c.Flags {
+		if _, ok := parsed[f.Name]; ok {
+			continue
+		}
 		if f.Type == "string" {
 			parsed[f.Name] = flag.String(f.Name, "", "")
 		}
@@ -1172,9 +2524,11 @@ func init() {
 type Cli struct {
 	flags     map[string]interface{}
 	arguments []string
-	path      CommandPath
-	Context   context.Context
-	cancel    context.CancelFunc
+	// dashed is true if the arguments were passed in after --
+	dashed  bool
+	path    CommandPath
+	Context context.Context
+	cancel  context.CancelFunc
 }
 
 func (c *Cli) Cancel() {
@@ -1203,6 +2557,20 @@ func (c *Cli) Arguments() []string {
 	return c.arguments
 }
 
+// Command returns the arguments as a command to run. The ones passed in after
+// -- are used as is, the others are split on spaces so `sst dev "npm run dev"`
+// works as well.
+func (c *Cli) Command() []string {
+	if c.dashed {
+		return c.arguments
+	}
+	var args []string
+	for _, arg := range c.arguments {
+		args = append(args, strings.Fields(arg)...)
+	}
+	return args
+}
+
 func (c *Cli) Positional(index int) string {
 	if index >= len(c.arguments) {
 		return ""
@@ -1248,6 +2616,9 @@ type Argument struct {
 	Name        string      `json:"name"`
 	Required    bool        `json:"required"`
 	Description Description `json:"description"`
+	// Complete is what the shell completes the argument with, like
+	// CompleteStage
+	Complete string `json:"complete,omitempty"`
 }
 
 type Description struct {
@@ -1273,6 +2644,8 @@ type Flag struct {
 	Name        string      `json:"name"`
 	Type        string      `json:"type"`
 	Description Description `json:"description"`
+	// Complete is what the shell completes the value with
+	Complete string `json:"complete,omitempty"`
 }
 
 type CommandPath []Command
@@ -1373,7 +2746,7 @@ func initProject(cli *Cli) (*project.Project, error) {
 
 	cfgPath, err := project.Discover()
 	if err != nil {
-		return nil, util.NewReadableError(err, "Could not find sst.config.ts")
+		return nil, util.NewReadableError(err, "Could not find an sst.config.ts or sst.config.js")
 	}
 
 	stage, err := getStage(cli, cfgPath)
@@ -1385,27 +2758,58 @@ func initProject(cli *Cli) (*project.Project, error) {
 		Version: version,
 		Stage:   stage,
 		Config:  cfgPath,
+		Output:  projectOutput(),
 	})
 	if err != nil {
 		return nil, err
 	}
+	// the branch stage rules changed since the config was last loaded
+	if expected := expectedStage(cli, p, cfgPath); expected != stage {
+		slog.Info("reloading project for branch stage", "stage", expected)
+		loadStageEnv(expected)
+		p, err = project.New(&project.ProjectConfig{
+			Version: version,
+			Stage:   expected,
+			Config:  cfgPath,
+			Output:  projectOutput(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	_, err = logFile.Seek(0, 0)
+	// the version the app pins changed since the config was last loaded
+	target, err := pinnedVersion(cli, p.App().Version)
 	if err != nil {
+		p.Cleanup()
 		return nil, err
 	}
-	nextLogFile, err := os.Create(filepath.Join(p.PathWorkingDir(), "sst.log"))
-	if err != nil {
-		return nil, util.NewReadableError(err, "Could not create log file")
+	if target != "" {
+		p.Cleanup()
+		return nil, switchVersion(target)
 	}
-	_, err = io.Copy(nextLogFile, logFile)
-	if err != nil {
-		return nil, util.NewReadableError(err, "Could not copy log file")
+
+	if cli.Bool("allow-reserved") {
+		p.AllowReservedStages()
+	}
+
+	// pulumi is looked up on the PATH of the process, with a runner it's the
+	// one that runs in the image
+	if p.App().Runner != nil {
+		os.Setenv("PATH", p.PathRunnerBin()+string(os.PathListSeparator)+os.Getenv("PATH"))
+	}
+
+	if cli.String("log-file") == "" {
+		// a running sst dev is still writing to sst.log, and the server it
+		// starts shares the log of the dev command that just rotated it
+		rotate := !server.Running(p.PathConfig()) && !(len(cli.path) > 1 && cli.path[1].Name == "server")
+		err = moveLogFile(cli, filepath.Join(p.PathOutputDir(), "sst.log"), rotate)
+		if err != nil {
+			return nil, util.NewReadableError(err, "Could not create log file")
+		}
 	}
-	logFile = nextLogFile
-	configureLog(cli)
 
-	spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+	spin := ui.NewSpinner()
 	defer spin.Stop()
 	if !p.CheckPlatform(version) {
 		spin.Suffix = "  Upgrading project..."
@@ -1419,7 +2823,20 @@ func initProject(cli *Cli) (*project.Project, error) {
 	if p.NeedsInstall() {
 		spin.Suffix = "  Installing providers..."
 		spin.Start()
-		err = p.Install()
+		installing := map[string]bool{}
+		err = p.InstallWithEvents(func(event *project.InstallEvent) {
+			if event.Status == "installing" {
+				installing[event.Package] = true
+			} else {
+				delete(installing, event.Package)
+			}
+			pending := make([]string, 0, len(installing))
+			for pkg := range installing {
+				pending = append(pending, pkg)
+			}
+			sort.Strings(pending)
+			spin.Suffix = "  Installing " + strings.Join(pending, ", ") + "..."
+		})
 		if err != nil {
 			return nil, util.NewReadableError(err, "Could not install dependencies")
 		}
@@ -1435,33 +2852,38 @@ func initProject(cli *Cli) (*project.Project, error) {
 	return p, nil
 }
 
-func configureLog(cli *Cli) {
-	writers := []io.Writer{logFile}
-	if cli.Bool("verbose") {
-		writers = append(writers, os.Stderr)
-	}
-	writer := io.MultiWriter(writers...)
-	slog.SetDefault(
-		slog.New(slog.NewTextHandler(writer, &slog.HandlerOptions{
-			Level: slog.LevelInfo,
-		})),
-	)
-}
-
 func getStage(cli *Cli, cfgPath string) (string, error) {
 	stage := cli.String("stage")
+	if stage == "" {
+		stage = project.LoadBranchStage(cfgPath)
+	}
 	if stage == "" {
 		stage = project.LoadPersonalStage(cfgPath)
 		if stage == "" {
-			stage = guessStage()
-			if stage == "" {
+			// a personal stage named after the CI user is never what's wanted
+			if cli.CI() {
+				stage = branchStageFromConfig(cfgPath)
+				if stage == "" {
+					return "", needsInput("pass in the stage with --stage")
+				}
+				loadStageEnv(stage)
+				return stage, nil
+			}
+			if config, err := global.LoadUserConfig(); err == nil && config.Stage != "" {
+				stage = config.Stage
+			} else {
+				stage = guessStage()
+			}
+			if project.ValidateStage(stage) != nil {
+				stage = ""
 				for {
 					fmt.Print("Enter a stage name for your personal stage: ")
 					_, err := fmt.Scanln(&stage)
 					if err != nil {
 						continue
 					}
-					if stage == "" {
+					if err := project.ValidateStage(stage); err != nil {
+						fmt.Println(err.Error())
 						continue
 					}
 					break
@@ -1473,10 +2895,68 @@ func getStage(cli *Cli, cfgPath string) (string, error) {
 			}
 		}
 	}
-	godotenv.Load(fmt.Sprintf(".env.%s", stage))
+	loadStageEnv(stage)
 	return stage, nil
 }
 
+// stageEnvKeys are the variables set from the .env file of a stage.
+var stageEnvKeys = []string{}
+
+// loadStageEnv loads the .env file of a stage. The variables set from the
+// file of a stage that was loaded before are removed first, so they don't
+// leak into the stage the project is reloaded with. Like godotenv.Load, it
+// doesn't override the variables that are already set.
+func loadStageEnv(stage string) {
+	for _, key := range stageEnvKeys {
+		os.Unsetenv(key)
+	}
+	stageEnvKeys = []string{}
+	values, err := godotenv.Read(fmt.Sprintf(".env.%s", stage))
+	if err != nil {
+		return
+	}
+	for key, value := range values {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		os.Setenv(key, value)
+		stageEnvKeys = append(stageEnvKeys, key)
+	}
+}
+
+// branchStageFromConfig loads the config to read its branch stage rules,
+// they aren't saved in .sst yet on a fresh checkout like in CI. The stage
+// the config is loaded with doesn't matter for the rules.
+func branchStageFromConfig(cfgPath string) string {
+	p, err := project.New(&project.ProjectConfig{
+		Version: version,
+		Stage:   "ci",
+		Config:  cfgPath,
+		Output:  projectOutput(),
+	})
+	if err != nil {
+		slog.Warn("failed to load the config for the branch stage", "err", err)
+		return ""
+	}
+	defer p.Cleanup()
+	return p.BranchStage()
+}
+
+// expectedStage is the stage getStage picks with the branch stage rules of
+// the loaded config, the rules it used are from the last time it was loaded.
+func expectedStage(cli *Cli, p *project.Project, cfgPath string) string {
+	if cli.String("stage") != "" {
+		return p.App().Stage
+	}
+	if stage := p.BranchStage(); stage != "" {
+		return stage
+	}
+	if stage := project.LoadPersonalStage(cfgPath); stage != "" {
+		return stage
+	}
+	return p.App().Stage
+}
+
 func guessStage() string {
 	u, err := user.Current()
 	if err != nil {
@@ -1490,3 +2970,19 @@ func guessStage() string {
 
 	return stage
 }
+
+// useToolchain puts the toolchain declared in the project first on the PATH
+// of the CLI. The CLI runs a single project, and pulumi is only looked up on
+// the PATH of the process.
+func useToolchain(cfgPath string) error {
+	toolchain, err := project.ResolveToolchain(cfgPath)
+	if err != nil {
+		return err
+	}
+	if toolchain == nil || len(toolchain.Path) == 0 {
+		return nil
+	}
+	slog.Info("using toolchain", "source", toolchain.Source, "path", toolchain.Path)
+	path := append(toolchain.Path, os.Getenv("PATH"))
+	return os.Setenv("PATH", strings.Join(path, string(os.PathListSeparator)))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sst/ion/cmd/sst/ui"
+)
+
+// outputFormat is the --format of a command, --json picks json for all of
+// them.
+func outputFormat(cli *Cli) string {
+	if ui.Output() == ui.OutputJSON {
+		return "json"
+	}
+	return cli.String("format")
+}
+
+// projectOutput is where what the config prints goes, with --json stdout
+// only has lines of JSON so it goes to stderr.
+func projectOutput() io.Writer {
+	if ui.Output() == ui.OutputJSON {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printJSON prints the result of a command. It's a line of the output with
+// --json, and indented JSON with --format json.
+func printJSON(value interface{}) error {
+	if ui.Output() == ui.OutputJSON {
+		ui.Result(value)
+		return nil
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
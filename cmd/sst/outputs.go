@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+func CmdOutputs(cli *Cli) error {
+	format := outputFormat(cli)
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "dotenv" && format != "export" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", must be one of: json, dotenv, export", format))
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	outputs, err := p.Stack.Outputs(cli.Context)
+	if err != nil {
+		if errors.Is(err, provider.ErrStateNotFound) {
+			return util.NewReadableError(err, fmt.Sprintf("Stage \"%s\" has not been deployed", p.App().Stage))
+		}
+		return util.NewReadableError(err, "Could not read the outputs of the stage")
+	}
+
+	switch format {
+	case "json":
+		if err := printJSON(outputs); err != nil {
+			return err
+		}
+	case "dotenv":
+		data, err := godotenv.Marshal(outputVariables(outputs))
+		if err != nil {
+			return err
+		}
+		fmt.Println(data)
+	case "export":
+		variables := outputVariables(outputs)
+		keys := make([]string, 0, len(variables))
+		for key := range variables {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("export %s='%s'\n", key, strings.ReplaceAll(variables[key], "'", `'\''`))
+		}
+	}
+	return nil
+}
+
+var invalidVariableChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// outputVariables turns the outputs into environment variables. The names
+// only keep the characters a shell allows and the values that aren't strings
+// are encoded as JSON.
+func outputVariables(outputs map[string]interface{}) map[string]string {
+	result := map[string]string{}
+	for key, value := range outputs {
+		name := invalidVariableChars.ReplaceAllString(key, "_")
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			name = "_" + name
+		}
+		if str, ok := value.(string); ok {
+			result[name] = str
+			continue
+		}
+		data, _ := json.Marshal(value)
+		result[name] = string(data)
+	}
+	return result
+}
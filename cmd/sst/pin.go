@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/global"
+)
+
+// pinnedFromEnv is set on the pinned version that's run, so it doesn't
+// switch again if it reports a version that doesn't match.
+const pinnedFromEnv = "SST_PINNED_FROM"
+
+// pinnedVersion returns the version of the CLI to switch to for the version
+// the app pins, or an empty string if this one matches. Switching is skipped
+// for dev builds, upgrade, and with SST_IGNORE_VERSION.
+func pinnedVersion(cli *Cli, constraint string) (string, error) {
+	if constraint == "" || version == "dev" || os.Getenv("SST_IGNORE_VERSION") != "" || os.Getenv(pinnedFromEnv) != "" {
+		return "", nil
+	}
+	if len(cli.path) > 1 && cli.path[1].Name == "upgrade" {
+		return "", nil
+	}
+	parsed, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", util.NewReadableError(err, fmt.Sprintf("Version %q is invalid, it must be a version like 0.0.300 or a range like ^0.0.300", constraint))
+	}
+	current, err := semver.NewVersion(version)
+	if err == nil && parsed.Check(current) {
+		return "", nil
+	}
+	resolved, err := global.ResolveVersion(constraint)
+	if err != nil {
+		return "", util.NewReadableError(err, fmt.Sprintf("Could not find a version of sst that matches %s, the version the app pins", constraint))
+	}
+	return resolved, nil
+}
+
+// switchVersion installs a version of the CLI and runs the command with it
+// instead, it only returns if that fails.
+func switchVersion(target string) error {
+	slog.Info("switching version", "from", version, "to", target)
+	spin := ui.NewSpinner()
+	spin.Suffix = "  Installing sst " + target + "..."
+	spin.Start()
+	binary, err := global.InstallVersion(target)
+	spin.Stop()
+	if err != nil {
+		return util.NewReadableError(err, "Could not install sst "+target)
+	}
+	os.Setenv(pinnedFromEnv, version)
+	// the other version logs on its own
+	if logFile.Name() == tempLogFile {
+		logFile.Close()
+		os.Remove(tempLogFile)
+	}
+	return execVersion(binary, os.Args[1:])
+}
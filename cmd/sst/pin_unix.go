@@ -0,0 +1,13 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// execVersion replaces the process with the binary of another version.
+func execVersion(binary string, args []string) error {
+	return syscall.Exec(binary, append([]string{binary}, args...), os.Environ())
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+func CmdRenameApp(cli *Cli) error {
+	format := outputFormat(cli)
+	if format != "" && format != "json" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", must be: json", format))
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	next := cli.Positional(0)
+	if cli.Bool("dry-run") {
+		moves, err := p.PlanRenameApp(cli.Context, next)
+		if err != nil {
+			return renameAppError(p, err)
+		}
+		if format == "json" {
+			if err := printJSON(moves); err != nil {
+				return err
+			}
+			return nil
+		}
+		printStageMoves(moves)
+		return nil
+	}
+
+	spin := ui.NewSpinner()
+	defer spin.Stop()
+	spin.Suffix = "  Renaming app..."
+	spin.Start()
+	err = p.RenameApp(cli.Context, next)
+	if err != nil {
+		return renameAppError(p, err)
+	}
+	spin.Stop()
+	ui.Success(fmt.Sprintf("Renamed app \"%s\" to \"%s\", update the name in your sst.config.ts to match", p.App().Name, next))
+	return nil
+}
+
+func renameAppError(p *project.Project, err error) error {
+	if err == provider.ErrSearchNotSupported {
+		return util.NewReadableError(err, fmt.Sprintf("Renaming apps is not supported by the \"%s\" home provider", p.App().Home))
+	}
+	if err == provider.ErrStageExists {
+		return err
+	}
+	if err == project.ErrInvalidAppName {
+		return err
+	}
+	return util.NewReadableError(err, "Could not rename app: "+err.Error())
+}
+
+func printStageMoves(moves []project.StageMove) {
+	for _, move := range moves {
+		color.New(color.FgWhite, color.Bold).Printf("%s/%s -> %s/%s\n", move.From.App, move.From.Stage, move.To.App, move.To.Stage)
+		for _, key := range move.Keys {
+			color.New(color.FgHiBlack).Print("  key ")
+			fmt.Println(key)
+		}
+		for _, urn := range move.URNs {
+			color.New(color.FgHiBlack).Print("  urn ")
+			fmt.Println(urn.From)
+			color.New(color.FgHiBlack).Print("   -> ")
+			fmt.Println(urn.To)
+		}
+		fmt.Println()
+	}
+}
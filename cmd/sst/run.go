@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sst/ion/internal/util"
+)
+
+func CmdRun(cli *Cli) error {
+	args := cli.Command()
+	if len(args) == 0 {
+		return util.NewReadableError(nil, "Pass in the command to run, like `sst run -- npm run migrate`")
+	}
+
+	dir, _ := os.Getwd()
+	if value := cli.String("dir"); value != "" {
+		dir, _ = filepath.Abs(value)
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			return util.NewReadableError(err, fmt.Sprintf("Directory \"%s\" does not exist", value))
+		}
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	env, err := stageEnv(p, dir)
+	if err != nil {
+		return err
+	}
+	credentials, err := p.Backend().Env()
+	if err != nil {
+		return util.NewReadableError(err, "Could not get the credentials of the stage")
+	}
+	for key, value := range credentials {
+		env = append(env, key+"="+value)
+	}
+	return runWithEnv(p, args, dir, env)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+func CmdSearch(cli *Cli) error {
+	format := outputFormat(cli)
+	if format != "" && format != "json" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", must be: json", format))
+	}
+
+	filter := map[string]string{}
+	for _, arg := range cli.Arguments() {
+		key, value, _ := strings.Cut(arg, "=")
+		filter[key] = value
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	stages, err := p.Search(filter)
+	if err != nil {
+		if err == provider.ErrSearchNotSupported {
+			return util.NewReadableError(err, fmt.Sprintf("Searching is not supported by the \"%s\" home provider", p.App().Home))
+		}
+		return util.NewReadableError(err, "Could not search stages")
+	}
+	sort.Slice(stages, func(i, j int) bool {
+		if stages[i].App != stages[j].App {
+			return stages[i].App < stages[j].App
+		}
+		return stages[i].Stage < stages[j].Stage
+	})
+
+	if format == "json" {
+		if err := printJSON(stages); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	for _, stage := range stages {
+		color.New(color.FgWhite, color.Bold).Print(stage.App + " / " + stage.Stage)
+		if branch, ok := stage.Tags["git:branch"]; ok {
+			color.New(color.FgHiBlack).Print("  " + branch)
+		}
+		fmt.Println()
+	}
+	return nil
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/joho/godotenv"
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// readSecretValue returns the value passed to `secret set` as an argument,
+// a file, or through stdin.
+func readSecretValue(cli *Cli) (string, error) {
+	fromFile := cli.String("from-file")
+	stdin := cli.Bool("stdin")
+	value := cli.Positional(1)
+
+	sources := 0
+	for _, set := range []bool{value != "", fromFile != "", stdin} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return "", util.NewReadableError(nil, "Pass in the value as an argument, or with one of --from-file or --stdin")
+	}
+
+	switch {
+	case fromFile != "":
+		data, err := os.ReadFile(fromFile)
+		if err != nil {
+			return "", util.NewReadableError(err, "Could not read "+fromFile)
+		}
+		return provider.EncodeSecret(data), nil
+	case stdin:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", util.NewReadableError(err, "Could not read from stdin")
+		}
+		return provider.EncodeSecret(data), nil
+	default:
+		return value, nil
+	}
+}
+
+// parseRotateBy parses either a date or an interval in days, like 90d.
+func parseRotateBy(value string) (*time.Time, time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count <= 0 {
+			return nil, 0, fmt.Errorf("invalid interval %v", value)
+		}
+		return nil, time.Duration(count) * 24 * time.Hour, nil
+	}
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid date %v", value)
+	}
+	return &date, 0, nil
+}
+
+// parseExpires parses either a duration from now, like 2h or 7d, or a
+// date and time.
+func parseExpires(value string, now time.Time) (*time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if count, err := strconv.Atoi(days); err == nil && count > 0 {
+			result := now.Add(time.Duration(count) * 24 * time.Hour)
+			return &result, nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+		result := now.Add(duration)
+		return &result, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return &date, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid expiry %v", value)
+}
+
+func CmdSecretSet(cli *Cli) error {
+	key := cli.Positional(0)
+	description := cli.String("description")
+	owner := cli.String("owner")
+	rotateBy := cli.String("rotate-by")
+	expires := cli.String("expires")
+	hasMetadata := description != "" || owner != "" || rotateBy != "" || expires != ""
+	metadataOnly := hasMetadata && cli.Positional(1) == "" && cli.String("from-file") == "" && !cli.Bool("stdin")
+
+	var deadline *time.Time
+	var interval time.Duration
+	if rotateBy != "" {
+		var err error
+		deadline, interval, err = parseRotateBy(rotateBy)
+		if err != nil {
+			return util.NewReadableError(err, fmt.Sprintf("Invalid --rotate-by \"%s\", use a date like 2025-01-31 or an interval like 90d", rotateBy))
+		}
+	}
+
+	var expiry *time.Time
+	if expires != "" {
+		var err error
+		expiry, err = parseExpires(expires, time.Now().UTC())
+		if err != nil {
+			return util.NewReadableError(err, fmt.Sprintf("Invalid --expires \"%s\", use a duration like 2h or 7d, or a date like 2025-01-31", expires))
+		}
+	}
+
+	var value string
+	if !metadataOnly {
+		var err error
+		value, err = readSecretValue(cli)
+		if err != nil {
+			return err
+		}
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+	backend := p.Backend()
+	secrets, err := provider.GetStageSecrets(backend, p.App().Name, p.App().Stage)
+	if err != nil {
+		return util.NewReadableError(err, "Could not get secrets")
+	}
+	metadata, err := provider.GetStageSecretMetadata(backend, p.App().Name, p.App().Stage)
+	if err != nil {
+		return util.NewReadableError(err, "Could not get secret metadata")
+	}
+
+	now := time.Now().UTC()
+	meta := metadata[key]
+	if description != "" {
+		meta.Description = description
+	}
+	if owner != "" {
+		meta.Owner = owner
+	}
+	if rotateBy != "" {
+		meta.RotateBy = deadline
+		meta.RotateEvery = interval
+		if interval > 0 {
+			base := meta.Updated
+			if base.IsZero() {
+				base = now
+			}
+			next := base.Add(interval)
+			meta.RotateBy = &next
+		}
+	}
+
+	if metadataOnly {
+		if _, ok := secrets[key]; !ok {
+			return util.NewReadableError(nil, fmt.Sprintf("Secret \"%s\" does not exist for stage \"%s\"", key, p.App().Stage))
+		}
+	} else {
+		meta.Rotated(now)
+		// a new value doesn't inherit the expiry of the old one
+		meta.Expires = nil
+		secrets[key] = value
+		err = provider.PutSecrets(backend, p.App().Name, p.App().Stage, secrets)
+		if err != nil {
+			return util.NewReadableError(err, "Could not set secret")
+		}
+	}
+
+	if expiry != nil {
+		meta.Expires = expiry
+	}
+
+	metadata[key] = meta
+	err = provider.PutSecretMetadata(backend, p.App().Name, p.App().Stage, metadata)
+	if err != nil {
+		return util.NewReadableError(err, "Could not set secret metadata")
+	}
+	if metadataOnly {
+		ui.Success(fmt.Sprintf("Updated \"%s\" for stage \"%s\"", key, p.App().Stage))
+		return nil
+	}
+	ui.Success(fmt.Sprintf("Set \"%s\" for stage \"%s\"", key, p.App().Stage))
+	return nil
+}
+
+// printSecretMetadata prints the metadata of a secret after its value.
+func printSecretMetadata(meta provider.SecretMetadata, now time.Time) {
+	if meta.Description != "" {
+		color.New(color.FgHiBlack).Print("  # " + meta.Description)
+	}
+	if meta.Owner != "" {
+		color.New(color.FgHiBlack).Print("  owner: " + meta.Owner)
+	}
+	if meta.Expires != nil {
+		color.New(color.FgHiBlack).Print("  expires " + meta.Expires.Local().Format("2006-01-02 15:04"))
+	}
+	if meta.RotateBy != nil {
+		deadline := "rotate by " + meta.RotateBy.Format(time.DateOnly)
+		if meta.Overdue(now) {
+			color.New(color.FgYellow).Print("  " + deadline + " (overdue)")
+		} else {
+			color.New(color.FgHiBlack).Print("  " + deadline)
+		}
+	}
+}
+
+func CmdSecretList(cli *Cli) error {
+	format := outputFormat(cli)
+	if format != "" && format != "json" && format != "dotenv" {
+		return util.NewReadableError(nil, fmt.Sprintf("Unknown format \"%s\", must be one of: json, dotenv", format))
+	}
+	showValues := cli.Bool("show-values")
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	if showValues {
+		ok, err := confirm(cli, fmt.Sprintf("Print the secret values for stage \"%s\"?", p.App().Stage))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+
+	backend := p.Backend()
+	secrets, err := provider.ListSecrets(backend, p.App().Name, p.App().Stage, showValues)
+	if err != nil {
+		return util.NewReadableError(err, "Could not get secrets")
+	}
+
+	switch format {
+	case "json":
+		if err := printJSON(secrets); err != nil {
+			return err
+		}
+	case "dotenv":
+		data, err := godotenv.Marshal(secrets)
+		if err != nil {
+			return err
+		}
+		fmt.Println(data)
+	default:
+		keys := make([]string, 0, len(secrets))
+		for key := range secrets {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		metadata, err := provider.GetSecretMetadata(backend, p.App().Name, p.App().Stage)
+		if err != nil {
+			return util.NewReadableError(err, "Could not get secret metadata")
+		}
+		now := time.Now()
+		for _, key := range keys {
+			fmt.Print(key, " = ", secrets[key])
+			printSecretMetadata(metadata[key], now)
+			fmt.Println()
+		}
+	}
+	return nil
+}
+
+type secretDiff struct {
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	Left   string `json:"left,omitempty"`
+	Right  string `json:"right,omitempty"`
+}
+
+// hashSecret is keyed so the short hash can't be used to guess the value
+// without the passphrase.
+func hashSecret(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+// diffSecrets compares the keys of two stages and, if hashKey is set, the
+// hashes of their values.
+func diffSecrets(left, right map[string]string, hashKey []byte) []secretDiff {
+	keys := map[string]bool{}
+	for key := range left {
+		keys[key] = true
+	}
+	for key := range right {
+		keys[key] = true
+	}
+	result := []secretDiff{}
+	for key := range keys {
+		l, inLeft := left[key]
+		r, inRight := right[key]
+		switch {
+		case !inRight:
+			result = append(result, secretDiff{Key: key, Status: "missing-right"})
+		case !inLeft:
+			result = append(result, secretDiff{Key: key, Status: "missing-left"})
+		case hashKey != nil && l != r:
+			result = append(result, secretDiff{Key: key, Status: "differs", Left: hashSecret(hashKey, l), Right: hashSecret(hashKey, r)})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func CmdSecretDiff(cli *Cli) error {
+	left := cli.Positional(0)
+	right := cli.Positional(1)
+	values := cli.Bool("values")
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	backend := p.Backend()
+	leftSecrets, err := provider.GetSecrets(backend, p.App().Name, left)
+	if err != nil {
+		return util.NewReadableError(err, fmt.Sprintf("Could not get secrets for stage \"%s\"", left))
+	}
+	rightSecrets, err := provider.GetSecrets(backend, p.App().Name, right)
+	if err != nil {
+		return util.NewReadableError(err, fmt.Sprintf("Could not get secrets for stage \"%s\"", right))
+	}
+
+	var hashKey []byte
+	if values {
+		// both sides are keyed with the passphrase of the first stage so
+		// the hashes can be compared
+		passphrase, err := provider.Passphrase(backend, p.App().Name, left)
+		if err != nil {
+			return util.NewReadableError(err, fmt.Sprintf("Could not get the passphrase for stage \"%s\"", left))
+		}
+		hashKey = []byte(passphrase)
+	}
+	diffs := diffSecrets(leftSecrets, rightSecrets, hashKey)
+	if ui.Output() == ui.OutputJSON {
+		ui.Result(diffs)
+		if len(diffs) > 0 {
+			return util.NewReadableError(nil, fmt.Sprintf("Secrets in \"%s\" and \"%s\" differ", left, right))
+		}
+		return nil
+	}
+	if len(diffs) == 0 {
+		ui.Success(fmt.Sprintf("Secrets in \"%s\" and \"%s\" match", left, right))
+		return nil
+	}
+
+	for _, diff := range diffs {
+		switch diff.Status {
+		case "missing-right":
+			color.New(color.FgRed, color.Bold).Print("- ")
+			color.New(color.FgWhite, color.Bold).Print(diff.Key)
+			color.New(color.FgWhite).Printf("  missing in %s\n", right)
+		case "missing-left":
+			color.New(color.FgRed, color.Bold).Print("- ")
+			color.New(color.FgWhite, color.Bold).Print(diff.Key)
+			color.New(color.FgWhite).Printf("  missing in %s\n", left)
+		case "differs":
+			color.New(color.FgYellow, color.Bold).Print("~ ")
+			color.New(color.FgWhite, color.Bold).Print(diff.Key)
+			color.New(color.FgWhite).Printf("  %s:%s %s:%s\n", left, diff.Left, right, diff.Right)
+		}
+	}
+	return util.NewReadableError(nil, fmt.Sprintf("Secrets in \"%s\" and \"%s\" differ", left, right))
+}
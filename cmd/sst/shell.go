@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+func CmdShell(cli *Cli) error {
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	backend := p.Backend()
+	cwd, _ := os.Getwd()
+	env, err := stageEnv(p, cwd)
+	if err != nil {
+		return err
+	}
+	// the credentials and region the stage is deployed with, so the aws cli
+	// talks to the same account
+	credentials, err := backend.Env()
+	if err != nil {
+		return util.NewReadableError(err, "Could not get the credentials of the stage")
+	}
+	for key, value := range credentials {
+		env = append(env, key+"="+value)
+	}
+	if cli.Bool("secrets") {
+		secrets, err := provider.GetSecrets(backend, p.App().Name, p.App().Stage)
+		if err != nil {
+			return util.NewReadableError(err, "Could not get the secrets of the stage")
+		}
+		keys := make([]string, 0, len(secrets))
+		for key := range secrets {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			env = append(env, "SST_SECRET_"+key+"="+secrets[key])
+		}
+	}
+	env = append(env, fmt.Sprintf("PS1=%s/%s> ", p.App().Name, p.App().Stage))
+
+	args := cli.Command()
+	if len(args) == 0 {
+		args = []string{defaultShell()}
+	}
+	return runWithEnv(p, args, cwd, env)
+}
+
+// defaultShell is the shell of the user, or sh if it's not set.
+func defaultShell() string {
+	if runtime.GOOS == "windows" {
+		if shell := os.Getenv("COMSPEC"); shell != "" {
+			return shell
+		}
+		return "cmd"
+	}
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "sh"
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+type stateResource struct {
+	URN    string `json:"urn"`
+	Type   string `json:"type"`
+	ID     string `json:"id,omitempty"`
+	Parent string `json:"parent,omitempty"`
+}
+
+func CmdStateList(cli *Cli) error {
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	resources, err := p.Stack.Resources()
+	if err != nil {
+		if errors.Is(err, provider.ErrStateNotFound) {
+			return util.NewReadableError(err, fmt.Sprintf("Stage \"%s\" has not been deployed", p.App().Stage))
+		}
+		return util.NewReadableError(err, "Could not read the state of the stage")
+	}
+	result := []stateResource{}
+	for _, resource := range resources {
+		result = append(result, stateResource{
+			URN:    string(resource.URN),
+			Type:   string(resource.Type),
+			ID:     string(resource.ID),
+			Parent: string(resource.Parent),
+		})
+	}
+
+	if ui.Output() == ui.OutputJSON {
+		ui.Result(result)
+		return nil
+	}
+	for _, resource := range result {
+		color.New(color.FgWhite, color.Bold).Print(resource.URN)
+		if resource.ID != "" {
+			color.New(color.FgHiBlack).Print("  " + resource.ID)
+		}
+		fmt.Println()
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/cmd/sst/ui"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// githubSecretRegex are the names GitHub accepts for secrets.
+var githubSecretRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type githubClient struct {
+	token string
+	repo  string
+}
+
+type githubError struct {
+	Status  int
+	Message string
+}
+
+func (e *githubError) Error() string {
+	return fmt.Sprintf("github returned %d: %s", e.Status, e.Message)
+}
+
+func (c *githubClient) request(method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	owner, name, _ := strings.Cut(c.repo, "/")
+	req, err := http.NewRequest(method, "https://api.github.com/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(name)+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var result struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return &githubError{Status: resp.StatusCode, Message: result.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// environmentPath is the path of the environment, and of what's under it,
+// with every segment escaped.
+func environmentPath(env string, segments ...string) string {
+	path := "/environments/" + url.PathEscape(env)
+	for _, segment := range segments {
+		path += "/" + url.PathEscape(segment)
+	}
+	return path
+}
+
+// ensureEnvironment creates the environment if it doesn't exist. Existing
+// ones aren't updated so their protection rules are kept.
+func (c *githubClient) ensureEnvironment(env string) error {
+	err := c.request("GET", environmentPath(env), nil, nil)
+	if gerr, ok := err.(*githubError); ok && gerr.Status == http.StatusNotFound {
+		return c.request("PUT", environmentPath(env), map[string]interface{}{}, nil)
+	}
+	return err
+}
+
+func (c *githubClient) listSecrets(env string) ([]string, error) {
+	names := []string{}
+	for page := 1; ; page++ {
+		var result struct {
+			Secrets []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+		}
+		err := c.request("GET", fmt.Sprintf("%s?per_page=100&page=%d", environmentPath(env, "secrets"), page), nil, &result)
+		if err != nil {
+			return nil, err
+		}
+		for _, secret := range result.Secrets {
+			names = append(names, secret.Name)
+		}
+		if len(result.Secrets) < 100 {
+			return names, nil
+		}
+	}
+}
+
+// putSecrets encrypts the secrets with the public key of the environment,
+// GitHub only accepts them as libsodium sealed boxes.
+func (c *githubClient) putSecrets(env string, secrets map[string]string) error {
+	var key struct {
+		KeyID string `json:"key_id"`
+		Key   string `json:"key"`
+	}
+	err := c.request("GET", environmentPath(env, "secrets", "public-key"), nil, &key)
+	if err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key.Key)
+	if err != nil {
+		return err
+	}
+	if len(decoded) != 32 {
+		return fmt.Errorf("unexpected public key length %d", len(decoded))
+	}
+	var recipient [32]byte
+	copy(recipient[:], decoded)
+
+	for name, value := range secrets {
+		sealed, err := box.SealAnonymous(nil, []byte(value), &recipient, rand.Reader)
+		if err != nil {
+			return err
+		}
+		err = c.request("PUT", environmentPath(env, "secrets", name), map[string]string{
+			"encrypted_value": base64.StdEncoding.EncodeToString(sealed),
+			"key_id":          key.KeyID,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (c *githubClient) removeSecret(env, name string) error {
+	return c.request("DELETE", environmentPath(env, "secrets", name), nil, nil)
+}
+
+func CmdSecretSyncGithub(cli *Cli) error {
+	repo := cli.String("repo")
+	if repo == "" || strings.Count(repo, "/") != 1 {
+		return util.NewReadableError(nil, "Pass in the repository as --repo=org/name")
+	}
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if token == "" {
+		return util.NewReadableError(nil, "Set GITHUB_TOKEN to a token that can manage the repository's environments and secrets")
+	}
+
+	p, err := initProject(cli)
+	if err != nil {
+		return err
+	}
+	defer p.Cleanup()
+
+	env := cli.String("environment")
+	if env == "" {
+		env = p.App().Stage
+	}
+
+	secrets, err := provider.GetSecrets(p.Backend(), p.App().Name, p.App().Stage)
+	if err != nil {
+		return util.NewReadableError(err, "Could not get secrets")
+	}
+	synced := map[string]string{}
+	// GitHub uppercases the names, so secrets that only differ in case
+	// would overwrite each other
+	sources := map[string][]string{}
+	for key, value := range secrets {
+		name := strings.ToUpper(key)
+		if !githubSecretRegex.MatchString(key) || strings.HasPrefix(name, "GITHUB_") {
+			ui.Error(fmt.Sprintf("Skipping \"%s\", GitHub secret names can only contain letters, numbers, and underscores and can't start with GITHUB_", key))
+			continue
+		}
+		synced[name] = value
+		sources[name] = append(sources[name], key)
+	}
+	collisions := []string{}
+	for _, keys := range sources {
+		if len(keys) > 1 {
+			sort.Strings(keys)
+			collisions = append(collisions, strings.Join(keys, " and "))
+		}
+	}
+	if len(collisions) > 0 {
+		sort.Strings(collisions)
+		return util.NewReadableError(nil, fmt.Sprintf("These secrets have the same name on GitHub, where names are uppercased: %s. Rename them so they differ in more than case.", strings.Join(collisions, ", ")))
+	}
+
+	client := &githubClient{token: token, repo: repo}
+	if err := client.ensureEnvironment(env); err != nil {
+		return util.NewReadableError(err, fmt.Sprintf("Could not create the \"%s\" environment in %s: %s", env, repo, err))
+	}
+	if err := client.putSecrets(env, synced); err != nil {
+		return util.NewReadableError(err, fmt.Sprintf("Could not sync secrets to %s: %s", repo, err))
+	}
+
+	removed := []string{}
+	if cli.Bool("prune") {
+		existing, err := client.listSecrets(env)
+		if err != nil {
+			return util.NewReadableError(err, fmt.Sprintf("Could not list the secrets in %s: %s", repo, err))
+		}
+		for _, name := range existing {
+			if _, ok := synced[name]; ok {
+				continue
+			}
+			if err := client.removeSecret(env, name); err != nil {
+				return util.NewReadableError(err, fmt.Sprintf("Could not remove %s from %s: %s", name, repo, err))
+			}
+			removed = append(removed, name)
+		}
+	}
+
+	names := make([]string, 0, len(synced))
+	for name := range synced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if ui.Output() == ui.OutputJSON {
+		ui.Result(map[string]interface{}{
+			"repo":        repo,
+			"environment": env,
+			"synced":      names,
+			"removed":     removed,
+		})
+		return nil
+	}
+	for _, name := range names {
+		fmt.Println("  " + name)
+	}
+	message := fmt.Sprintf("Synced %d secrets to the \"%s\" environment in %s", len(synced), env, repo)
+	if len(removed) > 0 {
+		message += fmt.Sprintf(", removed %s", strings.Join(removed, ", "))
+	}
+	ui.Success(message)
+	return nil
+}
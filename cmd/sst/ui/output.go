@@ -0,0 +1,125 @@
+package ui
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/sst/ion/pkg/project"
+)
+
+type OutputMode string
+
+const (
+	// OutputPretty has colors and a spinner, for a terminal
+	OutputPretty OutputMode = "pretty"
+	// OutputPlain is the same text without colors or a spinner, for logs
+	OutputPlain OutputMode = "plain"
+	// OutputJSON writes every event as a line of JSON
+	OutputJSON OutputMode = "json"
+)
+
+var output = OutputPretty
+
+// SetOutput changes how every UI prints, it's set once from the flags.
+func SetOutput(mode OutputMode) {
+	output = mode
+	if mode != OutputPretty {
+		color.NoColor = true
+	}
+}
+
+func Output() OutputMode {
+	return output
+}
+
+// NewSpinner is a spinner for a step of a command, it's hidden unless the
+// output is pretty.
+func NewSpinner() *spinner.Spinner {
+	spin := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+	if output != OutputPretty {
+		spin.Writer = io.Discard
+	}
+	return spin
+}
+
+var jsonLock sync.Mutex
+var jsonOut io.Writer = os.Stdout
+
+// JSONLine is a line of the JSON output. Type is the name of the event, like
+// CompleteEvent, and Event is the event as it's sent by the CLI. Besides the
+// events of a deploy these are written:
+//
+//   - Header, the version, app, stage, and mode a command runs with
+//   - Success, the message of a command that's done
+//   - Error, the message of a command that failed, it's the last line
+//   - Warning, a problem the command carries on from
+//   - Result, what a command returns, like the secrets or the diff
+//
+// Fields are only ever added to these, the ones that are there don't change.
+type JSONLine struct {
+	Type  string      `json:"type"`
+	Event interface{} `json:"event"`
+}
+
+// WriteJSON writes a line of JSON output.
+func WriteJSON(kind string, event interface{}) {
+	jsonLock.Lock()
+	defer jsonLock.Unlock()
+	data, err := json.Marshal(JSONLine{Type: kind, Event: event})
+	if err != nil {
+		data, _ = json.Marshal(JSONLine{Type: kind, Event: map[string]string{"error": err.Error()}})
+	}
+	jsonOut.Write(append(data, '\n'))
+}
+
+// Result writes the result of a command, it's the last line of the output
+// when the command succeeds.
+func Result(value interface{}) {
+	WriteJSON("Result", value)
+}
+
+var engineEventType = reflect.TypeOf(events.EngineEvent{})
+
+// writeEvents writes a line for each of the events set on evt, a
+// project.StackEvent or a server.Event.
+func writeEvents(evt interface{}) {
+	value := reflect.ValueOf(evt)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		info := value.Type().Field(i)
+		if !info.IsExported() {
+			continue
+		}
+		if info.Type == engineEventType {
+			if !field.IsZero() {
+				WriteJSON("EngineEvent", field.Interface())
+			}
+			continue
+		}
+		if info.Anonymous && field.Kind() == reflect.Struct {
+			writeEvents(field.Addr().Interface())
+			continue
+		}
+		if field.Kind() == reflect.Pointer && !field.IsNil() {
+			event := field.Interface()
+			// the decrypted links and outputs would end up in CI logs
+			if complete, ok := event.(*project.CompleteEvent); ok {
+				event = complete.Redacted()
+			}
+			WriteJSON(info.Name, event)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/server"
+)
+
+func TestWriteEvents(t *testing.T) {
+	var buf bytes.Buffer
+	jsonOut = &buf
+	defer func() { jsonOut = os.Stdout }()
+
+	writeEvents(&project.StackEvent{StdOutEvent: &project.StdOutEvent{Text: "hi"}})
+	writeEvents(&project.StackEvent{EngineEvent: events.EngineEvent{EngineEvent: apitype.EngineEvent{Sequence: 1}}})
+	writeEvents(&server.Event{StackEvent: project.StackEvent{CompleteEvent: &project.CompleteEvent{Finished: true, Links: project.Links{"Database": "hunter2"}}}})
+	Result([]string{"a"})
+
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("the links were not redacted: %s", buf.String())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		`{"type":"StdOutEvent","event":{"Text":"hi"}}`,
+		`{"type":"EngineEvent","event":{"sequence":1`,
+		`{"type":"CompleteEvent","event":{`,
+		`{"type":"Result","event":["a"]}`,
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %v", len(expected), lines)
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, expected[i]) {
+			t.Errorf("line %d: expected %s, got %s", i, expected[i], line)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"log/slog"
+	"os"
 	"strings"
 	"time"
 
@@ -11,7 +12,10 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/project/provider"
 	"github.com/sst/ion/pkg/server"
+	"github.com/sst/ion/pkg/server/dev/aws"
+	"github.com/sst/ion/pkg/server/dev/frontend"
 )
 
 type ProgressMode string
@@ -41,11 +45,14 @@ type UI struct {
 	colors      map[string]color.Attribute
 	workerTime  map[string]time.Time
 	complete    *project.CompleteEvent
+	logFilter   *aws.LogFilter
+	// suffix to restore once a state transfer is done
+	transferSuffix *string
 }
 
 func New(mode ProgressMode) *UI {
 	result := &UI{
-		spinner:    spinner.New(spinner.CharSets[14], 100*time.Millisecond),
+		spinner:    NewSpinner(),
 		mode:       mode,
 		colors:     map[string]color.Attribute{},
 		workerTime: map[string]time.Time{},
@@ -54,6 +61,11 @@ func New(mode ProgressMode) *UI {
 	return result
 }
 
+// FilterLogs hides the function logs that don't match the filter.
+func (u *UI) FilterLogs(filter *aws.LogFilter) {
+	u.logFilter = filter
+}
+
 func (u *UI) Reset() {
 	u.hasProgress = false
 	u.parents = map[string]string{}
@@ -64,6 +76,10 @@ func (u *UI) Reset() {
 }
 
 func (u *UI) Trigger(evt *project.StackEvent) {
+	if output == OutputJSON {
+		writeEvents(evt)
+		return
+	}
 	if evt.ConcurrentUpdateEvent != nil {
 		u.printEvent(color.FgRed, "Locked", "A concurrent update was detected on the stack. Run `sst unlock` to delete the lock file and retry.")
 	}
@@ -88,6 +104,12 @@ func (u *UI) Trigger(evt *project.StackEvent) {
 			u.spinner.Suffix = "  Refreshing..."
 		}
 
+		if evt.StackCommandEvent.Command == "preview" {
+			color.New(color.FgCyan, color.Bold).Print("~")
+			color.New(color.FgWhite, color.Bold).Println("  Diffing")
+			u.spinner.Suffix = "  Diffing..."
+		}
+
 		fmt.Println()
 		u.spinner.Start()
 		u.spinner.Enable()
@@ -310,6 +332,134 @@ func (u *UI) Trigger(evt *project.StackEvent) {
 		}
 	}
 
+	if evt.WarningEvent != nil {
+		label, fg := "Warning", color.FgYellow
+		if evt.WarningEvent.Level == "info" {
+			label, fg = "Info", color.FgMagenta
+		}
+		message := evt.WarningEvent.Message
+		if evt.WarningEvent.Component != "" {
+			message = evt.WarningEvent.Component + " " + message
+		}
+		u.printEvent(fg, label, message)
+	}
+
+	if evt.ReloadEvent != nil {
+		if evt.ReloadEvent.InFlight {
+			u.printEvent(color.FgMagenta, "Reload", evt.ReloadEvent.Path+" changed, deploying after the current deploy")
+		} else {
+			u.printEvent(color.FgMagenta, "Reload", evt.ReloadEvent.Path+" changed, deploying")
+		}
+	}
+
+	if evt.HotswapFallbackEvent != nil {
+		u.printEvent(color.FgMagenta, "Info", "Falling back to a full deploy: "+evt.HotswapFallbackEvent.Reason)
+	}
+
+	if evt.InspectorEvent != nil {
+		u.printEvent(color.FgBlue, "Inspector", u.functionName(evt.InspectorEvent.FunctionID)+" "+evt.InspectorEvent.URL)
+	}
+
+	if evt.HotswapEvent != nil {
+		u.printEvent(color.FgGreen, "Hotswap", fmt.Sprintf("%s (%s)", evt.HotswapEvent.Name, evt.HotswapEvent.Duration.Round(time.Millisecond)))
+	}
+
+	if evt.TypecheckErrorEvent != nil {
+		diagnostic := evt.TypecheckErrorEvent
+		u.printEvent(color.FgRed, "Error", fmt.Sprintf("%s:%d:%d %s %s", diagnostic.File, diagnostic.Line, diagnostic.Column, diagnostic.Code, diagnostic.Message))
+	}
+
+	if evt.BuildWarningEvent != nil {
+		message := evt.BuildWarningEvent.Text
+		if evt.BuildWarningEvent.File != "" {
+			message = fmt.Sprintf("%s:%d:%d %s", evt.BuildWarningEvent.File, evt.BuildWarningEvent.Line, evt.BuildWarningEvent.Column+1, message)
+		}
+		u.printEvent(color.FgYellow, "Warning", message)
+	}
+
+	if evt.WaitEvent != nil {
+		label := evt.WaitEvent.Condition
+		if evt.WaitEvent.Resource != "" {
+			label = evt.WaitEvent.Resource + " " + label
+		}
+		switch evt.WaitEvent.Status {
+		case project.WaitStatusWaiting:
+			u.spinner.Suffix = "  Waiting for " + label + "..."
+			u.printEvent(color.FgYellow, "Waiting", label)
+		case project.WaitStatusReady:
+			u.spinner.Suffix = "  Finalizing..."
+			u.printEvent(color.FgGreen, "Ready", label)
+		case project.WaitStatusFailed:
+			u.printEvent(color.FgRed, "Not Ready", label+" "+evt.WaitEvent.Error)
+		}
+	}
+
+	if evt.StateTransferEvent != nil {
+		transfer := evt.StateTransferEvent
+		if transfer.Done {
+			if u.transferSuffix != nil {
+				u.spinner.Suffix = *u.transferSuffix
+				u.transferSuffix = nil
+			}
+		} else {
+			if u.transferSuffix == nil {
+				suffix := u.spinner.Suffix
+				u.transferSuffix = &suffix
+			}
+			label := "Pulling state"
+			if transfer.Direction == provider.TransferPush {
+				label = "Pushing state"
+			}
+			if transfer.Total > 0 {
+				u.spinner.Suffix = fmt.Sprintf("  %s %s / %s (%.0f%%), %s left...", label, FormatBytes(transfer.Bytes), FormatBytes(transfer.Total), transfer.Percent, transfer.ETA.Round(time.Second))
+			} else {
+				u.spinner.Suffix = fmt.Sprintf("  %s %s...", label, FormatBytes(transfer.Bytes))
+			}
+		}
+	}
+
+	if evt.DiagnosticContextEvent != nil {
+		for _, line := range evt.DiagnosticContextEvent.Lines {
+			u.printEvent(color.FgRed, "Debug", u.formatURN(evt.DiagnosticContextEvent.URN)+" "+line)
+		}
+	}
+
+	if evt.SecretLeakEvent != nil {
+		for _, leak := range evt.SecretLeakEvent.Leaks {
+			u.printEvent(color.FgYellow, "Leak", fmt.Sprintf("Possible plaintext %s in %s %s", leak.Rule, leak.Location, leak.Path))
+		}
+	}
+
+	if evt.LintEvent != nil {
+		for _, finding := range evt.LintEvent.Findings {
+			message := finding.Message
+			if finding.Location != "" {
+				message = finding.Location + " " + message
+			}
+			u.printEvent(color.FgYellow, "Lint", message)
+		}
+	}
+
+	if evt.SecretRotationEvent != nil {
+		for _, secret := range evt.SecretRotationEvent.Secrets {
+			message := fmt.Sprintf("Secret %s was due for rotation on %s", secret.Name, secret.RotateBy.Format(time.DateOnly))
+			if secret.Owner != "" {
+				message += ", owned by " + secret.Owner
+			}
+			u.printEvent(color.FgYellow, "Warning", message)
+		}
+	}
+
+	if evt.SecurityScanEvent != nil {
+		for _, finding := range evt.SecurityScanEvent.Findings {
+			barColor := color.FgYellow
+			if finding.Severity == project.SeverityCritical || finding.Severity == project.SeverityHigh {
+				barColor = color.FgRed
+			}
+			u.printEvent(barColor, "Security", fmt.Sprintf("[%s] %s %s", finding.Severity, u.formatURN(finding.URN), finding.Message))
+		}
+	}
+
 	if evt.CompleteEvent != nil {
 		u.complete = evt.CompleteEvent
 		u.spinner.Disable()
@@ -397,6 +547,10 @@ func (u *UI) getColor(input string) color.Attribute {
 }
 
 func (u *UI) Event(evt *server.Event) {
+	if output == OutputJSON {
+		writeEvents(evt)
+		return
+	}
 	// if evt.ConcurrentUpdateEvent != nil {
 	// 	u.printEvent(color.FgRed, "Locked", "A concurrent update was detected on the stack. Run `sst unlock` to delete the lock file and retry.")
 	// 	return
@@ -413,7 +567,13 @@ func (u *UI) Event(evt *server.Event) {
 		u.printEvent(u.getColor(evt.FunctionResponseEvent.WorkerID), "Done", formattedDuration)
 	}
 
-	if evt.FunctionLogEvent != nil {
+	if evt.FunctionLogEvent != nil && !u.logFilter.Match(evt.FunctionLogEvent) {
+		return
+	}
+
+	if evt.FunctionLogEvent != nil && evt.FunctionLogEvent.Remote {
+		u.printEvent(u.getColor(evt.FunctionLogEvent.WorkerID), fmt.Sprintf("%.11s", evt.FunctionLogEvent.FunctionID), evt.FunctionLogEvent.Line)
+	} else if evt.FunctionLogEvent != nil {
 		duration := time.Since(u.workerTime[evt.FunctionLogEvent.WorkerID]).Round(time.Millisecond)
 		formattedDuration := fmt.Sprintf("%.9s", fmt.Sprintf("+%v", duration))
 		u.printEvent(u.getColor(evt.FunctionLogEvent.WorkerID), formattedDuration, evt.FunctionLogEvent.Line)
@@ -427,6 +587,110 @@ func (u *UI) Event(evt *server.Event) {
 		u.printEvent(color.FgGreen, "Build", u.functionName(evt.FunctionBuildEvent.FunctionID))
 	}
 
+	if evt.FunctionReloadEvent != nil {
+		u.printEvent(color.FgMagenta, "Reload", u.functionName(evt.FunctionReloadEvent.FunctionID)+" links or environment changed, restarted without rebuilding")
+	}
+
+	if evt.FunctionBuildProgressEvent != nil && !evt.FunctionBuildProgressEvent.Failed {
+		progress := evt.FunctionBuildProgressEvent
+		message := u.functionName(progress.FunctionID)
+		if progress.Total > 1 {
+			message += fmt.Sprintf(" (%d/%d)", progress.Completed, progress.Total)
+		}
+		if progress.Cached {
+			message += " cached"
+		}
+		u.printEvent(color.FgGreen, "Build", message)
+	}
+
+	if evt.QueuePollEvent != nil {
+		if evt.QueuePollEvent.Error != "" {
+			u.printEvent(color.FgRed, "Queue", evt.QueuePollEvent.Name+" could not be polled: "+evt.QueuePollEvent.Error)
+			return
+		}
+		u.printEvent(color.FgBlue, "Queue", "Polling "+evt.QueuePollEvent.Name+" for "+u.functionName(evt.QueuePollEvent.FunctionID))
+	}
+
+	if evt.MetricsEvent != nil {
+		for _, function := range evt.MetricsEvent.Functions {
+			message := fmt.Sprintf("%s %d invocations, avg %v, max %v, %d cold starts",
+				u.functionName(function.FunctionID),
+				function.Invocations,
+				function.AverageDuration().Round(time.Millisecond),
+				function.MaxDuration.Round(time.Millisecond),
+				function.ColdStarts,
+			)
+			if function.Errors > 0 {
+				message += fmt.Sprintf(", %.0f%% errors", function.ErrorRate()*100)
+			}
+			u.printEvent(color.FgCyan, "Metrics", message)
+		}
+	}
+
+	if evt.LogTailEvent != nil {
+		if evt.LogTailEvent.Error != "" {
+			u.printEvent(color.FgRed, "Logs", evt.LogTailEvent.Group+" could not be read: "+evt.LogTailEvent.Error)
+			return
+		}
+		u.printEvent(color.FgBlue, "Logs", "Tailing "+evt.LogTailEvent.FunctionID+" from CloudWatch")
+	}
+
+	if evt.BusPollEvent != nil {
+		if evt.BusPollEvent.Error != "" {
+			u.printEvent(color.FgRed, "Bus", evt.BusPollEvent.Name+" could not be polled: "+evt.BusPollEvent.Error)
+			return
+		}
+		names := []string{}
+		for _, functionID := range evt.BusPollEvent.Subscribers {
+			names = append(names, u.functionName(functionID))
+		}
+		u.printEvent(color.FgBlue, "Bus", "Forwarding "+evt.BusPollEvent.Name+" to "+strings.Join(names, ", "))
+	}
+
+	if evt.TunnelEvent != nil {
+		if evt.TunnelEvent.Error != "" {
+			u.printEvent(color.FgRed, "Tunnel", evt.TunnelEvent.Name+" "+evt.TunnelEvent.Error)
+			return
+		}
+		u.printEvent(color.FgBlue, "Tunnel", evt.TunnelEvent.Name+" "+evt.TunnelEvent.Remote+" → "+evt.TunnelEvent.Local)
+	}
+
+	if evt.SessionConflictEvent != nil {
+		session := evt.SessionConflictEvent.Session
+		who := session.User + "@" + session.Host
+		if evt.SessionConflictEvent.Attached {
+			u.printEvent(color.FgBlue, "Session", "Attached to the session of "+who+", started "+session.Started.Local().Format(time.Kitchen))
+			return
+		}
+		u.printEvent(color.FgYellow, "Session", who+" is also running sst dev on this stage since "+session.Started.Local().Format(time.Kitchen)+", run with --attach to not deploy over it")
+	}
+
+	if evt.FrontendStatusEvent != nil {
+		event := evt.FrontendStatusEvent
+		switch {
+		case event.Error != "":
+			u.printEvent(color.FgRed, "Dev", event.Name+" exited: "+event.Error)
+		case event.Status == frontend.StatusStarted:
+			u.printEvent(color.FgBlue, "Dev", event.Name+" "+event.Command)
+		case event.Status == frontend.StatusRestarting:
+			u.printEvent(color.FgBlue, "Dev", event.Name+" restarting")
+		default:
+			u.printEvent(color.FgBlue, "Dev", event.Name+" exited")
+		}
+	}
+
+	if evt.FrontendLogEvent != nil {
+		u.printEvent(u.getColor(evt.FrontendLogEvent.Name), fmt.Sprintf("%.11s", evt.FrontendLogEvent.Name), evt.FrontendLogEvent.Line)
+	}
+
+	if evt.CronEvent != nil {
+		if evt.CronEvent.Error != "" {
+			u.printEvent(color.FgRed, "Cron", evt.CronEvent.Name+" "+evt.CronEvent.Error)
+			return
+		}
+		u.printEvent(color.FgBlue, "Cron", evt.CronEvent.Name)
+	}
+
 	if evt.FunctionErrorEvent != nil {
 		u.printEvent(u.getColor(evt.FunctionErrorEvent.WorkerID), color.New(color.FgRed).Sprintf("%-11s", "Error"), evt.FunctionErrorEvent.ErrorMessage)
 		for _, item := range evt.FunctionErrorEvent.Trace {
@@ -470,6 +734,15 @@ func (u *UI) Destroy() {
 }
 
 func (u *UI) Header(version, app, stage string) {
+	if output == OutputJSON {
+		WriteJSON("Header", map[string]string{
+			"version": version,
+			"app":     app,
+			"stage":   stage,
+			"mode":    string(u.mode),
+		})
+		return
+	}
 	color.New(color.FgCyan, color.Bold).Print("SST ❍ ion " + version + "  ")
 	color.New(color.FgHiBlack).Print("ready!")
 	fmt.Println()
@@ -582,12 +855,44 @@ func (u *UI) printProgress(progress Progress) {
 	u.hasProgress = true
 }
 
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
 func Success(msg string) {
+	if output == OutputJSON {
+		WriteJSON("Success", map[string]string{"message": msg})
+		return
+	}
 	color.New(color.FgGreen, color.Bold).Print(IconCheck + "  ")
 	color.New(color.FgWhite).Println(msg)
 }
 
 func Error(msg string) {
+	if output == OutputJSON {
+		WriteJSON("Error", map[string]string{"message": msg})
+		return
+	}
 	color.New(color.FgRed, color.Bold).Print(IconX + "  ")
 	color.New(color.FgWhite).Println(msg)
 }
+
+// Warn prints a problem the command carries on from, to stderr so it never
+// mixes with the output of the command.
+func Warn(msg string) {
+	if output == OutputJSON {
+		WriteJSON("Warning", map[string]string{"message": msg})
+		return
+	}
+	color.New(color.FgYellow, color.Bold).Fprint(os.Stderr, "!  ")
+	color.New(color.FgWhite).Fprintln(os.Stderr, msg)
+}
@@ -2,8 +2,10 @@ package fs
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func FindUp(initialPath, fileName string) (string, error) {
@@ -27,6 +29,26 @@ func FindUp(initialPath, fileName string) (string, error) {
 	}
 }
 
+// FindUpAny is FindUp for several file names. The closest directory wins,
+// within a directory the names are checked in order.
+func FindUpAny(initialPath string, fileNames ...string) (string, error) {
+	currentDir := initialPath
+	for {
+		for _, fileName := range fileNames {
+			filePath := filepath.Join(currentDir, fileName)
+			if _, err := os.Stat(filePath); err == nil {
+				return filePath, nil
+			}
+		}
+
+		if currentDir == filepath.Dir(currentDir) {
+			return "", fmt.Errorf("None of '%s' found", strings.Join(fileNames, "', '"))
+		}
+
+		currentDir = filepath.Dir(currentDir)
+	}
+}
+
 func Exists(path string) bool {
 	_, err := os.Stat(path)
 	if os.IsNotExist(err) {
@@ -34,3 +56,44 @@ func Exists(path string) bool {
 	}
 	return err == nil
 }
+
+// CopyFile copies a file and its permissions, creating the directory of
+// the destination.
+func CopyFile(from string, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	dest, err := os.OpenFile(to, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+	_, err = io.Copy(dest, source)
+	return err
+}
+
+// CopyDir copies the regular files in a directory, symlinks are skipped.
+func CopyDir(from string, to string) error {
+	return filepath.Walk(from, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		return CopyFile(path, filepath.Join(to, rel))
+	})
+}
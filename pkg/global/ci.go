@@ -0,0 +1,43 @@
+package global
+
+import (
+	"os"
+	"strings"
+)
+
+// ciProviders are the environment variables CI providers set, checked in
+// order.
+var ciProviders = []struct {
+	Env  string
+	Name string
+}{
+	{"GITHUB_ACTIONS", "GitHub Actions"},
+	{"GITLAB_CI", "GitLab CI"},
+	{"CIRCLECI", "CircleCI"},
+	{"JENKINS_URL", "Jenkins"},
+	{"TRAVIS", "Travis CI"},
+	{"BITBUCKET_BUILD_NUMBER", "Bitbucket Pipelines"},
+	{"CODEBUILD_BUILD_ID", "AWS CodeBuild"},
+	{"BUILDKITE", "Buildkite"},
+	{"TF_BUILD", "Azure Pipelines"},
+}
+
+// CI returns the name of the CI provider the CLI is running in, or an empty
+// string outside of CI. CI=false turns the detection off.
+func CI() string {
+	if value, ok := os.LookupEnv("CI"); ok {
+		value = strings.ToLower(value)
+		if value == "false" || value == "0" {
+			return ""
+		}
+	}
+	for _, provider := range ciProviders {
+		if _, ok := os.LookupEnv(provider.Env); ok {
+			return provider.Name
+		}
+	}
+	if value := strings.ToLower(os.Getenv("CI")); value == "true" || value == "1" {
+		return "CI"
+	}
+	return ""
+}
@@ -0,0 +1,159 @@
+package global
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// UserConfig is the config.toml in the config directory, the defaults of the
+// CLI for every app. The flags, the environment, and the config of the app
+// take precedence over it.
+type UserConfig struct {
+	// Stage is the personal stage of the apps that don't have one yet
+	Stage string `toml:"stage,omitempty"`
+	// Parallelism is how many functions are bundled at once
+	Parallelism int `toml:"parallelism,omitempty"`
+	// Telemetry turns telemetry off when it's false
+	Telemetry *bool `toml:"telemetry,omitempty"`
+	// Proxy is used for the requests of the CLI and the processes it runs
+	Proxy string `toml:"proxy,omitempty"`
+	// Color is auto, always, or never
+	Color string `toml:"color,omitempty"`
+}
+
+// UserConfigKeys are the keys of the user config, in the order they're
+// listed.
+var UserConfigKeys = []string{"stage", "parallelism", "telemetry", "proxy", "color"}
+
+var ErrUnknownConfigKey = fmt.Errorf("unknown config key")
+
+func UserConfigPath() string {
+	if path := os.Getenv("SST_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(configDir, "config.toml")
+}
+
+var userConfig = sync.OnceValues(func() (*UserConfig, error) {
+	result, err := ReadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := result.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", UserConfigPath(), err)
+	}
+	return result, nil
+})
+
+// LoadUserConfig returns the user config, it's read and validated once. The
+// config is empty if the file doesn't exist.
+func LoadUserConfig() (*UserConfig, error) {
+	return userConfig()
+}
+
+// ReadUserConfig reads the user config from disk, without validating the
+// values so they can be fixed with Set.
+func ReadUserConfig() (*UserConfig, error) {
+	result := &UserConfig{}
+	data, err := os.ReadFile(UserConfigPath())
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := toml.Decode(string(data), result); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", UserConfigPath(), err)
+	}
+	return result, nil
+}
+
+func (c *UserConfig) Save() error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return err
+	}
+	path := UserConfigPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func (c *UserConfig) validate() error {
+	if c.Parallelism < 0 {
+		return fmt.Errorf("parallelism must be a positive number")
+	}
+	if c.Color != "" && c.Color != "auto" && c.Color != "always" && c.Color != "never" {
+		return fmt.Errorf("color must be one of: auto, always, never")
+	}
+	return nil
+}
+
+// Get returns the value of a key as it's passed to Set, it's empty if the key
+// isn't set.
+func (c *UserConfig) Get(key string) (string, error) {
+	switch key {
+	case "stage":
+		return c.Stage, nil
+	case "parallelism":
+		if c.Parallelism == 0 {
+			return "", nil
+		}
+		return strconv.Itoa(c.Parallelism), nil
+	case "telemetry":
+		if c.Telemetry == nil {
+			return "", nil
+		}
+		return strconv.FormatBool(*c.Telemetry), nil
+	case "proxy":
+		return c.Proxy, nil
+	case "color":
+		return c.Color, nil
+	}
+	return "", ErrUnknownConfigKey
+}
+
+// Set sets a key from a string, an empty value unsets it.
+func (c *UserConfig) Set(key string, value string) error {
+	next := *c
+	switch key {
+	case "stage":
+		next.Stage = value
+	case "parallelism":
+		next.Parallelism = 0
+		if value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("parallelism must be a number")
+			}
+			next.Parallelism = parsed
+		}
+	case "telemetry":
+		next.Telemetry = nil
+		if value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("telemetry must be true or false")
+			}
+			next.Telemetry = &parsed
+		}
+	case "proxy":
+		next.Proxy = value
+	case "color":
+		next.Color = value
+	default:
+		return ErrUnknownConfigKey
+	}
+	if err := next.validate(); err != nil {
+		return err
+	}
+	*c = next
+	return nil
+}
@@ -0,0 +1,194 @@
+package global
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// versionsDir is where the versions of the CLI pinned by apps are installed,
+// one directory per version.
+func versionsDir() string {
+	return filepath.Join(configDir, "versions")
+}
+
+// VersionBinary returns the path of an installed version of the CLI, like
+// v0.1.0.
+func VersionBinary(version string) string {
+	return filepath.Join(versionsDir(), version, binaryName())
+}
+
+// InstalledVersions returns the versions of the CLI in the versions
+// directory, newest first.
+func InstalledVersions() []*semver.Version {
+	entries, err := os.ReadDir(versionsDir())
+	if err != nil {
+		return nil
+	}
+	result := []*semver.Version{}
+	for _, entry := range entries {
+		parsed, err := semver.NewVersion(entry.Name())
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(VersionBinary(entry.Name())); err != nil {
+			continue
+		}
+		result = append(result, parsed)
+	}
+	sort.Sort(sort.Reverse(semver.Collection(result)))
+	return result
+}
+
+// ResolveVersion returns the newest version of the CLI that matches the
+// constraint, preferring the ones already installed over a release. An
+// exact version, like 0.1.0, is looked up directly instead of listing the
+// releases.
+func ResolveVersion(constraint string) (string, error) {
+	parsed, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+	for _, installed := range InstalledVersions() {
+		if parsed.Check(installed) {
+			return installed.Original(), nil
+		}
+	}
+	if exact, err := semver.StrictNewVersion(strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(constraint), "="), "v")); err == nil {
+		return findRelease("v" + exact.String())
+	}
+	releases, err := listReleases()
+	if err != nil {
+		return "", err
+	}
+	for _, release := range releases {
+		if parsed.Check(release) {
+			return release.Original(), nil
+		}
+	}
+	return "", fmt.Errorf("no release of sst matches %s", constraint)
+}
+
+type release struct {
+	TagName    string `json:"tag_name"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// findRelease returns the tag of a release, like v0.1.0.
+func findRelease(tag string) (string, error) {
+	resp, err := http.Get("https://api.github.com/repos/sst/ion/releases/tags/" + tag)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("there is no release %s of sst", tag)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP status when getting release %s: %s", tag, resp.Status)
+	}
+	var found release
+	if err := json.NewDecoder(resp.Body).Decode(&found); err != nil {
+		return "", err
+	}
+	return found.TagName, nil
+}
+
+// listReleases returns the released versions of the CLI, newest first. It
+// follows the pages of the GitHub API until there are no more.
+func listReleases() ([]*semver.Version, error) {
+	result := []*semver.Version{}
+	url := "https://api.github.com/repos/sst/ion/releases?per_page=100"
+	for url != "" {
+		releases, next, err := listReleasesPage(url)
+		if err != nil {
+			return nil, err
+		}
+		for _, release := range releases {
+			if release.Draft || release.Prerelease {
+				continue
+			}
+			parsed, err := semver.NewVersion(release.TagName)
+			if err != nil {
+				continue
+			}
+			result = append(result, parsed)
+		}
+		url = next
+	}
+	sort.Sort(sort.Reverse(semver.Collection(result)))
+	return result, nil
+}
+
+// listReleasesPage returns a page of releases and the URL of the next one
+// from the Link header, it's empty on the last page.
+func listReleasesPage(url string) ([]release, string, error) {
+	slog.Info("listing releases", "url", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected HTTP status when listing releases: %s", resp.Status)
+	}
+	var releases []release
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, "", err
+	}
+	return releases, nextLink(resp.Header.Get("Link")), nil
+}
+
+// nextLink returns the rel="next" URL of a Link header, like
+// <https://api.github.com/...&page=2>; rel="next", <...>; rel="last".
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(link, ";")
+		if !ok {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(target), "<>")
+			}
+		}
+	}
+	return ""
+}
+
+// InstallVersion downloads a version of the CLI to the versions directory,
+// unless it's already there, and returns the path of the binary.
+func InstallVersion(version string) (string, error) {
+	binary := VersionBinary(version)
+	if _, err := os.Stat(binary); err == nil {
+		return binary, nil
+	}
+	slog.Info("installing version", "version", version)
+	if err := os.MkdirAll(versionsDir(), 0755); err != nil {
+		return "", err
+	}
+	// downloaded next to the final directory so a failed download is never
+	// picked up as installed
+	tmp, err := os.MkdirTemp(versionsDir(), ".download-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	if err := downloadRelease(version, tmp); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, filepath.Dir(binary)); err != nil {
+		return "", err
+	}
+	return binary, nil
+}
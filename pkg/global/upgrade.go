@@ -2,7 +2,11 @@ package global
 
 import (
 	"archive/tar"
+	"archive/zip"
+	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,26 +19,6 @@ import (
 )
 
 func Upgrade(version string) (string, error) {
-	var filename string
-	switch runtime.GOOS {
-	case "darwin":
-		filename = "mac-"
-	case "windows":
-		filename = "windows-"
-	default:
-		filename = "linux-"
-	}
-
-	switch runtime.GOARCH {
-	case "amd64":
-		filename += "x86_64.tar.gz"
-	case "arm64":
-		filename += "arm64.tar.gz"
-	case "386":
-		filename += "i386.tar.gz"
-	default:
-		return "", fmt.Errorf("unsupported architecture")
-	}
 	if version != "" {
 		if !strings.HasPrefix(version, "v") {
 			version = "v" + version
@@ -59,45 +43,142 @@ func Upgrade(version string) (string, error) {
 		}
 		version = releaseInfo.TagName
 	}
-	url := "https://github.com/sst/ion/releases/download/" + version + "/sst-" + filename
-	slog.Info("downloading", "url", url)
-	resp, err := http.Get(url)
+
+	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected HTTP status when downloading release: %s", resp.Status)
+	sstBinPath := filepath.Join(homeDir, ".sst", "bin")
+	if err := os.MkdirAll(filepath.Dir(sstBinPath), 0755); err != nil {
+		return "", err
 	}
-
-	homeDir, err := os.UserHomeDir()
+	// the current binary is only replaced once the download is verified
+	tmp, err := os.MkdirTemp(filepath.Dir(sstBinPath), ".upgrade-")
 	if err != nil {
 		return "", err
 	}
-
-	sstBinPath := filepath.Join(homeDir, ".sst", "bin")
+	defer os.RemoveAll(tmp)
+	if err := downloadRelease(version, tmp); err != nil {
+		return "", err
+	}
 	os.RemoveAll(sstBinPath)
-	if err := os.MkdirAll(sstBinPath, os.ModePerm); err != nil {
+	if err := os.Rename(tmp, sstBinPath); err != nil {
 		return "", err
 	}
+	return version, nil
+}
 
-	// Assuming we have a variable `resp` which is the response from a *http.Request
-	body, err := gzip.NewReader(resp.Body)
+func releaseFilename() (string, error) {
+	var filename string
+	switch runtime.GOOS {
+	case "darwin":
+		filename = "mac-"
+	case "windows":
+		filename = "windows-"
+	default:
+		filename = "linux-"
+	}
+
+	switch runtime.GOARCH {
+	case "amd64":
+		filename += "x86_64"
+	case "arm64":
+		filename += "arm64"
+	case "386":
+		filename += "i386"
+	default:
+		return "", fmt.Errorf("unsupported architecture")
+	}
+	// the windows releases are zipped
+	if runtime.GOOS == "windows" {
+		return filename + ".zip", nil
+	}
+	return filename + ".tar.gz", nil
+}
+
+// binaryName is the name of the CLI in a release.
+func binaryName() string {
+	if runtime.GOOS == "windows" {
+		return "sst.exe"
+	}
+	return "sst"
+}
+
+// downloadRelease downloads the CLI of a release, like v0.1.0, to target.
+// The archive is checked against the checksums of the release before
+// anything is written.
+func downloadRelease(version string, target string) error {
+	filename, err := releaseFilename()
 	if err != nil {
-		return "", err
+		return err
+	}
+	filename = "sst-" + filename
+	base := "https://github.com/sst/ion/releases/download/" + version + "/"
+	checksums, err := download(base + "checksums.txt")
+	if err != nil {
+		return err
+	}
+	expected, err := findChecksum(checksums, filename)
+	if err != nil {
+		return err
+	}
+	archive, err := download(base + filename)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(archive)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("checksum of %s does not match the release, expected %s but got %s", filename, expected, actual)
 	}
-	defer body.Close()
 
-	if err := untar(body, sstBinPath); err != nil {
-		return "", err
+	if err := os.MkdirAll(target, os.ModePerm); err != nil {
+		return err
 	}
+	if strings.HasSuffix(filename, ".zip") {
+		err = unzip(archive, target)
+	} else {
+		err = untargz(archive, target)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Chmod(filepath.Join(target, binaryName()), 0755)
+}
 
-	if err := os.Chmod(filepath.Join(sstBinPath, "sst"), 0755); err != nil {
-		return "", err
+func download(url string) ([]byte, error) {
+	slog.Info("downloading", "url", url)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return version, nil
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status when downloading %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// findChecksum returns the sha256 of a file in the checksums.txt of a
+// release, it has a line of "<sha256>  <filename>" for each archive.
+func findChecksum(checksums []byte, filename string) (string, error) {
+	for _, line := range strings.Split(string(checksums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == filename {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s is not in the checksums of the release", filename)
+}
+
+func untargz(archive []byte, target string) error {
+	body, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return untar(body, target)
 }
 
 func untar(reader io.Reader, target string) error {
@@ -110,22 +191,74 @@ func untar(reader io.Reader, target string) error {
 		if err != nil {
 			return err
 		}
+		path, err := archivePath(target, header.Name)
+		if err != nil {
+			return err
+		}
 		switch header.Typeflag {
 		case tar.TypeDir:
-			if err := os.MkdirAll(filepath.Join(target, header.Name), 0755); err != nil {
+			if err := os.MkdirAll(path, 0755); err != nil {
 				return err
 			}
 		case tar.TypeReg:
-			outFile, err := os.Create(filepath.Join(target, header.Name))
-			if err != nil {
+			if err := writeFile(path, tarReader); err != nil {
 				return err
 			}
-			if _, err := io.Copy(outFile, tarReader); err != nil {
-				outFile.Close()
+		}
+	}
+	return nil
+}
+
+func unzip(archive []byte, target string) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+	for _, file := range reader.File {
+		path, err := archivePath(target, file.Name)
+		if err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
 				return err
 			}
-			outFile.Close()
+			continue
+		}
+		contents, err := file.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(path, contents)
+		contents.Close()
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
+
+// archivePath is where an entry of an archive is written, entries that
+// would end up outside of target are an error.
+func archivePath(target string, name string) (string, error) {
+	path := filepath.Join(target, filepath.FromSlash(name))
+	if path != filepath.Clean(target) && !strings.HasPrefix(path, filepath.Clean(target)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+	return path, nil
+}
+
+func writeFile(path string, reader io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	outFile, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(outFile, reader); err != nil {
+		outFile.Close()
+		return err
+	}
+	return outFile.Close()
+}
@@ -0,0 +1,120 @@
+package js
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Analysis is how the output of a bundle breaks down by the files and the
+// packages that went into it.
+type Analysis struct {
+	Bytes    int           `json:"bytes"`
+	Inputs   []InputSize   `json:"inputs"`
+	Packages []PackageSize `json:"packages"`
+	// Duplicates are packages bundled from more than one copy
+	Duplicates []DuplicatePackage `json:"duplicates"`
+}
+
+type InputSize struct {
+	Path  string `json:"path"`
+	Bytes int    `json:"bytes"`
+}
+
+type PackageSize struct {
+	Name  string `json:"name"`
+	Bytes int    `json:"bytes"`
+}
+
+type DuplicatePackage struct {
+	Name  string   `json:"name"`
+	Paths []string `json:"paths"`
+}
+
+type metafile struct {
+	Outputs map[string]struct {
+		Inputs map[string]struct {
+			BytesInOutput int `json:"bytesInOutput"`
+		} `json:"inputs"`
+	} `json:"outputs"`
+}
+
+// Analyze reads an esbuild metafile. Sizes are the bytes each input takes
+// up in the outputs, after tree shaking and minification.
+func Analyze(data []byte) (*Analysis, error) {
+	var meta metafile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	result := &Analysis{
+		Inputs:     []InputSize{},
+		Packages:   []PackageSize{},
+		Duplicates: []DuplicatePackage{},
+	}
+	inputs := map[string]int{}
+	packages := map[string]int{}
+	copies := map[string]map[string]bool{}
+	for _, output := range meta.Outputs {
+		for path, input := range output.Inputs {
+			inputs[path] += input.BytesInOutput
+			result.Bytes += input.BytesInOutput
+			name, dir := packageOf(path)
+			if name == "" {
+				continue
+			}
+			packages[name] += input.BytesInOutput
+			if copies[name] == nil {
+				copies[name] = map[string]bool{}
+			}
+			copies[name][dir] = true
+		}
+	}
+	for path, bytes := range inputs {
+		result.Inputs = append(result.Inputs, InputSize{Path: path, Bytes: bytes})
+	}
+	for name, bytes := range packages {
+		result.Packages = append(result.Packages, PackageSize{Name: name, Bytes: bytes})
+		if len(copies[name]) < 2 {
+			continue
+		}
+		paths := []string{}
+		for dir := range copies[name] {
+			paths = append(paths, dir)
+		}
+		sort.Strings(paths)
+		result.Duplicates = append(result.Duplicates, DuplicatePackage{Name: name, Paths: paths})
+	}
+	sort.Slice(result.Inputs, func(i, j int) bool {
+		if result.Inputs[i].Bytes != result.Inputs[j].Bytes {
+			return result.Inputs[i].Bytes > result.Inputs[j].Bytes
+		}
+		return result.Inputs[i].Path < result.Inputs[j].Path
+	})
+	sort.Slice(result.Packages, func(i, j int) bool {
+		if result.Packages[i].Bytes != result.Packages[j].Bytes {
+			return result.Packages[i].Bytes > result.Packages[j].Bytes
+		}
+		return result.Packages[i].Name < result.Packages[j].Name
+	})
+	sort.Slice(result.Duplicates, func(i, j int) bool {
+		return result.Duplicates[i].Name < result.Duplicates[j].Name
+	})
+	return result, nil
+}
+
+// packageOf returns the package an input belongs to and the directory it
+// was installed in, from the innermost node_modules in its path.
+func packageOf(path string) (string, string) {
+	path = filepath.ToSlash(path)
+	index := strings.LastIndex(path, "node_modules/")
+	if index == -1 {
+		return "", ""
+	}
+	rest := strings.Split(path[index+len("node_modules/"):], "/")
+	name := rest[0]
+	if strings.HasPrefix(name, "@") && len(rest) > 1 {
+		name += "/" + rest[1]
+	}
+	return name, path[:index] + "node_modules/" + name
+}
@@ -0,0 +1,94 @@
+package js
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+)
+
+// Builder keeps an esbuild context alive between builds so a rebuild only
+// reparses the files that changed. The context is recreated when the
+// options change. The zero value is ready to use.
+type Builder struct {
+	mutex   sync.Mutex
+	context esbuild.BuildContext
+	key     string
+}
+
+// Build is js.Build with the build cached in the Builder. Every build
+// writes to the same file, so a build replaces the output of the last one.
+func (b *Builder) Build(input EvalOptions) (esbuild.BuildResult, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	key, err := OptionsKey(input, "")
+	if err != nil {
+		return esbuild.BuildResult{}, err
+	}
+	if b.context == nil || key != b.key {
+		b.dispose()
+		outfile := filepath.Join(input.Dir, "eval", fmt.Sprintf("eval-%v-incremental.mjs", os.Getpid()))
+		slog.Info("esbuild creating context", "outfile", outfile)
+		ctx, ctxErr := esbuild.Context(buildOptions(input, outfile))
+		if ctxErr != nil {
+			return esbuild.BuildResult{}, fmt.Errorf("esbuild errors: %v", ctxErr.Errors)
+		}
+		b.context = ctx
+		b.key = key
+	}
+
+	slog.Info("esbuild rebuilding")
+	result := b.context.Rebuild()
+	if len(result.Errors) > 0 {
+		slog.Error("esbuild errors", "errors", result.Errors)
+		return result, fmt.Errorf("esbuild errors: %v", result.Errors)
+	}
+	slog.Info("esbuild rebuilt")
+	return result, nil
+}
+
+// Dispose stops the esbuild context, the next build starts cold.
+func (b *Builder) Dispose() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.dispose()
+}
+
+func (b *Builder) dispose() {
+	if b.context != nil {
+		b.context.Dispose()
+		b.context = nil
+	}
+}
+
+// OptionsKey identifies a build by its options. Plugins can't be compared
+// so only their names are part of it. Paths in root are replaced so the key
+// is the same for a checkout in a different directory.
+func OptionsKey(input EvalOptions, root string) (string, error) {
+	plugins := []string{}
+	for _, plugin := range input.Plugins {
+		plugins = append(plugins, plugin.Name)
+	}
+	input.Plugins = nil
+	data, err := json.Marshal(struct {
+		Options EvalOptions
+		Plugins []string
+	}{input, plugins})
+	if err != nil {
+		return "", err
+	}
+	if root != "" {
+		encoded, _ := json.Marshal(root)
+		data = bytes.ReplaceAll(data, encoded[1:len(encoded)-1], []byte("$root"))
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,140 @@
+package js
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+)
+
+var nodeBuiltins = map[string]bool{
+	"assert": true, "async_hooks": true, "buffer": true, "child_process": true,
+	"cluster": true, "console": true, "constants": true, "crypto": true,
+	"dgram": true, "diagnostics_channel": true, "dns": true, "domain": true,
+	"events": true, "fs": true, "http": true, "http2": true, "https": true,
+	"inspector": true, "module": true, "net": true, "os": true, "path": true,
+	"perf_hooks": true, "process": true, "punycode": true, "querystring": true,
+	"readline": true, "repl": true, "stream": true, "string_decoder": true,
+	"timers": true, "tls": true, "trace_events": true, "tty": true, "url": true,
+	"util": true, "v8": true, "vm": true, "wasi": true, "worker_threads": true,
+	"zlib": true,
+}
+
+// specifiers Deno resolves on its own
+var denoSpecifierRegex = regexp.MustCompile(`^(npm|jsr|node|https?):`)
+
+// DenoPlugin makes the bundle runnable with Deno. Node builtins are imported
+// with node: and the packages left out of the bundle with npm: pinned to the
+// version installed in modules. Imports that match the import map in the
+// deno.json in root are left for Deno to resolve.
+func DenoPlugin(root string, modules string) esbuild.Plugin {
+	imports := denoImports(root)
+	return esbuild.Plugin{
+		Name: "deno",
+		Setup: func(build esbuild.PluginBuild) {
+			build.OnResolve(esbuild.OnResolveOptions{Filter: `.*`}, func(args esbuild.OnResolveArgs) (esbuild.OnResolveResult, error) {
+				if args.Kind == esbuild.ResolveEntryPoint {
+					return esbuild.OnResolveResult{}, nil
+				}
+				if denoSpecifierRegex.MatchString(args.Path) {
+					return esbuild.OnResolveResult{Path: args.Path, External: true}, nil
+				}
+				for key := range imports {
+					if args.Path == key || (strings.HasSuffix(key, "/") && strings.HasPrefix(args.Path, key)) {
+						return esbuild.OnResolveResult{Path: args.Path, External: true}, nil
+					}
+				}
+				name, subpath := splitPackage(args.Path)
+				if nodeBuiltins[name] {
+					return esbuild.OnResolveResult{Path: "node:" + args.Path, External: true}, nil
+				}
+				if isExternal(name) {
+					specifier := "npm:" + name
+					if version := installedVersion(modules, name); version != "" {
+						specifier += "@" + version
+					}
+					return esbuild.OnResolveResult{Path: specifier + subpath, External: true}, nil
+				}
+				return esbuild.OnResolveResult{}, nil
+			})
+		},
+	}
+}
+
+// denoOnlyRegex are the specifiers only Deno resolves, node: works in both
+var denoOnlyRegex = regexp.MustCompile(`^(npm|jsr|https?):`)
+
+// DenoOnlyPlugin is for a bundle of a Deno config that's run with Node. A
+// dynamic import of a specifier only Deno resolves is left in, it only fails
+// if it's run. A static one would fail as soon as the bundle is loaded, so
+// it's an error.
+func DenoOnlyPlugin(message string) esbuild.Plugin {
+	return esbuild.Plugin{
+		Name: "deno-only",
+		Setup: func(build esbuild.PluginBuild) {
+			build.OnResolve(esbuild.OnResolveOptions{Filter: denoOnlyRegex.String()}, func(args esbuild.OnResolveArgs) (esbuild.OnResolveResult, error) {
+				if args.Kind == esbuild.ResolveJSDynamicImport {
+					return esbuild.OnResolveResult{Path: args.Path, External: true}, nil
+				}
+				return esbuild.OnResolveResult{
+					Errors: []esbuild.Message{{Text: fmt.Sprintf("%q %s", args.Path, message)}},
+				}, nil
+			})
+		},
+	}
+}
+
+func denoImports(root string) map[string]string {
+	result := map[string]string{}
+	for _, name := range []string{"deno.json", "deno.jsonc"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		var parsed struct {
+			Imports map[string]string `json:"imports"`
+		}
+		if json.Unmarshal(data, &parsed) == nil && parsed.Imports != nil {
+			return parsed.Imports
+		}
+	}
+	return result
+}
+
+// splitPackage splits an import into the package name and the subpath.
+func splitPackage(path string) (string, string) {
+	parts := strings.SplitN(path, "/", 3)
+	if strings.HasPrefix(path, "@") && len(parts) >= 2 {
+		name := parts[0] + "/" + parts[1]
+		return name, strings.TrimPrefix(path, name)
+	}
+	return parts[0], strings.TrimPrefix(path, parts[0])
+}
+
+func isExternal(name string) bool {
+	for _, pattern := range external {
+		if pattern == name {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func installedVersion(modules string, name string) string {
+	data, err := os.ReadFile(filepath.Join(modules, name, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var parsed struct {
+		Version string `json:"version"`
+	}
+	json.Unmarshal(data, &parsed)
+	return parsed.Version
+}
@@ -16,6 +16,25 @@ type EvalOptions struct {
 	Banner string
 	Inject []string
 	Define map[string]string
+	// External are extra imports left out of the bundle
+	External []string
+	// Plugins run in order before esbuild resolves an import itself, like
+	// resolving custom protocols or stubbing modules. An import a plugin
+	// returns nothing for goes on to the next one and then to esbuild.
+	Plugins []esbuild.Plugin
+	// Minify, KeepNames, and Footer tune the output, it's readable by
+	// default
+	Minify    bool
+	KeepNames bool
+	Footer    string
+}
+
+var external = []string{
+	"@pulumi/*",
+	"@aws-sdk/*",
+	"esbuild",
+	"archiver",
+	"glob",
 }
 
 func Build(input EvalOptions) (esbuild.BuildResult, error) {
@@ -24,26 +43,31 @@ func Build(input EvalOptions) (esbuild.BuildResult, error) {
 		fmt.Sprintf("eval-%v.mjs", time.Now().UnixMilli()),
 	)
 	slog.Info("esbuild building")
-	result := esbuild.Build(esbuild.BuildOptions{
+	result := esbuild.Build(buildOptions(input, outfile))
+	if len(result.Errors) > 0 {
+		slog.Error("esbuild errors", "errors", result.Errors)
+		return result, fmt.Errorf("esbuild errors: %v", result.Errors)
+	}
+	slog.Info("esbuild built", "outfile", outfile)
+
+	return result, nil
+}
+
+func buildOptions(input EvalOptions, outfile string) esbuild.BuildOptions {
+	return esbuild.BuildOptions{
 		Banner: map[string]string{
 			"js": `
-import { createRequire as topLevelCreateRequire } from 'module';
+import { createRequire as topLevelCreateRequire } from 'node:module';
 const require = topLevelCreateRequire(import.meta.url);
-import { fileURLToPath as topLevelFileUrlToPath, URL as topLevelURL } from "url"
+import { fileURLToPath as topLevelFileUrlToPath, URL as topLevelURL } from "node:url"
 const __dirname = topLevelFileUrlToPath(new topLevelURL(".", import.meta.url))
 ` + input.Banner,
 		},
 		MainFields: []string{"module", "main"},
-		External: []string{
-			"@pulumi/*",
-			"@aws-sdk/*",
-			"esbuild",
-			"archiver",
-			"glob",
-		},
-		Format:    esbuild.FormatESModule,
-		Platform:  esbuild.PlatformNode,
-		Sourcemap: esbuild.SourceMapInline,
+		External:   append(append([]string{}, external...), input.External...),
+		Format:     esbuild.FormatESModule,
+		Platform:   esbuild.PlatformNode,
+		Sourcemap:  esbuild.SourceMapInline,
 		Stdin: &esbuild.StdinOptions{
 			Contents:   input.Code,
 			ResolveDir: input.Dir,
@@ -52,16 +76,42 @@ const __dirname = topLevelFileUrlToPath(new topLevelURL(".", import.meta.url))
 		},
 		Define:   input.Define,
 		Inject:   input.Inject,
+		Plugins:  input.Plugins,
 		Outfile:  outfile,
 		Write:    true,
 		Bundle:   true,
 		Metafile: true,
+
+		MinifyWhitespace:  input.Minify,
+		MinifySyntax:      input.Minify,
+		MinifyIdentifiers: input.Minify,
+		KeepNames:         input.KeepNames,
+		Footer:            map[string]string{"js": input.Footer},
+	}
+}
+
+// BuildModule bundles code into a standalone module at outfile. It's used
+// for generated code that can be cached and imported by later builds.
+func BuildModule(dir string, code string, outfile string) error {
+	slog.Info("esbuild building module", "outfile", outfile)
+	result := esbuild.Build(esbuild.BuildOptions{
+		MainFields: []string{"module", "main"},
+		External:   external,
+		Format:     esbuild.FormatESModule,
+		Platform:   esbuild.PlatformNode,
+		Stdin: &esbuild.StdinOptions{
+			Contents:   code,
+			ResolveDir: dir,
+			Sourcefile: "module.ts",
+			Loader:     esbuild.LoaderTS,
+		},
+		Outfile: outfile,
+		Write:   true,
+		Bundle:  true,
 	})
 	if len(result.Errors) > 0 {
 		slog.Error("esbuild errors", "errors", result.Errors)
-		return result, fmt.Errorf("esbuild errors: %v", result.Errors)
+		return fmt.Errorf("esbuild errors: %v", result.Errors)
 	}
-	slog.Info("esbuild built", "outfile", outfile)
-
-	return result, nil
+	return nil
 }
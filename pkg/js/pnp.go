@@ -0,0 +1,167 @@
+package js
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/sst/ion/internal/fs"
+)
+
+// the hooks yarn adds to NODE_OPTIONS when running a script under PnP
+var pnpOptionRegex = regexp.MustCompile(`(?:--require|-r|--experimental-loader|--loader|--import)[ =](?:"[^"]*\.pnp(?:\.loader)?\.[cm]?js"|\S*\.pnp(?:\.loader)?\.[cm]?js)`)
+
+// FindPnP returns the Yarn PnP manifest that applies to dir, or an empty
+// string if the project doesn't use PnP.
+func FindPnP(dir string) string {
+	match, err := fs.FindUpAny(dir, ".pnp.cjs", ".pnp.js", ".pnp.data.json")
+	if err != nil {
+		return ""
+	}
+	return match
+}
+
+// WithoutPnP removes the Yarn PnP hooks from NODE_OPTIONS. Programs that
+// run from the platform directory import packages that aren't in the PnP
+// manifest, so they have to use the node_modules resolution.
+func WithoutPnP(options string) string {
+	return strings.Join(strings.Fields(pnpOptionRegex.ReplaceAllString(options, "")), " ")
+}
+
+// NodeModulesPlugin resolves the bare imports of files in dir from the
+// node_modules directories in it. Yarn PnP claims every file in the
+// project, including the ones in dir, and fails for packages that were
+// installed there instead of through yarn. Imports that aren't installed in
+// dir fall through to the regular resolution.
+func NodeModulesPlugin(dir string) esbuild.Plugin {
+	return esbuild.Plugin{
+		Name: "node-modules",
+		Setup: func(build esbuild.PluginBuild) {
+			build.OnResolve(esbuild.OnResolveOptions{Filter: `^[^./]`}, func(args esbuild.OnResolveArgs) (esbuild.OnResolveResult, error) {
+				if filepath.IsAbs(args.Path) || denoSpecifierRegex.MatchString(args.Path) {
+					return esbuild.OnResolveResult{}, nil
+				}
+				from := args.ResolveDir
+				if filepath.IsAbs(args.Importer) {
+					from = filepath.Dir(args.Importer)
+				}
+				if !isWithin(dir, from) {
+					return esbuild.OnResolveResult{}, nil
+				}
+				name, subpath := splitPackage(args.Path)
+				if nodeBuiltins[name] || isExternal(name) {
+					return esbuild.OnResolveResult{}, nil
+				}
+				pkgDir := findPackage(dir, from, name)
+				if pkgDir == "" {
+					return esbuild.OnResolveResult{}, nil
+				}
+				entry := strings.TrimPrefix(strings.TrimPrefix(packageEntry(pkgDir, subpath), "./"), "/")
+				result := build.Resolve("./"+entry, esbuild.ResolveOptions{
+					ResolveDir: pkgDir,
+					Kind:       args.Kind,
+				})
+				if len(result.Errors) > 0 {
+					return esbuild.OnResolveResult{Errors: result.Errors}, nil
+				}
+				return esbuild.OnResolveResult{
+					Path:      result.Path,
+					External:  result.External,
+					Namespace: result.Namespace,
+				}, nil
+			})
+		},
+	}
+}
+
+func isWithin(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// findPackage looks for name in the node_modules directories from the
+// directory of the importer up to dir.
+func findPackage(dir string, from string, name string) string {
+	for current := from; isWithin(dir, current); current = filepath.Dir(current) {
+		match := filepath.Join(current, "node_modules", name)
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			return match
+		}
+		if current == dir {
+			break
+		}
+	}
+	return ""
+}
+
+// packageEntry maps the subpath of an import to a file in the package with
+// its exports, or returns the subpath as is if there are none.
+func packageEntry(pkgDir string, subpath string) string {
+	data, err := os.ReadFile(filepath.Join(pkgDir, "package.json"))
+	if err != nil {
+		return subpath
+	}
+	var parsed struct {
+		Exports interface{} `json:"exports"`
+	}
+	if json.Unmarshal(data, &parsed) != nil || parsed.Exports == nil {
+		return subpath
+	}
+	key := "." + subpath
+	var target interface{}
+	switch exports := parsed.Exports.(type) {
+	case map[string]interface{}:
+		subpaths := false
+		for name := range exports {
+			subpaths = subpaths || strings.HasPrefix(name, ".")
+		}
+		if !subpaths {
+			if key == "." {
+				target = exports
+			}
+			break
+		}
+		target = exports[key]
+		if target == nil {
+			for pattern, value := range exports {
+				prefix, ok := strings.CutSuffix(pattern, "*")
+				if ok && strings.HasPrefix(key, prefix) {
+					if result := exportTarget(value); result != "" {
+						return strings.ReplaceAll(result, "*", strings.TrimPrefix(key, prefix))
+					}
+				}
+			}
+		}
+	default:
+		if key == "." {
+			target = exports
+		}
+	}
+	if result := exportTarget(target); result != "" {
+		return result
+	}
+	return subpath
+}
+
+func exportTarget(value interface{}) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case []interface{}:
+		for _, item := range value {
+			if result := exportTarget(item); result != "" {
+				return result
+			}
+		}
+	case map[string]interface{}:
+		for _, condition := range []string{"node", "import", "module", "default", "require"} {
+			if result := exportTarget(value[condition]); result != "" {
+				return result
+			}
+		}
+	}
+	return ""
+}
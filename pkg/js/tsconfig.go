@@ -0,0 +1,240 @@
+package js
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+)
+
+// tsconfigPaths are the path aliases of a tsconfig, with the targets made
+// absolute.
+type tsconfigPaths struct {
+	baseUrl string
+	paths   map[string][]string
+}
+
+type tsconfigFile struct {
+	Extends         json.RawMessage `json:"extends"`
+	CompilerOptions struct {
+		BaseUrl *string             `json:"baseUrl"`
+		Paths   map[string][]string `json:"paths"`
+	} `json:"compilerOptions"`
+}
+
+// readTsconfigPaths reads the paths and baseUrl of a tsconfig and the ones it
+// extends. Like tsc, paths without a baseUrl are relative to the tsconfig
+// they are declared in.
+func readTsconfigPaths(path string, seen map[string]bool) (*tsconfigPaths, error) {
+	if seen[path] {
+		return &tsconfigPaths{}, nil
+	}
+	seen[path] = true
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var parsed tsconfigFile
+	if err := json.Unmarshal(stripJSONC(data), &parsed); err != nil {
+		return nil, err
+	}
+
+	result := &tsconfigPaths{}
+	var extends []string
+	if json.Unmarshal(parsed.Extends, &extends) != nil {
+		var single string
+		if json.Unmarshal(parsed.Extends, &single) == nil && single != "" {
+			extends = []string{single}
+		}
+	}
+	for _, extend := range extends {
+		resolved := resolveTsconfigExtends(filepath.Dir(path), extend)
+		if resolved == "" {
+			continue
+		}
+		base, err := readTsconfigPaths(resolved, seen)
+		if err != nil {
+			return nil, err
+		}
+		if base.baseUrl != "" {
+			result.baseUrl = base.baseUrl
+		}
+		if base.paths != nil {
+			result.paths = base.paths
+		}
+	}
+
+	dir := filepath.Dir(path)
+	if parsed.CompilerOptions.BaseUrl != nil {
+		result.baseUrl = filepath.Join(dir, *parsed.CompilerOptions.BaseUrl)
+	}
+	if parsed.CompilerOptions.Paths != nil {
+		base := result.baseUrl
+		if base == "" {
+			base = dir
+		}
+		result.paths = map[string][]string{}
+		for pattern, targets := range parsed.CompilerOptions.Paths {
+			for _, target := range targets {
+				result.paths[pattern] = append(result.paths[pattern], filepath.Join(base, target))
+			}
+		}
+	}
+	return result, nil
+}
+
+// resolveTsconfigExtends finds an extended tsconfig, either relative to the
+// one extending it or in a package.
+func resolveTsconfigExtends(dir string, extend string) string {
+	candidates := []string{}
+	if filepath.IsAbs(extend) || strings.HasPrefix(extend, ".") {
+		candidates = append(candidates, filepath.Join(dir, extend))
+	} else {
+		for current := dir; ; current = filepath.Dir(current) {
+			candidates = append(candidates, filepath.Join(current, "node_modules", extend))
+			if current == filepath.Dir(current) {
+				break
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		for _, name := range []string{candidate, candidate + ".json", filepath.Join(candidate, "tsconfig.json")} {
+			if info, err := os.Stat(name); err == nil && !info.IsDir() {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// match returns the targets of the longest pattern that matches the import,
+// with the wildcard filled in.
+func (t *tsconfigPaths) match(path string) []string {
+	patterns := make([]string, 0, len(t.paths))
+	for pattern := range t.paths {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		return len(patterns[i]) > len(patterns[j])
+	})
+	for _, pattern := range patterns {
+		prefix, suffix, wildcard := strings.Cut(pattern, "*")
+		if !wildcard {
+			if path == pattern {
+				return t.paths[pattern]
+			}
+			continue
+		}
+		if len(path) < len(prefix)+len(suffix) || !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, suffix) {
+			continue
+		}
+		value := path[len(prefix) : len(path)-len(suffix)]
+		result := []string{}
+		for _, target := range t.paths[pattern] {
+			result = append(result, strings.Replace(target, "*", value, 1))
+		}
+		return result
+	}
+	return nil
+}
+
+// TsconfigPlugin resolves the path aliases of a tsconfig, and imports
+// relative to its baseUrl, for the files in root. The files in the exclude
+// directories and in node_modules keep resolving with their own tsconfig.
+func TsconfigPlugin(tsconfig string, root string, exclude ...string) (esbuild.Plugin, error) {
+	aliases, err := readTsconfigPaths(tsconfig, map[string]bool{})
+	if err != nil {
+		return esbuild.Plugin{}, err
+	}
+	return esbuild.Plugin{
+		Name: "tsconfig-paths",
+		Setup: func(build esbuild.PluginBuild) {
+			build.OnResolve(esbuild.OnResolveOptions{Filter: `^[^./]`}, func(args esbuild.OnResolveArgs) (esbuild.OnResolveResult, error) {
+				// the resolves of the plugin itself
+				if args.PluginData == aliases || filepath.IsAbs(args.Path) || !filepath.IsAbs(args.Importer) {
+					return esbuild.OnResolveResult{}, nil
+				}
+				if !isWithin(root, args.Importer) || strings.Contains(args.Importer, string(filepath.Separator)+"node_modules"+string(filepath.Separator)) {
+					return esbuild.OnResolveResult{}, nil
+				}
+				for _, dir := range exclude {
+					if isWithin(dir, args.Importer) {
+						return esbuild.OnResolveResult{}, nil
+					}
+				}
+				targets := aliases.match(args.Path)
+				if targets == nil && aliases.baseUrl != "" {
+					targets = []string{filepath.Join(aliases.baseUrl, args.Path)}
+				}
+				for _, target := range targets {
+					result := build.Resolve(target, esbuild.ResolveOptions{
+						ResolveDir: filepath.Dir(args.Importer),
+						Kind:       args.Kind,
+						PluginData: aliases,
+					})
+					if len(result.Errors) == 0 {
+						return esbuild.OnResolveResult{
+							Path:      result.Path,
+							External:  result.External,
+							Namespace: result.Namespace,
+						}, nil
+					}
+				}
+				return esbuild.OnResolveResult{}, nil
+			})
+		},
+	}, nil
+}
+
+// stripJSONC removes the comments and trailing commas tsconfig allows.
+func stripJSONC(data []byte) []byte {
+	result := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			result = append(result, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				result = append(result, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			result = append(result, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				result = append(result, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		case c == ']' || c == '}':
+			// drop a trailing comma before the closing bracket
+			j := len(result) - 1
+			for j >= 0 && strings.ContainsRune(" \t\r\n", rune(result[j])) {
+				j--
+			}
+			if j >= 0 && result[j] == ',' {
+				result = append(result[:j], result[j+1:]...)
+			}
+			result = append(result, c)
+		default:
+			result = append(result, c)
+		}
+	}
+	return result
+}
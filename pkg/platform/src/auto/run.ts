@@ -1,12 +1,15 @@
 import { Link } from "../components/link";
 import { Hint } from "../components/hint";
 import { Warp } from "../components/warp";
+import { DevCommand } from "../components/dev-command";
+import { SharedLayer } from "../components/aws/shared-layer";
 import {
   ResourceTransformationArgs,
   interpolate,
   mergeOptions,
   runtime,
   automation,
+  output,
 } from "@pulumi/pulumi";
 
 import aws from "@pulumi/aws";
@@ -36,14 +39,53 @@ export async function run(program: automation.PulumiFn) {
   Hint.reset();
   Link.reset();
   Warp.reset();
+  DevCommand.reset();
+  SharedLayer.reset();
   const outputs = (await program()) || {};
+  if ($app.linkStore === "ssm") storeLinkParameters();
   outputs._links = Link.list();
   outputs._hints = Hint.list();
   outputs._warps = Warp.list();
   outputs._receivers = Link.Receiver.list();
+  outputs._dev = DevCommand.list();
+  outputs._logs = Warp.listLogs();
   return outputs;
 }
 
+/**
+ * Runs the run function of the config, the stacks, and the overlay of the
+ * stage in order. Outputs returned later take precedence.
+ */
+export function compose(
+  ...programs: (automation.PulumiFn | undefined)[]
+): automation.PulumiFn {
+  return async () => {
+    const outputs: Record<string, any> = {};
+    for (const program of programs) {
+      if (!program) continue;
+      Object.assign(outputs, (await program()) || {});
+    }
+    return outputs;
+  };
+}
+
+function storeLinkParameters() {
+  const parameters: Record<string, aws.ssm.Parameter> = {};
+  for (const [name, properties] of Object.entries(Link.list())) {
+    // same shape as the links bundled into functions
+    const value = output(properties).apply((props) =>
+      JSON.stringify({ ...props, type: props.type.replaceAll(".", ":") }),
+    );
+    parameters[name] = new aws.ssm.Parameter(`${name}LinkParameter`, {
+      name: `${Link.AWS.parameterPrefix()}${name}`,
+      type: "SecureString",
+      tier: value.apply((v) => (v.length > 4096 ? "Advanced" : "Standard")),
+      value,
+    });
+  }
+  Link.AWS.setParameters(parameters);
+}
+
 function addTransformationToRetainResourcesOnDelete() {
   runtime.registerStackTransformation((args: ResourceTransformationArgs) => {
     if (
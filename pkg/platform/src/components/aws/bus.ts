@@ -0,0 +1,337 @@
+import { ComponentResourceOptions, all, output } from "@pulumi/pulumi";
+import * as aws from "@pulumi/aws";
+import { Component, Transform, transform } from "../component";
+import { Link } from "../link";
+import type { Input } from "../input";
+import { Function, FunctionArgs } from "./function";
+import { hashStringToPrettyString, sanitizeToPascalCase } from "../naming";
+import { Warp } from "../warp.js";
+
+export interface BusArgs {
+  /**
+   * [Transform](/docs/components#transform) how this component creates its underlying
+   * resources.
+   */
+  transform?: {
+    /**
+     * Transform the EventBridge Bus resource.
+     */
+    bus?: Transform<aws.cloudwatch.EventBusArgs>;
+  };
+}
+
+export interface BusSubscribeArgs {
+  /**
+   * Filter the events that'll be processed by the `subscriber` function.
+   *
+   * :::tip
+   * Learn more about [event patterns](https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns.html).
+   * :::
+   *
+   * @default All the events sent to the bus
+   * @example
+   * ```js
+   * {
+   *   pattern: {
+   *     source: ["my.app"],
+   *     "detail-type": ["order.placed"]
+   *   }
+   * }
+   * ```
+   */
+  pattern?: Input<Record<string, any>>;
+  /**
+   * [Transform](/docs/components#transform) how this subscription creates its underlying
+   * resources.
+   */
+  transform?: {
+    /**
+     * Transform the EventBridge Rule resource.
+     */
+    rule?: Transform<aws.cloudwatch.EventRuleArgs>;
+    /**
+     * Transform the EventBridge Target resource.
+     */
+    target?: Transform<aws.cloudwatch.EventTargetArgs>;
+  };
+}
+
+/**
+ * The `Bus` component lets you add an [Amazon EventBridge Event Bus](https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-bus.html) to your app.
+ *
+ * In `sst dev`, the events sent to the bus are forwarded to your machine and
+ * matched against the patterns of the subscribers locally. So changing a
+ * pattern doesn't need a deploy. Patterns that use `cidr` can't be matched
+ * locally, their subscribers aren't run in `sst dev`.
+ *
+ * @example
+ *
+ * #### Create a bus
+ *
+ * ```ts
+ * const bus = new sst.aws.Bus("MyBus");
+ * ```
+ *
+ * #### Add a subscriber
+ *
+ * ```ts
+ * bus.subscribe("src/subscriber.handler", {
+ *   pattern: {
+ *     source: ["my.app"]
+ *   }
+ * });
+ * ```
+ *
+ * #### Link the bus to a resource
+ *
+ * You can link the bus to other resources, like a function or your Next.js app.
+ *
+ * ```ts
+ * new sst.aws.Nextjs("MyWeb", {
+ *   link: [bus]
+ * });
+ * ```
+ *
+ * Once linked, you can put events on the bus from your function code.
+ *
+ * ```ts title="app/page.tsx" {1,7}
+ * import { Resource } from "sst";
+ * import { EventBridgeClient, PutEventsCommand } from "@aws-sdk/client-eventbridge";
+ *
+ * const client = new EventBridgeClient({});
+ *
+ * await client.send(new PutEventsCommand({
+ *   Entries: [{
+ *     EventBusName: Resource.MyBus.name,
+ *     Source: "my.app",
+ *     DetailType: "order.placed",
+ *     Detail: JSON.stringify({ id: "123" })
+ *   }]
+ * }));
+ * ```
+ */
+export class Bus
+  extends Component
+  implements Link.Linkable, Link.AWS.Linkable
+{
+  private constructorName: string;
+  private bus: aws.cloudwatch.EventBus;
+  private devQueue?: aws.sqs.Queue;
+
+  constructor(
+    name: string,
+    args: BusArgs = {},
+    opts: ComponentResourceOptions = {},
+  ) {
+    super("sst:aws:Bus", name, args, opts);
+
+    const parent = this;
+
+    const bus = createBus();
+    const devQueue = createDevQueue();
+
+    this.constructorName = name;
+    this.bus = bus;
+    this.devQueue = devQueue;
+
+    function createBus() {
+      return new aws.cloudwatch.EventBus(
+        `${name}Bus`,
+        transform(args.transform?.bus, {}),
+        { parent },
+      );
+    }
+
+    // In dev, every event on the bus goes to a queue that sst dev polls.
+    function createDevQueue() {
+      if (!$dev) return;
+
+      const queue = new aws.sqs.Queue(
+        `${name}DevQueue`,
+        { messageRetentionSeconds: 3600 },
+        { parent },
+      );
+      const rule = new aws.cloudwatch.EventRule(
+        `${name}DevRule`,
+        {
+          eventBusName: bus.name,
+          eventPattern: JSON.stringify({ source: [{ prefix: "" }] }),
+        },
+        { parent },
+      );
+      new aws.sqs.QueuePolicy(
+        `${name}DevQueuePolicy`,
+        {
+          queueUrl: queue.url,
+          policy: aws.iam.getPolicyDocumentOutput({
+            statements: [
+              {
+                actions: ["sqs:SendMessage"],
+                resources: [queue.arn],
+                principals: [
+                  {
+                    type: "Service",
+                    identifiers: ["events.amazonaws.com"],
+                  },
+                ],
+                conditions: [
+                  {
+                    test: "ArnEquals",
+                    variable: "aws:SourceArn",
+                    values: [rule.arn],
+                  },
+                ],
+              },
+            ],
+          }).json,
+        },
+        { parent },
+      );
+      new aws.cloudwatch.EventTarget(
+        `${name}DevTarget`,
+        {
+          arn: queue.arn,
+          rule: rule.name,
+          eventBusName: bus.name,
+        },
+        { parent },
+      );
+      return queue;
+    }
+  }
+
+  /**
+   * The ARN of the EventBridge Bus.
+   */
+  public get arn() {
+    return this.bus.arn;
+  }
+
+  /**
+   * The name of the EventBridge Bus.
+   */
+  public get name() {
+    return this.bus.name;
+  }
+
+  /**
+   * The underlying [resources](/docs/components/#nodes) this component creates.
+   */
+  public get nodes() {
+    return {
+      /**
+       * The Amazon EventBridge Bus.
+       */
+      bus: this.bus,
+    };
+  }
+
+  /**
+   * Subscribes to this Bus.
+   *
+   * @param subscriber The function that'll be notified.
+   * @param args Configure the subscription.
+   *
+   * @example
+   *
+   * ```js
+   * bus.subscribe("src/subscriber.handler", {
+   *   pattern: {
+   *     "detail-type": ["order.placed"]
+   *   }
+   * });
+   * ```
+   */
+  public subscribe(
+    subscriber: string | FunctionArgs,
+    args: BusSubscribeArgs = {},
+  ) {
+    const parent = this;
+    const parentName = this.constructorName;
+    const pattern = output(args.pattern).apply(
+      (pattern) => pattern ?? { source: [{ prefix: "" }] },
+    );
+
+    const id = sanitizeToPascalCase(
+      hashStringToPrettyString(JSON.stringify(args.pattern ?? {}), 4),
+    );
+
+    const fn = Function.fromDefinition(
+      parent,
+      `${parentName}Subscriber${id}`,
+      subscriber,
+      {
+        description: `Subscribed to ${parentName}`,
+      },
+    );
+    // the events go through the dev queue instead while the function is live
+    const live = output(subscriber).apply(
+      (subscriber) =>
+        $dev && (typeof subscriber === "string" || subscriber.live !== false),
+    );
+    const rule = new aws.cloudwatch.EventRule(
+      `${parentName}Rule${id}`,
+      transform(args.transform?.rule, {
+        eventBusName: this.bus.name,
+        eventPattern: pattern.apply((pattern) => JSON.stringify(pattern)),
+        isEnabled: live.apply((live) => !live),
+      }),
+      { parent },
+    );
+    const permission = new aws.lambda.Permission(
+      `${parentName}Subscriber${id}Permissions`,
+      {
+        action: "lambda:InvokeFunction",
+        function: fn.arn,
+        principal: "events.amazonaws.com",
+        sourceArn: rule.arn,
+      },
+      { parent },
+    );
+    new aws.cloudwatch.EventTarget(
+      `${parentName}Target${id}`,
+      transform(args.transform?.target, {
+        arn: fn.arn,
+        rule: rule.name,
+        eventBusName: this.bus.name,
+      }),
+      { parent, dependsOn: [permission] },
+    );
+
+    if (this.devQueue) {
+      Warp.bus(
+        `${parentName}Subscriber${id}`,
+        parentName,
+        all([this.devQueue.url, this.devQueue.arn, pattern]).apply(
+          ([url, arn, pattern]) => ({
+            url,
+            arn,
+            pattern: JSON.stringify(pattern),
+          }),
+        ),
+      );
+    }
+
+    return this;
+  }
+
+  /** @internal */
+  public getSSTLink() {
+    return {
+      properties: {
+        arn: this.arn,
+        name: this.name,
+      },
+    };
+  }
+
+  /** @internal */
+  public getSSTAWSPermissions() {
+    return [
+      {
+        actions: ["events:*"],
+        resources: [this.arn],
+      },
+    ];
+  }
+}
@@ -3,6 +3,7 @@ import * as aws from "@pulumi/aws";
 import { Component, Transform, transform } from "../component";
 import { Function, FunctionArgs } from "./function";
 import { Input } from "../input.js";
+import { Warp } from "../warp.js";
 
 export interface CronArgs {
   /**
@@ -112,6 +113,7 @@ export class Cron extends Component {
     const rule = createRule();
     const target = createTarget();
     createPermission();
+    Warp.schedule(`${name}Handler`, name, args.schedule);
 
     this.fn = fn;
     this.rule = rule;
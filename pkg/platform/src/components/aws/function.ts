@@ -13,6 +13,15 @@ import {
 } from "@pulumi/pulumi";
 import * as aws from "@pulumi/aws";
 import { build } from "../../runtime/node.js";
+import { build as buildRust } from "../../runtime/rust.js";
+import {
+  build as buildDotnet,
+  parseHandler as parseDotnetHandler,
+} from "../../runtime/dotnet.js";
+import {
+  build as buildJava,
+  parseHandler as parseJavaHandler,
+} from "../../runtime/java.js";
 import { FunctionCodeUpdater } from "./providers/function-code-updater.js";
 import { bootstrap } from "./helpers/bootstrap.js";
 import { Duration, DurationMinutes, toSeconds } from "../duration.js";
@@ -20,9 +29,42 @@ import { Size, toMBs } from "../size.js";
 import { Component, Prettify, Transform, transform } from "../component.js";
 import { Link } from "../link.js";
 import { VisibleError } from "../error.js";
+import { Warning } from "../warning.js";
 import { Warp } from "../warp.js";
 import type { Input } from "../input.js";
 import { prefixName } from "../naming.js";
+import { SharedLayer } from "./shared-layer.js";
+
+// Loads linked values from SSM on the first invocation. Uses the AWS SDK
+// that's included in the Lambda runtime.
+const linkParameterLoader = [
+  `let sstLinksLoaded;`,
+  `const sstLoadLinks = () => sstLinksLoaded ??= (async () => {`,
+  `  const { SSMClient, GetParametersCommand } = await import("@aws-sdk/client-ssm");`,
+  `  const client = new SSMClient({});`,
+  `  const names = process.env.SST_LINK_NAMES.split(",");`,
+  `  for (let i = 0; i < names.length; i += 10) {`,
+  `    const result = await client.send(new GetParametersCommand({`,
+  `      Names: names.slice(i, i + 10).map((name) => process.env.SST_LINK_SSM + name),`,
+  `      WithDecryption: true,`,
+  `    }));`,
+  `    for (const parameter of result.Parameters ?? []) {`,
+  `      const name = parameter.Name.slice(process.env.SST_LINK_SSM.length);`,
+  `      process.env["SST_RESOURCE_" + name] = parameter.Value;`,
+  `    }`,
+  `  }`,
+  `})();`,
+  ``,
+].join("\n");
+
+// Runtimes that aren't bundled with esbuild or wrapped in javascript.
+function isNodeRuntime(runtime?: string) {
+  return (
+    runtime !== "rust" &&
+    !runtime?.startsWith("dotnet") &&
+    !runtime?.startsWith("java")
+  );
+}
 
 const RETENTION = {
   "1 day": 1,
@@ -224,7 +266,19 @@ export interface FunctionArgs {
    */
   description?: Input<string>;
   /**
-   * The runtime environment for the function. Support for other runtimes is on our roadmap.
+   * The runtime environment for the function.
+   *
+   * With `rust`, the `handler` is the directory of the crate and it's built with
+   * [cargo-lambda](https://www.cargo-lambda.info/), which needs to be installed.
+   * It runs on the `provided.al2023` runtime.
+   *
+   * With `dotnet8`, the `handler` is the directory of the project followed by the
+   * class and method, `{project}::{namespace}.{class}::{method}`. The assembly is
+   * named after the directory. It's built with `dotnet publish`.
+   *
+   * With `java17` and `java21`, the `handler` is the directory of the Gradle or Maven
+   * project followed by the class and method, `{project}::{package}.{class}::{method}`.
+   * Kotlin works the same way.
    *
    * @default `"nodejs20.x"`
    * @example
@@ -234,7 +288,15 @@ export interface FunctionArgs {
    * }
    * ```
    */
-  runtime?: Input<"nodejs18.x" | "nodejs20.x" | "provided.al2023">;
+  runtime?: Input<
+    | "nodejs18.x"
+    | "nodejs20.x"
+    | "provided.al2023"
+    | "rust"
+    | "dotnet8"
+    | "java17"
+    | "java21"
+  >;
   /**
    * Path to the source code directory for the function. By default, the handler is
    * bundled with [esbuild](https://esbuild.github.io/). Use `bundle` to skip bundling.
@@ -468,6 +530,57 @@ export interface FunctionArgs {
    * ```
    */
   architecture?: Input<"x86_64" | "arm64">;
+  /**
+   * Configure the function to connect to private subnets in a VPC. This
+   * lets it reach resources like an RDS database or an ElastiCache cluster.
+   *
+   * In `sst dev`, the function runs on your machine instead. If you pass in a
+   * `bastion`, `sst dev` tunnels to the linked resources that have a `host`
+   * and a `port` through it, using an
+   * [SSM session](https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager.html).
+   * This needs the [Session Manager plugin](https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html).
+   *
+   * @example
+   * ```js
+   * {
+   *   vpc: {
+   *     securityGroups: ["sg-0399348378a4c256c"],
+   *     subnets: ["subnet-0b6a2b73896dc8c4c", "subnet-021389ebee680c2f0"],
+   *     bastion: "i-0e2c5d9b5d5f5a8e1"
+   *   }
+   * }
+   * ```
+   */
+  vpc?: Input<{
+    /**
+     * A list of VPC security group IDs.
+     */
+    securityGroups: Input<Input<string>[]>;
+    /**
+     * A list of VPC subnet IDs.
+     */
+    subnets: Input<Input<string>[]>;
+    /**
+     * The ID of an EC2 instance in the VPC with the SSM agent running. Used
+     * by `sst dev` to reach the linked resources from your machine.
+     */
+    bastion?: Input<string>;
+  }>;
+  /**
+   * The ARNs of the [Lambda layers](https://docs.aws.amazon.com/lambda/latest/dg/chapter-layers.html)
+   * to add to the function.
+   *
+   * In `sst dev` the function runs on your machine, where the layers are not
+   * available. So what they provide needs to be installed locally as well.
+   *
+   * @example
+   * ```js
+   * {
+   *   layers: ["arn:aws:lambda:us-east-1:123456789012:layer:my-layer:1"]
+   * }
+   * ```
+   */
+  layers?: Input<Input<string>[]>;
   /**
    * Enable [Lambda function URLs](https://docs.aws.amazon.com/lambda/latest/dg/lambda-urls.html).
    * These are dedicated endpoints for your Lambda functions.
@@ -535,6 +648,90 @@ export interface FunctionArgs {
         cors?: Input<boolean | Prettify<FunctionUrlCorsArgs>>;
       }
   >;
+  /**
+   * Configure how your Rust function is built, when the `runtime` is `rust`.
+   *
+   * The crates are built into a target directory in `.sst` that's shared by all the
+   * functions, so only what changed is rebuilt.
+   */
+  rust?: Input<{
+    /**
+     * The binary to build, for crates with more than one.
+     *
+     * @default The package name from the `Cargo.toml`
+     * @example
+     * ```js
+     * {
+     *   rust: {
+     *     binary: "api"
+     *   }
+     * }
+     * ```
+     */
+    binary?: Input<string>;
+    /**
+     * The Cargo features to enable.
+     *
+     * @example
+     * ```js
+     * {
+     *   rust: {
+     *     features: ["tracing"]
+     *   }
+     * }
+     * ```
+     */
+    features?: Input<Input<string>[]>;
+  }>;
+  /**
+   * Configure how your .NET function is built, when the `runtime` is `dotnet8`.
+   */
+  dotnet?: Input<{
+    /**
+     * Compile the assemblies ahead of time with
+     * [ReadyToRun](https://learn.microsoft.com/en-us/dotnet/core/deploying/ready-to-run)
+     * to improve cold starts, at the cost of a larger package. Only applies when deploying.
+     *
+     * @default `false`
+     * @example
+     * ```js
+     * {
+     *   dotnet: {
+     *     readyToRun: true
+     *   }
+     * }
+     * ```
+     */
+    readyToRun?: Input<boolean>;
+  }>;
+  /**
+   * Configure how your Java function is built and deployed, when the `runtime` is
+   * `java17` or `java21`.
+   *
+   * The jar of the project is deployed with the function and its dependencies are
+   * deployed as a layer. So the dependencies are only uploaded when they change.
+   */
+  java?: Input<{
+    /**
+     * Enable [SnapStart](https://docs.aws.amazon.com/lambda/latest/dg/snapstart.html)
+     * to improve cold starts.
+     *
+     * A version of the function is published on every deploy. Only invocations of a
+     * published version use SnapStart, not the ones of `$LATEST`. It doesn't apply
+     * in `sst dev`.
+     *
+     * @default `false`
+     * @example
+     * ```js
+     * {
+     *   java: {
+     *     snapStart: true
+     *   }
+     * }
+     * ```
+     */
+    snapStart?: Input<boolean>;
+  }>;
   /**
    * Configure how your function is bundled.
    *
@@ -579,6 +776,26 @@ export interface FunctionArgs {
      * ```
      */
     install?: Input<string[]>;
+    /**
+     * Install the `install` packages into a Lambda layer instead of the function
+     * package. Functions that install the same packages, with the same versions
+     * and architecture, share a single layer that's built once.
+     *
+     * This keeps large or native dependencies, like `sharp`, out of the package
+     * of each function.
+     *
+     * @default `false`
+     * @example
+     * ```js
+     * {
+     *   nodejs: {
+     *     install: ["sharp"],
+     *     layer: true
+     *   }
+     * }
+     * ```
+     */
+    layer?: Input<boolean>;
     /**
      * Use this to insert a string at the beginning of the generated JS file.
      *
@@ -617,6 +834,65 @@ export interface FunctionArgs {
      * ```
      */
     minify?: Input<boolean>;
+    /**
+     * Keep the original names of functions and classes when minifying, so stack traces
+     * and `constructor.name` stay readable. Turn it off for a slightly smaller bundle.
+     *
+     * @default `true`
+     *
+     * @example
+     * ```js
+     * {
+     *   nodejs: {
+     *     keepNames: false
+     *   }
+     * }
+     * ```
+     */
+    keepNames?: Input<boolean>;
+    /**
+     * Remove the code that's never used from the bundle. Turn it off if a package relies
+     * on side effects that are tree shaken by mistake.
+     *
+     * @default `true`
+     *
+     * @example
+     * ```js
+     * {
+     *   nodejs: {
+     *     treeShaking: false
+     *   }
+     * }
+     * ```
+     */
+    treeShaking?: Input<boolean>;
+    /**
+     * Use this to insert a string at the end of the generated JS file.
+     *
+     * @example
+     * ```js
+     * {
+     *   nodejs: {
+     *     footer: "// built with sst"
+     *   }
+     * }
+     * ```
+     */
+    footer?: Input<string>;
+    /**
+     * Packages that are left out of the bundle and not installed, because they are
+     * already available in the Lambda runtime or in a layer.
+     *
+     * @example
+     * ```js
+     * {
+     *   nodejs: {
+     *     external: ["@aws-sdk/client-s3"]
+     *   }
+     * }
+     * ```
+     */
+    external?: Input<string[]>;
     /**
      * Configure the format of the generated JS code; ESM or CommonJS.
      *
@@ -757,7 +1033,7 @@ export interface FunctionArgs {
  * It uses [AWS Lambda](https://aws.amazon.com/lambda/).
  *
  * :::note
- * Currently supports Node.js functions only. Support for other runtimes is on the roadmap.
+ * Currently supports Node.js, Rust, .NET, and Java functions. Support for other runtimes is on the roadmap.
  * :::
  *
  * @example
@@ -872,25 +1148,30 @@ export class Function
     const timeout = normalizeTimeout();
     const memory = normalizeMemory();
     const architectures = normalizeArchitectures();
+    const linkData = buildLinkData();
     const environment = normalizeEnvironment();
     const streaming = normalizeStreaming();
     const logging = normalizeLogging();
     const url = normalizeUrl();
     const copyFiles = normalizeCopyFiles();
 
-    const linkData = buildLinkData();
     const linkPermissions = buildLinkPermissions();
-    const { bundle, handler: handler0 } = buildHandler();
+    const handlerBuild = buildHandler();
+    const { bundle, handler: handler0 } = handlerBuild;
     const { handler, wrapper } = buildHandlerWrapper();
     const role = createRole();
     const zipPath = zipBundleFolder();
     const bundleHash = calculateHash();
     const file = createBucketObject();
     const logGroup = createLogGroup();
+    const layer = createLayer();
+    const sharedLayer = createSharedLayer();
+    const snapStart = normalizeSnapStart();
     const fn = createFunction();
     const codeUpdater = updateFunctionCode();
 
     const fnUrl = createUrl();
+    writeHotswapRecord();
 
     const links = output(linkData).apply((input) =>
       input.map((item) => item.name),
@@ -906,18 +1187,66 @@ export class Function
         args.bundle,
         args.runtime,
         args.nodejs,
-      ]).apply(([dev, name, links, handler, bundle, runtime, nodejs]) => {
-        if (!dev) return undefined;
-        return {
-          functionID: name,
+        args.rust,
+        args.dotnet,
+        args.java,
+        output(args.vpc).apply((vpc) => vpc?.bastion),
+        args.layers,
+        all([timeout, memory, architectures]).apply(
+          ([timeout, memory, architectures]) => ({
+            timeout,
+            memory,
+            architecture: architectures[0],
+          }),
+        ),
+      ]).apply(
+        ([
+          dev,
+          name,
           links,
-          handler: handler,
-          bundle: bundle,
-          runtime: runtime || "nodejs20.x",
-          properties: nodejs,
-        };
-      }),
+          handler,
+          bundle,
+          runtime,
+          nodejs,
+          rust,
+          dotnet,
+          java,
+          bastion,
+          layers,
+          settings,
+        ]) => {
+          if (!dev) return undefined;
+          return {
+            functionID: name,
+            links,
+            handler: handler,
+            bundle: bundle,
+            runtime: runtime || "nodejs20.x",
+            properties: { ...warpProperties(), ...settings },
+            bastion,
+            layers: layers ?? [],
+          };
+
+          function warpProperties() {
+            if (runtime === "rust") return rust;
+            if (runtime?.startsWith("dotnet"))
+              return { ...dotnet, ...parseDotnetHandler(handler) };
+            if (runtime?.startsWith("java"))
+              return { ...java, ...parseJavaHandler(handler) };
+            return nodejs;
+          }
+        },
+      ),
     );
+    if ($dev)
+      Warp.log(
+        name,
+        all([logGroup.name, region, dev]).apply(([group, region, live]) => ({
+          group,
+          region,
+          live,
+        })),
+      );
 
     all([bundle, handler]).apply(([bundle, handler]) => {
       Link.Receiver.register(bundle || handler, links, environment);
@@ -944,16 +1273,32 @@ export class Function
 
     function normalizeRuntime() {
       return all([args.runtime, dev]).apply(([v, dev]) =>
-        dev ? "provided.al2023" : v ?? "nodejs20.x",
+        dev || v === "rust" ? "provided.al2023" : v ?? "nodejs20.x",
       );
     }
 
     function normalizeTimeout() {
-      return output(args.timeout).apply((timeout) => timeout ?? "20 seconds");
+      return output(args.timeout).apply((timeout) => {
+        const value = timeout ?? "20 seconds";
+        const seconds = toSeconds(value);
+        if (seconds < 1 || seconds > 900)
+          throw new VisibleError(
+            `Function "${name}" has a timeout of "${value}", it must be between 1 second and 15 minutes.`,
+          );
+        return value;
+      });
     }
 
     function normalizeMemory() {
-      return output(args.memory).apply((memory) => memory ?? "1024 MB");
+      return output(args.memory).apply((memory) => {
+        const value = memory ?? "1024 MB";
+        const mbs = toMBs(value);
+        if (mbs < 128 || mbs > 10240)
+          throw new VisibleError(
+            `Function "${name}" has "${value}" of memory, it must be between 128 MB and 10240 MB.`,
+          );
+        return value;
+      });
     }
 
     function normalizeArchitectures() {
@@ -963,19 +1308,47 @@ export class Function
     }
 
     function normalizeEnvironment() {
-      return all([args.environment, dev]).apply(([environment, dev]) => {
-        const result = environment ?? {};
-        if (dev) {
-          result.SST_FUNCTION_ID = name;
-          result.SST_APP = $app.name;
-          result.SST_STAGE = $app.stage;
-        }
-        return result;
-      });
+      return all([args.environment, args.runtime, dev, linkData]).apply(
+        ([environment, runtime, dev, linkData]) => {
+          const result = environment ?? {};
+          if (dev) {
+            result.SST_FUNCTION_ID = name;
+            result.SST_APP = $app.name;
+            result.SST_STAGE = $app.stage;
+          }
+          // there's no bundle to inject them into
+          if (
+            !dev &&
+            !isNodeRuntime(runtime) &&
+            $app.linkStore !== "ssm" &&
+            linkData.length > 0
+          ) {
+            for (const item of linkData) {
+              result[`SST_RESOURCE_${item.name}`] = JSON.stringify(
+                item.properties,
+              );
+            }
+          }
+          // only the names are set so changing a linked value doesn't
+          // change the function
+          if (!dev && $app.linkStore === "ssm" && linkData.length > 0) {
+            result.SST_LINK_SSM = Link.AWS.parameterPrefix();
+            result.SST_LINK_NAMES = linkData.map((item) => item.name).join(",");
+          }
+          return result;
+        },
+      );
     }
 
     function normalizeStreaming() {
-      return output(args.streaming).apply((streaming) => streaming ?? false);
+      return all([args.streaming, args.url]).apply(([streaming, url]) => {
+        if (streaming && !url)
+          Warning.warn(
+            "Streaming is enabled but there is no url, responses are only streamed through function URLs",
+            name,
+          );
+        return streaming ?? false;
+      });
     }
 
     function normalizeLogging() {
@@ -1054,12 +1427,22 @@ export class Function
     }
 
     function buildLinkPermissions() {
-      return output(args.link ?? []).apply((links) =>
-        links.flatMap((l) => {
+      return output(args.link ?? []).apply((links) => [
+        ...links.flatMap((l) => {
           if (!Link.AWS.isLinkable(l)) return [];
           return l.getSSTAWSPermissions();
         }),
-      );
+        ...($app.linkStore === "ssm" && links.length > 0
+          ? [
+              {
+                actions: ["ssm:GetParameters"],
+                resources: [
+                  `arn:aws:ssm:*:*:parameter${Link.AWS.parameterPrefix()}*`,
+                ],
+              },
+            ]
+          : []),
+      ]);
     }
 
     function buildHandler() {
@@ -1091,21 +1474,38 @@ export class Function
 
         const buildResult = all([args, linkData]).apply(
           async ([args, linkData]) => {
-            const result = await build(name, {
-              ...args,
-              links: linkData,
-            });
+            const result = await (args.runtime === "rust"
+              ? buildRust(name, args)
+              : args.runtime?.startsWith("dotnet")
+                ? buildDotnet(name, args)
+                : args.runtime?.startsWith("java")
+                  ? buildJava(name, args)
+                  : build(name, {
+                      ...args,
+                      // read from SSM by the handler wrapper instead
+                      links: $app.linkStore === "ssm" ? [] : linkData,
+                    }));
             if (result.type === "error") {
               throw new Error(
                 "Failed to build function: " + result.errors.join("\n").trim(),
               );
             }
-            return result;
+            return {
+              handler: result.handler,
+              out: result.out,
+              layer: "layer" in result ? result.layer : undefined,
+              sharedLayer:
+                "sharedLayer" in result ? result.sharedLayer : undefined,
+              hotswap: "hotswap" in result ? result.hotswap : undefined,
+            };
           },
         );
         return {
           handler: buildResult.handler,
           bundle: buildResult.out,
+          layer: buildResult.layer,
+          sharedLayer: buildResult.sharedLayer,
+          hotswap: buildResult.hotswap,
         };
       });
     }
@@ -1118,15 +1518,29 @@ export class Function
         linkData,
         streaming,
         injections,
+        args.runtime,
       ]).apply(
-        async ([dev, bundle, handler, linkData, streaming, injections]) => {
-          if (dev) return { handler };
+        async ([
+          dev,
+          bundle,
+          handler,
+          linkData,
+          streaming,
+          injections,
+          runtime,
+        ]) => {
+          // the wrapper is javascript
+          if (dev || !isNodeRuntime(runtime)) return { handler };
 
           const hasUserInjections = injections.length > 0;
+          const hasLinkParameters =
+            $app.linkStore === "ssm" && linkData.length > 0;
           // already injected via esbuild when bundle is undefined
-          const hasLinkInjections = args.bundle && linkData.length > 0;
+          const hasLinkInjections =
+            args.bundle && linkData.length > 0 && !hasLinkParameters;
 
-          if (!hasUserInjections && !hasLinkInjections) return { handler };
+          if (!hasUserInjections && !hasLinkInjections && !hasLinkParameters)
+            return { handler };
 
           const linkInjection = hasLinkInjections
             ? linkData
@@ -1136,7 +1550,10 @@ export class Function
                   )};\n`,
                 ])
                 .join("")
-            : "";
+            : hasLinkParameters
+              ? linkParameterLoader
+              : "";
+          const linkLoad = hasLinkParameters ? [`  await sstLoadLinks();`] : [];
 
           const parsed = path.posix.parse(handler);
           const handlerDir = parsed.dir;
@@ -1168,6 +1585,7 @@ export class Function
                 ? [
                     linkInjection,
                     `export const ${newHandlerFunction} = awslambda.streamifyResponse(async (event, context) => {`,
+                    ...linkLoad,
                     ...injections,
                     `  const { ${oldHandlerFunction}: rawHandler} = await import("./${oldHandlerFileName}${newHandlerFileExt}");`,
                     `  return rawHandler(event, context);`,
@@ -1176,6 +1594,7 @@ export class Function
                 : [
                     linkInjection,
                     `export const ${newHandlerFunction} = async (event, context) => {`,
+                    ...linkLoad,
                     ...injections,
                     `  const { ${oldHandlerFunction}: rawHandler} = await import("./${oldHandlerFileName}${newHandlerFileExt}");`,
                     `  return rawHandler(event, context);`,
@@ -1233,9 +1652,14 @@ export class Function
                     ]
                   : [],
               ),
-              managedPolicyArns: [
+              managedPolicyArns: output(args.vpc).apply((vpc) => [
                 "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole",
-              ],
+                ...(vpc
+                  ? [
+                      "arn:aws:iam::aws:policy/service-role/AWSLambdaVPCAccessExecutionRole",
+                    ]
+                  : []),
+              ]),
             }),
             { parent },
           );
@@ -1251,8 +1675,7 @@ export class Function
       return all([bundle, wrapper, copyFiles]).apply(
         async ([bundle, wrapper, copyFiles]) => {
           const zipPath = path.resolve(
-            $cli.paths.work,
-            "artifacts",
+            $cli.paths.artifacts,
             name,
             "code.zip",
           );
@@ -1377,13 +1800,40 @@ export class Function
             variables: environment,
           },
           architectures,
+          layers: all([layer, sharedLayer, args.layers]).apply(
+            ([layer, sharedLayer, layers]) => [
+              ...(layer ? [layer.arn] : []),
+              ...(sharedLayer ? [sharedLayer.arn] : []),
+              ...(layers ?? []),
+            ],
+          ),
+          snapStart: snapStart.apply((snapStart) =>
+            snapStart ? { applyOn: "PublishedVersions" } : undefined,
+          ),
           loggingConfig: {
             logFormat: "Text",
             logGroup: logGroup.name,
           },
+          vpcConfig: output(args.vpc).apply((vpc) =>
+            vpc
+              ? {
+                  securityGroupIds: vpc.securityGroups,
+                  subnetIds: vpc.subnets,
+                }
+              : undefined,
+          ),
         }),
         {
           parent,
+          // the handler wrapper reads the links from the parameters
+          dependsOn:
+            $app.linkStore === "ssm"
+              ? Link.AWS.parametersOf(
+                  output(linkData).apply((items) =>
+                    items.map((item) => item.name),
+                  ),
+                )
+              : undefined,
           ignoreChanges: args._ignoreCodeChanges
             ? ["code", "handler"]
             : undefined,
@@ -1391,6 +1841,103 @@ export class Function
       );
     }
 
+    function normalizeSnapStart() {
+      return all([args.java, args.runtime, dev]).apply(
+        ([java, runtime, dev]) =>
+          !dev && runtime?.startsWith("java") && java?.snapStart === true,
+      );
+    }
+
+    function createLayer() {
+      // the dependencies of java functions, only deployed when they change
+      return output(handlerBuild).apply((build) => {
+        if (!("layer" in build) || !build.layer) return;
+        return new aws.lambda.LayerVersion(
+          `${name}Dependencies`,
+          {
+            layerName: prefixName(`${name}Dependencies`),
+            code: new asset.FileArchive(build.layer),
+            compatibleRuntimes: [runtime],
+          },
+          { parent },
+        );
+      });
+    }
+
+    function createSharedLayer() {
+      // the dependencies of nodejs.layer, shared with the functions that
+      // install the same ones
+      return all([handlerBuild, architectures]).apply(
+        ([build, architectures]) => {
+          if (!("sharedLayer" in build) || !build.sharedLayer) return;
+          return SharedLayer.get(build.sharedLayer, architectures[0]);
+        },
+      );
+    }
+
+    function writeHotswapRecord() {
+      // lets `sst deploy --hotswap` rebuild the bundle and update the code
+      // directly when nothing else changed
+      all([
+        dev,
+        handlerBuild,
+        wrapper,
+        copyFiles,
+        fn.name,
+        region,
+        snapStart,
+        // written once the code is deployed
+        codeUpdater.version,
+      ]).apply(
+        async ([
+          dev,
+          build,
+          wrapper,
+          copyFiles,
+          functionName,
+          region,
+          publish,
+        ]) => {
+          if (dev) return;
+          if (!("hotswap" in build) || !build.hotswap) return;
+          const inputs: Record<string, string> = {};
+          for (const file of build.hotswap.inputs) {
+            const data = await fs.promises.readFile(file).catch(() => undefined);
+            if (!data) continue;
+            const rel = path
+              .relative($cli.paths.root, file)
+              .split(path.sep)
+              .join(path.posix.sep);
+            inputs[rel] = crypto.createHash("sha256").update(data).digest("hex");
+          }
+          const file = path.join(
+            $cli.paths.work,
+            "hotswap",
+            $app.stage,
+            "functions",
+            `${name}.json`,
+          );
+          await fs.promises.mkdir(path.dirname(file), { recursive: true });
+          await fs.promises.writeFile(
+            file,
+            JSON.stringify({
+              name,
+              functionName,
+              region,
+              publish,
+              bundle: build.bundle,
+              sourcemap: build.hotswap.sourcemap,
+              options: build.hotswap.options,
+              install: build.hotswap.install,
+              inputs,
+              wrapper,
+              copyFiles,
+            }),
+          );
+        },
+      );
+    }
+
     function createUrl() {
       return url.apply((url) => {
         if (url === undefined) return;
@@ -1419,6 +1966,7 @@ export class Function
           s3Key: file.key,
           functionLastModified: fn.lastModified,
           region,
+          publish: snapStart,
         },
         { parent },
       );
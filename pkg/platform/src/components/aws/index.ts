@@ -1,6 +1,7 @@
 export * from "./apigatewayv2.js";
 export * from "./auth.js";
 export * from "./bucket.js";
+export * from "./bus.js";
 export * from "./cron.js";
 export * from "./dynamo.js";
 export * from "./function.js";
@@ -266,6 +266,8 @@ export class Postgres
         clusterArn: this.clusterArn,
         secretArn: this.secretArn,
         database: this.database,
+        host: this.cluster.endpoint,
+        port: this.cluster.port,
       },
     };
   }
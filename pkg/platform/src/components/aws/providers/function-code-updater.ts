@@ -18,6 +18,10 @@ export interface FunctionCodeUpdaterInputs {
    */
   functionLastModified: Input<string>;
   region: Input<string>;
+  /**
+   * Publish a version with the code, for SnapStart.
+   */
+  publish?: Input<boolean>;
 }
 
 interface Inputs {
@@ -26,6 +30,7 @@ interface Inputs {
   functionName: string;
   functionLastModified: string;
   region: string;
+  publish?: boolean;
 }
 
 interface Outputs {
@@ -69,6 +74,7 @@ class Provider implements dynamic.ResourceProvider {
         FunctionName: inputs.functionName,
         S3Bucket: inputs.s3Bucket,
         S3Key: inputs.s3Key,
+        Publish: inputs.publish,
       }),
     );
     return ret.Version ?? "unknown";
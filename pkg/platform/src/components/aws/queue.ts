@@ -1,4 +1,4 @@
-import { ComponentResourceOptions, output } from "@pulumi/pulumi";
+import { ComponentResourceOptions, all, output } from "@pulumi/pulumi";
 import * as aws from "@pulumi/aws";
 import { Component, Transform, transform } from "../component";
 import { Link } from "../link";
@@ -6,6 +6,7 @@ import type { Input } from "../input";
 import { Function, FunctionArgs } from "./function";
 import { DurationMinutes, toSeconds } from "../duration";
 import { VisibleError } from "../error";
+import { Warp } from "../warp.js";
 
 export interface QueueArgs {
   /**
@@ -284,11 +285,18 @@ export class Queue
         ],
       },
     );
-    new aws.lambda.EventSourceMapping(
+    // in dev the queue is polled locally instead, so the messages don't go
+    // to the function in AWS as well
+    const live = output(subscriber).apply(
+      (subscriber) =>
+        $dev && (typeof subscriber === "string" || subscriber.live !== false),
+    );
+    const mapping = new aws.lambda.EventSourceMapping(
       `${parentName}EventSourceMapping`,
       transform(args?.transform?.eventSourceMapping, {
         eventSourceArn: this.arn,
         functionName: fn.name,
+        enabled: live.apply((live) => !live),
         filterCriteria: args?.filters && {
           filters: output(args.filters).apply((filters) =>
             filters.map((filter) => ({
@@ -299,6 +307,27 @@ export class Queue
       }),
       { parent },
     );
+    Warp.queue(
+      `${parentName}Subscriber`,
+      parentName,
+      all([
+        this.url,
+        mapping.eventSourceArn,
+        mapping.batchSize,
+        mapping.functionResponseTypes,
+        mapping.filterCriteria,
+      ]).apply(([url, arn, batchSize, responseTypes, filterCriteria]) => ({
+        url,
+        arn,
+        batchSize,
+        reportBatchItemFailures: (responseTypes || []).includes(
+          "ReportBatchItemFailures",
+        ),
+        filters: (filterCriteria?.filters || []).map(
+          (filter) => filter.pattern!,
+        ),
+      })),
+    );
 
     return this;
   }
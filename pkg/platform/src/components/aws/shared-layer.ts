@@ -0,0 +1,28 @@
+import * as aws from "@pulumi/aws";
+import { asset } from "@pulumi/pulumi";
+import { prefixName } from "../naming.js";
+
+/**
+ * The layers of the dependencies of `nodejs.layer`, one for each set of
+ * dependencies no matter how many functions use it.
+ */
+export module SharedLayer {
+  let layers: Record<string, aws.lambda.LayerVersion> = {};
+  export function reset() {
+    layers = {};
+  }
+
+  export function get(
+    layer: { hash: string; dir: string },
+    architecture: string,
+  ) {
+    layers[layer.hash] =
+      layers[layer.hash] ||
+      new aws.lambda.LayerVersion(`NodeDependencies${layer.hash}`, {
+        layerName: prefixName(`NodeDependencies${layer.hash}`),
+        code: new asset.FileArchive(layer.dir),
+        compatibleArchitectures: [architecture],
+      });
+    return layers[layer.hash];
+  }
+}
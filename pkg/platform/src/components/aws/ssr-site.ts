@@ -26,6 +26,7 @@ import { transform, type Prettify, type Transform } from "../component.js";
 import { VisibleError } from "../error.js";
 import { Cron } from "./cron.js";
 import { OriginAccessIdentity } from "./providers/origin-access-identity.js";
+import { DevCommand } from "../dev-command.js";
 
 type CloudFrontFunctionConfig = { injections: string[] };
 type EdgeFunctionConfig = { function: Unwrap<FunctionArgs> };
@@ -89,6 +90,23 @@ export interface SsrSiteArgs {
    * ```
    */
   buildCommand?: Input<string>;
+  /**
+   * Configure the dev server of the site. `sst dev` starts it with the linked resources in
+   * its environment, restarts it when they change, and shows its output.
+   *
+   * Set it to `false` to start the dev server yourself, like with `sst dev next dev`.
+   *
+   * @default `{ command: "npm run dev" }`
+   * @example
+   * ```js
+   * {
+   *   dev: {
+   *     command: "pnpm dev --port 3001"
+   *   }
+   * }
+   * ```
+   */
+  dev?: false | DevCommand.Args;
   /**
    * Set a custom domain for your SSR site. Supports domains hosted either on
    * [Route 53](https://aws.amazon.com/route53/) or outside AWS.
@@ -425,6 +443,8 @@ export function buildApp(
 ) {
   const defaultCommand = "npm run build";
 
+  if ($dev) DevCommand.site(name, args, sitePath);
+
   return all([sitePath, buildCommand, args.link, args.environment]).apply(
     ([sitePath, buildCommand, links, environment]) => {
       const cmd = buildCommand || defaultCommand;
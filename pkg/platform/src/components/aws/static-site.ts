@@ -19,6 +19,7 @@ import { execSync } from "child_process";
 import { globSync } from "glob";
 import { BucketFile, BucketFiles } from "./providers/bucket-files.js";
 import { DistributionInvalidation } from "./providers/distribution-invalidation.js";
+import { DevCommand } from "../dev-command.js";
 
 interface FileOptions {
   /**
@@ -197,6 +198,22 @@ export interface StaticSiteArgs {
      */
     output: Input<string>;
   }>;
+  /**
+   * Configure the dev server of the site, like `vite dev`. `sst dev` starts it with the
+   * environment of the site and shows its output. It's started if the site has a `build`
+   * configured, set it to `false` to start it yourself.
+   *
+   * @default `{ command: "npm run dev" }`
+   * @example
+   * ```js
+   * {
+   *   dev: {
+   *     command: "npx vite dev"
+   *   }
+   * }
+   * ```
+   */
+  dev?: false | DevCommand.Args;
   /**
    * Configure [Vite](https://vitejs.dev) related options.
    *
@@ -557,6 +574,8 @@ export class StaticSite extends Component implements Link.Linkable {
     all([sitePath, environment]).apply(([sitepath, environment]) => {
       Link.Receiver.register(sitepath, [], environment);
     });
+    if ($dev && (args.dev || (args.dev === undefined && args.build)))
+      DevCommand.site(name, { dev: args.dev, environment }, sitePath);
 
     this.assets = bucket;
     this.cdn = distribution;
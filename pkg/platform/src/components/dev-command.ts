@@ -0,0 +1,85 @@
+import { Input, Output, all, output } from "@pulumi/pulumi";
+import { Link } from "./link.js";
+
+/**
+ * The dev servers of the frontends, started and restarted by `sst dev` with
+ * their links.
+ */
+export module DevCommand {
+  export interface Definition {
+    directory: string;
+    command: string;
+    links: string[];
+    environment: Record<string, string>;
+    autostart: boolean;
+  }
+
+  export interface Args {
+    /**
+     * The command that starts the dev server.
+     * @default `"npm run dev"`
+     */
+    command?: Input<string>;
+    /**
+     * The directory to run the command in, relative to the root of your app.
+     * @default The `path` of the site
+     */
+    directory?: Input<string>;
+    /**
+     * Start the dev server with `sst dev`.
+     * @default `true`
+     */
+    autostart?: Input<boolean>;
+  }
+
+  let commands: Record<string, Input<Definition | undefined>> = {};
+  export function reset() {
+    commands = {};
+  }
+
+  export function register(
+    name: string,
+    definition: Input<Definition | undefined>,
+  ) {
+    commands[name] = definition;
+  }
+
+  export function list() {
+    return commands;
+  }
+
+  /**
+   * Registers the dev server of a site, with the links of the site.
+   */
+  export function site(
+    name: string,
+    args: {
+      dev?: false | Args;
+      link?: Input<any[]>;
+      environment?: Input<Record<string, Input<string>>>;
+    },
+    sitePath: Output<string>,
+  ) {
+    if (args.dev === false) return;
+    const links = output(args.link).apply((links) => Link.build(links || []));
+    register(
+      name,
+      all([
+        sitePath,
+        args.dev?.command,
+        args.dev?.directory,
+        args.dev?.autostart,
+        links,
+        args.environment,
+      ]).apply(
+        ([sitePath, command, directory, autostart, links, environment]) => ({
+          directory: directory ?? sitePath,
+          command: command ?? "npm run dev",
+          links: links.map((item) => item.name),
+          environment: (environment ?? {}) as Record<string, string>,
+          autostart: autostart !== false,
+        }),
+      ),
+    );
+  }
+}
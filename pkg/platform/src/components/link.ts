@@ -1,4 +1,11 @@
-import { Input, Output, runtime, output, all } from "@pulumi/pulumi";
+import {
+  Input,
+  Output,
+  Resource,
+  runtime,
+  output,
+  all,
+} from "@pulumi/pulumi";
 import { FunctionPermissionArgs } from "./aws/function.js";
 import { VisibleError } from "./error.js";
 
@@ -10,6 +17,7 @@ export module Link {
   let links: Record<string, Record<string, any>> = {};
   export function reset() {
     links = {};
+    AWS.resetParameters();
     runtime.registerStackTransformation((args) => {
       const resource = args.resource;
       process.nextTick(() => {
@@ -85,6 +93,37 @@ export module Link {
     ) {
       obj.prototype.getSSTAWSPermissions = cb;
     }
+
+    /**
+     * The SSM path links are stored under when `linkStore` is `ssm`.
+     */
+    export function parameterPrefix() {
+      return `/sst/link/${$app.name}/${$app.stage}/`;
+    }
+
+    // the parameters are created once every link is known, after the program
+    let parameters: Promise<Record<string, Resource>>;
+    let resolveParameters: (parameters: Record<string, Resource>) => void;
+    export function resetParameters() {
+      parameters = new Promise((resolve) => (resolveParameters = resolve));
+    }
+
+    /**
+     * Sets the SSM parameters the links are stored in, by link name.
+     */
+    export function setParameters(value: Record<string, Resource>) {
+      resolveParameters(value);
+    }
+
+    /**
+     * The SSM parameters of the given links, for the functions that read
+     * them to depend on.
+     */
+    export function parametersOf(names: Input<string[]>) {
+      return all([names, parameters]).apply(([names, stored]) =>
+        names.flatMap((name) => (stored[name] ? [stored[name]] : [])),
+      );
+    }
   }
 
   export module Receiver {
@@ -11,6 +11,8 @@ export class SecretMissingError extends VisibleError {
   }
 }
 
+const BINARY_PREFIX = "sst:base64:";
+
 /**
  * The `Secret` component lets you create secrets in your app.
  *
@@ -97,7 +99,10 @@ export class Secret extends Component implements Link.Linkable {
     if (!value) {
       throw new SecretMissingError(this._name);
     }
-    this._value = value;
+    // binary values are stored base64 encoded behind a prefix
+    this._value = value.startsWith(BINARY_PREFIX)
+      ? value.slice(BINARY_PREFIX.length)
+      : value;
   }
 
   /**
@@ -109,6 +114,8 @@ export class Secret extends Component implements Link.Linkable {
 
   /**
    * The value of the secret. It'll be `undefined` if the secret has not been set through the CLI or if the `placeholder` hasn't been set.
+   *
+   * Binary values set with `--from-file` or `--stdin` are base64 encoded.
    */
   public get value() {
     return secret(this._value);
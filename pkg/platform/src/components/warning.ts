@@ -0,0 +1,20 @@
+export module Warning {
+  // picked up by the CLI and shown separately from the Pulumi diagnostics
+  const PREFIX = "~w";
+
+  export function warn(message: string, component?: string) {
+    emit("warning", message, component);
+  }
+
+  export function info(message: string, component?: string) {
+    emit("info", message, component);
+  }
+
+  function emit(
+    level: "warning" | "info",
+    message: string,
+    component?: string,
+  ) {
+    console.log(PREFIX + JSON.stringify({ level, message, component }));
+  }
+}
@@ -1,4 +1,4 @@
-import { Input } from "@pulumi/pulumi";
+import { Input, all } from "@pulumi/pulumi";
 
 export module Warp {
   export interface Definition {
@@ -8,14 +8,109 @@ export module Warp {
     handler: string;
     bundle?: string;
     links: string[];
+    schedules?: Record<string, string>;
+    queues?: Record<string, Queue>;
+    buses?: Record<string, Bus>;
+    bastion?: string;
+    layers?: string[];
   }
+
+  export interface Queue {
+    url: string;
+    arn: string;
+    batchSize?: number;
+    reportBatchItemFailures?: boolean;
+    filters?: string[];
+  }
+
+  export interface Bus {
+    url: string;
+    arn: string;
+    pattern: string;
+  }
+
+  export interface Log {
+    group: string;
+    region: string;
+    live: boolean;
+  }
+
   let warps: Record<string, Input<Definition | undefined>> = {};
+  let schedules: Record<string, Record<string, Input<string>>> = {};
+  let queues: Record<string, Record<string, Input<Queue>>> = {};
+  let buses: Record<string, Record<string, Input<Bus>>> = {};
+  let logs: Record<string, Input<Log>> = {};
   export function reset() {
     warps = {};
+    schedules = {};
+    queues = {};
+    buses = {};
+    logs = {};
   }
 
   export function list() {
-    return warps;
+    return Object.fromEntries(
+      Object.entries(warps).map(([functionID, definition]) => [
+        functionID,
+        all([
+          definition,
+          schedules[functionID] || {},
+          queues[functionID] || {},
+          buses[functionID] || {},
+        ]).apply(([definition, schedules, queues, buses]) => {
+          if (!definition) return definition;
+          return {
+            ...definition,
+            ...(Object.keys(schedules).length ? { schedules } : {}),
+            ...(Object.keys(queues).length ? { queues } : {}),
+            ...(Object.keys(buses).length ? { buses } : {}),
+          };
+        }),
+      ]),
+    );
+  }
+
+  /**
+   * Attaches a schedule expression to a function, so dev can fire it
+   * locally.
+   */
+  export function schedule(
+    functionID: string,
+    name: string,
+    expression: Input<string>,
+  ) {
+    schedules[functionID] = schedules[functionID] || {};
+    schedules[functionID][name] = expression;
+  }
+
+  /**
+   * Attaches a queue to a function, so dev can poll it and run the function
+   * locally.
+   */
+  export function queue(functionID: string, name: string, queue: Input<Queue>) {
+    queues[functionID] = queues[functionID] || {};
+    queues[functionID][name] = queue;
+  }
+
+  /**
+   * Attaches the dev queue of an EventBridge bus to a function, so dev can
+   * match the events locally and run the function.
+   */
+  export function bus(functionID: string, name: string, bus: Input<Bus>) {
+    buses[functionID] = buses[functionID] || {};
+    buses[functionID][name] = bus;
+  }
+
+  /**
+   * Registers the log group of a function, so dev can tail the logs of the
+   * ones that don't run locally.
+   */
+  export function log(functionID: string, log: Input<Log>) {
+    logs[functionID] = log;
+  }
+
+  export function listLogs() {
+    return logs;
   }
 
   export function register(
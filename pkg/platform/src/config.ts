@@ -178,6 +178,560 @@ export interface App {
    *
    */
   home: "aws" | "cloudflare";
+
+  /**
+   * Conditions that are checked after a deploy completes. The deploy is only marked
+   * as successful once every condition is met or it fails once the `timeout` is reached.
+   * They are checked after the stage is unlocked and aren't checked in `sst dev`.
+   *
+   * Each condition checks one of:
+   * - `http`: The URL returns a `200`.
+   * - `ssm`: The SSM parameter exists. Requires the `aws` provider.
+   * - `dns`: The hostname resolves.
+   *
+   * Use `${name}` to reference an output returned from the `run` function. If `resource`
+   * is set, the condition is only checked when a resource with that name is deployed.
+   *
+   * @example
+   * ```ts
+   * {
+   *   wait: [
+   *     { resource: "MyApi", http: "${url}/health", timeout: 120 }
+   *   ]
+   * }
+   * ```
+   */
+  wait?: {
+    resource?: string;
+    http?: string;
+    ssm?: string;
+    dns?: string;
+    /**
+     * Timeout in seconds.
+     * @default `300`
+     */
+    timeout?: number;
+  }[];
+  /**
+   * Derive the passphrase used to encrypt your state and secrets from a key, instead
+   * of storing the passphrase itself in your home provider. Only the encrypted data key
+   * is stored.
+   *
+   * Existing stages keep using their stored passphrase until you run `sst rotate-passphrase`.
+   *
+   * @example
+   * ```ts
+   * {
+   *   passphrase: {
+   *     kms: "alias/my-app"
+   *   }
+   * }
+   * ```
+   */
+  passphrase?: {
+    /**
+     * The ID, ARN, or alias of the KMS key to generate data keys with. Requires the `aws` provider.
+     */
+    kms?: string;
+    /**
+     * Path to an [age](https://age-encryption.org) identity file, relative to your app.
+     */
+    age?: string;
+  };
+  /**
+   * Stream every event of a deploy, like resource updates and diagnostics, to an external
+   * system. Exporting is best effort and never fails the deploy.
+   *
+   * @example
+   * ```ts
+   * {
+   *   exporters: [
+   *     { type: "sqs", queueUrl: "https://sqs.us-east-1.amazonaws.com/123456789012/deploys" },
+   *     { type: "kafka", brokers: ["localhost:9092"], topic: "deploys" },
+   *     { type: "datadog", site: "datadoghq.eu" }
+   *   ]
+   * }
+   * ```
+   */
+  exporters?: (
+    | {
+        type: "sqs";
+        /**
+         * Requires the `aws` provider.
+         */
+        queueUrl: string;
+      }
+    | {
+        type: "kafka";
+        brokers: string[];
+        topic: string;
+      }
+    | {
+        type: "datadog";
+        /**
+         * Defaults to the `DD_API_KEY` environment variable.
+         */
+        apiKey?: string;
+        /**
+         * @default `"datadoghq.com"`
+         */
+        site?: string;
+      }
+  )[];
+  /**
+   * Tags to write for the stage after every deploy. Use `sst search` to find stages by tag.
+   *
+   * The CLI also sets `sst:app`, `sst:stage`, `sst:version`, `git:branch`, `git:commit`, and `git:remote`, as `host/owner/repo`. Tags starting with `sst:` or `git:` are reserved.
+   *
+   * @example
+   * ```ts
+   * {
+   *   tags: {
+   *     team: "payments"
+   *   }
+   * }
+   * ```
+   */
+  tags?: Record<string, string>;
+  /**
+   * Only pass the host environment variables matching these glob patterns to your app.
+   * By default every variable is passed.
+   *
+   * `PATH`, `HOME`, and a few others that are needed to run your app are always passed.
+   *
+   * @example
+   * ```ts
+   * {
+   *   envAllowlist: ["AWS_*", "STRIPE_*"]
+   * }
+   * ```
+   */
+  envAllowlist?: string[];
+  /**
+   * Never pass the host environment variables matching these glob patterns to your app.
+   * Takes precedence over `envAllowlist`. The variables that aren't passed are set to an
+   * empty string for your app.
+   *
+   * @example
+   * ```ts
+   * {
+   *   envDenylist: ["GITHUB_TOKEN", "NPM_*"]
+   * }
+   * ```
+   */
+  envDenylist?: string[];
+  /**
+   * The secrets that need to be set before the app can be deployed. The deploy fails right away
+   * with a list of the missing ones, instead of failing in your functions at runtime.
+   *
+   * Secrets set in the `_fallback` stage count as set.
+   *
+   * @example
+   * ```ts
+   * {
+   *   requiredSecrets: ["StripeSecret", "DatabaseUrl"]
+   * }
+   * ```
+   */
+  requiredSecrets?: string[];
+  /**
+   * Run your deploys inside a container image, for builds that don't depend on what's installed on
+   * the machine running the CLI. The Pulumi engine, your `run` function, and the function builds it
+   * starts all run in the container. The CLI still manages the state, the lock, and the events.
+   *
+   * The image needs `pulumi` and `node` installed, and has to be pinned to a tag or, better, a digest.
+   * The project directory is mounted into the container at the same path.
+   *
+   * Not supported on Windows.
+   *
+   * @example
+   * ```ts
+   * {
+   *   runner: {
+   *     image: "ghcr.io/acme/sst-runner@sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"
+   *   }
+   * }
+   * ```
+   */
+  runner?: {
+    /**
+     * The container image to run in.
+     */
+    image: string;
+    /**
+     * The container CLI to use.
+     * @default `"docker"`
+     */
+    command?: string;
+  };
+  /**
+   * Where functions get the values of the resources they are linked to.
+   *
+   * - `bundle` includes the values in the function bundle.
+   * - `ssm` stores them as `SecureString` parameters in SSM Parameter Store under
+   *   `/sst/link/{app}/{stage}/`. Functions load them on the first invocation, so
+   *   rotating a secret doesn't require redeploying every function that's linked to it.
+   *   Requires the `aws` provider.
+   *
+   * This only applies to deployed functions, `sst dev` always passes the values through.
+   *
+   * @default `"bundle"`
+   * @example
+   * ```ts
+   * {
+   *   linkStore: "ssm"
+   * }
+   * ```
+   */
+  linkStore?: "bundle" | "ssm";
+  /**
+   * Keep the state and secrets of the app under a separate prefix in the `home`. This is
+   * useful when multiple teams share the same bucket or account and might pick the same
+   * app name.
+   *
+   * The first deploy of a stage records the git repository it was deployed from. Deploying
+   * the same app and stage from another repository fails instead of overwriting it.
+   *
+   * :::caution
+   * Changing the namespace of an app that's already deployed makes its existing stages
+   * and secrets unreachable.
+   * :::
+   *
+   * @example
+   * ```ts
+   * {
+   *   namespace: "payments"
+   * }
+   * ```
+   */
+  namespace?: string;
+  /**
+   * The JavaScript runtime your `sst.config.ts` is run with.
+   *
+   * By default, it's `bun` if your project only has a `bun.lockb` or `bun.lock`, `deno` if it
+   * only has a `deno.json` or `deno.lock`, and `node` otherwise. The `app` function is always
+   * run with the detected runtime since it decides this setting.
+   *
+   * With `deno`, the `app` function is run with Deno and can use the import map in your
+   * `deno.json` and `npm:` or `jsr:` specifiers. The `run` function is still run with Node
+   * since Pulumi doesn't support Deno. So import `npm:`, `jsr:`, and URL specifiers dynamically
+   * inside the `app` function, importing them at the top of the config fails the deploy.
+   *
+   * This doesn't change the runtime of your functions. `bun` is also not supported on Windows
+   * or together with the `runner`.
+   *
+   * @example
+   * ```ts
+   * {
+   *   jsRuntime: "bun"
+   * }
+   * ```
+   */
+  jsRuntime?: "node" | "bun" | "deno";
+  /**
+   * Configure the build cache. The compiled config and the function bundles, including the
+   * ones `sst dev` runs on your machine, are cached in the `.sst` directory and reused while
+   * the files they were built from stay the same. The compiled config is encrypted with the
+   * same key as the remote cache.
+   *
+   * With `remote`, the cache is also stored in your `home` provider so other machines, like
+   * your CI, can reuse the bundles. It's shared by every stage of the app and encrypted, since
+   * function bundles include their linked resources.
+   *
+   * @default `{ enabled: true, remote: false }`
+   * @example
+   * ```ts
+   * {
+   *   buildCache: {
+   *     remote: true
+   *   }
+   * }
+   * ```
+   */
+  buildCache?: {
+    /**
+     * Turn the cache off to always rebuild.
+     * @default `true`
+     */
+    enabled?: boolean;
+    /**
+     * Share the cache through the `home` provider.
+     * @default `false`
+     */
+    remote?: boolean;
+  };
+  /**
+   * Customize how the `run` function is bundled. Adds to the defines and injected files sst
+   * uses itself. The `$app`, `$cli`, and `$dev` globals are reserved.
+   *
+   * This doesn't apply to the `app` function, or to your functions, those are configured
+   * with the `nodejs` prop of the function.
+   *
+   * @example
+   * ```ts
+   * {
+   *   build: {
+   *     define: {
+   *       __VERSION__: JSON.stringify("1.2.3")
+   *     },
+   *     inject: ["./polyfills.ts"]
+   *   }
+   * }
+   * ```
+   */
+  build?: {
+    /**
+     * Replace global identifiers with JavaScript expressions, like esbuild's `define`.
+     */
+    define?: Record<string, string>;
+    /**
+     * Files, relative to the project root, that are imported into every module, like
+     * esbuild's `inject`.
+     */
+    inject?: string[];
+    /**
+     * Minify the bundle.
+     * @default `false`
+     */
+    minify?: boolean;
+    /**
+     * Keep the original names of functions and classes when minifying.
+     * @default `false`
+     */
+    keepNames?: boolean;
+    /**
+     * Insert a string at the end of the bundle.
+     */
+    footer?: string;
+  };
+  /**
+   * Route outbound traffic through an HTTP proxy. This applies to the CLI, the state and
+   * secrets in your `home`, downloading providers and their plugins, and the providers
+   * themselves.
+   *
+   * The `HTTP_PROXY`, `HTTPS_PROXY`, and `NO_PROXY` environment variables are respected
+   * without this. Anything set here takes precedence over them.
+   *
+   * @example
+   * ```ts
+   * {
+   *   proxy: {
+   *     https: "http://proxy.internal:3128",
+   *     noProxy: ["localhost", ".internal"]
+   *   }
+   * }
+   * ```
+   */
+  proxy?: {
+    /**
+     * The proxy for `http` requests.
+     */
+    http?: string;
+    /**
+     * The proxy for `https` requests.
+     */
+    https?: string;
+    /**
+     * Hosts and domains that are reached directly.
+     */
+    noProxy?: string[];
+  };
+  /**
+   * The Node.js that runs the `run` function, and the minimum version it needs. Before
+   * running, sst checks that it exists and is recent enough, and fails with a clear error
+   * otherwise.
+   *
+   * This doesn't apply with the `bun` runtime or a `runner`.
+   *
+   * @example
+   * ```ts
+   * {
+   *   node: {
+   *     path: "/opt/node-20/bin/node",
+   *     version: "20.11"
+   *   }
+   * }
+   * ```
+   */
+  node?: {
+    /**
+     * The path to the `node` executable, relative to the project root or absolute.
+     * @default The `node` on the `PATH`
+     */
+    path?: string;
+    /**
+     * The minimum version, like `18` or `20.11.0`.
+     */
+    version?: string;
+  };
+  /**
+   * Typecheck the config with `tsc` before every deploy, and stop if there are type errors.
+   * The `sst.config.ts`, your stacks, the overlay of the stage, and the files they import are
+   * checked with your `tsconfig.json`.
+   *
+   * You can also do this for a single deploy with `sst deploy --typecheck`.
+   *
+   * @default `false`
+   */
+  typecheck?: boolean;
+  /**
+   * Run the files in the `stacks/` directory next to the config after the `run` function.
+   * Each file default exports a function like `run`, they are run in alphabetical order and
+   * their outputs are merged.
+   *
+   * @default `false`
+   */
+  stacks?: boolean;
+  /**
+   * The number of functions `sst dev` bundles at the same time when it starts, or when
+   * the config changes. Set with `SST_BUILD_CONCURRENCY`.
+   *
+   * @default The number of CPUs
+   */
+  buildConcurrency?: number;
+  /**
+   * Configure how `sst dev` watches your files for changes.
+   *
+   * @example
+   * ```ts
+   * {
+   *   watch: {
+   *     ignore: ["*.test.ts", "src/generated"],
+   *     debounce: 300
+   *   }
+   * }
+   * ```
+   */
+  watch?: {
+    /**
+     * Glob patterns of the files and directories to ignore. A pattern without a `/` matches
+     * a file or directory with that name anywhere, like `.git`. Otherwise it's matched against
+     * the path relative to the project root, where `**` matches any number of directories.
+     *
+     * `.sst`, `node_modules`, `.git`, and the build output of Rust, Java, and .NET projects
+     * are always ignored.
+     */
+    ignore?: string[];
+    /**
+     * How long to wait, in milliseconds, for the changes to settle before rebuilding.
+     * @default `100`
+     */
+    debounce?: number;
+    /**
+     * Check for changes every second instead of using file system events. This is used
+     * automatically if the files can't be watched, like on some network drives.
+     * @default `false`
+     */
+    poll?: boolean;
+  };
+  /**
+   * Stages that can't be deployed, removed, or renamed unless you pass in `--allow-reserved`.
+   * This is useful for stages that should only be changed from CI.
+   *
+   * @example
+   * ```ts
+   * {
+   *   reservedStages: ["production"]
+   * }
+   * ```
+   */
+  reservedStages?: string[];
+  /**
+   * The version of the CLI the app needs, as a version or a semver range. When the CLI
+   * that's installed doesn't match, the newest version that does is downloaded to the
+   * `versions` directory next to the global config of sst, like `~/.config/sst/versions`,
+   * and the command is run with it instead. This keeps everyone on your team, and your CI,
+   * on the same version.
+   *
+   * Set the `SST_IGNORE_VERSION` environment variable to use the installed version
+   * anyway. Running `sst upgrade` is never switched.
+   *
+   * @example
+   * ```ts
+   * {
+   *   version: "^0.0.300"
+   * }
+   * ```
+   */
+  version?: string;
+  /**
+   * Use a stage for each git branch. When you don't pass in `--stage`, the stage is
+   * derived from the current branch instead of using your personal stage. The branch name
+   * is lowercased, and the characters that can't be in a stage name are replaced with `-`.
+   *
+   * In CI, where a commit is usually checked out instead of a branch, the branch is read
+   * from the environment variables of GitHub Actions, GitLab, Bitbucket, CircleCI, and
+   * Jenkins.
+   *
+   * @example
+   * ```ts
+   * {
+   *   branchStage: {
+   *     stages: {
+   *       main: "production",
+   *       "release/*": "staging",
+   *       "dependabot/*": ""
+   *     },
+   *     prefix: "pr-"
+   *   }
+   * }
+   * ```
+   *
+   * Set it to `{}` to use the branch name as is.
+   */
+  branchStage?: {
+    /**
+     * Map branches to stages, by name or a glob pattern like `release/*`. If more than one
+     * pattern matches, the longest one wins. Map a branch to `""` to use your personal stage.
+     */
+    stages?: Record<string, string>;
+    /**
+     * Added to the stages that are derived from a branch name.
+     */
+    prefix?: string;
+    /**
+     * The longest a stage derived from a branch name can be, longer ones are truncated.
+     * @default `32`
+     */
+    maxLength?: number;
+  };
+  /**
+   * Change where the CLI writes its files. Relative paths are resolved from the project root.
+   *
+   * Each one can also be set with an environment variable, which takes precedence. This is useful in
+   * containerized CI runners with a read-only root filesystem.
+   *
+   * The `.sst` directory itself, with the compiled config, the providers it loads, the caches, and
+   * the locks, can be moved with `SST_WORKING_DIR`. It can't be set here since the config is compiled
+   * into it. The `build` and `output` directories default to it.
+   *
+   * @example
+   * ```ts
+   * {
+   *   paths: {
+   *     temp: "/scratch/tmp",
+   *     build: "/scratch/build",
+   *     output: "/scratch/logs"
+   *   }
+   * }
+   * ```
+   */
+  paths?: {
+    /**
+     * Temporary files, for the CLI and the processes it runs. Defaults to the system temp directory.
+     * Set with `SST_TEMP_DIR`.
+     */
+    temp?: string;
+    /**
+     * Function bundles and zips, written to an `artifacts` directory inside. Defaults to `.sst`.
+     * Set with `SST_BUILD_DIR`.
+     */
+    build?: string;
+    /**
+     * The event log and the CLI log. Defaults to `.sst`. Set with `SST_OUTPUT_DIR`.
+     */
+    output?: string;
+  };
 }
 
 export interface AppInput {
@@ -238,6 +792,29 @@ export interface Config {
    *   };
    * }
    * ```
+   *
+   * Larger apps can split their resources across files in a `stacks/` directory next to the
+   * config, with `stacks: true` in the `app`. Each file default exports a function like `run`,
+   * they are run after this one in alphabetical order and their outputs are merged.
+   *
+   * ```ts title="stacks/storage.ts"
+   * export default async function () {
+   *   const bucket = new sst.aws.Bucket("MyBucket");
+   *   return { bucket: bucket.name };
+   * }
+   * ```
+   *
+   * A stage can also have its own overlay file, like `sst.production.ts`. Its `app` function
+   * is merged over the one in the config and its `run` function is run last. Both are
+   * optional.
+   *
+   * ```ts title="sst.production.ts"
+   * export default {
+   *   app() {
+   *     return { removal: "retain-all" };
+   *   },
+   * };
+   * ```
    */
   run(): Promise<Record<string, any> | void>;
 }
@@ -49,6 +49,14 @@ interface $APP
      * The providers currently being used in the app.
      */
     providers: App["providers"];
+    /**
+     * Where the values of links are stored for functions. If `linkStore` was not set in the `sst.config.ts`, this will return its default value, `bundle`.
+     */
+    linkStore: "bundle" | "ssm";
+    /**
+     * The build cache settings from the `sst.config.ts`.
+     */
+    buildCache?: App["buildCache"];
   }> {}
 
 declare global {
@@ -197,6 +205,33 @@ declare global {
    */
   export const $app: Simplify<$APP>;
 
+  /**
+   * Reference a secret set with `sst secret set`. This is useful for configuring providers
+   * with credentials that you don't want to keep in environment variables.
+   *
+   * In the `app` function it returns a reference that's replaced with the value of the
+   * secret before the providers are loaded. The value is passed to the provider as secret
+   * config, so `$app.providers` keeps the reference. In the `run` function it returns the value.
+   *
+   * The `home` provider can't reference secrets since that's where they are stored.
+   *
+   * @example
+   * ```ts title="sst.config.ts"
+   * app(input) {
+   *   return {
+   *     name: "my-app",
+   *     home: "aws",
+   *     providers: {
+   *       cloudflare: {
+   *         apiToken: $secret("CloudflareApiToken")
+   *       }
+   *     }
+   *   };
+   * }
+   * ```
+   */
+  export const $secret: (name: string) => string;
+
   /**
    * Returns `true` if the app is running in `sst dev`.
    */
@@ -210,6 +245,8 @@ declare global {
       root: string;
       work: string;
       platform: string;
+      artifacts: string;
+      temp: string;
     };
     home: string;
   };
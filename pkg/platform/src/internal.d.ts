@@ -6,6 +6,8 @@ declare global {
       root: string;
       work: string;
       platform: string;
+      artifacts: string;
+      temp: string;
     };
     home: string;
   };
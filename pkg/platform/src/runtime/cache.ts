@@ -0,0 +1,100 @@
+import path from "path";
+import fs from "fs/promises";
+import crypto from "crypto";
+import { existsAsync } from "../util/fs.js";
+
+// Same layout as the config cache in the CLI, which also syncs it with the
+// remote cache. A manifest named after the key of the build options points
+// to an entry built from the listed inputs.
+const dir = () => path.join($cli.paths.work, "cache", "build");
+
+interface Manifest {
+  inputs: Record<string, string>;
+  entry: string;
+}
+
+function sha256(data: string | Buffer) {
+  return crypto.createHash("sha256").update(data).digest("hex");
+}
+
+export function enabled() {
+  return $app.buildCache?.enabled !== false;
+}
+
+export function key(kind: string, options: object) {
+  // plugins can't be compared, only their names are part of the key
+  const json = JSON.stringify(options, (key, value) =>
+    key === "plugins" && Array.isArray(value)
+      ? value.map((plugin) => plugin.name)
+      : value,
+  );
+  const root = JSON.stringify($cli.paths.root).slice(1, -1);
+  return sha256(kind + "\n" + json.split(root).join("$root"));
+}
+
+async function matches(manifest: Manifest) {
+  for (const [rel, hash] of Object.entries(manifest.inputs)) {
+    const data = await fs
+      .readFile(path.join($cli.paths.root, rel))
+      .catch(() => undefined);
+    if (!data || sha256(data) !== hash) return false;
+  }
+  return true;
+}
+
+/**
+ * Returns the directory of the entry built from the current inputs.
+ */
+export async function lookup(key: string) {
+  if (!enabled()) return;
+  const manifest: Manifest | undefined = await fs
+    .readFile(path.join(dir(), key + ".json"))
+    .then((data) => JSON.parse(data.toString()))
+    .catch(() => undefined);
+  if (!manifest || !(await matches(manifest))) return;
+  const entry = path.join(dir(), "entries", manifest.entry);
+  if (!(await existsAsync(entry))) return;
+  return entry;
+}
+
+/**
+ * Stores the output of a build made from the files. `fill` writes the
+ * output to the entry directory.
+ */
+export async function store(
+  key: string,
+  files: string[],
+  fill: (entry: string) => Promise<void>,
+) {
+  if (!enabled()) return;
+  const inputs: Record<string, string> = {};
+  for (const file of files) {
+    const data = await fs.readFile(file).catch(() => undefined);
+    if (!data) continue;
+    const rel = path
+      .relative($cli.paths.root, file)
+      .split(path.sep)
+      .join(path.posix.sep);
+    inputs[rel] = sha256(data);
+  }
+  const entry = sha256(
+    [key, ...Object.keys(inputs).sort().map((rel) => rel + "=" + inputs[rel])]
+      .join("\n"),
+  );
+  const target = path.join(dir(), "entries", entry);
+  if (!(await existsAsync(target))) {
+    const tmp = `${target}.tmp-${process.pid}-${Date.now()}`;
+    await fs.mkdir(tmp, { recursive: true });
+    try {
+      await fill(tmp);
+      await fs.rename(tmp, target);
+    } catch (ex) {
+      await fs.rm(tmp, { recursive: true, force: true });
+      throw ex;
+    }
+  }
+  await fs.writeFile(
+    path.join(dir(), key + ".json"),
+    JSON.stringify({ inputs, entry } satisfies Manifest),
+  );
+}
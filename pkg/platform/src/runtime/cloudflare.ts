@@ -14,7 +14,7 @@ export async function build(
     }[];
   },
 ) {
-  const out = path.join($cli.paths.work, "artifacts", `${name}-src`);
+  const out = path.join($cli.paths.artifacts, `${name}-src`);
   await fs.rm(out, { recursive: true, force: true });
   await fs.mkdir(out, { recursive: true });
 
@@ -0,0 +1,91 @@
+import path from "path";
+import fs from "fs/promises";
+import { exec } from "child_process";
+import pulumi from "@pulumi/pulumi";
+import { existsAsync } from "../util/fs.js";
+import { FunctionArgs } from "../components/aws/function.js";
+
+/**
+ * Splits a handler like `services/Api::Api.Function::Handler` into the
+ * project directory and the handler Lambda expects, where the assembly is
+ * the name of the directory, `Api::Api.Function::Handler`.
+ */
+export function parseHandler(handler: string) {
+  const [project, ...rest] = handler.split("::");
+  if (rest.length !== 2) return;
+  const assembly = path.basename(project);
+  return {
+    project,
+    assembly,
+    handler: [assembly, ...rest].join("::"),
+  };
+}
+
+export async function build(
+  name: string,
+  input: pulumi.Unwrap<FunctionArgs>,
+) {
+  const out = path.join($cli.paths.artifacts, `${name}-src`);
+  await fs.rm(out, { recursive: true, force: true });
+  await fs.mkdir(out, { recursive: true });
+
+  const parsed = parseHandler(input.handler!);
+  if (!parsed)
+    return {
+      type: "error" as const,
+      errors: [
+        `Handler "${input.handler}" must look like "{project}::{namespace}.{class}::{method}"`,
+      ],
+    };
+  const dir = path.resolve(parsed.project);
+  if (!(await existsAsync(dir)))
+    return {
+      type: "error" as const,
+      errors: [`Could not find the project for handler "${input.handler}"`],
+    };
+
+  const dotnet = input.dotnet || {};
+  const cmd = [
+    "dotnet publish",
+    "--nologo",
+    "--configuration Release",
+    `--runtime ${input.architecture === "arm64" ? "linux-arm64" : "linux-x64"}`,
+    "--self-contained false",
+    `--output ${JSON.stringify(out)}`,
+    "-p:GenerateRuntimeConfigurationFiles=true",
+  ];
+  if (dotnet.readyToRun) cmd.push("-p:PublishReadyToRun=true");
+
+  try {
+    await new Promise<void>((resolve, reject) => {
+      exec(
+        cmd.join(" "),
+        { cwd: dir, maxBuffer: 64 * 1024 * 1024 },
+        (error, stdout) => {
+          if (error) {
+            // msbuild prints the errors to stdout
+            const errors = stdout
+              .split("\n")
+              .filter((line) => line.includes(": error "));
+            reject(
+              new Error(errors.length ? errors.join("\n") : error.message),
+            );
+            return;
+          }
+          resolve();
+        },
+      );
+    });
+  } catch (ex: any) {
+    return {
+      type: "error" as const,
+      errors: [ex.message],
+    };
+  }
+
+  return {
+    type: "success" as const,
+    out,
+    handler: parsed.handler,
+  };
+}
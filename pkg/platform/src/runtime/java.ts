@@ -0,0 +1,167 @@
+import path from "path";
+import fs from "fs/promises";
+import { exec } from "child_process";
+import pulumi from "@pulumi/pulumi";
+import { existsAsync, findAbove } from "../util/fs.js";
+import { FunctionArgs } from "../components/aws/function.js";
+
+// Adds a task that copies the runtime dependencies of a project, so the
+// build of the project doesn't need to be changed.
+const gradleInitScript = `
+allprojects {
+  plugins.withId("java") {
+    tasks.register("sstDependencies", Copy) {
+      from configurations.runtimeClasspath
+      into System.getenv("SST_JAVA_DEPENDENCIES")
+    }
+  }
+}
+`;
+
+/**
+ * Splits a handler like `services/api::com.example.Handler::handleRequest`
+ * into the project directory and the handler Lambda expects.
+ */
+export function parseHandler(handler: string) {
+  const [project, ...rest] = handler.split("::");
+  if (rest.length < 1 || rest.length > 2) return;
+  return {
+    project,
+    handler: rest.join("::"),
+  };
+}
+
+/**
+ * Gradle if there's a build.gradle, Maven if there's a pom.xml, and their
+ * wrappers if the project has one.
+ */
+export async function buildTool(dir: string) {
+  for (const file of ["build.gradle", "build.gradle.kts"]) {
+    if (await existsAsync(path.join(dir, file))) {
+      const wrapper = await findAbove(dir, "gradlew");
+      return {
+        name: "gradle" as const,
+        command: wrapper ? path.join(wrapper, "gradlew") : "gradle",
+      };
+    }
+  }
+  if (await existsAsync(path.join(dir, "pom.xml"))) {
+    const wrapper = await findAbove(dir, "mvnw");
+    return {
+      name: "maven" as const,
+      command: wrapper ? path.join(wrapper, "mvnw") : "mvn",
+    };
+  }
+}
+
+function run(command: string, cwd: string, env: Record<string, string>) {
+  return new Promise<void>((resolve, reject) => {
+    exec(
+      command,
+      { cwd, env: { ...process.env, ...env }, maxBuffer: 64 * 1024 * 1024 },
+      (error, stdout, stderr) => {
+        if (error) {
+          reject(
+            new Error([stdout, stderr].join("\n").trim() || error.message),
+          );
+          return;
+        }
+        resolve();
+      },
+    );
+  });
+}
+
+/**
+ * Builds the jar of the project into `lib` of the function and its
+ * dependencies into `java/lib` of a layer, the dependencies rarely change
+ * and are only deployed when they do.
+ */
+export async function build(
+  name: string,
+  input: pulumi.Unwrap<FunctionArgs>,
+) {
+  const out = path.join($cli.paths.artifacts, `${name}-src`);
+  const layer = path.join($cli.paths.artifacts, `${name}-deps`);
+  await fs.rm(out, { recursive: true, force: true });
+  await fs.rm(layer, { recursive: true, force: true });
+  await fs.mkdir(path.join(out, "lib"), { recursive: true });
+  await fs.mkdir(path.join(layer, "java", "lib"), { recursive: true });
+
+  const parsed = parseHandler(input.handler!);
+  if (!parsed)
+    return {
+      type: "error" as const,
+      errors: [
+        `Handler "${input.handler}" must look like "{project}::{package}.{class}::{method}"`,
+      ],
+    };
+  const dir = path.resolve(parsed.project);
+  const tool = await buildTool(dir);
+  if (!tool)
+    return {
+      type: "error" as const,
+      errors: [
+        `Could not find a build.gradle or pom.xml for handler "${input.handler}"`,
+      ],
+    };
+
+  const dependencies = path.join(layer, "java", "lib");
+  try {
+    if (tool.name === "gradle") {
+      const init = path.join($cli.paths.work, "java", "init.gradle");
+      await fs.mkdir(path.dirname(init), { recursive: true });
+      await fs.writeFile(init, gradleInitScript);
+      await run(
+        `${tool.command} --quiet --init-script ${JSON.stringify(init)} jar sstDependencies -x test`,
+        dir,
+        { SST_JAVA_DEPENDENCIES: dependencies },
+      );
+    } else {
+      await run(
+        [
+          tool.command,
+          "--quiet",
+          "-DskipTests",
+          "package",
+          "dependency:copy-dependencies",
+          "-DincludeScope=runtime",
+          `-DoutputDirectory=${JSON.stringify(dependencies)}`,
+        ].join(" "),
+        dir,
+        {},
+      );
+    }
+  } catch (ex: any) {
+    return {
+      type: "error" as const,
+      errors: [ex.message],
+    };
+  }
+
+  const libs = path.join(
+    dir,
+    tool.name === "gradle" ? "build/libs" : "target",
+  );
+  const jars = (await fs.readdir(libs).catch(() => [] as string[])).filter(
+    (file) =>
+      file.endsWith(".jar") &&
+      !/-(plain|sources|javadoc)\.jar$/.test(file) &&
+      !file.startsWith("original-"),
+  );
+  if (!jars.length)
+    return {
+      type: "error" as const,
+      errors: [`Could not find the jar built in "${libs}"`],
+    };
+  for (const jar of jars) {
+    await fs.copyFile(path.join(libs, jar), path.join(out, "lib", jar));
+  }
+
+  return {
+    type: "success" as const,
+    out,
+    handler: parsed.handler,
+    layer,
+  };
+}
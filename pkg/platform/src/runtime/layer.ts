@@ -0,0 +1,67 @@
+import path from "path";
+import fs from "fs/promises";
+import crypto from "crypto";
+import { exec } from "child_process";
+import * as cache from "./cache.js";
+
+const builds: Record<string, Promise<string>> = {};
+
+/**
+ * Installs the dependencies into a directory laid out like a Node.js Lambda
+ * layer. Functions that install the same dependencies share the build, it
+ * runs once per deploy and is cached between deploys.
+ */
+export async function buildNodeLayer(input: {
+  dependencies: Record<string, string>;
+  architecture?: string;
+}) {
+  const dependencies = Object.fromEntries(
+    Object.entries(input.dependencies).sort(([a], [b]) => a.localeCompare(b)),
+  );
+  const architecture = input.architecture === "arm64" ? "arm64" : "x86_64";
+  const hash = crypto
+    .createHash("sha256")
+    .update(JSON.stringify({ dependencies, architecture }))
+    .digest("hex")
+    .substring(0, 16);
+  builds[hash] = builds[hash] || build();
+  return { hash, dir: await builds[hash] };
+
+  async function build() {
+    const dir = path.join($cli.paths.artifacts, "layers", `nodejs-${hash}`);
+    await fs.rm(dir, { recursive: true, force: true });
+    const key = cache.key("layer", { dependencies, architecture });
+    const cached = await cache.lookup(key);
+    if (cached) {
+      await fs.cp(cached, dir, { recursive: true });
+      return dir;
+    }
+
+    // node_modules of a layer are read from nodejs/ in /opt
+    const nodejs = path.join(dir, "nodejs");
+    await fs.mkdir(nodejs, { recursive: true });
+    await fs.writeFile(
+      path.join(nodejs, "package.json"),
+      JSON.stringify({ dependencies }),
+    );
+    const cmd = ["npm install"];
+    if ("sharp" in dependencies) {
+      cmd.push(
+        "--platform=linux",
+        architecture === "arm64" ? "--arch=arm64" : "--arch=x64",
+      );
+    }
+    await new Promise<void>((resolve, reject) => {
+      exec(cmd.join(" "), { cwd: nodejs }, (error) => {
+        if (error) {
+          reject(error);
+        }
+        resolve();
+      });
+    });
+    await cache
+      .store(key, [], (entry) => fs.cp(dir, entry, { recursive: true }))
+      .catch(() => {});
+    return dir;
+  }
+}
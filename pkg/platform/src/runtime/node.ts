@@ -4,6 +4,8 @@ import { exec } from "child_process";
 import esbuild, { BuildOptions, BuildResult } from "esbuild";
 import pulumi from "@pulumi/pulumi";
 import { findAbove } from "../util/fs.js";
+import * as cache from "./cache.js";
+import { buildNodeLayer } from "./layer.js";
 import { FunctionArgs } from "../components/aws/function.js";
 import fsSync from "fs";
 
@@ -16,8 +18,10 @@ export async function build(
     }[];
   },
 ) {
-  const out = path.join($cli.paths.work, "artifacts", `${name}-src`);
-  const sourcemapOut = path.join($cli.paths.work, "artifacts", `${name}-map`);
+  const out = path.join($cli.paths.artifacts, `${name}-src`);
+  const sourcemapOut = path.join($cli.paths.artifacts, `${name}-map`);
+  // kept outside of the artifacts for `sst analyze`
+  const metafile = path.join($cli.paths.work, "metafile", `${name}.json`);
   await fs.rm(out, { recursive: true, force: true });
   await fs.mkdir(out, { recursive: true });
   await fs.mkdir(sourcemapOut, { recursive: true });
@@ -64,9 +68,12 @@ export async function build(
       ...forceExternal,
       ...(nodejs.install || []),
       ...(external || []),
+      ...(nodejs.external || []),
     ],
     loader: nodejs.loader,
-    keepNames: true,
+    keepNames: nodejs.keepNames ?? true,
+    treeShaking: nodejs.treeShaking,
+    footer: nodejs.footer ? { js: nodejs.footer } : undefined,
     bundle: true,
     logLevel: "silent",
     splitting: nodejs.splitting,
@@ -108,8 +115,63 @@ export async function build(
     ...override,
   };
 
+  const cacheKey = cache.key("function", {
+    options,
+    install: nodejs.install,
+    layer: nodejs.layer,
+    sourcemap: nodejs.sourcemap,
+    architecture: input.architecture,
+  });
+  const cached = await cache.lookup(cacheKey);
+  if (cached) {
+    const meta = JSON.parse(
+      await fs.readFile(path.join(cached, "result.json")).then((x) =>
+        x.toString(),
+      ),
+    );
+    await fs.cp(path.join(cached, "out"), out, { recursive: true });
+    const sourcemap = meta.sourcemap
+      ? path.join(sourcemapOut, meta.sourcemap)
+      : undefined;
+    if (sourcemap)
+      await fs.copyFile(path.join(cached, "map", meta.sourcemap), sourcemap);
+    const sharedLayer = meta.layer
+      ? await buildNodeLayer({
+          dependencies: meta.layer,
+          architecture: input.architecture,
+        })
+      : undefined;
+    await fs.mkdir(path.dirname(metafile), { recursive: true });
+    await fs
+      .copyFile(path.join(cached, "metafile.json"), metafile)
+      .catch(() => {});
+    const inputs = await fs
+      .readFile(metafile)
+      .then((x) => Object.keys(JSON.parse(x.toString()).inputs || {}))
+      .catch(() => [] as string[]);
+    return {
+      type: "success" as const,
+      out,
+      handler,
+      sourcemap,
+      sharedLayer,
+      hotswap: {
+        options,
+        inputs: inputs.map((file) => path.resolve(file)),
+        install: nodejs.layer ? [] : nodejs.install || [],
+        // where the sourcemap is moved out of the bundle to
+        sourcemap: nodejs.sourcemap ? undefined : sourcemapOut,
+      },
+    };
+  }
+
   try {
     const result = await esbuild.build(options);
+    await fs.mkdir(path.dirname(metafile), { recursive: true });
+    await fs.writeFile(metafile, JSON.stringify(result.metafile));
+    const inputs = Object.keys(result.metafile?.inputs || {}).map((file) =>
+      path.resolve(file),
+    );
 
     // Install node_modules
     const installPackages = [
@@ -136,6 +198,8 @@ export async function build(
           }),
     );
 
+    let sharedLayer: { hash: string; dir: string } | undefined;
+    let layerDependencies: Record<string, string> | undefined;
     if (installPackages.length) {
       const src = await findAbove(parsed.dir, "package.json");
       if (!src) {
@@ -146,34 +210,43 @@ export async function build(
           ],
         };
       }
+      // the versions of the installed packages come from it
+      inputs.push(path.join(src, "package.json"));
       const json = JSON.parse(
         await fs
           .readFile(path.join(src, "package.json"))
           .then((x) => x.toString()),
       );
-      await fs.writeFile(
-        path.join(out, "package.json"),
-        JSON.stringify({
-          dependencies: Object.fromEntries(
-            installPackages.map((x) => [x, json.dependencies?.[x] || "*"]),
-          ),
-        }),
+      const dependencies = Object.fromEntries(
+        installPackages.map((x) => [x, json.dependencies?.[x] || "*"]),
       );
-      const cmd = ["npm install"];
-      if (installPackages.includes("sharp")) {
-        cmd.push(
-          "--platform=linux",
-          input.architecture === "arm64" ? "--arch=arm64" : "--arch=x64",
+      if (nodejs.layer) {
+        layerDependencies = dependencies;
+        sharedLayer = await buildNodeLayer({
+          dependencies,
+          architecture: input.architecture,
+        });
+      } else {
+        await fs.writeFile(
+          path.join(out, "package.json"),
+          JSON.stringify({ dependencies }),
         );
-      }
-      await new Promise<void>((resolve, reject) => {
-        exec(cmd.join(" "), { cwd: out }, (error) => {
-          if (error) {
-            reject(error);
-          }
-          resolve();
+        const cmd = ["npm install"];
+        if (installPackages.includes("sharp")) {
+          cmd.push(
+            "--platform=linux",
+            input.architecture === "arm64" ? "--arch=arm64" : "--arch=x64",
+          );
+        }
+        await new Promise<void>((resolve, reject) => {
+          exec(cmd.join(" "), { cwd: out }, (error) => {
+            if (error) {
+              reject(error);
+            }
+            resolve();
+          });
         });
-      });
+      }
     }
 
     const moveSourcemap = async () => {
@@ -190,11 +263,43 @@ export async function build(
       return newPath;
     };
 
+    const sourcemap = await moveSourcemap();
+    await cache
+      .store(cacheKey, inputs, async (entry) => {
+        await fs.cp(out, path.join(entry, "out"), { recursive: true });
+        await fs.copyFile(metafile, path.join(entry, "metafile.json"));
+        if (sourcemap) {
+          await fs.mkdir(path.join(entry, "map"));
+          await fs.copyFile(
+            sourcemap,
+            path.join(entry, "map", path.basename(sourcemap)),
+          );
+        }
+        await fs.writeFile(
+          path.join(entry, "result.json"),
+          JSON.stringify({
+            sourcemap: sourcemap && path.basename(sourcemap),
+            layer: layerDependencies,
+          }),
+        );
+      })
+      .catch(() => {});
+
     return {
       type: "success" as const,
       out,
       handler,
-      sourcemap: await moveSourcemap(),
+      sourcemap,
+      sharedLayer,
+      hotswap: {
+        options,
+        inputs: Object.keys(result.metafile?.inputs || {}).map((file) =>
+          path.resolve(file),
+        ),
+        install: nodejs.layer ? [] : nodejs.install || [],
+        // where the sourcemap is moved out of the bundle to
+        sourcemap: nodejs.sourcemap ? undefined : sourcemapOut,
+      },
     };
   } catch (ex: any) {
     const result = ex as BuildResult;
@@ -0,0 +1,96 @@
+import path from "path";
+import fs from "fs/promises";
+import { exec } from "child_process";
+import pulumi from "@pulumi/pulumi";
+import { existsAsync } from "../util/fs.js";
+import { FunctionArgs } from "../components/aws/function.js";
+
+// Shared by every Rust function and by dev so cargo only rebuilds the
+// crates that changed.
+export const targetDir = () => path.join($cli.paths.work, "rust", "target");
+
+/**
+ * The name of the binary to build, the package name from the Cargo.toml
+ * unless one is picked.
+ */
+export async function binaryName(dir: string, binary?: string) {
+  if (binary) return binary;
+  const manifest = await fs
+    .readFile(path.join(dir, "Cargo.toml"))
+    .then((x) => x.toString())
+    .catch(() => undefined);
+  if (!manifest) return;
+  const pkg = manifest.split(/^\s*\[/m).find((x) => x.startsWith("package]"));
+  return pkg?.match(/^\s*name\s*=\s*["']([^"']+)["']/m)?.[1];
+}
+
+export async function build(
+  name: string,
+  input: pulumi.Unwrap<FunctionArgs>,
+) {
+  const out = path.join($cli.paths.artifacts, `${name}-src`);
+  await fs.rm(out, { recursive: true, force: true });
+  await fs.mkdir(out, { recursive: true });
+
+  const dir = path.resolve(input.handler!);
+  if (!(await existsAsync(path.join(dir, "Cargo.toml"))))
+    return {
+      type: "error" as const,
+      errors: [`Could not find Cargo.toml for handler "${input.handler}"`],
+    };
+
+  const rust = input.rust || {};
+  const binary = await binaryName(dir, rust.binary);
+  if (!binary)
+    return {
+      type: "error" as const,
+      errors: [
+        `Could not find the package name in "${input.handler}/Cargo.toml"`,
+      ],
+    };
+
+  const cmd = [
+    "cargo lambda build",
+    "--release",
+    "--output-format binary",
+    `--bin ${binary}`,
+  ];
+  if (input.architecture === "arm64") cmd.push("--arm64");
+  if (rust.features?.length)
+    cmd.push(`--features ${JSON.stringify(rust.features.join(","))}`);
+
+  try {
+    await new Promise<void>((resolve, reject) => {
+      exec(
+        cmd.join(" "),
+        {
+          cwd: dir,
+          env: { ...process.env, CARGO_TARGET_DIR: targetDir() },
+          maxBuffer: 64 * 1024 * 1024,
+        },
+        (error, _stdout, stderr) => {
+          if (error) {
+            reject(new Error(stderr || error.message));
+            return;
+          }
+          resolve();
+        },
+      );
+    });
+  } catch (ex: any) {
+    return {
+      type: "error" as const,
+      errors: [ex.message],
+    };
+  }
+
+  await fs.copyFile(
+    path.join(targetDir(), "lambda", binary, "bootstrap"),
+    path.join(out, "bootstrap"),
+  );
+  return {
+    type: "success" as const,
+    out,
+    handler: "bootstrap",
+  };
+}
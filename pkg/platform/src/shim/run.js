@@ -1,13 +1,45 @@
 import * as util from "@pulumi/pulumi";
+import fs from "fs";
+import crypto from "crypto";
 import * as sst from "../components/";
 import { Link } from "../components/link";
 import { $config } from "../config";
 
+// large secret sets are passed in an encrypted file instead of the env
+if (process.env.SST_SECRETS_FILE) {
+  const data = fs.readFileSync(process.env.SST_SECRETS_FILE);
+  const key = Buffer.from(process.env.SST_SECRETS_KEY, "base64");
+  const decipher = crypto.createDecipheriv(
+    "aes-256-gcm",
+    key,
+    data.subarray(0, 12),
+  );
+  decipher.setAuthTag(data.subarray(data.length - 16));
+  const secrets = JSON.parse(
+    Buffer.concat([
+      decipher.update(data.subarray(12, data.length - 16)),
+      decipher.final(),
+    ]).toString(),
+  );
+  for (const [name, value] of Object.entries(secrets)) {
+    process.env["SST_SECRET_" + name] = value;
+  }
+}
+
 const $secrets = JSON.parse(process.env.SST_SECRETS || "{}");
 const { output, apply, all, interpolate, concat, jsonParse, jsonStringify } =
   util;
 
 const makeLinkable = Link.makeLinkable;
+
+// in app() this is defined by the CLI and returns a reference that's
+// resolved once the secrets can be read
+function $secret(name) {
+  const value = process.env["SST_SECRET_" + name];
+  if (value === undefined) throw new Error(`Secret "${name}" is not set`);
+  return value;
+}
+
 export {
   makeLinkable as "$linkable",
   output as "$output",
@@ -21,4 +53,5 @@ export {
   sst as "sst",
   $config as "$config",
   $secrets as "$secrets",
+  $secret as "$secret",
 };
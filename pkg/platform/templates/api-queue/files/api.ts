@@ -0,0 +1,18 @@
+import { Resource } from "sst";
+import { SQSClient, SendMessageCommand } from "@aws-sdk/client-sqs";
+
+const client = new SQSClient();
+
+export const handler = async () => {
+  await client.send(
+    new SendMessageCommand({
+      QueueUrl: Resource.MyQueue.url,
+      MessageBody: JSON.stringify({ sent: Date.now() }),
+    })
+  );
+
+  return {
+    statusCode: 200,
+    body: JSON.stringify({ status: "sent" }, null, 2),
+  };
+};
@@ -0,0 +1,25 @@
+/// <reference path="./.sst/platform/config.d.ts" />
+
+export default $config({
+  app(input) {
+    return {
+      name: "{{.App}}",
+      removal: input?.stage === "production" ? "retain" : "remove",
+      home: "{{.Home}}",
+    };
+  },
+  async run() {
+    const queue = new sst.aws.Queue("MyQueue");
+    queue.subscribe("subscriber.handler");
+
+    const api = new sst.aws.Function("MyApi", {
+      handler: "api.handler",
+      link: [queue],
+      url: true,
+    });
+
+    return {
+      api: api.url,
+    };
+  },
+});
@@ -0,0 +1,8 @@
+import type { SQSEvent } from "aws-lambda";
+
+export const handler = async (event: SQSEvent) => {
+  for (const record of event.Records) {
+    console.log("received", record.body);
+  }
+  return "ok";
+};
@@ -0,0 +1,3 @@
+export function hello(name: string) {
+  return `Hello ${name}!`;
+}
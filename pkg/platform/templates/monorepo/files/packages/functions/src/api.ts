@@ -0,0 +1,9 @@
+import { Resource } from "sst";
+import { hello } from "@{{.App}}/core/example";
+
+export const handler = async () => {
+  return {
+    statusCode: 200,
+    body: `${hello("{{.App}}")} Linked to ${Resource.MyBucket.name}.`,
+  };
+};
@@ -0,0 +1,24 @@
+/// <reference path="./.sst/platform/config.d.ts" />
+
+export default $config({
+  app(input) {
+    return {
+      name: "{{.App}}",
+      removal: input?.stage === "production" ? "retain" : "remove",
+      home: "{{.Home}}",
+    };
+  },
+  async run() {
+    const bucket = new sst.aws.Bucket("MyBucket");
+
+    const api = new sst.aws.Function("MyApi", {
+      handler: "packages/functions/src/api.handler",
+      link: [bucket],
+      url: true,
+    });
+
+    return {
+      api: api.url,
+    };
+  },
+});
@@ -0,0 +1,8 @@
+export default function Home() {
+  return (
+    <main>
+      <h1>{{.App}}</h1>
+      <p>Edit app/page.tsx and run sst dev to see your changes.</p>
+    </main>
+  );
+}
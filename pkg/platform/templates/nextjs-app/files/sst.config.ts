@@ -0,0 +1,18 @@
+/// <reference path="./.sst/platform/config.d.ts" />
+
+export default $config({
+  app(input) {
+    return {
+      name: "{{.App}}",
+      removal: input?.stage === "production" ? "retain" : "remove",
+      home: "{{.Home}}",
+    };
+  },
+  async run() {
+    const web = new sst.aws.Nextjs("MyWeb");
+
+    return {
+      url: web.url,
+    };
+  },
+});
@@ -1,7 +1,6 @@
 package project
 
 import (
-	"os"
 	"os/exec"
 	"path/filepath"
 
@@ -12,7 +11,7 @@ func (p *Project) Add(pkg string) error {
 	cmd := exec.Command(global.BunPath(), filepath.Join(p.PathPlatformDir(), "src/ast/add.ts"),
 		p.PathConfig(),
 		pkg)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = p.output
+	cmd.Stderr = p.output
 	return cmd.Run()
 }
@@ -0,0 +1,62 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/pkg/js"
+)
+
+// BundleAnalysis is the breakdown of the config bundle or of a function.
+type BundleAnalysis struct {
+	Name string `json:"name"`
+	*js.Analysis
+}
+
+// PathMetafiles returns where the functions record the metafile of their
+// last build, it outlives the artifacts so they can be analyzed later.
+func (p *Project) PathMetafiles() string {
+	return filepath.Join(p.PathWorkingDir(), "metafile")
+}
+
+// Analyze bundles the config again and reads the metafiles the functions
+// were last built with. Functions that haven't been built with sst dev or
+// deploy are missing.
+func (s *Stack) Analyze() ([]BundleAnalysis, error) {
+	options, _, err := s.programOptions(&StackInput{Command: "up"})
+	if err != nil {
+		return nil, err
+	}
+	buildResult, err := js.Build(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range buildResult.OutputFiles {
+		os.Remove(file.Path)
+	}
+	config, err := js.Analyze([]byte(buildResult.Metafile))
+	if err != nil {
+		return nil, err
+	}
+	result := []BundleAnalysis{{Name: "config", Analysis: config}}
+
+	matches, _ := filepath.Glob(filepath.Join(s.project.PathMetafiles(), "*.json"))
+	sort.Strings(matches)
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return nil, err
+		}
+		analysis, err := js.Analyze(data)
+		if err != nil {
+			continue
+		}
+		result = append(result, BundleAnalysis{
+			Name:     strings.TrimSuffix(filepath.Base(match), ".json"),
+			Analysis: analysis,
+		})
+	}
+	return result, nil
+}
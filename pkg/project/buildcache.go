@@ -0,0 +1,504 @@
+package project
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sst/ion/pkg/js"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// BuildCache configures the cache of the config and function bundles.
+type BuildCache struct {
+	// Enabled defaults to true
+	Enabled *bool `json:"enabled,omitempty"`
+	// Remote shares the cache through the home provider so other machines,
+	// like CI, can reuse the bundles
+	Remote bool `json:"remote,omitempty"`
+}
+
+// buildRootToken replaces the project root in cached programs so they can
+// be used from a checkout in a different directory.
+const buildRootToken = "__SST_ROOT__"
+
+// buildManifest records the inputs a build was made from, with their
+// hashes. The entry it points to is reused while they stay the same.
+type buildManifest = provider.BuildCacheManifest
+
+// buildCache reuses bundles while the files they were built from are
+// unchanged. A manifest named after the key of the build options points to
+// the entry, a directory with the output. The functions are cached in the
+// same layout by the platform.
+type buildCache struct {
+	dir     string
+	root    string
+	version string
+	// home and app encrypt the cached programs since they embed $app
+	home provider.Home
+	app  string
+}
+
+func (p *Project) pathBuildCache() string {
+	return filepath.Join(p.PathWorkingDir(), "cache", "build")
+}
+
+// buildCache returns nil when the cache is turned off, nothing is cached
+// then.
+func (p *Project) buildCache() *buildCache {
+	if p.app.BuildCache != nil && p.app.BuildCache.Enabled != nil && !*p.app.BuildCache.Enabled {
+		return nil
+	}
+	return &buildCache{
+		dir:     p.pathBuildCache(),
+		root:    p.PathRoot(),
+		version: p.version,
+		home:    p.home,
+		app:     p.app.Name,
+	}
+}
+
+func (c *buildCache) key(options js.EvalOptions) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	key, err := js.OptionsKey(options, c.root)
+	if err != nil {
+		return "", err
+	}
+	return digest([]byte(c.version + "\n" + key)), nil
+}
+
+// BuildCacheKey is the key of a build of kind made with options, like the
+// functions bundled in dev. It doesn't depend on where the project is.
+func (p *Project) BuildCacheKey(kind string, options interface{}) (string, error) {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+	root, _ := json.Marshal(p.PathRoot())
+	normalized := strings.ReplaceAll(string(data), strings.Trim(string(root), `"`), "$root")
+	return digest([]byte(p.version + "\n" + kind + "\n" + normalized)), nil
+}
+
+// LookupBuild returns the directory of the cached build for the key if the
+// files it was built from are unchanged, or an empty string.
+func (p *Project) LookupBuild(key string) string {
+	cache := p.buildCache()
+	manifest := cache.lookup(key)
+	if manifest == nil {
+		return ""
+	}
+	return cache.entryPath(manifest.Entry)
+}
+
+// StoreBuild caches the output of a build made from files, write fills the
+// directory of the entry. It's synced with the remote cache like the rest.
+func (p *Project) StoreBuild(key string, files []string, write func(dir string) error) error {
+	return p.buildCache().put(key, files, write)
+}
+
+func (c *buildCache) manifestPath(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *buildCache) entryPath(entry string) string {
+	return filepath.Join(c.dir, "entries", entry)
+}
+
+func (c *buildCache) readManifest(key string) (*buildManifest, error) {
+	data, err := os.ReadFile(c.manifestPath(key))
+	if err != nil {
+		return nil, err
+	}
+	var manifest buildManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// hashInputs hashes files by their path relative to the root.
+func (c *buildCache) hashInputs(files []string) map[string]string {
+	result := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(c.root, file)
+		if err != nil {
+			rel = file
+		}
+		result[filepath.ToSlash(rel)] = digest(data)
+	}
+	return result
+}
+
+// matches checks that every input still has the hash it was built with.
+func (c *buildCache) matches(manifest *buildManifest) bool {
+	for rel, hash := range manifest.Inputs {
+		data, err := os.ReadFile(filepath.Join(c.root, filepath.FromSlash(rel)))
+		if err != nil || digest(data) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// lookup returns the entry built from the current inputs, or nil.
+func (c *buildCache) lookup(key string) *buildManifest {
+	if c == nil {
+		return nil
+	}
+	manifest, err := c.readManifest(key)
+	if err != nil || !c.matches(manifest) {
+		return nil
+	}
+	if _, err := os.Stat(c.entryPath(manifest.Entry)); err != nil {
+		return nil
+	}
+	return manifest
+}
+
+// put stores the output of a build made from files. write fills the entry
+// directory.
+func (c *buildCache) put(key string, files []string, write func(dir string) error) error {
+	if c == nil {
+		return nil
+	}
+	inputs := c.hashInputs(files)
+	paths := make([]string, 0, len(inputs))
+	for rel := range inputs {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+	hash := sha256.New()
+	hash.Write([]byte(key))
+	for _, rel := range paths {
+		hash.Write([]byte("\n" + rel + "=" + inputs[rel]))
+	}
+	manifest := buildManifest{
+		Inputs: inputs,
+		Entry:  hex.EncodeToString(hash.Sum(nil)),
+	}
+
+	entry := c.entryPath(manifest.Entry)
+	if _, err := os.Stat(entry); err != nil {
+		tmp := entry + fmt.Sprintf(".tmp-%d", time.Now().UnixNano())
+		if err := os.MkdirAll(tmp, 0755); err != nil {
+			return err
+		}
+		if err := write(tmp); err != nil {
+			os.RemoveAll(tmp)
+			return err
+		}
+		if err := os.Rename(tmp, entry); err != nil {
+			os.RemoveAll(tmp)
+			return err
+		}
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.manifestPath(key), data, 0644)
+}
+
+// program restores a cached program into dir.
+func (c *buildCache) program(key string, dir string) *program {
+	manifest := c.lookup(key)
+	if manifest == nil {
+		return nil
+	}
+	if c.home == nil {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(c.entryPath(manifest.Entry), "program.mjs.enc"))
+	if err != nil {
+		return nil
+	}
+	data, err = provider.DecryptBuildCache(c.home, c.app, data)
+	if err != nil {
+		slog.Error("failed to decrypt cached program", "err", err)
+		return nil
+	}
+	contents := []byte(replaceRoot(string(data), buildRootToken, filepath.ToSlash(c.root)))
+	outfile := filepath.Join(dir, "eval", fmt.Sprintf("eval-%v.mjs", time.Now().UnixMilli()))
+	if err := os.MkdirAll(filepath.Dir(outfile), 0755); err != nil {
+		return nil
+	}
+	if err := os.WriteFile(outfile, contents, 0644); err != nil {
+		return nil
+	}
+	files := []string{}
+	for rel := range manifest.Inputs {
+		files = append(files, filepath.Join(c.root, filepath.FromSlash(rel)))
+	}
+	// the warnings of the build are shown again when it's reused
+	warnings := []BuildWarningEvent{}
+	if data, err := os.ReadFile(filepath.Join(c.entryPath(manifest.Entry), "warnings.json")); err == nil {
+		json.Unmarshal(data, &warnings)
+	}
+	return &program{
+		main:     outfile,
+		files:    files,
+		hash:     digest(contents),
+		warnings: warnings,
+	}
+}
+
+func (c *buildCache) putProgram(key string, prog *program) error {
+	if c == nil || c.home == nil {
+		return nil
+	}
+	return c.put(key, prog.files, func(dir string) error {
+		data, err := os.ReadFile(prog.main)
+		if err != nil {
+			return err
+		}
+		data = []byte(replaceRoot(string(data), filepath.ToSlash(c.root), buildRootToken))
+		// the program embeds $app so it's encrypted like the remote copy
+		data, err = provider.EncryptBuildCache(c.home, c.app, data)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "program.mjs.enc"), data, 0600); err != nil {
+			return err
+		}
+		if len(prog.warnings) == 0 {
+			return nil
+		}
+		warnings, err := json.Marshal(prog.warnings)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, "warnings.json"), warnings, 0644)
+	})
+}
+
+// replaceRoot replaces the occurrences of the root path in a bundle, only
+// where it's the whole path or the start of one, so a string that happens to
+// contain it is left alone.
+func replaceRoot(data string, from string, to string) string {
+	if from == "" {
+		return data
+	}
+	pattern := regexp.MustCompile(`(^|[^A-Za-z0-9_.\-/$])` + regexp.QuoteMeta(from) + `([/"'\x60]|$)`)
+	return pattern.ReplaceAllString(data, "${1}"+strings.ReplaceAll(to, "$", "$$")+"${2}")
+}
+
+// manifests returns every manifest in the cache by key.
+func (c *buildCache) manifests() map[string]*buildManifest {
+	result := map[string]*buildManifest{}
+	matches, _ := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	for _, match := range matches {
+		key := strings.TrimSuffix(filepath.Base(match), ".json")
+		manifest, err := c.readManifest(key)
+		if err != nil {
+			continue
+		}
+		result[key] = manifest
+	}
+	return result
+}
+
+// prune removes the entries no manifest points to anymore.
+func (c *buildCache) prune() {
+	if c == nil {
+		return
+	}
+	used := map[string]bool{}
+	for _, manifest := range c.manifests() {
+		used[manifest.Entry] = true
+	}
+	entries, _ := os.ReadDir(filepath.Join(c.dir, "entries"))
+	for _, entry := range entries {
+		if !used[entry.Name()] {
+			os.RemoveAll(c.entryPath(entry.Name()))
+		}
+	}
+}
+
+func (s *Stack) remoteBuildCache() bool {
+	cache := s.project.app.BuildCache
+	return s.project.buildCache() != nil && cache != nil && cache.Remote
+}
+
+// pullBuildCache downloads the entries of the remote cache that were built
+// from the same inputs as the local files.
+func (s *Stack) pullBuildCache() error {
+	cache := s.project.buildCache()
+	index, err := provider.GetBuildCacheIndex(s.project.home, s.project.app.Name)
+	if err != nil {
+		return err
+	}
+	local := cache.manifests()
+	for key, remote := range index {
+		if existing, ok := local[key]; ok && existing.Entry == remote.Entry {
+			continue
+		}
+		manifest := &remote
+		if !cache.matches(manifest) {
+			continue
+		}
+		if _, err := os.Stat(cache.entryPath(manifest.Entry)); err != nil {
+			data, err := provider.GetBuildCacheEntry(s.project.home, s.project.app.Name, manifest.Entry)
+			if err != nil {
+				return err
+			}
+			if data == nil {
+				continue
+			}
+			slog.Info("downloaded build cache entry", "entry", manifest.Entry)
+			if err := untarEntry(data, cache.entryPath(manifest.Entry)); err != nil {
+				return err
+			}
+		}
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(cache.manifestPath(key), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushBuildCache uploads the local entries the remote cache doesn't have.
+func (s *Stack) pushBuildCache() error {
+	cache := s.project.buildCache()
+	index, err := provider.GetBuildCacheIndex(s.project.home, s.project.app.Name)
+	if err != nil {
+		return err
+	}
+	uploaded := map[string]bool{}
+	for _, remote := range index {
+		uploaded[remote.Entry] = true
+	}
+	changed := false
+	for key, manifest := range cache.manifests() {
+		if remote, ok := index[key]; ok && remote.Entry == manifest.Entry {
+			continue
+		}
+		if !uploaded[manifest.Entry] {
+			data, err := tarEntry(cache.entryPath(manifest.Entry))
+			if err != nil {
+				return err
+			}
+			if err := provider.PutBuildCacheEntry(s.project.home, s.project.app.Name, manifest.Entry, data); err != nil {
+				return err
+			}
+			slog.Info("uploaded build cache entry", "entry", manifest.Entry)
+			uploaded[manifest.Entry] = true
+		}
+		index[key] = *manifest
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return provider.PutBuildCacheIndex(s.project.home, s.project.app.Name, index)
+}
+
+func tarEntry(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func untarEntry(data []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	tmp := dir + fmt.Sprintf(".tmp-%d", time.Now().UnixNano())
+	defer os.RemoveAll(tmp)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(tmp, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, tmp+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in build cache entry: %s", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0755|0644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tr)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(tmp, dir)
+}
@@ -0,0 +1,23 @@
+package project
+
+import "testing"
+
+func TestReplaceRoot(t *testing.T) {
+	tests := map[string]string{
+		`import "/app/sst.config.ts";`:       `import "__SST_ROOT__/sst.config.ts";`,
+		`const root = "/app";`:               `const root = "__SST_ROOT__";`,
+		`path.join('/app/src', x)`:           `path.join('__SST_ROOT__/src', x)`,
+		`const url = "https://x.com/app/a";`: `const url = "https://x.com/app/a";`,
+		`const other = "/app-admin/file";`:   `const other = "/app-admin/file";`,
+		`const nested = "/data/app/file";`:   `const nested = "/data/app/file";`,
+		`const word = "/apple";`:             `const word = "/apple";`,
+	}
+	for input, want := range tests {
+		if got := replaceRoot(input, "/app", buildRootToken); got != want {
+			t.Errorf("replaceRoot(%q) = %q, want %q", input, got, want)
+		}
+		if got := replaceRoot(want, buildRootToken, "/app"); got != input {
+			t.Errorf("restoring %q = %q, want %q", want, got, input)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Completions are the names of a stage the shell completes. They're saved
+// from the last CompleteEvent so completing doesn't need to read the state.
+type Completions struct {
+	Functions []string `json:"functions"`
+	URNs      []string `json:"urns"`
+	// Secrets are the names of the secrets the stage was deployed with
+	Secrets []string `json:"secrets"`
+}
+
+func resolveCompletionsDir(cfgPath string) string {
+	return filepath.Join(ResolveWorkingDir(cfgPath), "completions")
+}
+
+func (s *Stack) saveCompletions(complete *CompleteEvent, secrets map[string]string) {
+	functions := map[string]bool{}
+	for name := range deployedFunctions(complete.Resources) {
+		functions[name] = true
+	}
+	for name := range complete.Warps {
+		functions[name] = true
+	}
+	completions := Completions{
+		Functions: []string{},
+		URNs:      []string{},
+		Secrets:   []string{},
+	}
+	for name := range functions {
+		completions.Functions = append(completions.Functions, name)
+	}
+	sort.Strings(completions.Functions)
+	for _, resource := range complete.Resources {
+		completions.URNs = append(completions.URNs, string(resource.URN))
+	}
+	for name := range secrets {
+		completions.Secrets = append(completions.Secrets, name)
+	}
+	sort.Strings(completions.Secrets)
+	data, err := json.Marshal(completions)
+	if err != nil {
+		return
+	}
+	dir := resolveCompletionsDir(s.project.PathConfig())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(dir, s.project.app.Stage+".json"), data, 0644)
+}
+
+// LoadCompletions reads the names saved for a stage, it's nil if the stage
+// hasn't been deployed from here.
+func LoadCompletions(cfgPath, stage string) *Completions {
+	data, err := os.ReadFile(filepath.Join(resolveCompletionsDir(cfgPath), stage+".json"))
+	if err != nil {
+		return nil
+	}
+	var completions Completions
+	if err := json.Unmarshal(data, &completions); err != nil {
+		return nil
+	}
+	return &completions
+}
+
+// CompletionStages are the stages that were deployed from here.
+func CompletionStages(cfgPath string) []string {
+	entries, _ := os.ReadDir(resolveCompletionsDir(cfgPath))
+	result := []string{}
+	for _, entry := range entries {
+		if stage, ok := strings.CutSuffix(entry.Name(), ".json"); ok {
+			result = append(result, stage)
+		}
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stackExtensions are the files in the stacks directory that are run.
+var stackExtensions = []string{".ts", ".mts", ".js", ".mjs", ".cjs"}
+
+// PathStacks returns the modules in the stacks directory next to the config
+// if the app opts in to them. Their default export is run after the run
+// function of the config, in alphabetical order.
+func (p *Project) PathStacks() []string {
+	if p.app == nil || !p.app.Stacks {
+		return []string{}
+	}
+	entries, err := os.ReadDir(filepath.Join(p.root, "stacks"))
+	if err != nil {
+		return []string{}
+	}
+	result := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasSuffix(name, ".d.ts") {
+			continue
+		}
+		for _, ext := range stackExtensions {
+			if strings.HasSuffix(name, ext) {
+				result = append(result, filepath.Join(p.root, "stacks", name))
+				break
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// PathOverlay returns the overlay for the stage, like sst.production.ts, or
+// an empty string if there is none. Its app function is merged over the
+// one in the config and its run function is run last.
+func (p *Project) PathOverlay(stage string) string {
+	// sst.config.ts is not an overlay
+	if stage == "config" {
+		return ""
+	}
+	for _, name := range ConfigFiles {
+		path := filepath.Join(p.root, strings.Replace(name, ".config.", "."+stage+".", 1))
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+func overlayImport(path string) string {
+	if path == "" {
+		return "const overlay = {};"
+	}
+	return fmt.Sprintf("import overlay from %s;", strconv.Quote(path))
+}
@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -39,9 +40,33 @@ type preset struct {
 	Steps []step `json:"steps"`
 }
 
-var ErrConfigExists = fmt.Errorf("sst.config.ts already exists")
+var ErrConfigExists = fmt.Errorf("sst.config already exists")
 
 func Create(templateName string, home string) error {
+	currentDirectory, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	source, err := fs.Sub(platform.Templates, path.Join("templates", templateName))
+	if err != nil {
+		return err
+	}
+	return CreateFrom(source, &CreateInput{
+		App:  strings.ToLower(filepath.Base(currentDirectory)),
+		Home: home,
+	})
+}
+
+// CreateInput are the values the files of a template are rendered with.
+type CreateInput struct {
+	App   string
+	Home  string
+	Stage string
+}
+
+// CreateFrom creates the project in the current directory from a template,
+// a preset.json with the steps and the files to copy.
+func CreateFrom(source fs.FS, input *CreateInput) error {
 	gitignoreSteps := []gitignoreStep{
 		{
 			Name: "# sst",
@@ -49,18 +74,12 @@ func Create(templateName string, home string) error {
 		},
 	}
 
-	if _, err := os.Stat("sst.config.ts"); err == nil {
+	if FindConfig(".") != "" {
 		return ErrConfigExists
 	}
+	slog.Info("creating project", "name", input.App)
 
-	currentDirectory, err := os.Getwd()
-	if err != nil {
-		return nil
-	}
-	directoryName := strings.ToLower(filepath.Base(currentDirectory))
-	slog.Info("creating project", "name", directoryName)
-
-	presetBytes, err := platform.Templates.ReadFile(filepath.Join("templates", templateName, "preset.json"))
+	presetBytes, err := fs.ReadFile(source, "preset.json")
 	if err != nil {
 		return err
 	}
@@ -111,8 +130,8 @@ func Create(templateName string, home string) error {
 			break
 
 		case "copy":
-			templateFilesPath := filepath.Join("templates", templateName, "files")
-			err = fs.WalkDir(platform.Templates, templateFilesPath, func(path string, d fs.DirEntry, err error) error {
+			templateFilesPath := "files"
+			err = fs.WalkDir(source, templateFilesPath, func(path string, d fs.DirEntry, err error) error {
 				if d.IsDir() {
 					// Create the directory if it doesn't exist
 					dir := filepath.Join(".", strings.TrimPrefix(path, templateFilesPath))
@@ -126,7 +145,7 @@ func Create(templateName string, home string) error {
 					return nil
 				}
 
-				src, err := platform.Templates.ReadFile(path)
+				src, err := fs.ReadFile(source, path)
 				if err != nil {
 					return err
 				}
@@ -135,12 +154,8 @@ func Create(templateName string, home string) error {
 
 				slog.Info("copying template", "path", path)
 				tmpl, err := template.New(path).Parse(string(src))
-				data := struct {
-					App  string
-					Home string
-				}{
-					App:  directoryName,
-					Home: home,
+				if err != nil {
+					return err
 				}
 
 				if _, err := os.Stat(name); os.IsExist(err) {
@@ -152,7 +167,7 @@ func Create(templateName string, home string) error {
 				}
 				defer output.Close()
 
-				err = tmpl.Execute(output, data)
+				err = tmpl.Execute(output, input)
 				if err != nil {
 					return err
 				}
@@ -0,0 +1,76 @@
+package project
+
+import (
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+)
+
+// DiagnosticContextEvent carries the detailed engine diagnostics of a
+// resource that failed. Lines buffered before the failure are sent at once,
+// later ones as they come in.
+type DiagnosticContextEvent struct {
+	URN   string
+	Lines []string
+}
+
+const diagnosticBufferSize = 50
+
+// diagnosticBuffer holds on to the debug diagnostics of every resource so
+// they can be surfaced once the resource starts failing, without printing
+// them for the resources that succeed.
+type diagnosticBuffer struct {
+	lines   map[string][]string
+	verbose map[string]bool
+}
+
+func newDiagnosticBuffer() *diagnosticBuffer {
+	return &diagnosticBuffer{
+		lines:   map[string][]string{},
+		verbose: map[string]bool{},
+	}
+}
+
+// handle processes an engine event and returns the diagnostics to surface
+// for it, if any. forward is false for debug diagnostics that should not be
+// passed on as they are.
+func (b *diagnosticBuffer) handle(event events.EngineEvent) (result *DiagnosticContextEvent, forward bool) {
+	if diag := event.DiagnosticEvent; diag != nil {
+		if diag.Severity == "debug" {
+			if diag.URN == "" {
+				return nil, false
+			}
+			line := strings.TrimSpace(diag.Message)
+			if b.verbose[diag.URN] {
+				return &DiagnosticContextEvent{URN: diag.URN, Lines: []string{line}}, false
+			}
+			lines := append(b.lines[diag.URN], line)
+			if len(lines) > diagnosticBufferSize {
+				lines = lines[len(lines)-diagnosticBufferSize:]
+			}
+			b.lines[diag.URN] = lines
+			return nil, false
+		}
+		if diag.Severity == "error" {
+			return b.escalate(diag.URN), true
+		}
+	}
+	if event.ResOpFailedEvent != nil {
+		return b.escalate(event.ResOpFailedEvent.Metadata.URN), true
+	}
+	return nil, true
+}
+
+// escalate raises the verbosity of urn and flushes what was buffered for it.
+func (b *diagnosticBuffer) escalate(urn string) *DiagnosticContextEvent {
+	if urn == "" || b.verbose[urn] {
+		return nil
+	}
+	b.verbose[urn] = true
+	lines := b.lines[urn]
+	delete(b.lines, urn)
+	if len(lines) == 0 {
+		return nil
+	}
+	return &DiagnosticContextEvent{URN: urn, Lines: lines}
+}
@@ -0,0 +1,233 @@
+package project
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+)
+
+// PropertyChange is a property of a resource that a deploy would change.
+type PropertyChange struct {
+	Path string      `json:"path"`
+	Kind string      `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// ResourceDiff is how a deploy would change a resource.
+type ResourceDiff struct {
+	URN        string           `json:"urn"`
+	Type       string           `json:"type"`
+	Name       string           `json:"name"`
+	Op         string           `json:"op"`
+	Properties []PropertyChange `json:"properties"`
+}
+
+// ComponentDiff are the changes to the resources of a component. The
+// resources that aren't in a component are grouped under the stack.
+type ComponentDiff struct {
+	URN       string         `json:"urn"`
+	Type      string         `json:"type"`
+	Name      string         `json:"name"`
+	Resources []ResourceDiff `json:"resources"`
+}
+
+const (
+	DiffOpCreate  = "create"
+	DiffOpUpdate  = "update"
+	DiffOpReplace = "replace"
+	DiffOpDelete  = "delete"
+)
+
+// Diff collects the changes a preview reports, see Stack.Run with the
+// preview command.
+type Diff struct {
+	resources map[string]*ResourceDiff
+	parents   map[string]string
+}
+
+func NewDiff() *Diff {
+	return &Diff{
+		resources: map[string]*ResourceDiff{},
+		parents:   map[string]string{},
+	}
+}
+
+// Add records the change in a ResourcePreEvent, the other events are
+// ignored.
+func (d *Diff) Add(event *StackEvent) {
+	if event.ResourcePreEvent == nil {
+		return
+	}
+	metadata := event.ResourcePreEvent.Metadata
+	state := metadata.New
+	if state == nil {
+		state = metadata.Old
+	}
+	if state == nil {
+		return
+	}
+	d.parents[metadata.URN] = state.Parent
+	// components don't have properties of their own
+	if !state.Custom {
+		return
+	}
+	var op string
+	switch metadata.Op {
+	case apitype.OpCreate:
+		op = DiffOpCreate
+	case apitype.OpUpdate:
+		op = DiffOpUpdate
+	case apitype.OpDelete:
+		op = DiffOpDelete
+	case apitype.OpReplace, apitype.OpCreateReplacement, apitype.OpDeleteReplaced:
+		op = DiffOpReplace
+	default:
+		return
+	}
+	// a replacement is reported in a few steps
+	if existing, ok := d.resources[metadata.URN]; ok && len(existing.Properties) > 0 {
+		return
+	}
+	urn := resource.URN(metadata.URN)
+	d.resources[metadata.URN] = &ResourceDiff{
+		URN:        metadata.URN,
+		Type:       string(urn.Type()),
+		Name:       urn.Name(),
+		Op:         op,
+		Properties: propertyChanges(metadata),
+	}
+}
+
+// Components returns the changes grouped by the component the resources
+// are in, in the order of their URNs.
+func (d *Diff) Components() []ComponentDiff {
+	components := map[string]*ComponentDiff{}
+	for _, diff := range d.resources {
+		parent := d.parents[diff.URN]
+		component, ok := components[parent]
+		if !ok {
+			urn := resource.URN(parent)
+			component = &ComponentDiff{URN: parent}
+			if urn.IsValid() {
+				component.Type = string(urn.Type())
+				component.Name = urn.Name()
+			}
+			components[parent] = component
+		}
+		component.Resources = append(component.Resources, *diff)
+	}
+	result := []ComponentDiff{}
+	for _, component := range components {
+		sort.Slice(component.Resources, func(i, j int) bool {
+			return component.Resources[i].URN < component.Resources[j].URN
+		})
+		result = append(result, *component)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].URN < result[j].URN
+	})
+	return result
+}
+
+func propertyChanges(metadata apitype.StepEventMetadata) []PropertyChange {
+	var before, after map[string]interface{}
+	if metadata.Old != nil {
+		before = metadata.Old.Inputs
+	}
+	if metadata.New != nil {
+		after = metadata.New.Inputs
+	}
+	result := []PropertyChange{}
+	if len(metadata.DetailedDiff) > 0 {
+		for path, diff := range metadata.DetailedDiff {
+			result = append(result, PropertyChange{
+				Path: path,
+				Kind: string(diff.Kind),
+				Old:  displayValue(lookupProperty(before, path)),
+				New:  displayValue(lookupProperty(after, path)),
+			})
+		}
+	} else if metadata.Op == apitype.OpUpdate || metadata.Op == apitype.OpReplace {
+		// not every provider reports a detailed diff
+		for _, key := range metadata.Diffs {
+			result = append(result, PropertyChange{
+				Path: key,
+				Kind: string(apitype.DiffUpdate),
+				Old:  displayValue(before[key]),
+				New:  displayValue(after[key]),
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+var propertyPathPart = regexp.MustCompile(`^(?:\.?([^.\[\]]+)|\[(\d+)\]|\["((?:[^"\\]|\\.)*)"\])`)
+
+// lookupProperty reads a value at a property path, like tags.Name or
+// layers[0] or tags["app.name"].
+func lookupProperty(value interface{}, path string) interface{} {
+	for path != "" {
+		match := propertyPathPart.FindStringSubmatch(path)
+		if match == nil {
+			return nil
+		}
+		path = path[len(match[0]):]
+		switch {
+		case match[2] != "":
+			list, ok := value.([]interface{})
+			index, _ := strconv.Atoi(match[2])
+			if !ok || index >= len(list) {
+				return nil
+			}
+			value = list[index]
+		default:
+			key := match[1]
+			if match[3] != "" {
+				key = strings.ReplaceAll(match[3], `\"`, `"`)
+			}
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			value = object[key]
+		}
+	}
+	return value
+}
+
+// displayValue hides secrets and marks the values that are only known
+// after the deploy.
+func displayValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		if value == plugin.UnknownStringValue {
+			return "[unknown]"
+		}
+		return value
+	case map[string]interface{}:
+		if value[resource.SigKey] == resource.SecretSig {
+			return "[secret]"
+		}
+		result := map[string]interface{}{}
+		for key, item := range value {
+			result[key] = displayValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for index, item := range value {
+			result[index] = displayValue(item)
+		}
+		return result
+	}
+	return value
+}
@@ -0,0 +1,68 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource/plugin"
+)
+
+func preEvent(metadata apitype.StepEventMetadata) *StackEvent {
+	return &StackEvent{EngineEvent: events.EngineEvent{EngineEvent: apitype.EngineEvent{
+		ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: metadata},
+	}}}
+}
+
+func TestDiff(t *testing.T) {
+	component := "urn:pulumi:dev::app::sst:aws:Function::MyApi"
+	function := "urn:pulumi:dev::app::sst:aws:Function$aws:lambda/function:Function::MyApiFunction"
+	diff := NewDiff()
+	diff.Add(preEvent(apitype.StepEventMetadata{
+		Op:  apitype.OpSame,
+		URN: component,
+		New: &apitype.StepEventStateMetadata{URN: component},
+	}))
+	diff.Add(preEvent(apitype.StepEventMetadata{
+		Op:  apitype.OpUpdate,
+		URN: function,
+		Old: &apitype.StepEventStateMetadata{Custom: true, Parent: component, Inputs: map[string]interface{}{
+			"memorySize":  1024.0,
+			"environment": map[string]interface{}{"variables": map[string]interface{}{"KEY": "old"}},
+			"layers":      []interface{}{"arn:a"},
+		}},
+		New: &apitype.StepEventStateMetadata{Custom: true, Parent: component, Inputs: map[string]interface{}{
+			"memorySize":  2048.0,
+			"environment": map[string]interface{}{"variables": map[string]interface{}{"KEY": map[string]interface{}{resource.SigKey: resource.SecretSig}}},
+			"layers":      []interface{}{plugin.UnknownStringValue},
+		}},
+		DetailedDiff: map[string]apitype.PropertyDiff{
+			"memorySize":                {Kind: apitype.DiffUpdate},
+			"environment.variables.KEY": {Kind: apitype.DiffUpdate},
+			"layers[0]":                 {Kind: apitype.DiffUpdate},
+		},
+	}))
+
+	components := diff.Components()
+	if len(components) != 1 || components[0].Name != "MyApi" || components[0].Type != "sst:aws:Function" {
+		t.Fatalf("unexpected components %+v", components)
+	}
+	resources := components[0].Resources
+	if len(resources) != 1 || resources[0].Op != DiffOpUpdate || resources[0].Name != "MyApiFunction" {
+		t.Fatalf("unexpected resources %+v", resources)
+	}
+	expected := []PropertyChange{
+		{Path: "environment.variables.KEY", Kind: "update", Old: "old", New: "[secret]"},
+		{Path: "layers[0]", Kind: "update", Old: "arn:a", New: "[unknown]"},
+		{Path: "memorySize", Kind: "update", Old: 1024.0, New: 2048.0},
+	}
+	if len(resources[0].Properties) != len(expected) {
+		t.Fatalf("unexpected properties %+v", resources[0].Properties)
+	}
+	for index, property := range resources[0].Properties {
+		if property != expected[index] {
+			t.Errorf("expected %+v, got %+v", expected[index], property)
+		}
+	}
+}
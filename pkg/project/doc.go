@@ -0,0 +1,25 @@
+// Package project loads an app from its sst.config.ts and runs it. It's
+// what the CLI is built on and can be embedded in other programs, nothing
+// in it reads flags or prints to the terminal.
+//
+//	cfgPath, err := project.DiscoverFrom(dir)
+//	p, err := project.NewContext(ctx, &project.ProjectConfig{
+//		Version: "0.0.0",
+//		Stage:   "production",
+//		Config:  cfgPath,
+//		Output:  logWriter,
+//	})
+//	defer p.Cleanup()
+//	if p.NeedsInstall() {
+//		err = p.Install()
+//	}
+//	err = p.LoadProviders()
+//	err = p.Stack.Run(ctx, &project.StackInput{
+//		Command: "up",
+//		OnEvent: func(event *project.StackEvent) { ... },
+//	})
+//
+// Apps can also be written in Go with Define and loaded with
+// NewFromDefinition. They run in-process, so Install isn't needed and node
+// doesn't have to be available.
+package project
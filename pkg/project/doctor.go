@@ -0,0 +1,200 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+const (
+	DoctorOk   = "ok"
+	DoctorWarn = "warn"
+	DoctorFail = "fail"
+)
+
+// DoctorCheck is the result of one of the checks sst doctor runs.
+type DoctorCheck struct {
+	Name string
+	// Status is ok, warn, or fail
+	Status  string
+	Message string
+	// Fix is what to do about it when it's not ok
+	Fix string
+}
+
+// Doctor checks the environment the app runs in without changing anything,
+// the checks that depend on the home provider are skipped if it can't be
+// loaded.
+func (p *Project) Doctor(ctx context.Context) []DoctorCheck {
+	result := []DoctorCheck{
+		p.doctorNode(ctx),
+		p.doctorPlatform(),
+	}
+	providers, ok := p.doctorProviders()
+	result = append(result, providers...)
+	if !ok {
+		result = append(result, DoctorCheck{
+			Name:    "Home",
+			Status:  DoctorWarn,
+			Message: fmt.Sprintf("Skipped, the \"%s\" provider could not be loaded", p.app.Home),
+			Fix:     "Fix the providers above and run sst doctor again",
+		})
+		return result
+	}
+	result = append(result, p.doctorHome()...)
+	return result
+}
+
+func (p *Project) doctorNode(ctx context.Context) DoctorCheck {
+	check := DoctorCheck{Name: "Node", Status: DoctorOk, Message: "Found " + p.nodePath()}
+	event := p.checkNode(ctx, map[string]string{"PATH": p.pathEnv(os.Getenv("PATH"))})
+	if event == nil {
+		if p.app.Node != nil && p.app.Node.Version != "" {
+			check.Message += ", at least " + p.app.Node.Version
+		}
+		return check
+	}
+	if event.Error == "" {
+		return check
+	}
+	check.Status = DoctorFail
+	check.Message = event.Error
+	check.Fix = "Install Node from https://nodejs.org"
+	if event.Required != "" {
+		check.Fix = fmt.Sprintf("Install Node %s or newer, or point node.path in the config to one", event.Required)
+	}
+	return check
+}
+
+func (p *Project) doctorPlatform() DoctorCheck {
+	check := DoctorCheck{Name: "Platform", Status: DoctorOk, Message: p.PathPlatformDir()}
+	fix := fmt.Sprintf("Delete %s and run sst install", p.PathPlatformDir())
+	contents, err := os.ReadFile(filepath.Join(p.PathPlatformDir(), "version"))
+	if err != nil {
+		check.Status = DoctorFail
+		check.Message = "The platform has not been installed"
+		check.Fix = "Run sst install"
+		return check
+	}
+	if string(contents) != p.version && p.version != "dev" {
+		check.Status = DoctorWarn
+		check.Message = fmt.Sprintf("The platform is for %s, this is %s", contents, p.version)
+		check.Fix = "Run sst install to upgrade it"
+		return check
+	}
+	for _, path := range []string{"src", "node_modules"} {
+		if _, err := os.Stat(filepath.Join(p.PathPlatformDir(), path)); err != nil {
+			check.Status = DoctorFail
+			check.Message = fmt.Sprintf("The platform is missing %s", path)
+			check.Fix = fix
+			return check
+		}
+	}
+	if p.NeedsInstall() {
+		check.Status = DoctorFail
+		check.Message = "Some providers have not been installed"
+		check.Fix = "Run sst install"
+	}
+	return check
+}
+
+// doctorProviders loads the providers, and then one by one to find the
+// failing ones if that doesn't work. It's false if the home isn't loaded.
+func (p *Project) doctorProviders() ([]DoctorCheck, bool) {
+	result := []DoctorCheck{}
+	err := p.LoadProviders()
+	if err == nil {
+		names := []string{}
+		for name := range p.Providers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			result = append(result, DoctorCheck{Name: "Provider " + name, Status: DoctorOk, Message: "Credentials are valid"})
+		}
+		return result, true
+	}
+
+	names := []string{}
+	for name := range p.app.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	p.Providers = map[string]provider.Provider{}
+	failed := false
+	for _, name := range names {
+		args := p.app.Providers[name]
+		if hasSecretRefs(args) {
+			continue
+		}
+		check := DoctorCheck{Name: "Provider " + name, Status: DoctorOk, Message: "Credentials are valid"}
+		if loadErr := p.loadProvider(name, args); loadErr != nil {
+			failed = true
+			check.Status = DoctorFail
+			check.Message = loadErr.Error()
+			check.Fix = providerFix(name)
+		} else if _, ok := p.Providers[name]; !ok {
+			continue
+		}
+		result = append(result, check)
+	}
+	if !failed {
+		result = append(result, DoctorCheck{
+			Name:    "Providers",
+			Status:  DoctorFail,
+			Message: err.Error(),
+			Fix:     "Check the providers in the config",
+		})
+	}
+	return result, false
+}
+
+func providerFix(name string) string {
+	switch name {
+	case "aws":
+		return "Configure the credentials with aws configure, or set AWS_PROFILE to a profile that has them"
+	case "cloudflare":
+		return "Set CLOUDFLARE_API_TOKEN and CLOUDFLARE_DEFAULT_ACCOUNT_ID"
+	}
+	return fmt.Sprintf("Check the credentials of the \"%s\" provider", name)
+}
+
+func (p *Project) doctorHome() []DoctorCheck {
+	home := DoctorCheck{Name: "Home", Status: DoctorOk, Message: fmt.Sprintf("Reached the \"%s\" provider", p.app.Home)}
+	locked, err := provider.LockedAt(p.home, p.app.Name, p.app.Stage)
+	if err != nil {
+		home.Status = DoctorFail
+		home.Message = err.Error()
+		home.Fix = fmt.Sprintf("Make sure the credentials of the \"%s\" provider can read and write the state", p.app.Home)
+		return []DoctorCheck{home}
+	}
+
+	lock := DoctorCheck{Name: "Lock", Status: DoctorOk, Message: "The stage is not locked"}
+	if !locked.IsZero() {
+		lock.Status = DoctorWarn
+		lock.Message = fmt.Sprintf("The stage was locked %s ago", time.Since(locked).Round(time.Second))
+		lock.Fix = "If nothing is deploying the stage, run sst unlock"
+	}
+
+	state := DoctorCheck{Name: "State", Status: DoctorOk, Message: "No pending operations"}
+	checkpoint, err := p.Stack.checkpoint()
+	switch {
+	case errors.Is(err, provider.ErrStateNotFound):
+		state.Message = "The stage has not been deployed"
+	case err != nil:
+		state.Status = DoctorFail
+		state.Message = err.Error()
+		state.Fix = "Run sst state edit to look at the state"
+	case checkpoint.Latest != nil && len(checkpoint.Latest.PendingOperations) > 0:
+		state.Status = DoctorWarn
+		state.Message = fmt.Sprintf("%d operations were interrupted", len(checkpoint.Latest.PendingOperations))
+		state.Fix = "Run sst refresh to check those resources, then deploy again"
+	}
+	return []DoctorCheck{home, lock, state}
+}
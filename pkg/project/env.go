@@ -0,0 +1,75 @@
+package project
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// hostEnvAlwaysAllowed are needed for node and pulumi to run at all, so
+// they are passed through regardless of the allowlist.
+var hostEnvAlwaysAllowed = []string{"PATH", "HOME", "USER", "TMPDIR", "TMP", "TEMP", "LANG", "SYSTEMROOT"}
+
+func matchEnv(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func validateEnvPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid env pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// filterHostEnv returns the variables from environ that are allowed into
+// the deployment. Without an allowlist every variable is allowed, the
+// denylist always wins.
+func filterHostEnv(environ []string, allow, deny []string) map[string]string {
+	result := map[string]string{}
+	for _, value := range environ {
+		pair := strings.SplitN(value, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		key := pair[0]
+		if !matchEnv(hostEnvAlwaysAllowed, key) {
+			if allow != nil && !matchEnv(allow, key) {
+				continue
+			}
+			if matchEnv(deny, key) {
+				continue
+			}
+		}
+		result[key] = pair[1]
+	}
+	return result
+}
+
+// maskHostEnv returns the host variables that aren't allowed into the
+// deployment set to an empty value. The automation api starts pulumi with
+// os.Environ() and the env of the workspace only adds to it, so they can
+// only be blanked out there without changing the env of the process.
+func maskHostEnv(environ []string, allow, deny []string) map[string]string {
+	result := map[string]string{}
+	if allow == nil && len(deny) == 0 {
+		return result
+	}
+	allowed := filterHostEnv(environ, allow, deny)
+	for _, value := range environ {
+		key, _, ok := strings.Cut(value, "=")
+		if !ok {
+			continue
+		}
+		if _, ok := allowed[key]; !ok {
+			result[key] = ""
+		}
+	}
+	return result
+}
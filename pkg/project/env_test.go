@@ -0,0 +1,85 @@
+package project
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestMaskHostEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs the env command")
+	}
+	t.Setenv("SST_TEST_DENIED", "secret")
+	t.Setenv("SST_TEST_ALLOWED", "visible")
+
+	// the automation api starts pulumi the same way
+	cmd := exec.Command("env")
+	cmd.Env = os.Environ()
+	for key, value := range maskHostEnv(os.Environ(), nil, []string{"SST_TEST_DENIED"}) {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Env = append(cmd.Env, "SST_TEST_WORKSPACE=set")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := string(output)
+	if strings.Contains(env, "secret") {
+		t.Errorf("denied variable reached the subprocess")
+	}
+	for _, key := range []string{"SST_TEST_ALLOWED", "SST_TEST_WORKSPACE", "PATH"} {
+		if !strings.Contains(env, key+"=") {
+			t.Errorf("%s is missing from the subprocess", key)
+		}
+	}
+	if os.Getenv("SST_TEST_DENIED") != "secret" {
+		t.Errorf("the env of the process changed")
+	}
+}
+
+func TestMaskHostEnvAllowlist(t *testing.T) {
+	environ := []string{"SST_TEST_OTHER=value", "AWS_REGION=us-east-1", "PATH=/bin"}
+	masked := maskHostEnv(environ, []string{"AWS_*"}, nil)
+	if value, ok := masked["SST_TEST_OTHER"]; !ok || value != "" {
+		t.Errorf("variable outside the allowlist is not blanked out")
+	}
+	if _, ok := masked["AWS_REGION"]; ok {
+		t.Errorf("allowed variable was blanked out")
+	}
+	if _, ok := masked["PATH"]; ok {
+		t.Errorf("PATH was blanked out")
+	}
+}
+
+func TestProjectPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"$PATH\"\n"
+	if err := os.WriteFile(dir+"/sst-test-tool", []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	before := os.Getenv("PATH")
+
+	p := &Project{path: []string{dir}}
+	output, err := p.command(context.Background(), "sst-test-tool").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(output), dir+string(os.PathListSeparator)) {
+		t.Errorf("PATH of the command = %q, want it to start with %q", output, dir)
+	}
+	if os.Getenv("PATH") != before {
+		t.Errorf("PATH of the process changed")
+	}
+
+	other := &Project{}
+	if path := other.lookPath("sst-test-tool"); path != "sst-test-tool" {
+		t.Errorf("lookPath without a Path = %q, want the name", path)
+	}
+}
@@ -0,0 +1,259 @@
+// Package eventlog reads and writes the engine event log of a project. The
+// log holds one JSON event per line of the last run, after a run marker.
+package eventlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+)
+
+type RunMarker struct {
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+}
+
+type markerLine struct {
+	Run *RunMarker `json:"sstRun"`
+}
+
+var markerPrefix = []byte(`{"sstRun":`)
+
+// Writer appends events of a single run to the log.
+type Writer struct {
+	file *os.File
+}
+
+// Create truncates the log at path for a new run and writes the run marker.
+func Create(path string, marker RunMarker) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{file: file}
+	if err := w.write(markerLine{Run: &marker}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) Write(event events.EngineEvent) error {
+	return w.write(event)
+}
+
+func (w *Writer) write(value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = w.file.Write(append(data, '\n'))
+	return err
+}
+
+func (w *Writer) Close() error {
+	return w.file.Close()
+}
+
+type Run struct {
+	Index   int
+	Command string
+	Started time.Time
+	// Events is the number of events in the run
+	Events int
+
+	first int
+	last  int
+}
+
+type line struct {
+	offset int
+	length int
+}
+
+// Reader gives indexed access to a memory mapped log. Events are only
+// decoded when they are read.
+type Reader struct {
+	data  []byte
+	unmap func() error
+	lines []line
+	runs  []Run
+	urns  map[string][]int
+	order []string
+}
+
+// only the fields needed to index an event are decoded
+type urnProbe struct {
+	ResourcePreEvent *struct {
+		Metadata struct {
+			URN string `json:"urn"`
+		} `json:"metadata"`
+	} `json:"resourcePreEvent"`
+	ResOutputsEvent *struct {
+		Metadata struct {
+			URN string `json:"urn"`
+		} `json:"metadata"`
+	} `json:"resOutputsEvent"`
+	ResOpFailedEvent *struct {
+		Metadata struct {
+			URN string `json:"urn"`
+		} `json:"metadata"`
+	} `json:"resOpFailedEvent"`
+	DiagnosticEvent *struct {
+		URN string `json:"urn"`
+	} `json:"diagnosticEvent"`
+}
+
+func (p *urnProbe) urn() string {
+	switch {
+	case p.ResourcePreEvent != nil:
+		return p.ResourcePreEvent.Metadata.URN
+	case p.ResOutputsEvent != nil:
+		return p.ResOutputsEvent.Metadata.URN
+	case p.ResOpFailedEvent != nil:
+		return p.ResOpFailedEvent.Metadata.URN
+	case p.DiagnosticEvent != nil:
+		return p.DiagnosticEvent.URN
+	}
+	return ""
+}
+
+// Open maps the log at path and builds the run and URN indexes in a single
+// pass.
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reader{
+		unmap: func() error { return nil },
+		urns:  map[string][]int{},
+	}
+	if info.Size() > 0 {
+		r.data, r.unmap, err = mmap(file, int(info.Size()))
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.index()
+	return r, nil
+}
+
+func (r *Reader) index() {
+	offset := 0
+	for offset < len(r.data) {
+		end := bytes.IndexByte(r.data[offset:], '\n')
+		if end == -1 {
+			end = len(r.data) - offset
+		}
+		raw := r.data[offset : offset+end]
+		if len(raw) > 0 {
+			r.indexLine(raw, offset)
+		}
+		offset += end + 1
+	}
+}
+
+func (r *Reader) indexLine(raw []byte, offset int) {
+	if bytes.HasPrefix(raw, markerPrefix) {
+		var marker markerLine
+		if json.Unmarshal(raw, &marker) == nil && marker.Run != nil {
+			r.runs = append(r.runs, Run{
+				Index:   len(r.runs),
+				Command: marker.Run.Command,
+				Started: marker.Run.Started,
+				first:   len(r.lines),
+				last:    len(r.lines),
+			})
+			return
+		}
+	}
+	// logs written before run markers existed hold a single run
+	if len(r.runs) == 0 {
+		r.runs = append(r.runs, Run{first: len(r.lines)})
+	}
+
+	index := len(r.lines)
+	r.lines = append(r.lines, line{offset: offset, length: len(raw)})
+	run := &r.runs[len(r.runs)-1]
+	run.last = index + 1
+	run.Events++
+
+	var probe urnProbe
+	if json.Unmarshal(raw, &probe) != nil {
+		return
+	}
+	if urn := probe.urn(); urn != "" {
+		if _, ok := r.urns[urn]; !ok {
+			r.order = append(r.order, urn)
+		}
+		r.urns[urn] = append(r.urns[urn], index)
+	}
+}
+
+// Runs returns the runs in the log, oldest first.
+func (r *Reader) Runs() []Run {
+	return r.runs
+}
+
+// URNs returns every resource that has events in the log, in the order
+// they first appeared.
+func (r *Reader) URNs() []string {
+	return r.order
+}
+
+// Raw returns the undecoded event at index. The slice points into the
+// mapped file and is only valid until Close.
+func (r *Reader) Raw(index int) []byte {
+	l := r.lines[index]
+	return r.data[l.offset : l.offset+l.length]
+}
+
+func (r *Reader) Event(index int) (events.EngineEvent, error) {
+	var event events.EngineEvent
+	err := json.Unmarshal(r.Raw(index), &event)
+	return event, err
+}
+
+// RunEvents calls fn with every event of a run until it returns false.
+func (r *Reader) RunEvents(run int, fn func(event events.EngineEvent) bool) error {
+	for index := r.runs[run].first; index < r.runs[run].last; index++ {
+		event, err := r.Event(index)
+		if err != nil {
+			return err
+		}
+		if !fn(event) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// URNEvents calls fn with every event of a resource until it returns false.
+func (r *Reader) URNEvents(urn string, fn func(event events.EngineEvent) bool) error {
+	for _, index := range r.urns[urn] {
+		event, err := r.Event(index)
+		if err != nil {
+			return err
+		}
+		if !fn(event) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *Reader) Close() error {
+	r.lines = nil
+	r.data = nil
+	return r.unmap()
+}
@@ -0,0 +1,50 @@
+package eventlog
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "event.log")
+	urn := "urn:pulumi:dev::app::aws:s3/bucketV2:BucketV2::Bucket"
+	for _, command := range []string{"up", "refresh"} {
+		w, err := Create(path, RunMarker{Command: command})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(events.EngineEvent{EngineEvent: apitype.EngineEvent{
+			ResourcePreEvent: &apitype.ResourcePreEvent{Metadata: apitype.StepEventMetadata{URN: urn}},
+		}})
+		w.Write(events.EngineEvent{EngineEvent: apitype.EngineEvent{
+			SummaryEvent: &apitype.SummaryEvent{},
+		}})
+		w.Close()
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	runs := r.Runs()
+	// each run truncates the log
+	if len(runs) != 1 || runs[0].Command != "refresh" || runs[0].Events != 2 {
+		t.Fatalf("unexpected runs %+v", runs)
+	}
+	count := 0
+	r.URNEvents(urn, func(event events.EngineEvent) bool {
+		if event.ResourcePreEvent == nil {
+			t.Errorf("expected a resource pre event")
+		}
+		count++
+		return true
+	})
+	if count != 1 {
+		t.Errorf("expected 1 event for %s, got %d", urn, count)
+	}
+}
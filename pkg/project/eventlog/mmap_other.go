@@ -0,0 +1,19 @@
+//go:build !unix
+
+package eventlog
+
+import (
+	"io"
+	"os"
+)
+
+// mmap falls back to reading the whole file where memory mapping isn't
+// available.
+func mmap(file *os.File, size int) ([]byte, func() error, error) {
+	data := make([]byte, size)
+	_, err := io.ReadFull(file, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,131 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/exporter"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+type ExporterConfig struct {
+	Type string `json:"type"`
+	// sqs
+	QueueUrl string `json:"queueUrl"`
+	// kafka
+	Brokers []string `json:"brokers"`
+	Topic   string   `json:"topic"`
+	// datadog
+	ApiKey string `json:"apiKey"`
+	Site   string `json:"site"`
+}
+
+const exportTimeout = 10 * time.Second
+
+func (proj *Project) loadExporters() error {
+	// the providers are loaded again when the config changes in dev
+	for _, e := range proj.exporters {
+		e.Close()
+	}
+	proj.exporters = []exporter.Exporter{}
+	for _, cfg := range proj.app.Exporters {
+		switch cfg.Type {
+		case "sqs":
+			aws, ok := proj.Providers["aws"].(*provider.AwsProvider)
+			if !ok {
+				return util.NewReadableError(nil, `The "sqs" exporter requires the "aws" provider.`)
+			}
+			if cfg.QueueUrl == "" {
+				return util.NewReadableError(nil, `The "sqs" exporter requires a "queueUrl".`)
+			}
+			proj.exporters = append(proj.exporters, exporter.NewSqsExporter(aws.Config(), cfg.QueueUrl))
+		case "kafka":
+			if len(cfg.Brokers) == 0 || cfg.Topic == "" {
+				return util.NewReadableError(nil, `The "kafka" exporter requires "brokers" and a "topic".`)
+			}
+			proj.exporters = append(proj.exporters, exporter.NewKafkaExporter(cfg.Brokers, cfg.Topic))
+		case "datadog":
+			apiKey := cfg.ApiKey
+			if apiKey == "" {
+				apiKey = os.Getenv("DD_API_KEY")
+			}
+			if apiKey == "" {
+				return util.NewReadableError(nil, `The "datadog" exporter requires an "apiKey" or the DD_API_KEY environment variable.`)
+			}
+			proj.exporters = append(proj.exporters, exporter.NewDatadogExporter(apiKey, cfg.Site))
+		default:
+			return util.NewReadableError(nil, fmt.Sprintf(`Unknown exporter type %q, must be one of: sqs, kafka, datadog`, cfg.Type))
+		}
+	}
+	return nil
+}
+
+// export returns a copy of input that also streams every event to the
+// configured exporters. The returned function flushes pending events and
+// must be called once the run is over.
+func (s *Stack) export(input *StackInput) (*StackInput, func()) {
+	if len(s.project.exporters) == 0 {
+		return input, func() {}
+	}
+
+	queue := make(chan exporter.Event, 1000)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for event := range queue {
+			for _, e := range s.project.exporters {
+				ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+				err := e.Export(ctx, event)
+				cancel()
+				if err != nil {
+					slog.Warn("failed to export event", "type", event.Type, "err", err)
+				}
+			}
+		}
+	}()
+
+	next := *input
+	next.OnEvent = func(event *StackEvent) {
+		input.OnEvent(event)
+		select {
+		case queue <- exporter.Event{
+			App:   s.project.app.Name,
+			Stage: s.project.app.Stage,
+			Type:  stackEventType(event),
+			Time:  time.Now(),
+			Data:  event.Redacted(),
+		}:
+		default:
+			slog.Warn("export queue full, dropping event")
+		}
+	}
+
+	return &next, func() {
+		close(queue)
+		wg.Wait()
+	}
+}
+
+// stackEventType returns the name of the event set on a StackEvent, for
+// example ResourcePreEvent or CompleteEvent.
+func stackEventType(event *StackEvent) string {
+	for _, value := range []reflect.Value{
+		reflect.ValueOf(event.EngineEvent),
+		reflect.ValueOf(*event),
+	} {
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Field(i)
+			if field.Kind() == reflect.Ptr && !field.IsNil() {
+				return value.Type().Field(i).Name
+			}
+		}
+	}
+	return "UnknownEvent"
+}
@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type DatadogExporter struct {
+	apiKey string
+	site   string
+}
+
+func NewDatadogExporter(apiKey string, site string) *DatadogExporter {
+	if site == "" {
+		site = "datadoghq.com"
+	}
+	return &DatadogExporter{
+		apiKey: apiKey,
+		site:   site,
+	}
+}
+
+func (e *DatadogExporter) Export(ctx context.Context, event Event) error {
+	text, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"title":            fmt.Sprintf("sst %v/%v: %v", event.App, event.Stage, event.Type),
+		"text":             string(text),
+		"date_happened":    event.Time.Unix(),
+		"aggregation_key":  event.App + "/" + event.Stage,
+		"source_type_name": "sst",
+		"alert_type":       alertType(event.Type),
+		"tags": []string{
+			"app:" + event.App,
+			"stage:" + event.Stage,
+			"event:" + event.Type,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api."+e.site+"/api/v1/events", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", e.apiKey)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("datadog returned %v: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+func alertType(eventType string) string {
+	if strings.Contains(eventType, "Failed") || strings.Contains(eventType, "Diagnostic") {
+		return "error"
+	}
+	return "info"
+}
+
+func (e *DatadogExporter) Close() error {
+	return nil
+}
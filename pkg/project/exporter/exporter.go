@@ -0,0 +1,23 @@
+package exporter
+
+import (
+	"context"
+	"time"
+)
+
+// Event is the envelope every stack event is wrapped in before it leaves
+// the CLI.
+type Event struct {
+	App   string      `json:"app"`
+	Stage string      `json:"stage"`
+	Type  string      `json:"type"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+type Exporter interface {
+	Export(ctx context.Context, event Event) error
+	// Close flushes and releases the connection, the exporter can't be used
+	// after
+	Close() error
+}
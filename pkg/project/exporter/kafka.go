@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type KafkaExporter struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaExporter(brokers []string, topic string) *KafkaExporter {
+	return &KafkaExporter{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{},
+			AllowAutoTopicCreation: true,
+			// events are written one at a time, the default of a second
+			// would hold each of them that long
+			BatchTimeout: 10 * time.Millisecond,
+		},
+	}
+}
+
+func (e *KafkaExporter) Export(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	// keyed by app and stage so events of one stage stay ordered
+	return e.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.App + "/" + event.Stage),
+		Value: body,
+	})
+}
+
+func (e *KafkaExporter) Close() error {
+	return e.writer.Close()
+}
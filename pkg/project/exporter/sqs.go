@@ -0,0 +1,37 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+type SqsExporter struct {
+	client   *sqs.Client
+	queueUrl string
+}
+
+func NewSqsExporter(cfg aws.Config, queueUrl string) *SqsExporter {
+	return &SqsExporter{
+		client:   sqs.NewFromConfig(cfg),
+		queueUrl: queueUrl,
+	}
+}
+
+func (e *SqsExporter) Export(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = e.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(e.queueUrl),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
+
+func (e *SqsExporter) Close() error {
+	return nil
+}
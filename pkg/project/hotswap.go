@@ -0,0 +1,442 @@
+package project
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// HotswapEvent is sent for each function whose code sst deploy --hotswap
+// updated directly, without a full deploy.
+type HotswapEvent struct {
+	Name     string
+	Function string
+	Duration time.Duration
+}
+
+// HotswapFallbackEvent is sent when the changes can't be hot-swapped and a
+// full deploy runs instead.
+type HotswapFallbackEvent struct {
+	Reason string
+}
+
+var ErrHotswapFailed = fmt.Errorf("hotswap failed")
+
+// hotswapState ties the records of the functions to the deploy that wrote
+// them.
+type hotswapState struct {
+	Started     time.Time `json:"started"`
+	SecretsHash string    `json:"secretsHash"`
+}
+
+// hotswapFunction is written by the function component for every bundle it
+// deploys, with what's needed to rebuild and upload it.
+type hotswapFunction struct {
+	Name         string                     `json:"name"`
+	FunctionName string                     `json:"functionName"`
+	Region       string                     `json:"region"`
+	Publish      bool                       `json:"publish"`
+	Bundle       string                     `json:"bundle"`
+	Sourcemap    string                     `json:"sourcemap"`
+	Options      map[string]json.RawMessage `json:"options"`
+	Install      []string                   `json:"install"`
+	Inputs       map[string]string          `json:"inputs"`
+	Wrapper      *struct {
+		Name    string `json:"name"`
+		Content string `json:"content"`
+	} `json:"wrapper"`
+	CopyFiles []struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		IsDir bool   `json:"isDir"`
+	} `json:"copyFiles"`
+}
+
+// the largest zip UpdateFunctionCode takes inline
+const hotswapMaxZip = 50 * 1024 * 1024
+
+func (s *Stack) pathHotswap() string {
+	return filepath.Join(s.project.PathWorkingDir(), "hotswap", s.project.app.Stage)
+}
+
+func hashSecrets(secrets map[string]string) string {
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key + "=" + secrets[key] + "\n"))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// resetHotswap drops the records of the last deploy, the program writes
+// new ones as it deploys.
+func (s *Stack) resetHotswap() {
+	os.RemoveAll(s.pathHotswap())
+}
+
+// saveHotswap marks the records as the ones of the deploy that finished.
+func (s *Stack) saveHotswap(manifest *Manifest, secrets map[string]string) error {
+	data, err := json.Marshal(hotswapState{
+		Started:     manifest.Started,
+		SecretsHash: hashSecrets(secrets),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.pathHotswap(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.pathHotswap(), "state.json"), data, 0644)
+}
+
+// hotswap updates the code of the functions whose bundles changed, if
+// nothing else changed since the last deploy from this machine. It returns
+// false when a full deploy is needed.
+func (s *Stack) hotswap(ctx context.Context, input *StackInput, manifest *Manifest, secrets map[string]string) (bool, error) {
+	fallback := func(reason string) (bool, error) {
+		input.OnEvent(&StackEvent{HotswapFallbackEvent: &HotswapFallbackEvent{Reason: reason}})
+		return false, nil
+	}
+
+	last, err := s.Manifest()
+	if err != nil || last == nil || !last.Success || last.Command != "up" {
+		return fallback("The last deploy of the stage didn't succeed")
+	}
+	var state hotswapState
+	data, err := os.ReadFile(filepath.Join(s.pathHotswap(), "state.json"))
+	if err == nil {
+		err = json.Unmarshal(data, &state)
+	}
+	if err != nil || !state.Started.Equal(last.Started) {
+		return fallback("The last deploy of the stage wasn't made from here")
+	}
+	if last.ProgramHash != manifest.ProgramHash || last.EnvHash != manifest.EnvHash || !maps.Equal(last.Providers, manifest.Providers) {
+		return fallback("The infrastructure changed")
+	}
+	if last.CLIVersion != manifest.CLIVersion || last.PlatformVersion != manifest.PlatformVersion {
+		return fallback("SST was upgraded")
+	}
+	if state.SecretsHash != hashSecrets(secrets) {
+		return fallback("The secrets changed")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(s.pathHotswap(), "functions", "*.json"))
+	changed := []*hotswapFunction{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return false, err
+		}
+		var function hotswapFunction
+		if err := json.Unmarshal(data, &function); err != nil {
+			return false, err
+		}
+		inputs := []string{}
+		for rel := range function.Inputs {
+			inputs = append(inputs, filepath.Join(s.project.PathRoot(), filepath.FromSlash(rel)))
+		}
+		if maps.Equal(s.hashSources(inputs), function.Inputs) {
+			continue
+		}
+		if len(function.Install) > 0 {
+			return fallback(fmt.Sprintf("%s installs packages, it can't be hot-swapped", function.Name))
+		}
+		if len(function.CopyFiles) > 0 {
+			return fallback(fmt.Sprintf("%s copies files into its bundle, it can't be hot-swapped", function.Name))
+		}
+		if _, err := hotswapBuildOptions(function.Options); err != nil {
+			return fallback(fmt.Sprintf("%s can't be hot-swapped, %v", function.Name, err))
+		}
+		changed = append(changed, &function)
+	}
+
+	awsProvider, ok := s.project.Providers["aws"].(*provider.AwsProvider)
+	if len(changed) > 0 && !ok {
+		return fallback(`Hot-swapping needs the "aws" provider`)
+	}
+	for _, function := range changed {
+		started := time.Now()
+		if err := s.hotswapFunction(ctx, awsProvider, function); err != nil {
+			return false, util.NewReadableError(ErrHotswapFailed, fmt.Sprintf("Could not hot-swap %s: %v", function.Name, err))
+		}
+		input.OnEvent(&StackEvent{HotswapEvent: &HotswapEvent{
+			Name:     function.Name,
+			Function: function.FunctionName,
+			Duration: time.Since(started),
+		}})
+	}
+
+	input.OnEvent(&StackEvent{CompleteEvent: &CompleteEvent{
+		Links:     Links{},
+		Receivers: Receivers{},
+		Warps:     Warps{},
+		Hints:     map[string]string{},
+		Outputs:   map[string]interface{}{},
+		Errors:    []Error{},
+		Finished:  true,
+	}})
+	return true, nil
+}
+
+func (s *Stack) hotswapFunction(ctx context.Context, awsProvider *provider.AwsProvider, function *hotswapFunction) error {
+	options, err := hotswapBuildOptions(function.Options)
+	if err != nil {
+		return err
+	}
+	// the paths in the options are relative to the root, like the inputs
+	options.AbsWorkingDir = s.project.PathRoot()
+	if err := os.RemoveAll(function.Bundle); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(function.Bundle, 0755); err != nil {
+		return err
+	}
+	result := esbuild.Build(options)
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(formatMessages(result.Errors), "\n"))
+	}
+	if function.Sourcemap != "" {
+		if err := moveSourcemaps(function.Bundle, function.Sourcemap); err != nil {
+			return err
+		}
+	}
+
+	code, err := hotswapZip(function)
+	if err != nil {
+		return err
+	}
+	if len(code) > hotswapMaxZip {
+		return fmt.Errorf("the bundle is larger than 50 MB, run a full deploy")
+	}
+
+	config := awsProvider.Config().Copy()
+	if function.Region != "" {
+		config.Region = function.Region
+	}
+	client := lambda.NewFromConfig(config)
+	_, err = client.UpdateFunctionCode(ctx, &lambda.UpdateFunctionCodeInput{
+		FunctionName: aws.String(function.FunctionName),
+		ZipFile:      code,
+		Publish:      function.Publish,
+	})
+	if err != nil {
+		return err
+	}
+	err = lambda.NewFunctionUpdatedV2Waiter(client).Wait(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(function.FunctionName),
+	}, 5*time.Minute)
+	if err != nil {
+		return err
+	}
+
+	// the next hotswap compares with this build
+	inputs := []string{}
+	var metafile struct {
+		Inputs map[string]interface{} `json:"inputs"`
+	}
+	json.Unmarshal([]byte(result.Metafile), &metafile)
+	for file := range metafile.Inputs {
+		inputs = append(inputs, filepath.Join(s.project.PathRoot(), file))
+	}
+	function.Inputs = s.hashSources(inputs)
+	data, err := json.Marshal(function)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.pathHotswap(), "functions", function.Name+".json"), data, 0644)
+}
+
+// moveSourcemaps moves the sourcemaps out of the bundle, like the function
+// component does when they aren't deployed.
+func moveSourcemaps(bundle string, dir string) error {
+	return filepath.WalkDir(bundle, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".map") {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		return os.Rename(path, filepath.Join(dir, filepath.Base(path)))
+	})
+}
+
+// hotswapZip zips the bundle and the wrapper the same way the function
+// component does, with the dates zeroed.
+func hotswapZip(function *hotswapFunction) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	add := func(name string, data []byte) error {
+		header := &zip.FileHeader{
+			Name:     filepath.ToSlash(name),
+			Method:   zip.Deflate,
+			Modified: time.Unix(0, 0).UTC(),
+		}
+		header.SetMode(0777)
+		file, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = file.Write(data)
+		return err
+	}
+	err := filepath.WalkDir(function.Bundle, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(function.Bundle, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return add(rel, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if function.Wrapper != nil {
+		if err := add(function.Wrapper.Name, []byte(function.Wrapper.Content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// hotswapBuildOptions maps the esbuild options the function was built with
+// to the ones of the esbuild in the CLI. Options it doesn't know, like
+// plugins, can't be hot-swapped.
+func hotswapBuildOptions(raw map[string]json.RawMessage) (esbuild.BuildOptions, error) {
+	var input struct {
+		EntryPoints  []string          `json:"entryPoints"`
+		Platform     string            `json:"platform"`
+		External     []string          `json:"external"`
+		Loader       map[string]string `json:"loader"`
+		KeepNames    bool              `json:"keepNames"`
+		TreeShaking  *bool             `json:"treeShaking"`
+		Footer       map[string]string `json:"footer"`
+		Banner       map[string]string `json:"banner"`
+		Bundle       bool              `json:"bundle"`
+		Splitting    bool              `json:"splitting"`
+		OutExtension map[string]string `json:"outExtension"`
+		Format       string            `json:"format"`
+		Target       string            `json:"target"`
+		MainFields   []string          `json:"mainFields"`
+		Outfile      string            `json:"outfile"`
+		Outdir       string            `json:"outdir"`
+		Sourcemap    bool              `json:"sourcemap"`
+		Minify       bool              `json:"minify"`
+	}
+	known := map[string]bool{"logLevel": true, "metafile": true}
+	for _, field := range []string{"entryPoints", "platform", "external", "loader", "keepNames", "treeShaking", "footer", "banner", "bundle", "splitting", "outExtension", "format", "target", "mainFields", "outfile", "outdir", "sourcemap", "minify"} {
+		known[field] = true
+	}
+	for key, value := range raw {
+		if !known[key] && string(value) != "null" {
+			return esbuild.BuildOptions{}, fmt.Errorf("it's built with the %q esbuild option", key)
+		}
+	}
+	data, _ := json.Marshal(raw)
+	if err := json.Unmarshal(data, &input); err != nil {
+		return esbuild.BuildOptions{}, err
+	}
+
+	loaders := map[string]esbuild.Loader{
+		"js":      esbuild.LoaderJS,
+		"jsx":     esbuild.LoaderJSX,
+		"ts":      esbuild.LoaderTS,
+		"tsx":     esbuild.LoaderTSX,
+		"css":     esbuild.LoaderCSS,
+		"json":    esbuild.LoaderJSON,
+		"text":    esbuild.LoaderText,
+		"base64":  esbuild.LoaderBase64,
+		"file":    esbuild.LoaderFile,
+		"dataurl": esbuild.LoaderDataURL,
+		"binary":  esbuild.LoaderBinary,
+	}
+	loader := map[string]esbuild.Loader{}
+	for ext, name := range input.Loader {
+		mapped, ok := loaders[name]
+		if !ok {
+			return esbuild.BuildOptions{}, fmt.Errorf("it uses the %q loader", name)
+		}
+		loader[ext] = mapped
+	}
+
+	options := esbuild.BuildOptions{
+		EntryPoints:       input.EntryPoints,
+		Platform:          esbuild.PlatformNode,
+		External:          input.External,
+		Loader:            loader,
+		KeepNames:         input.KeepNames,
+		Footer:            input.Footer,
+		Banner:            input.Banner,
+		Bundle:            input.Bundle,
+		Splitting:         input.Splitting,
+		OutExtension:      input.OutExtension,
+		MainFields:        input.MainFields,
+		Outfile:           input.Outfile,
+		Outdir:            input.Outdir,
+		MinifyWhitespace:  input.Minify,
+		MinifySyntax:      input.Minify,
+		MinifyIdentifiers: input.Minify,
+		Metafile:          true,
+		Write:             true,
+		LogLevel:          esbuild.LogLevelSilent,
+		Target:            esbuild.ESNext,
+	}
+	if input.TreeShaking != nil {
+		options.TreeShaking = esbuild.TreeShakingFalse
+		if *input.TreeShaking {
+			options.TreeShaking = esbuild.TreeShakingTrue
+		}
+	}
+	if input.Sourcemap {
+		options.Sourcemap = esbuild.SourceMapLinked
+	}
+	switch input.Format {
+	case "esm":
+		options.Format = esbuild.FormatESModule
+	case "cjs":
+		options.Format = esbuild.FormatCommonJS
+	default:
+		return esbuild.BuildOptions{}, fmt.Errorf("it's built with the %q format", input.Format)
+	}
+	switch {
+	case input.Target == "" || input.Target == "esnext":
+	case strings.HasPrefix(input.Target, "node"):
+		options.Target = esbuild.DefaultTarget
+		options.Engines = []esbuild.Engine{{Name: esbuild.EngineNode, Version: strings.TrimPrefix(input.Target, "node")}}
+	default:
+		return esbuild.BuildOptions{}, fmt.Errorf("it's built for the %q target", input.Target)
+	}
+	if input.Platform != "" && input.Platform != "node" {
+		return esbuild.BuildOptions{}, fmt.Errorf("it's built for the %q platform", input.Platform)
+	}
+	return options, nil
+}
@@ -1,15 +1,18 @@
 package project
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/sst/ion/pkg/global"
 )
@@ -38,15 +41,69 @@ func (p *Project) NeedsInstall() bool {
 	return false
 }
 
+// InstallEvent is the progress of installing one provider.
+type InstallEvent struct {
+	Package string
+	// Status is installing, installed, or failed
+	Status string
+	Error  error
+}
+
 func (p *Project) Install() error {
+	return p.InstallWithEvents(func(*InstallEvent) {})
+}
+
+// InstallWithEvents is Install that reports the progress of every provider
+// to onEvent. The packages are installed with one bun install, then the
+// Pulumi plugins they need are downloaded concurrently so the first run
+// doesn't download them one by one. onEvent is never called concurrently.
+func (p *Project) InstallWithEvents(onEvent func(*InstallEvent)) error {
 	slog.Info("installing deps")
+	var mutex sync.Mutex
+	emit := func(event *InstallEvent) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		onEvent(event)
+	}
+
 	err := p.writePackageJson()
 	if err != nil {
 		return err
 	}
 
+	names := make([]string, 0, len(p.app.Providers))
+	for name := range p.app.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		emit(&InstallEvent{Package: getProviderPackage(name), Status: "installing"})
+	}
+
 	err = p.fetchDeps()
 	if err != nil {
+		for _, name := range names {
+			emit(&InstallEvent{Package: getProviderPackage(name), Status: "failed", Error: err})
+		}
+		return err
+	}
+
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, pkg string) {
+			defer wg.Done()
+			if err := p.installPlugin(pkg); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", pkg, err)
+				emit(&InstallEvent{Package: pkg, Status: "failed", Error: err})
+				return
+			}
+			emit(&InstallEvent{Package: pkg, Status: "installed"})
+		}(i, getProviderPackage(name))
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
 		return err
 	}
 
@@ -58,6 +115,55 @@ func (p *Project) Install() error {
 	return nil
 }
 
+// installPlugin downloads the Pulumi plugin an installed provider package
+// declares, unless it's already in the plugin cache.
+func (p *Project) installPlugin(pkg string) error {
+	data, err := os.ReadFile(filepath.Join(p.PathPlatformDir(), "node_modules", pkg, "package.json"))
+	if err != nil {
+		return err
+	}
+	var parsed struct {
+		Version string `json:"version"`
+		Pulumi  struct {
+			Resource bool   `json:"resource"`
+			Name     string `json:"name"`
+			Version  string `json:"version"`
+			Server   string `json:"server"`
+		} `json:"pulumi"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+	plugin := parsed.Pulumi
+	if !plugin.Resource {
+		return nil
+	}
+	if plugin.Name == "" {
+		plugin.Name = strings.TrimPrefix(filepath.Base(pkg), "pulumi-")
+	}
+	if plugin.Version == "" {
+		plugin.Version = parsed.Version
+	}
+	plugin.Version = strings.TrimPrefix(plugin.Version, "v")
+	cached := filepath.Join(global.ConfigDir(), "plugins", "resource-"+plugin.Name+"-v"+plugin.Version)
+	if _, err := os.Stat(cached); err == nil {
+		return nil
+	}
+
+	slog.Info("installing plugin", "name", plugin.Name, "version", plugin.Version)
+	args := []string{"plugin", "install", "resource", plugin.Name, plugin.Version}
+	if plugin.Server != "" {
+		args = append(args, "--server", plugin.Server)
+	}
+	cmd := p.command(context.Background(), "pulumi", args...)
+	cmd.Env = append(cmd.Env, "PULUMI_HOME="+global.ConfigDir())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.New("failed to install plugin " + plugin.Name + " " + string(output))
+	}
+	return nil
+}
+
 func (p *Project) writePackageJson() error {
 	slog.Info("writing package.json")
 	packageJsonPath := filepath.Join(p.PathPlatformDir(), "package.json")
@@ -153,8 +259,11 @@ func (p *Project) writeTypes() error {
 }
 
 func (p *Project) fetchDeps() error {
+	if restored, err := p.restoreOffline(); err != nil || restored {
+		return err
+	}
 	slog.Info("fetching deps")
-	cmd := exec.Command(global.BunPath(), "install")
+	cmd := p.command(context.Background(), global.BunPath(), "install")
 	cmd.Dir = p.PathPlatformDir()
 
 	output, err := cmd.CombinedOutput()
@@ -0,0 +1,200 @@
+package project
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/global"
+	"github.com/sst/ion/pkg/js"
+)
+
+// lockfiles that mark a project as using bun instead of node
+var bunLockfiles = []string{"bun.lockb", "bun.lock"}
+
+// nodeLockfiles take precedence over the bun ones, projects that have both
+// are still installed with a node package manager
+var nodeLockfiles = []string{"package-lock.json", "yarn.lock", "pnpm-lock.yaml"}
+
+// files that mark a project as using deno
+var denoFiles = []string{"deno.json", "deno.jsonc", "deno.lock"}
+
+// detectJSRuntime returns the runtime the config is run with, node, bun, or
+// deno. It's bun or deno for projects that only have their lockfile or
+// config.
+func detectJSRuntime(root string) string {
+	if runtime.GOOS == "windows" {
+		return "node"
+	}
+	for _, file := range nodeLockfiles {
+		if _, err := os.Stat(filepath.Join(root, file)); err == nil {
+			return "node"
+		}
+	}
+	for _, file := range bunLockfiles {
+		if _, err := os.Stat(filepath.Join(root, file)); err == nil {
+			return "bun"
+		}
+	}
+	for _, file := range denoFiles {
+		if _, err := os.Stat(filepath.Join(root, file)); err == nil {
+			return "deno"
+		}
+	}
+	return "node"
+}
+
+func validateJSRuntime(value string) error {
+	switch value {
+	case "node", "deno":
+		return nil
+	case "bun":
+		if runtime.GOOS == "windows" {
+			return fmt.Errorf(`The "bun" runtime is not supported on Windows`)
+		}
+		return nil
+	}
+	return fmt.Errorf(`Runtime %q is invalid, it must be "node", "bun", or "deno"`, value)
+}
+
+// bunPath prefers the bun on the Path of the project or the PATH over the one
+// installed by the CLI.
+func (p *Project) bunPath() string {
+	if path, err := exec.LookPath(p.lookPath("bun")); err == nil {
+		return path
+	}
+	return global.BunPath()
+}
+
+// evalCommand runs a bundled config file with the runtime of the project.
+func (p *Project) evalCommand(ctx context.Context, file string) *exec.Cmd {
+	switch detectJSRuntime(p.root) {
+	case "bun":
+		return p.command(ctx, p.bunPath(), file)
+	case "deno":
+		// run from the root so the deno.json of the project is picked up
+		cmd := p.command(ctx, "deno", "run", "--allow-all", "--quiet", file)
+		cmd.Dir = p.root
+		return cmd
+	}
+	cmd := p.command(ctx, p.nodePath(), "--no-warnings", file)
+	cmd.Env = append(cmd.Env, "NODE_OPTIONS="+nodeOptions())
+	return cmd
+}
+
+var ErrConfigFailed = fmt.Errorf("config failed to evaluate")
+
+// evalError turns what a config that threw printed into an error with paths
+// relative to the root, without the frames of the runtime itself.
+func (p *Project) evalError(err error) error {
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || len(bytes.TrimSpace(exit.Stderr)) == 0 {
+		return err
+	}
+	root := filepath.ToSlash(p.root) + "/"
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(exit.Stderr)), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Node.js v") {
+			continue
+		}
+		// frames of the runtime and of the generated entry of the bundle
+		if strings.HasPrefix(trimmed, "at ") && (strings.Contains(trimmed, "node:internal/") || strings.Contains(trimmed, "eval.ts:")) {
+			continue
+		}
+		if trimmed == "" && len(lines) > 0 && lines[len(lines)-1] == "" {
+			continue
+		}
+		line = strings.ReplaceAll(line, "file://"+root, "")
+		line = strings.ReplaceAll(filepath.ToSlash(line), root, "")
+		lines = append(lines, line)
+	}
+	return util.NewReadableError(ErrConfigFailed, strings.TrimSpace(strings.Join(lines, "\n")))
+}
+
+// nodeOptions are the NODE_OPTIONS the bundled config runs with. The source
+// maps inlined in the bundle are used so errors point at the files of the
+// project instead of the bundle.
+func nodeOptions() string {
+	options := js.WithoutPnP(os.Getenv("NODE_OPTIONS"))
+	if strings.Contains(options, "--enable-source-maps") {
+		return options
+	}
+	return strings.TrimSpace(options + " --enable-source-maps")
+}
+
+// evalPlugins are the plugins for building the config for the runtime of
+// the project.
+func (p *Project) evalPlugins() []esbuild.Plugin {
+	if detectJSRuntime(p.root) == "deno" {
+		return append([]esbuild.Plugin{js.DenoPlugin(p.root, filepath.Join(p.PathPlatformDir(), "node_modules"))}, p.plugins...)
+	}
+	return append(p.tsconfigPlugins(), p.plugins...)
+}
+
+// programPlugins are the plugins for building the program. Under Yarn PnP
+// the platform's dependencies are resolved from its own node_modules. The
+// program is run with node even for deno apps.
+func (p *Project) programPlugins() []esbuild.Plugin {
+	plugins := []esbuild.Plugin{}
+	if p.app.JSRuntime == "deno" {
+		// only the app function is run with deno
+		plugins = append(plugins, js.DenoOnlyPlugin("can only be imported with Deno, the run function is run with Node. Import it dynamically in the app function instead."))
+	}
+	plugins = append(plugins, p.tsconfigPlugins()...)
+	if js.FindPnP(p.root) != "" {
+		plugins = append(plugins, js.NodeModulesPlugin(p.PathPlatformDir()))
+	}
+	return append(plugins, p.plugins...)
+}
+
+// tsconfigPlugins resolve the path aliases of the tsconfig of the project in
+// its own files. The platform is bundled with its own tsconfig.
+func (p *Project) tsconfigPlugins() []esbuild.Plugin {
+	tsconfig := p.PathTsconfig()
+	if tsconfig == "" {
+		return nil
+	}
+	plugin, err := js.TsconfigPlugin(tsconfig, p.pathSourceRoot(), p.PathWorkingDir())
+	if err != nil {
+		slog.Warn("could not read tsconfig", "path", tsconfig, "err", err)
+		return nil
+	}
+	return []esbuild.Plugin{plugin}
+}
+
+// useJSRuntime puts a node executable that runs bun first on the PATH of
+// the program when the app runs with bun, the pulumi language host runs
+// whichever node it finds there. Only the program's env is changed, the
+// functions in dev keep running with node. The language host can't run
+// under deno, so with deno the program still runs with node.
+func (p *Project) useJSRuntime(env map[string]string) error {
+	if p.app.JSRuntime != "bun" {
+		return nil
+	}
+	binDir := filepath.Join(p.PathWorkingDir(), "bun", "bin")
+	err := os.MkdirAll(binDir, 0755)
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf("#!/bin/sh\nexec %q \"$@\"\n", p.bunPath())
+	err = os.WriteFile(filepath.Join(binDir, "node"), []byte(script), 0755)
+	if err != nil {
+		return err
+	}
+	path := env["PATH"]
+	if path == "" {
+		path = os.Getenv("PATH")
+	}
+	env["PATH"] = binDir + string(os.PathListSeparator) + path
+	return nil
+}
@@ -0,0 +1,37 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+)
+
+func TestDenoProgramImports(t *testing.T) {
+	root := t.TempDir()
+	p := &Project{root: root, app: &App{JSRuntime: "deno"}}
+	build := func(code string) esbuild.BuildResult {
+		path := filepath.Join(root, "sst.config.ts")
+		if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return esbuild.Build(esbuild.BuildOptions{
+			EntryPoints: []string{path},
+			Bundle:      true,
+			Format:      esbuild.FormatESModule,
+			Write:       false,
+			Plugins:     p.programPlugins(),
+		})
+	}
+
+	result := build(`import { z } from "npm:zod"; console.log(z)`)
+	if len(result.Errors) == 0 {
+		t.Errorf("static npm: import was bundled for node")
+	}
+
+	result = build(`export default { async app() { const { z } = await import("npm:zod"); return z } }`)
+	if len(result.Errors) > 0 {
+		t.Errorf("dynamic npm: import failed: %v", result.Errors[0].Text)
+	}
+}
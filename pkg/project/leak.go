@@ -0,0 +1,92 @@
+package project
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type SecretLeak struct {
+	Location string
+	Path     string
+	Rule     string
+}
+
+// SecretLeakEvent is a warning that plaintext secrets were found in places
+// that end up printed or committed.
+type SecretLeakEvent struct {
+	Leaks []SecretLeak
+}
+
+var secretPatterns = []struct {
+	rule  string
+	regex *regexp.Regexp
+}{
+	{"aws-access-key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"github-token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[baprs]-[A-Za-z0-9-]{10,}\b`)},
+	{"stripe-key", regexp.MustCompile(`\b[sr]k_live_[A-Za-z0-9]{24,}\b`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+}
+
+// secrets shorter than this are too likely to match by accident
+const minLeakLength = 8
+
+type leakScanner struct {
+	secrets map[string]string
+	leaks   []SecretLeak
+}
+
+func (l *leakScanner) scanString(location, path, value string) {
+	for _, pattern := range secretPatterns {
+		if pattern.regex.MatchString(value) {
+			l.leaks = append(l.leaks, SecretLeak{Location: location, Path: path, Rule: pattern.rule})
+			return
+		}
+	}
+	for name, secret := range l.secrets {
+		if len(secret) >= minLeakLength && strings.Contains(value, secret) {
+			l.leaks = append(l.leaks, SecretLeak{Location: location, Path: path, Rule: "secret " + name})
+			return
+		}
+	}
+}
+
+func (l *leakScanner) scanValue(location, path string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		l.scanString(location, path, v)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			next := key
+			if path != "" {
+				next = path + "." + key
+			}
+			l.scanValue(location, next, v[key])
+		}
+	case []interface{}:
+		for i, item := range v {
+			l.scanValue(location, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	}
+}
+
+// scanLeaks looks for plaintext secrets in the stack outputs and the
+// generated types file. Values are matched against known secret formats
+// and against the secrets set for the stage.
+func scanLeaks(secrets map[string]string, outputs map[string]interface{}, types string) []SecretLeak {
+	scanner := &leakScanner{secrets: secrets, leaks: []SecretLeak{}}
+	scanner.scanValue("outputs", "", outputs)
+	for i, line := range strings.Split(types, "\n") {
+		scanner.scanString("types.generated.ts", fmt.Sprintf("line %d", i+1), line)
+	}
+	return scanner.leaks
+}
@@ -0,0 +1,157 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/pkg/js"
+)
+
+type LintFinding struct {
+	Rule     string
+	Message  string
+	Location string
+}
+
+// LintEvent is a warning about the config that doesn't stop it from
+// running, like a provider that's never used.
+type LintEvent struct {
+	Findings []LintFinding
+}
+
+// the name a component is created with, new sst.aws.Function("Name", ...)
+var componentNameRegex = regexp.MustCompile("new\\s+[A-Za-z_$][\\w$.]*\\s*\\(\\s*[\"'`]([^\"'`$]+)[\"'`]")
+
+// provider args that hold credentials
+var sensitiveArgRegex = regexp.MustCompile(`(?i)(secret|token|password|apikey|api_key|accesskey|privatekey)`)
+
+// userFiles are the files of the program that belong to the project, not
+// to its dependencies or the platform.
+func (p *Project) userFiles(files []string) []string {
+	result := []string{}
+	for _, file := range files {
+		rel, err := filepath.Rel(p.root, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.Contains(rel, "node_modules/") || strings.HasPrefix(rel, ".sst/") {
+			continue
+		}
+		result = append(result, file)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// lint checks the evaluated app and the files of the program.
+func (p *Project) lint(files []string) []LintFinding {
+	findings := []LintFinding{}
+	contents := map[string]string{}
+	files = p.userFiles(files)
+	for _, file := range files {
+		if data, err := os.ReadFile(file); err == nil {
+			contents[file] = string(data)
+		}
+	}
+	location := func(file string, offset int) string {
+		rel, _ := filepath.Rel(p.root, file)
+		return fmt.Sprintf("%s:%d", filepath.ToSlash(rel), strings.Count(contents[file][:offset], "\n")+1)
+	}
+
+	names := make([]string, 0, len(p.app.Providers))
+	for name := range p.app.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name != p.app.Home {
+			used := regexp.MustCompile(`\b` + regexp.QuoteMeta(cleanProviderName(name)) + `\.`)
+			found := false
+			for _, file := range files {
+				if used.MatchString(contents[file]) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				findings = append(findings, LintFinding{
+					Rule:    "unused-provider",
+					Message: fmt.Sprintf("The %q provider is configured but never used", name),
+				})
+			}
+		}
+
+		args, _ := p.app.Providers[name].(map[string]interface{})
+		if name == "aws" && args["region"] == nil && os.Getenv("AWS_REGION") == "" && os.Getenv("AWS_DEFAULT_REGION") == "" {
+			findings = append(findings, LintFinding{
+				Rule:    "missing-region",
+				Message: `The "aws" provider has no region, it depends on the profile or environment of whoever deploys`,
+			})
+		}
+		keys := make([]string, 0, len(args))
+		for key := range args {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, ok := args[key].(string)
+			if ok && value != "" && sensitiveArgRegex.MatchString(key) && !strings.HasPrefix(value, secretRefPrefix) {
+				findings = append(findings, LintFinding{
+					Rule:    "hardcoded-secret",
+					Message: fmt.Sprintf("The %q arg of the %q provider is hardcoded, use $secret instead", key, name),
+				})
+			}
+		}
+	}
+
+	seen := map[string]string{}
+	for _, file := range files {
+		content := contents[file]
+		for _, match := range componentNameRegex.FindAllStringSubmatchIndex(content, -1) {
+			name := content[match[2]:match[3]]
+			at := location(file, match[0])
+			if first, ok := seen[name]; ok {
+				findings = append(findings, LintFinding{
+					Rule:     "duplicate-name",
+					Message:  fmt.Sprintf("%q is also the name of a component at %s", name, first),
+					Location: at,
+				})
+				continue
+			}
+			seen[name] = at
+		}
+		for _, pattern := range secretPatterns {
+			for _, match := range pattern.regex.FindAllStringIndex(content, -1) {
+				findings = append(findings, LintFinding{
+					Rule:     "hardcoded-secret",
+					Message:  fmt.Sprintf("Possible plaintext %s, use $secret or sst secret instead", pattern.rule),
+					Location: location(file, match[0]),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// Lint bundles the config and checks it without running it.
+func (s *Stack) Lint() ([]LintFinding, error) {
+	options, _, err := s.programOptions(&StackInput{Command: "up"})
+	if err != nil {
+		return nil, err
+	}
+	buildResult, err := js.Build(options)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := newProgram(buildResult)
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(prog.main)
+	return s.project.lint(prog.files), nil
+}
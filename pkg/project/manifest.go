@@ -0,0 +1,151 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// Manifest records everything that went into a run so the exact deploy can
+// be reproduced or audited later. It only contains digests, never values.
+type Manifest struct {
+	App             string            `json:"app"`
+	Stage           string            `json:"stage"`
+	Command         string            `json:"command"`
+	Started         time.Time         `json:"started"`
+	Finished        time.Time         `json:"finished"`
+	Success         bool              `json:"success"`
+	CLIVersion      string            `json:"cliVersion"`
+	PlatformVersion string            `json:"platformVersion"`
+	PulumiVersion   string            `json:"pulumiVersion"`
+	RunnerImage     string            `json:"runnerImage,omitempty"`
+	GitCommit       string            `json:"gitCommit,omitempty"`
+	ProgramHash     string            `json:"programHash"`
+	Sources         map[string]string `json:"sources"`
+	Providers       map[string]string `json:"providers"`
+	EnvKeys         []string          `json:"envKeys"`
+	EnvHash         string            `json:"envHash"`
+	Artifacts       map[string]string `json:"artifacts"`
+}
+
+// outputs that hold a digest of the artifact a resource was deployed from
+var artifactDigestKeys = []string{"sourceCodeHash", "imageDigest", "etag"}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashEnv hashes the environment handed to the program. Secrets, the
+// secrets file, the CLI state, and the passphrase are left out, they are
+// versioned separately.
+func hashEnv(env map[string]string) ([]string, string) {
+	keys := []string{}
+	for key := range env {
+		if strings.HasPrefix(key, "SST_SECRET_") || key == "SST_SECRETS_FILE" || key == "SST_SECRETS_KEY" || key == "SST_CLI" || key == "PULUMI_CONFIG_PASSPHRASE" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key + "=" + env[key] + "\n"))
+	}
+	return keys, hex.EncodeToString(hash.Sum(nil))
+}
+
+func (s *Stack) hashSources(files []string) map[string]string {
+	result := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(s.project.PathRoot(), file)
+		if err != nil {
+			rel = file
+		}
+		result[filepath.ToSlash(rel)] = digest(data)
+	}
+	return result
+}
+
+func (s *Stack) providerVersions() map[string]string {
+	result := map[string]string{}
+	for name := range s.project.app.Providers {
+		pkg := getProviderPackage(name)
+		parsed, err := getPackageJson(s.project, pkg)
+		if err != nil {
+			continue
+		}
+		result[pkg] = parsed.Version
+	}
+	return result
+}
+
+func artifactDigests(resources []apitype.ResourceV3) map[string]string {
+	result := map[string]string{}
+	for _, resource := range resources {
+		for _, key := range artifactDigestKeys {
+			value, ok := resource.Outputs[key].(string)
+			if ok && value != "" {
+				result[string(resource.URN)] = value
+				break
+			}
+		}
+	}
+	return result
+}
+
+// git runs a git command in the project root and returns its trimmed
+// output, or an empty string if it fails.
+func (s *Stack) git(args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = s.project.PathRoot()
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// repository returns the origin remote of the project as host/owner/repo,
+// or an empty string if there is none.
+func (s *Stack) repository() string {
+	return provider.NormalizeRepository(s.git("config", "--get", "remote.origin.url"))
+}
+
+func (s *Stack) platformVersion() string {
+	contents, err := os.ReadFile(filepath.Join(s.project.PathPlatformDir(), "version"))
+	if err != nil {
+		return ""
+	}
+	return string(contents)
+}
+
+func (s *Stack) putManifest(manifest *Manifest) {
+	err := provider.PutManifest(s.project.home, s.project.app.Name, s.project.app.Stage, manifest)
+	if err != nil {
+		slog.Error("failed to write manifest", "err", err)
+	}
+}
+
+// Manifest returns the manifest of the last run of the stage.
+func (s *Stack) Manifest() (*Manifest, error) {
+	var result *Manifest
+	err := provider.GetManifest(s.project.home, s.project.app.Name, s.project.app.Stage, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
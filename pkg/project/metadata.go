@@ -0,0 +1,55 @@
+package project
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+type Metadata struct {
+	Links     Links             `json:"links"`
+	Hints     map[string]string `json:"hints"`
+	Receivers Receivers         `json:"receivers"`
+	Warps     Warps             `json:"warps"`
+	Updated   time.Time         `json:"updated"`
+}
+
+var metadataMutex sync.Mutex
+
+// Metadata returns the dev metadata for the stage. It does not require the
+// stage to be locked.
+func (s *Stack) Metadata() (*Metadata, error) {
+	result := &Metadata{
+		Links:     Links{},
+		Hints:     map[string]string{},
+		Receivers: Receivers{},
+		Warps:     Warps{},
+	}
+	err := provider.GetMetadata(s.project.home, s.project.app.Name, s.project.app.Stage, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateMetadata applies fn to the current metadata and writes it back
+// without taking the deploy lock or touching the checkpoint. Writes that are
+// older than what is already stored are dropped.
+func (s *Stack) UpdateMetadata(updated time.Time, fn func(metadata *Metadata)) error {
+	metadataMutex.Lock()
+	defer metadataMutex.Unlock()
+
+	existing, err := s.Metadata()
+	if err != nil {
+		return err
+	}
+	if existing.Updated.After(updated) {
+		slog.Info("skipping stale metadata update", "existing", existing.Updated, "updated", updated)
+		return nil
+	}
+	fn(existing)
+	existing.Updated = updated
+	return provider.PutMetadata(s.project.home, s.project.app.Name, s.project.app.Stage, existing)
+}
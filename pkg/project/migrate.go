@@ -0,0 +1,149 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// layoutVersion is the version of the .sst layout this CLI writes. Bump it
+// and add a migration when files in the working directory move.
+const layoutVersion = 1
+
+type layoutMigration struct {
+	// version the layout is at after the migration runs
+	version     int
+	description string
+	run         func(dir string) error
+}
+
+// layoutMigrations upgrade the working directory in place, in order.
+var layoutMigrations = []layoutMigration{}
+
+// layoutSkip are regenerated on every run so they aren't backed up.
+var layoutSkip = map[string]bool{
+	"backup":    true,
+	"platform":  true,
+	"artifacts": true,
+	".pulumi":   true,
+}
+
+var ErrLayoutNewer = fmt.Errorf("working directory created by a newer version")
+
+type layout struct {
+	Version int `json:"version"`
+}
+
+func readLayoutVersion(dir string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "layout.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var result layout
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0, err
+	}
+	return result.Version, nil
+}
+
+func writeLayoutVersion(dir string, version int) error {
+	data, err := json.Marshal(layout{Version: version})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "layout.json"), data, 0644)
+}
+
+// migrateWorkingDir upgrades a working directory written by an older
+// version of the CLI. It's backed up to backup/v<version> first so the
+// local caches can be recovered if a migration fails.
+func migrateWorkingDir(dir string) error {
+	current, err := readLayoutVersion(dir)
+	if err != nil {
+		return err
+	}
+	if current > layoutVersion {
+		return ErrLayoutNewer
+	}
+	if current == layoutVersion {
+		return nil
+	}
+
+	pending := []layoutMigration{}
+	for _, migration := range layoutMigrations {
+		if migration.version > current {
+			pending = append(pending, migration)
+		}
+	}
+	if len(pending) > 0 {
+		backup := filepath.Join(dir, "backup", fmt.Sprintf("v%d", current))
+		slog.Info("backing up working directory", "from", current, "to", backup)
+		if err := backupWorkingDir(dir, backup); err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			slog.Info("migrating working directory", "version", migration.version, "description", migration.description)
+			if err := migration.run(dir); err != nil {
+				return fmt.Errorf("migrating to layout v%d failed, a backup is in %s: %w", migration.version, backup, err)
+			}
+			if err := writeLayoutVersion(dir, migration.version); err != nil {
+				return err
+			}
+		}
+	}
+	return writeLayoutVersion(dir, layoutVersion)
+}
+
+func backupWorkingDir(dir string, backup string) error {
+	if err := os.RemoveAll(backup); err != nil {
+		return err
+	}
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if entry.IsDir() && layoutSkip[rel] {
+			return filepath.SkipDir
+		}
+		target := filepath.Join(backup, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target)
+	})
+}
+
+func copyFile(from string, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	dest, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+	_, err = io.Copy(dest, source)
+	return err
+}
@@ -0,0 +1,45 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateWorkingDir(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "sst.log"), []byte("log"), 0644)
+	os.MkdirAll(filepath.Join(dir, "platform"), 0755)
+
+	original := layoutMigrations
+	defer func() { layoutMigrations = original }()
+	layoutMigrations = []layoutMigration{{
+		version:     1,
+		description: "move logs",
+		run: func(dir string) error {
+			return os.Rename(filepath.Join(dir, "sst.log"), filepath.Join(dir, "output.log"))
+		},
+	}}
+
+	if err := migrateWorkingDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "output.log")); err != nil {
+		t.Fatal("migration did not run")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "backup", "v0", "sst.log")); err != nil {
+		t.Fatal("backup missing")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "backup", "v0", "platform")); err == nil {
+		t.Fatal("platform should not be backed up")
+	}
+	version, _ := readLayoutVersion(dir)
+	if version != layoutVersion {
+		t.Fatalf("expected version %d, got %d", layoutVersion, version)
+	}
+
+	writeLayoutVersion(dir, layoutVersion+1)
+	if err := migrateWorkingDir(dir); err != ErrLayoutNewer {
+		t.Fatalf("expected ErrLayoutNewer, got %v", err)
+	}
+}
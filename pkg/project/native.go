@@ -0,0 +1,69 @@
+package project
+
+import (
+	"context"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/sst/ion/internal/util"
+)
+
+// AppInput is passed to the app function of a Definition, like the input
+// to app() in sst.config.ts.
+type AppInput struct {
+	Stage string
+}
+
+// Ctx is passed to the run function of an app defined in Go. Resources are
+// registered with the embedded Pulumi context.
+type Ctx struct {
+	*pulumi.Context
+	App *App
+	Dev bool
+	// Secrets are the secrets set for the stage
+	Secrets map[string]string
+}
+
+// Definition is an app written in Go instead of an sst.config.ts.
+type Definition struct {
+	app func(input AppInput) App
+	run func(ctx *Ctx) error
+}
+
+// Define creates an app in Go. The app function returns the same settings
+// as app() in sst.config.ts and run registers the resources with the Pulumi
+// Go SDK. It runs in-process so neither node nor esbuild are needed.
+//
+//	def := project.Define(
+//		func(input project.AppInput) project.App {
+//			return project.App{Name: "my-app", Home: "aws"}
+//		},
+//		func(ctx *project.Ctx) error {
+//			_, err := s3.NewBucket(ctx.Context, "MyBucket", nil)
+//			return err
+//		},
+//	)
+func Define(app func(input AppInput) App, run func(ctx *Ctx) error) *Definition {
+	return &Definition{
+		app: app,
+		run: run,
+	}
+}
+
+// NewFromDefinition loads a project defined in Go. The root of the project
+// is the directory of input.Config, which is the Go file with the
+// definition.
+func NewFromDefinition(ctx context.Context, input *ProjectConfig, def *Definition) (*Project, error) {
+	proj, err := newProject(input)
+	if err != nil {
+		return nil, err
+	}
+	proj.definition = def
+	app := def.app(AppInput{Stage: input.Stage})
+	if app.Runner != nil {
+		return nil, util.NewReadableError(nil, `Apps defined in Go run in-process so they can't use a "runner"`)
+	}
+	if err := proj.configure(&app, input.Stage); err != nil {
+		return nil, err
+	}
+	return proj, nil
+}
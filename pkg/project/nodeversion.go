@@ -0,0 +1,149 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Node picks the node that runs the program and the version it needs.
+type Node struct {
+	// Path is the node executable, relative to the root or absolute
+	Path string `json:"path,omitempty"`
+	// Version is the minimum version, like 18 or 20.11.0
+	Version string `json:"version,omitempty"`
+}
+
+// NodeVersionEvent is sent when the node that would run the program is
+// missing or older than the version the config asks for.
+type NodeVersionEvent struct {
+	Path     string
+	Version  string
+	Required string
+	Error    string
+}
+
+var ErrNodeVersion = fmt.Errorf("node version check failed")
+
+func (n *Node) validate() error {
+	if n.Version == "" {
+		return nil
+	}
+	if _, err := parseNodeVersion(n.Version); err != nil {
+		return fmt.Errorf("Node version %q is invalid, it must look like 18 or 20.11.0", n.Version)
+	}
+	return nil
+}
+
+func parseNodeVersion(value string) ([3]int, error) {
+	var result [3]int
+	value = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(value), ">="), "v")
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 {
+		return result, fmt.Errorf("invalid version %q", value)
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return result, fmt.Errorf("invalid version %q", value)
+		}
+		result[i] = number
+	}
+	return result, nil
+}
+
+func compareNodeVersions(a [3]int, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// nodePath returns the node executable from the config, or node from the
+// PATH.
+func (p *Project) nodePath() string {
+	if p.app == nil || p.app.Node == nil || p.app.Node.Path == "" {
+		return "node"
+	}
+	if filepath.IsAbs(p.app.Node.Path) {
+		return p.app.Node.Path
+	}
+	return filepath.Join(p.root, p.app.Node.Path)
+}
+
+// useNode puts the node from the config first on the PATH of the program,
+// the pulumi language host runs whichever node it finds there.
+func (p *Project) useNode(env map[string]string) {
+	if p.nodePath() == "node" {
+		return
+	}
+	path := env["PATH"]
+	if path == "" {
+		path = os.Getenv("PATH")
+	}
+	env["PATH"] = filepath.Dir(p.nodePath()) + string(os.PathListSeparator) + path
+}
+
+// checkNode makes sure the node that runs the program exists and is at
+// least the version from the config, before the program is started.
+func (p *Project) checkNode(ctx context.Context, env map[string]string) *NodeVersionEvent {
+	required := ""
+	if p.app.Node != nil {
+		required = p.app.Node.Version
+	}
+	event := &NodeVersionEvent{Path: p.nodePath(), Required: required}
+
+	path := event.Path
+	if path == "node" {
+		// resolved from the PATH the program runs with
+		for _, dir := range filepath.SplitList(env["PATH"]) {
+			candidate := filepath.Join(dir, "node")
+			if resolved, err := exec.LookPath(candidate); err == nil {
+				path = resolved
+				break
+			}
+		}
+		if path == "node" {
+			resolved, err := exec.LookPath("node")
+			if err != nil {
+				event.Error = "Node was not found on the PATH, install it or set the path of node in the config"
+				return event
+			}
+			path = resolved
+		}
+		event.Path = path
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		event.Error = fmt.Sprintf("Could not run %s: %v", path, err)
+		return event
+	}
+	event.Version = strings.TrimSpace(string(output))
+	if required == "" {
+		return nil
+	}
+	actual, err := parseNodeVersion(event.Version)
+	if err != nil {
+		event.Error = fmt.Sprintf("Could not read the version of %s: %s", path, event.Version)
+		return event
+	}
+	minimum, _ := parseNodeVersion(required)
+	if compareNodeVersions(actual, minimum) < 0 {
+		event.Error = fmt.Sprintf("Node %s is older than the required %s", event.Version, required)
+		return event
+	}
+	return nil
+}
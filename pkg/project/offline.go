@@ -0,0 +1,141 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sst/ion/pkg/global"
+)
+
+// pathOfflineCache is where sst bundle-deps keeps the installed platform so
+// it can be restored without the network. It's keyed by the CLI version and
+// the providers, so projects using the same ones share it. It only holds
+// relative paths and can be copied to another machine with the rest of the
+// config directory, which also has the plugins.
+func (p *Project) pathOfflineCache() string {
+	names := make([]string, 0, len(p.app.Providers))
+	for name := range p.app.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		version := "latest"
+		if args, ok := p.app.Providers[name].(map[string]interface{}); ok {
+			if value, ok := args["version"].(string); ok && value != "" {
+				version = value
+			}
+		}
+		hash.Write([]byte(getProviderPackage(name) + "@" + version + "\n"))
+	}
+	return filepath.Join(global.ConfigDir(), "offline", p.version, hex.EncodeToString(hash.Sum(nil))[:16])
+}
+
+// BundleDeps installs the providers, downloads the Pulumi plugins of every
+// package of the platform, and saves the platform to the offline cache.
+// Installing the project again, or on another machine with the config
+// directory copied over, then works without the network.
+func (p *Project) BundleDeps(onEvent func(*InstallEvent)) error {
+	if err := p.InstallWithEvents(onEvent); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.PathPlatformDir(), "package.json"))
+	if err != nil {
+		return err
+	}
+	var parsed PackageJson
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+	packages := make([]string, 0, len(parsed.Dependencies))
+	for pkg := range parsed.Dependencies {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	errs := make([]error, len(packages))
+	var wg sync.WaitGroup
+	for i, pkg := range packages {
+		wg.Add(1)
+		go func(i int, pkg string) {
+			defer wg.Done()
+			if err := p.installPlugin(pkg); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", pkg, err)
+			}
+		}(i, pkg)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	target := p.pathOfflineCache()
+	tmp := target + fmt.Sprintf(".tmp-%d", time.Now().UnixNano())
+	defer os.RemoveAll(tmp)
+	if err := copyDir(p.PathPlatformDir(), tmp); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	slog.Info("saved offline cache", "path", target)
+	return os.Rename(tmp, target)
+}
+
+// restoreOffline copies the packages from the offline cache into the
+// platform directory. It returns false if there's no cache for the
+// providers of the project.
+func (p *Project) restoreOffline() (bool, error) {
+	source := filepath.Join(p.pathOfflineCache(), "node_modules")
+	if _, err := os.Stat(source); err != nil {
+		return false, nil
+	}
+	slog.Info("restoring packages from the offline cache", "path", source)
+	target := filepath.Join(p.PathPlatformDir(), "node_modules")
+	if err := os.RemoveAll(target); err != nil {
+		return false, err
+	}
+	if err := copyDir(source, target); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// copyDir copies a directory, keeping symlinks as they are.
+func copyDir(source string, target string) error {
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(dest, 0755)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, dest)
+		case info.Mode().IsRegular():
+			if err := copyFile(path, dest); err != nil {
+				return err
+			}
+			return os.Chmod(dest, info.Mode().Perm())
+		}
+		return nil
+	})
+}
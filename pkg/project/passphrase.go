@@ -0,0 +1,66 @@
+package project
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	"github.com/sst/ion/pkg/global"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// RotatePassphrase replaces the passphrase of the stage. The Pulumi state is
+// re-encrypted with the new passphrase along with the secrets and links
+// stored in the home provider.
+func (s *Stack) RotatePassphrase(ctx context.Context) error {
+	err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer s.Unlock()
+
+	_, err = s.PullState()
+	if err != nil {
+		return err
+	}
+
+	return provider.RotatePassphrase(s.project.home, s.project.app.Name, s.project.app.Stage, provider.RotateInput{
+		Reencrypt: func(old, next string) error {
+			env, err := s.project.home.Env()
+			if err != nil {
+				return err
+			}
+			env["PULUMI_CONFIG_PASSPHRASE"] = old
+
+			ws, err := auto.NewLocalWorkspace(ctx,
+				auto.WorkDir(s.project.PathWorkingDir()),
+				auto.PulumiHome(global.ConfigDir()),
+				auto.Project(workspace.Project{
+					Name:    tokens.PackageName(s.project.app.Name),
+					Runtime: workspace.NewProjectRuntimeInfo("nodejs", nil),
+					Backend: &workspace.ProjectBackend{
+						URL: fmt.Sprintf("file://%v", s.project.PathWorkingDir()),
+					},
+				}),
+				auto.EnvVars(env),
+			)
+			if err != nil {
+				return err
+			}
+
+			stack, err := auto.SelectStack(ctx, s.project.app.Stage, ws)
+			if err != nil {
+				return err
+			}
+
+			slog.Info("re-encrypting state")
+			return stack.ChangeSecretsProvider(ctx, "passphrase", &auto.ChangeSecretsProviderOptions{
+				NewPassphrase: &next,
+			})
+		},
+		Commit: s.PushState,
+	})
+}
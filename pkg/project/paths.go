@@ -0,0 +1,119 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/sst/ion/internal/fs"
+)
+
+// Paths moves the files the CLI writes out of the .sst directory. Each one
+// can also be set with an environment variable, which takes precedence.
+type Paths struct {
+	// Temp holds temporary files, SST_TEMP_DIR
+	Temp string `json:"temp"`
+	// Build holds function bundles and zips, SST_BUILD_DIR
+	Build string `json:"build"`
+	// Output holds the logs, SST_OUTPUT_DIR
+	Output string `json:"output"`
+}
+
+// resolveDir returns the directory set in env or in the config, relative to
+// the project root, or an empty string if neither is set.
+func (p *Project) resolveDir(env string, configured func(paths Paths) string) string {
+	dir := os.Getenv(env)
+	if dir == "" && p.app != nil {
+		dir = configured(p.app.Paths)
+	}
+	if dir == "" {
+		return ""
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(p.root, dir)
+	}
+	return dir
+}
+
+// PathTempDir returns where temporary files are written. It's the system
+// temp directory unless overridden.
+func (p *Project) PathTempDir() string {
+	dir := p.resolveDir("SST_TEMP_DIR", func(paths Paths) string { return paths.Temp })
+	if dir == "" {
+		return os.TempDir()
+	}
+	return dir
+}
+
+func (p *Project) PathBuildDir() string {
+	dir := p.resolveDir("SST_BUILD_DIR", func(paths Paths) string { return paths.Build })
+	if dir == "" {
+		return p.PathWorkingDir()
+	}
+	return dir
+}
+
+func (p *Project) PathOutputDir() string {
+	dir := p.resolveDir("SST_OUTPUT_DIR", func(paths Paths) string { return paths.Output })
+	if dir == "" {
+		return p.PathWorkingDir()
+	}
+	return dir
+}
+
+// PathArtifacts returns where functions are built. It's removed on cleanup
+// so it is always a subdirectory of the build directory.
+func (p *Project) PathArtifacts() string {
+	return filepath.Join(p.PathBuildDir(), "artifacts")
+}
+
+func (p *Project) ensureDirs() error {
+	for _, dir := range []string{p.PathTempDir(), p.PathBuildDir(), p.PathOutputDir()} {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tempEnv points the temp directory of child processes at the configured
+// one, it's empty if the system default is used.
+func (p *Project) tempEnv() map[string]string {
+	dir := p.resolveDir("SST_TEMP_DIR", func(paths Paths) string { return paths.Temp })
+	if dir == "" {
+		return map[string]string{}
+	}
+	return map[string]string{
+		"TMPDIR": dir,
+		"TEMP":   dir,
+		"TMP":    dir,
+	}
+}
+
+// PathTsconfig returns the tsconfig whose path aliases the config is bundled
+// with, or an empty string if there is none. Monorepos often keep the
+// aliases in a tsconfig.base.json at the repository root, it isn't looked
+// for above that.
+func (p *Project) PathTsconfig() string {
+	root := p.pathSourceRoot()
+	for dir := p.root; ; dir = filepath.Dir(dir) {
+		for _, name := range []string{"tsconfig.json", "tsconfig.base.json"} {
+			if match := filepath.Join(dir, name); fs.Exists(match) {
+				return match
+			}
+		}
+		if dir == root || dir == filepath.Dir(dir) {
+			return ""
+		}
+	}
+}
+
+// pathSourceRoot is the root of the git repository the project is in, or
+// the root of the project if it isn't in one.
+func (p *Project) pathSourceRoot() string {
+	match, err := fs.FindUp(p.root, ".git")
+	if err != nil {
+		return p.root
+	}
+	return filepath.Dir(match)
+}
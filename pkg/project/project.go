@@ -3,19 +3,27 @@ package project
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"regexp"
+	goruntime "runtime"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/Masterminds/semver/v3"
+	esbuild "github.com/evanw/esbuild/pkg/api"
 	"github.com/sst/ion/internal/fs"
 	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/global"
 	"github.com/sst/ion/pkg/js"
+	"github.com/sst/ion/pkg/project/exporter"
 	"github.com/sst/ion/pkg/project/provider"
 )
 
@@ -25,12 +33,108 @@ type App struct {
 	Removal   string                 `json:"removal"`
 	Providers map[string]interface{} `json:"providers"`
 	Home      string                 `json:"home"`
+	Wait      []WaitCondition        `json:"wait"`
+	// Passphrase derives the stage passphrase from a key instead of storing it
+	Passphrase *Passphrase `json:"passphrase"`
+	// Exporters stream every stack event to an external system
+	Exporters []ExporterConfig `json:"exporters"`
+	// Tags are written to the home provider after every deploy
+	Tags map[string]string `json:"tags"`
+	// EnvAllowlist and EnvDenylist filter the host environment variables
+	// passed to the deployment, as glob patterns
+	EnvAllowlist []string `json:"envAllowlist"`
+	EnvDenylist  []string `json:"envDenylist"`
+	// Paths overrides where temp files, builds, and logs are written
+	Paths Paths `json:"paths"`
+	// RequiredSecrets must be set before the app can be deployed
+	RequiredSecrets []string `json:"requiredSecrets"`
+	// Runner runs deploys inside a container image
+	Runner *Runner `json:"runner"`
+	// LinkStore is where functions read their links from, bundle or ssm
+	LinkStore string `json:"linkStore"`
+	// Namespace separates the state and secrets of apps sharing a home
+	Namespace string `json:"namespace"`
+	// JSRuntime runs the config, node, bun, or deno
+	JSRuntime string `json:"jsRuntime"`
+	// BuildCache configures the cache of the config and function bundles
+	BuildCache *BuildCache `json:"buildCache,omitempty"`
+	// Build customizes how the run function of the config is bundled
+	Build *Build `json:"build,omitempty"`
+	// Proxy routes outbound traffic through an HTTP proxy
+	Proxy *Proxy `json:"proxy,omitempty"`
+	// Node is the node that runs the program and the version it needs
+	Node *Node `json:"node,omitempty"`
+	// Typecheck runs tsc over the config before every deploy
+	Typecheck bool `json:"typecheck,omitempty"`
+	// Stacks runs the files in the stacks directory after the config
+	Stacks bool `json:"stacks,omitempty"`
+	// BuildConcurrency is how many functions dev bundles at once
+	BuildConcurrency int `json:"buildConcurrency,omitempty"`
+	// Watch configures how dev watches the files for changes
+	Watch *Watch `json:"watch,omitempty"`
+	// BranchStage derives the stage from the git branch
+	BranchStage *BranchStage `json:"branchStage,omitempty"`
+	// ReservedStages can only be changed with --allow-reserved
+	ReservedStages []string `json:"reservedStages,omitempty"`
+	// Version is the constraint on the version of the CLI, like 0.0.300 or
+	// ^0.0.300
+	Version string `json:"version,omitempty"`
 	// Deprecated: Backend is now Home
 	Backend string `json:"backend"`
 	// Deprecated: RemovalPolicy is now Removal
 	RemovalPolicy string `json:"removalPolicy"`
 }
 
+type Passphrase struct {
+	KMS string `json:"kms"`
+	Age string `json:"age"`
+}
+
+// Build adds defines and injected files to the bundle of the config, next
+// to the ones sst uses.
+type Watch struct {
+	// Ignore are glob patterns of the files and directories not watched
+	Ignore []string `json:"ignore,omitempty"`
+	// Debounce is how long to wait for changes to settle, in milliseconds
+	Debounce int `json:"debounce,omitempty"`
+	// Poll checks the files periodically instead of using events
+	Poll bool `json:"poll,omitempty"`
+}
+
+type Build struct {
+	// Define replaces global identifiers with JS expressions
+	Define map[string]string `json:"define,omitempty"`
+	// Inject are files, relative to the root, imported into every module
+	Inject []string `json:"inject,omitempty"`
+	// Minify, KeepNames, and Footer tune the output of the bundle
+	Minify    bool   `json:"minify,omitempty"`
+	KeepNames bool   `json:"keepNames,omitempty"`
+	Footer    string `json:"footer,omitempty"`
+}
+
+// reservedDefines are set by sst and can't be replaced.
+var reservedDefines = []string{"$app", "$cli", "$dev"}
+
+func (b *Build) validate(root string) error {
+	for key := range b.Define {
+		if slices.Contains(reservedDefines, key) {
+			return fmt.Errorf("Define %q is reserved, it can't be replaced", key)
+		}
+	}
+	for i, file := range b.Inject {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(root, file)
+		}
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("Inject %q was not found", b.Inject[i])
+		}
+		b.Inject[i] = file
+	}
+	return nil
+}
+
+// Project is an app loaded from its config for one stage. Create it with
+// New, call LoadProviders before using the Stack, and Cleanup when done.
 type Project struct {
 	version   string
 	root      string
@@ -39,16 +143,51 @@ type Project struct {
 	home      provider.Home
 	Providers map[string]provider.Provider
 	env       map[string]string
+	exporters []exporter.Exporter
+	// secretConfig are the provider:path args that were resolved from secrets
+	secretConfig map[string]bool
+	// resolvedProviders are the args of the providers that reference
+	// secrets, with the secrets resolved. $app keeps the references.
+	resolvedProviders map[string]map[string]interface{}
+	// allowReserved lets the reserved stages be changed
+	allowReserved bool
+	plugins       []esbuild.Plugin
+	output        io.Writer
+	// path is put first on the PATH of the commands the project runs
+	path []string
+	// definition is set when the app is defined in Go
+	definition *Definition
+
+	Stack *Stack
+}
 
-	Stack *stack
+// ConfigFiles are the names the config can have, in order of precedence.
+var ConfigFiles = []string{"sst.config.ts", "sst.config.js", "sst.config.mjs", "sst.config.cjs"}
+
+// FindConfig returns the config in dir, or an empty string if there is none.
+func FindConfig(dir string) string {
+	for _, name := range ConfigFiles {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
 }
 
+// Discover finds the config in the working directory or its parents.
 func Discover() (string, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return "", err
 	}
-	cfgPath, err := fs.FindUp(cwd, "sst.config.ts")
+	return DiscoverFrom(cwd)
+}
+
+// DiscoverFrom finds the config in dir or its parents and creates the
+// working directory next to it.
+func DiscoverFrom(dir string) (string, error) {
+	cfgPath, err := fs.FindUpAny(dir, ConfigFiles...)
 	if err != nil {
 		return "", err
 	}
@@ -59,8 +198,22 @@ func Discover() (string, error) {
 	return cfgPath, nil
 }
 
+// ResolveWorkingDir returns the .sst directory next to the config, or the
+// one set with SST_WORKING_DIR. The config is compiled into it, so unlike
+// the Paths it can't be set in the config.
 func ResolveWorkingDir(cfgPath string) string {
-	return path.Join(filepath.Dir(cfgPath), ".sst")
+	return resolveWorkingDir(filepath.Dir(cfgPath))
+}
+
+func resolveWorkingDir(root string) string {
+	dir := os.Getenv("SST_WORKING_DIR")
+	if dir == "" {
+		return path.Join(root, ".sst")
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir
 }
 
 func ResolvePlatformDir(cfgPath string) string {
@@ -68,42 +221,42 @@ func ResolvePlatformDir(cfgPath string) string {
 }
 
 type ProjectConfig struct {
+	// Version of the CLI, or of the program embedding it
 	Version string
 	Stage   string
-	Config  string
+	// Config is the path to the sst.config.ts, or one of the other
+	// ConfigFiles
+	Config string
+	// Plugins are added to every build of the config, after the ones the
+	// project adds itself
+	Plugins []esbuild.Plugin
+	// Output receives what the config and the tools it runs print, it
+	// defaults to stdout
+	Output io.Writer
+	// Path is put first on the PATH of the commands the project runs, like
+	// the Path of a Toolchain. The PATH of the process is left alone, so
+	// pulumi itself is still found there.
+	Path []string
 }
 
 var ErrInvalidStageName = fmt.Errorf("invalid stage name")
+var ErrInvalidAppName = fmt.Errorf("invalid app name")
 var ErrV2Config = fmt.Errorf("sstv2 config detected")
 var StageRegex = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+var AppRegex = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
 
+// New loads the project by evaluating the app function of the config.
 func New(input *ProjectConfig) (*Project, error) {
-	if !StageRegex.MatchString(input.Stage) {
-		return nil, ErrInvalidStageName
-	}
-
-	rootPath := filepath.Dir(input.Config)
-
-	proj := &Project{
-		version: input.Version,
-		root:    rootPath,
-		config:  input.Config,
-	}
-	proj.Stack = &stack{
-		project: proj,
-	}
-	tmp := proj.PathWorkingDir()
+	return NewContext(context.Background(), input)
+}
 
-	_, err := os.Stat(tmp)
+// NewContext is New with a context that cancels evaluating the config.
+func NewContext(ctx context.Context, input *ProjectConfig) (*Project, error) {
+	proj, err := newProject(input)
 	if err != nil {
-		if !os.IsNotExist(err) {
-			return nil, err
-		}
-		err := os.Mkdir(tmp, 0755)
-		if err != nil {
-			return nil, err
-		}
+		return nil, err
 	}
+	tmp := proj.PathWorkingDir()
 
 	inputBytes, err := json.Marshal(map[string]string{
 		"stage": input.Stage,
@@ -113,20 +266,35 @@ func New(input *ProjectConfig) (*Project, error) {
 			Dir: tmp,
 			Banner: `
       function $config(input) { return input }
+      function $secret(name) { return "` + secretRefPrefix + `" + name }
       `,
 			Define: map[string]string{
 				"$input": string(inputBytes),
 			},
+			Plugins: proj.evalPlugins(),
 			Code: fmt.Sprintf(`
+import process from 'node:process';
 import mod from '%s';
+%s
 if (mod.stacks || mod.config) {
   console.log("~v2")
   process.exit(0)
 }
-console.log("~j" + JSON.stringify(mod.app({
+function merge(base, overlay) {
+  if (overlay === undefined) return base;
+  const isObject = (value) => value && typeof value === "object" && !Array.isArray(value);
+  if (!isObject(base) || !isObject(overlay)) return overlay;
+  const result = { ...base };
+  for (const [key, value] of Object.entries(overlay)) result[key] = merge(base[key], value);
+  return result;
+}
+const input = {
   stage: $input.stage || undefined,
-})))`,
-				input.Config),
+};
+console.log("~j" + JSON.stringify(merge(mod.app(input), overlay.app?.(input))))`,
+				input.Config,
+				overlayImport(proj.PathOverlay(input.Stage)),
+			),
 		},
 	)
 	if err != nil {
@@ -134,10 +302,10 @@ console.log("~j" + JSON.stringify(mod.app({
 	}
 
 	slog.Info("evaluating config")
-	output, err := exec.Command("node", "--no-warnings", buildResult.OutputFiles[0].Path).Output()
+	output, err := proj.evalCommand(ctx, buildResult.OutputFiles[0].Path).Output()
 	slog.Info("config evaluated")
 	if err != nil {
-		return nil, err
+		return nil, proj.evalError(err)
 	}
 	scanner := bufio.NewScanner(bytes.NewReader(output))
 	for scanner.Scan() {
@@ -146,81 +314,231 @@ console.log("~j" + JSON.stringify(mod.app({
 			return nil, ErrV2Config
 		}
 		if strings.HasPrefix(line, "~j") {
+			sources := []string{proj.PathConfig()}
+			if overlay := proj.PathOverlay(input.Stage); overlay != "" {
+				sources = append(sources, overlay)
+			}
+			if err := validateAppSchema([]byte(line[2:]), proj.PathRoot(), sources); err != nil {
+				return nil, err
+			}
 			var parsed App
 			err = json.Unmarshal([]byte(line[2:]), &parsed)
 			if err != nil {
 				return nil, err
 			}
-			proj.app = &parsed
-			proj.app.Stage = input.Stage
-
-			if proj.app.Providers == nil {
-				proj.app.Providers = map[string]interface{}{}
-			}
-
-			for name, args := range proj.app.Providers {
-				if argsBool, ok := args.(bool); ok && argsBool {
-					proj.app.Providers[name] = make(map[string]interface{})
-				}
-			}
-
-			if _, ok := proj.app.Providers[proj.app.Home]; !ok {
-				proj.app.Providers[proj.app.Home] = map[string]interface{}{}
+			if err := proj.configure(&parsed, input.Stage); err != nil {
+				return nil, err
 			}
+			continue
+		}
 
-			if proj.app.Name == "" {
-				return nil, fmt.Errorf("Project name is required")
-			}
+		fmt.Fprintln(proj.output, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 
-			if proj.app.Home == "" {
-				return nil, util.NewReadableError(nil, `You must specify a "home" provider in the project configuration file.`)
-			}
+	return proj, nil
+}
 
-			if proj.app.RemovalPolicy != "" {
-				return nil, util.NewReadableError(nil, `The "removalPolicy" has been renamed to "removal"`)
-			}
+// newProject creates the working directory of the project described by
+// input, the app is set by the caller.
+func newProject(input *ProjectConfig) (*Project, error) {
+	if err := validateExistingStage(input.Stage); err != nil {
+		return nil, err
+	}
 
-			if proj.app.Removal == "" {
-				proj.app.Removal = "retain"
-			}
+	rootPath := filepath.Dir(input.Config)
 
-			if proj.app.Removal != "remove" && proj.app.Removal != "retain" && proj.app.Removal != "retain-all" {
-				return nil, fmt.Errorf("Removal must be one of: remove, retain, retain-all")
-			}
-			continue
+	proj := &Project{
+		version: input.Version,
+		root:    rootPath,
+		config:  input.Config,
+		plugins: input.Plugins,
+		output:  input.Output,
+		path:    input.Path,
+	}
+	if proj.output == nil {
+		proj.output = os.Stdout
+	}
+	proj.Stack = &Stack{
+		project: proj,
+	}
+	tmp := proj.PathWorkingDir()
+	_, err := os.Stat(tmp)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		err := os.Mkdir(tmp, 0755)
+		if err != nil {
+			return nil, err
 		}
-
-		fmt.Println(line)
 	}
-	if err := scanner.Err(); err != nil {
+
+	err = migrateWorkingDir(tmp)
+	if err != nil {
 		return nil, err
 	}
-
 	return proj, nil
 }
 
-func (proj *Project) LoadProviders() error {
-	proj.Providers = map[string]provider.Provider{}
+// configure validates app and fills in its defaults.
+func (proj *Project) configure(app *App, stage string) error {
+	proj.app = app
+	proj.app.Stage = stage
+
+	if proj.app.Providers == nil {
+		proj.app.Providers = map[string]interface{}{}
+	}
+
 	for name, args := range proj.app.Providers {
-		var p provider.Provider
+		if err := validateProviderName(name); err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+		if argsBool, ok := args.(bool); ok && argsBool {
+			proj.app.Providers[name] = make(map[string]interface{})
+		}
+	}
+
+	if _, ok := proj.app.Providers[proj.app.Home]; !ok {
+		proj.app.Providers[proj.app.Home] = map[string]interface{}{}
+	}
+
+	if proj.app.Name == "" {
+		return fmt.Errorf("Project name is required")
+	}
+
+	if proj.app.Home == "" {
+		return util.NewReadableError(nil, `You must specify a "home" provider in the project configuration file.`)
+	}
+
+	if proj.app.RemovalPolicy != "" {
+		return util.NewReadableError(nil, `The "removalPolicy" has been renamed to "removal"`)
+	}
+
+	if proj.app.Removal == "" {
+		proj.app.Removal = "retain"
+	}
+
+	if proj.app.Removal != "remove" && proj.app.Removal != "retain" && proj.app.Removal != "retain-all" {
+		return fmt.Errorf("Removal must be one of: remove, retain, retain-all")
+	}
 
-		if name == "aws" {
-			p = &provider.AwsProvider{}
+	for _, patterns := range [][]string{proj.app.EnvAllowlist, proj.app.EnvDenylist} {
+		if err := validateEnvPatterns(patterns); err != nil {
+			return util.NewReadableError(err, err.Error())
 		}
+	}
 
-		if name == "cloudflare" {
-			p = &provider.CloudflareProvider{}
+	for key := range proj.app.Tags {
+		if !validateTag(key) {
+			return util.NewReadableError(nil, fmt.Sprintf(`Tag %q is invalid, tags can't be empty or start with "sst:" or "git:"`, key))
 		}
+	}
 
-		if p == nil {
-			continue
+	for _, condition := range proj.app.Wait {
+		if err := condition.validate(); err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+	}
+
+	if proj.app.Runner != nil {
+		if err := proj.app.Runner.validate(); err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+	}
+
+	if proj.app.BranchStage != nil {
+		if err := proj.app.BranchStage.validate(); err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+	}
+	if err := saveBranchStage(proj.config, proj.app.BranchStage); err != nil {
+		slog.Warn("failed to save branch stage rules", "err", err)
+	}
+
+	if proj.app.Version != "" {
+		if _, err := semver.NewConstraint(proj.app.Version); err != nil {
+			return util.NewReadableError(err, fmt.Sprintf("Version %q is invalid, it must be a version like 0.0.300 or a range like ^0.0.300", proj.app.Version))
 		}
+	}
+	if err := savePinnedVersion(proj.config, proj.app.Version); err != nil {
+		slog.Warn("failed to save pinned version", "err", err)
+	}
+
+	if proj.app.Namespace != "" && !AppRegex.MatchString(proj.app.Namespace) {
+		return util.NewReadableError(nil, fmt.Sprintf("Namespace %q is invalid, it can only contain alphanumeric characters and hyphens", proj.app.Namespace))
+	}
+
+	// the runner image brings its own node
+	if proj.app.JSRuntime == "" && proj.app.Runner == nil {
+		proj.app.JSRuntime = detectJSRuntime(proj.root)
+	}
+	if proj.app.JSRuntime == "" {
+		proj.app.JSRuntime = "node"
+	}
+	if err := validateJSRuntime(proj.app.JSRuntime); err != nil {
+		return util.NewReadableError(err, err.Error())
+	}
+	if proj.app.JSRuntime == "bun" && proj.app.Runner != nil {
+		return util.NewReadableError(nil, `The "bun" runtime can't be used with a "runner"`)
+	}
+
+	if proj.app.Node != nil {
+		if err := proj.app.Node.validate(); err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+	}
+
+	if proj.app.Proxy != nil {
+		if err := proj.app.Proxy.validate(); err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+		useProxy(proj.app.Proxy)
+	}
 
-		err := p.Init(proj.app.Name, proj.app.Stage, args.(map[string]interface{}))
+	if proj.app.Build != nil {
+		if err := proj.app.Build.validate(proj.root); err != nil {
+			return util.NewReadableError(err, err.Error())
+		}
+	}
+
+	switch proj.app.LinkStore {
+	case "":
+		proj.app.LinkStore = "bundle"
+	case "bundle":
+	case "ssm":
+		if _, ok := proj.app.Providers["aws"]; !ok {
+			return util.NewReadableError(nil, `Link store "ssm" requires the "aws" provider`)
+		}
+	default:
+		return util.NewReadableError(nil, fmt.Sprintf(`Link store %q is invalid, it must be "bundle" or "ssm"`, proj.app.LinkStore))
+	}
+
+	if err := proj.ensureDirs(); err != nil {
+		return util.NewReadableError(err, "Could not create directories: "+err.Error())
+	}
+	return nil
+}
+
+func (proj *Project) LoadProviders() error {
+	proj.Providers = map[string]provider.Provider{}
+	// providers that reference secrets are loaded after the home provider
+	// since that's where the secrets are read from
+	deferred := []string{}
+	for name, args := range proj.app.Providers {
+		if hasSecretRefs(args) {
+			if name == proj.app.Home {
+				return util.NewReadableError(ErrSecretRefHome, fmt.Sprintf(`The "%s" provider is where secrets are stored so it can't use $secret`, name))
+			}
+			deferred = append(deferred, name)
+			continue
+		}
+		err := proj.loadProvider(name, args)
 		if err != nil {
-			return fmt.Errorf("Error initializing %s:\n   %w", name, err)
+			return err
 		}
-		proj.Providers[name] = p
 	}
 
 	p := proj.Providers[proj.app.Home]
@@ -229,17 +547,119 @@ func (proj *Project) LoadProviders() error {
 		return util.NewReadableError(nil, proj.app.Home+` is not a valid backend provider.`)
 	}
 	proj.home = casted
+	if proj.app.Namespace != "" {
+		provider.UseNamespace(proj.home, proj.app.Namespace)
+	}
+	provider.SetSecretsCacheDir(proj.home, filepath.Join(proj.PathWorkingDir(), "cache"))
+
+	if proj.app.Passphrase != nil {
+		key, err := proj.loadPassphraseKey()
+		if err != nil {
+			return err
+		}
+		provider.UsePassphraseKey(proj.home, key)
+	}
+
+	if len(deferred) > 0 {
+		secrets, err := provider.GetSecrets(proj.home, proj.app.Name, proj.app.Stage)
+		if err != nil {
+			return fmt.Errorf("failed to get secrets for providers: %w", err)
+		}
+		proj.secretConfig = map[string]bool{}
+		proj.resolvedProviders = map[string]map[string]interface{}{}
+		for _, name := range deferred {
+			args, resolved, missing := resolveSecretRefs(proj.app.Providers[name].(map[string]interface{}), secrets)
+			if len(missing) > 0 {
+				return util.NewReadableError(ErrMissingSecrets, fmt.Sprintf(
+					"The \"%s\" provider references secrets that are not set: %s\nSet them with `sst secret set <name> <value>`",
+					name,
+					strings.Join(missing, ", "),
+				))
+			}
+			for _, path := range resolved {
+				proj.secretConfig[name+":"+path] = true
+			}
+			proj.resolvedProviders[name] = args
+			err := proj.loadProvider(name, args)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return proj.loadExporters()
+}
+
+// providerArgs returns the args of a provider with the secrets it references
+// resolved.
+func (proj *Project) providerArgs(name string) map[string]interface{} {
+	if args, ok := proj.resolvedProviders[name]; ok {
+		return args
+	}
+	args, _ := proj.app.Providers[name].(map[string]interface{})
+	return args
+}
+
+func (proj *Project) loadProvider(name string, args interface{}) error {
+	var p provider.Provider
+
+	if name == "aws" {
+		p = &provider.AwsProvider{}
+	}
+
+	if name == "cloudflare" {
+		p = &provider.CloudflareProvider{}
+	}
 
+	if p == nil {
+		return nil
+	}
+
+	err := p.Init(proj.app.Name, proj.app.Stage, args.(map[string]interface{}))
+	if err != nil {
+		return fmt.Errorf("Error initializing %s:\n   %w", name, err)
+	}
+	proj.Providers[name] = p
 	return nil
 }
 
+func (proj *Project) loadPassphraseKey() (provider.PassphraseKey, error) {
+	cfg := proj.app.Passphrase
+	if cfg.KMS != "" && cfg.Age != "" {
+		return nil, util.NewReadableError(nil, `The "passphrase" config can only set one of: kms, age`)
+	}
+	if cfg.KMS != "" {
+		aws, ok := proj.Providers["aws"].(*provider.AwsProvider)
+		if !ok {
+			return nil, util.NewReadableError(nil, `Deriving the passphrase from KMS requires the "aws" provider.`)
+		}
+		return provider.NewKmsPassphraseKey(aws.Config(), cfg.KMS), nil
+	}
+	if cfg.Age != "" {
+		path := cfg.Age
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(proj.root, path)
+		}
+		key, err := provider.NewAgePassphraseKey(path)
+		if err != nil {
+			return nil, util.NewReadableError(err, "Could not load age identity from "+cfg.Age)
+		}
+		return key, nil
+	}
+	return nil, util.NewReadableError(nil, `The "passphrase" config must set one of: kms, age`)
+}
+
 func (p *Project) getPath(path ...string) string {
 	paths := append([]string{p.PathWorkingDir()}, path...)
 	return filepath.Join(paths...)
 }
 
 func (p *Project) PathWorkingDir() string {
-	return filepath.Join(p.root, ".sst")
+	return filepath.Clean(resolveWorkingDir(p.root))
+}
+
+func (p *Project) PathEventLog() string {
+	return filepath.Join(p.PathOutputDir(), "event.log")
 }
 
 func (p *Project) PathPlatformDir() string {
@@ -262,12 +682,32 @@ func (p *Project) App() *App {
 	return p.app
 }
 
+// BuildConcurrency is how many functions are bundled at once, from
+// SST_BUILD_CONCURRENCY, the config, the user config, or the number of CPUs.
+func (p *Project) BuildConcurrency() int {
+	if value, err := strconv.Atoi(os.Getenv("SST_BUILD_CONCURRENCY")); err == nil && value > 0 {
+		return value
+	}
+	if p.app.BuildConcurrency > 0 {
+		return p.app.BuildConcurrency
+	}
+	if config, err := global.LoadUserConfig(); err == nil && config.Parallelism > 0 {
+		return config.Parallelism
+	}
+	return goruntime.NumCPU()
+}
+
 func (p *Project) Backend() provider.Home {
 	return p.home
 }
 
 func (p *Project) Cleanup() error {
-	return os.RemoveAll(
-		filepath.Join(p.PathWorkingDir(), "artifacts"),
-	)
+	p.Stack.builder.Dispose()
+	for _, e := range p.exporters {
+		if err := e.Close(); err != nil {
+			slog.Warn("failed to close exporter", "err", err)
+		}
+	}
+	p.exporters = nil
+	return os.RemoveAll(p.PathArtifacts())
 }
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"sort"
+	"sync"
+	"time"
+)
+
+// AuditHome is implemented by homes that can keep an append-only log of
+// who accessed the secrets and state of a stage.
+type AuditHome interface {
+	Home
+
+	appendAudit(app, stage string, entry []byte) error
+	listAudit(app, stage string) ([][]byte, error)
+	principal() (string, error)
+}
+
+type AuditOperation string
+
+const (
+	AuditSecretRead  AuditOperation = "secret.read"
+	AuditSecretWrite AuditOperation = "secret.write"
+	AuditStatePull   AuditOperation = "state.pull"
+	AuditStatePush   AuditOperation = "state.push"
+)
+
+type AuditEntry struct {
+	Time      time.Time      `json:"time"`
+	Operation AuditOperation `json:"operation"`
+	// Principal is the identity the home provider was accessed with
+	Principal string `json:"principal"`
+	User      string `json:"user"`
+	Host      string `json:"host"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+var ErrAuditNotSupported = fmt.Errorf("home provider does not support audit logs")
+
+var principals sync.Map
+
+func auditPrincipal(backend AuditHome) string {
+	if cached, ok := principals.Load(backend); ok {
+		return cached.(string)
+	}
+	principal, err := backend.principal()
+	if err != nil {
+		slog.Error("failed to resolve principal", "err", err)
+		return ""
+	}
+	principals.Store(backend, principal)
+	return principal
+}
+
+// audit records an operation on a stage. It is best effort, a failure to
+// write the entry is logged but doesn't fail the operation.
+func audit(backend Home, app, stage string, operation AuditOperation, detail string) {
+	auditable, ok := backend.(AuditHome)
+	if !ok {
+		return
+	}
+	entry := AuditEntry{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		Principal: auditPrincipal(auditable),
+		Detail:    detail,
+	}
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+	entry.Host, _ = os.Hostname()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	err = auditable.appendAudit(app, stage, data)
+	if err != nil {
+		slog.Error("failed to write audit entry", "operation", operation, "err", err)
+	}
+}
+
+// ListAudit returns the audit log of a stage, oldest first.
+func ListAudit(backend Home, app, stage string) ([]AuditEntry, error) {
+	slog.Info("listing audit log", "app", app, "stage", stage)
+	auditable, ok := backend.(AuditHome)
+	if !ok {
+		return nil, ErrAuditNotSupported
+	}
+	raw, err := auditable.listAudit(app, stage)
+	if err != nil {
+		return nil, err
+	}
+	result := []AuditEntry{}
+	for _, data := range raw {
+		var entry AuditEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Time.Before(result[j].Time)
+	})
+	return result, nil
+}
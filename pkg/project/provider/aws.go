@@ -10,13 +10,16 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/sst/ion/internal/util"
 
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -24,6 +27,7 @@ import (
 )
 
 type AwsProvider struct {
+	homeState
 	args        map[string]interface{}
 	config      aws.Config
 	bootstrap   *awsBootstrapData
@@ -92,19 +96,19 @@ func (a *AwsProvider) Lock(app string, stage string, out *os.File) error {
 }
 
 func (a *AwsProvider) pathForData(key, app, stage string) string {
-	return filepath.Join(key, app, fmt.Sprintf("%v.json", stage))
+	return namespacePath(a, filepath.Join(key, app, fmt.Sprintf("%v.json", stage)))
 }
 
 func (a *AwsProvider) pathForState(app string, stage string) string {
-	return filepath.Join("app", app, fmt.Sprintf("%v.json", stage))
+	return namespacePath(a, filepath.Join("app", app, fmt.Sprintf("%v.json", stage)))
 }
 
 func (a *AwsProvider) pathForLock(app string, stage string) string {
-	return filepath.Join("lock", app, fmt.Sprintf("%v.json", stage))
+	return namespacePath(a, filepath.Join("lock", app, fmt.Sprintf("%v.json", stage)))
 }
 
 func (a *AwsProvider) pathForPassphrase(app string, stage string) string {
-	return "/" + strings.Join([]string{"sst", "passphrase", app, stage}, "/")
+	return "/" + namespacePath(a, strings.Join([]string{"sst", "passphrase", app, stage}, "/"))
 }
 
 func (a *AwsProvider) Unlock(app string, stage string, in *os.File) error {
@@ -339,7 +343,16 @@ func (a *AwsProvider) getData(key, app, stage string) (io.Reader, error) {
 		}
 		return nil, err
 	}
-	return result.Body, nil
+	return &sizedBody{ReadCloser: result.Body, size: aws.ToInt64(result.ContentLength)}, nil
+}
+
+type sizedBody struct {
+	io.ReadCloser
+	size int64
+}
+
+func (b *sizedBody) Size() int64 {
+	return b.size
 }
 
 func (a *AwsProvider) putData(key, app, stage string, data io.Reader) error {
@@ -372,6 +385,142 @@ func (a *AwsProvider) removeData(key, app, stage string) error {
 	return nil
 }
 
+func (a *AwsProvider) listVersions(key, app, stage string) ([]Version, error) {
+	s3Client := s3.NewFromConfig(a.config)
+	path := a.pathForData(key, app, stage)
+
+	versions := []Version{}
+	paginator := s3.NewListObjectVersionsPaginator(s3Client, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(a.bootstrap.State),
+		Prefix: aws.String(path),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Versions {
+			if item.Key == nil || *item.Key != path {
+				continue
+			}
+			versions = append(versions, Version{
+				ID:       aws.ToString(item.VersionId),
+				Modified: aws.ToTime(item.LastModified),
+				Latest:   aws.ToBool(item.IsLatest),
+			})
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Modified.After(versions[j].Modified)
+	})
+	return versions, nil
+}
+
+func (a *AwsProvider) listStages(key string) ([]StageRef, error) {
+	s3Client := s3.NewFromConfig(a.config)
+
+	refs := []StageRef{}
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(a.bootstrap.State),
+		Prefix: aws.String(namespacePath(a, key) + "/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Contents {
+			trimmed, ok := trimNamespace(a, aws.ToString(item.Key))
+			if !ok {
+				continue
+			}
+			parts := strings.Split(trimmed, "/")
+			if len(parts) != 3 || !strings.HasSuffix(parts[2], ".json") {
+				continue
+			}
+			refs = append(refs, StageRef{
+				App:   parts[1],
+				Stage: strings.TrimSuffix(parts[2], ".json"),
+			})
+		}
+	}
+	return refs, nil
+}
+
+func (a *AwsProvider) pathForAudit(app, stage string) string {
+	return namespacePath(a, strings.Join([]string{"audit", app, stage}, "/")) + "/"
+}
+
+func (a *AwsProvider) appendAudit(app, stage string, entry []byte) error {
+	s3Client := s3.NewFromConfig(a.config)
+	// objects are never overwritten, every entry gets its own sortable key
+	key := a.pathForAudit(app, stage) + time.Now().UTC().Format("20060102T150405.000000000Z") + "-" + util.RandomString(8) + ".json"
+	_, err := s3Client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket: aws.String(a.bootstrap.State),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(entry),
+	})
+	return err
+}
+
+func (a *AwsProvider) listAudit(app, stage string) ([][]byte, error) {
+	s3Client := s3.NewFromConfig(a.config)
+
+	result := [][]byte{}
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(a.bootstrap.State),
+		Prefix: aws.String(a.pathForAudit(app, stage)),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Contents {
+			object, err := s3Client.GetObject(context.TODO(), &s3.GetObjectInput{
+				Bucket: aws.String(a.bootstrap.State),
+				Key:    item.Key,
+			})
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(object.Body)
+			object.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, data)
+		}
+	}
+	return result, nil
+}
+
+func (a *AwsProvider) principal() (string, error) {
+	identity, err := sts.NewFromConfig(a.config).GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(identity.Arn), nil
+}
+
+func (a *AwsProvider) getDataVersion(key, app, stage, version string) (io.Reader, error) {
+	s3Client := s3.NewFromConfig(a.config)
+
+	result, err := s3Client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket:    aws.String(a.bootstrap.State),
+		Key:       aws.String(a.pathForData(key, app, stage)),
+		VersionId: aws.String(version),
+	})
+	if err != nil {
+		var nsk *s3types.NoSuchKey
+		if errors.As(err, &nsk) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return result.Body, nil
+}
+
 func (a *AwsProvider) getPassphrase(app string, stage string) (string, error) {
 	ssmClient := ssm.NewFromConfig(a.config)
 
@@ -402,6 +551,34 @@ func (a *AwsProvider) setPassphrase(app, stage, passphrase string) error {
 	return err
 }
 
+func (a *AwsProvider) rotatePassphrase(app, stage, passphrase string) error {
+	ssmClient := ssm.NewFromConfig(a.config)
+
+	_, err := ssmClient.PutParameter(context.TODO(), &ssm.PutParameterInput{
+		Name:      aws.String(a.pathForPassphrase(app, stage)),
+		Type:      ssmTypes.ParameterTypeSecureString,
+		Value:     aws.String(passphrase),
+		Overwrite: aws.Bool(true),
+	})
+	return err
+}
+
+func (a *AwsProvider) removePassphrase(app, stage string) error {
+	ssmClient := ssm.NewFromConfig(a.config)
+
+	_, err := ssmClient.DeleteParameter(context.TODO(), &ssm.DeleteParameterInput{
+		Name: aws.String(a.pathForPassphrase(app, stage)),
+	})
+	if err != nil {
+		pnf := &ssmTypes.ParameterNotFound{}
+		if errors.As(err, &pnf) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
 type fragment struct {
 	ID    string `json:"id"`
 	Index int    `json:"index"`
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"bytes"
+	"io"
+)
+
+// buildCacheStage holds the passphrase the build cache of an app is
+// encrypted with. The cache is shared by every stage, bundles embed linked
+// values so they are encrypted like the rest of the stage data.
+const buildCacheStage = "_cache"
+
+// BuildCacheManifest records the inputs a cached build was made from and
+// the entry with its output.
+type BuildCacheManifest struct {
+	Inputs map[string]string `json:"inputs"`
+	Entry  string            `json:"entry"`
+}
+
+// GetBuildCacheIndex returns the manifests in the remote build cache of the
+// app by key.
+func GetBuildCacheIndex(backend Home, app string) (map[string]BuildCacheManifest, error) {
+	result := map[string]BuildCacheManifest{}
+	err := getData(backend, "build-cache", app, "index", false, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func PutBuildCacheIndex(backend Home, app string, index map[string]BuildCacheManifest) error {
+	return putData(backend, "build-cache", app, "index", false, index)
+}
+
+// GetBuildCacheEntry returns an archived entry of the remote build cache,
+// or nil if it doesn't exist.
+func GetBuildCacheEntry(backend Home, app, entry string) ([]byte, error) {
+	reader, err := backend.getData("build-cache", app, entry)
+	if err != nil {
+		return nil, err
+	}
+	if reader == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptBuildCache(backend, app, data)
+}
+
+func PutBuildCacheEntry(backend Home, app, entry string, data []byte) error {
+	encrypted, err := EncryptBuildCache(backend, app, data)
+	if err != nil {
+		return err
+	}
+	return backend.putData("build-cache", app, entry, bytes.NewReader(encrypted))
+}
+
+// EncryptBuildCache encrypts a file of the build cache with the passphrase
+// of the cache, for the files that embed values of the app.
+func EncryptBuildCache(backend Home, app string, data []byte) ([]byte, error) {
+	return encryptData(backend, app, buildCacheStage, data)
+}
+
+func DecryptBuildCache(backend Home, app string, data []byte) ([]byte, error) {
+	return decryptData(backend, app, buildCacheStage, data)
+}
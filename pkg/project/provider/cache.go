@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type secretsCacheEntry struct {
+	secrets map[string]string
+	expires time.Time
+	// modified time of the disk cache the entry was loaded from, if it
+	// changed another process invalidated it
+	modified time.Time
+}
+
+type diskCacheEntry struct {
+	Secrets map[string]string `json:"secrets"`
+	Expires time.Time         `json:"expires"`
+}
+
+// SetSecretsCacheDir sets where the on-disk secrets cache of the backend
+// lives. It is set even when the cache is disabled so writes can invalidate
+// it for other processes.
+func SetSecretsCacheDir(backend Home, dir string) {
+	state := backend.state()
+	state.secretsMu.Lock()
+	defer state.secretsMu.Unlock()
+	state.secretsDir = dir
+}
+
+// EnableSecretsCache caches the secrets of every stage of the backend for
+// ttl. It's meant for dev mode where the same secrets are read on every
+// deploy.
+func EnableSecretsCache(backend Home, ttl time.Duration) {
+	state := backend.state()
+	state.secretsMu.Lock()
+	defer state.secretsMu.Unlock()
+	state.secretsTTL = ttl
+}
+
+func secretsCachePath(dir, app, stage string) string {
+	return filepath.Join(dir, "secret", app, stage+".json")
+}
+
+func cachedSecrets(backend Home, app, stage string) (map[string]string, bool) {
+	state := backend.state()
+	state.secretsMu.Lock()
+	defer state.secretsMu.Unlock()
+	if state.secretsTTL == 0 {
+		return nil, false
+	}
+	dir := state.secretsDir
+
+	var modified time.Time
+	if dir != "" {
+		info, err := os.Stat(secretsCachePath(dir, app, stage))
+		if err != nil {
+			return nil, false
+		}
+		modified = info.ModTime()
+	}
+
+	entry, ok := state.secrets[app+stage]
+	if ok && time.Now().Before(entry.expires) && entry.modified.Equal(modified) {
+		return entry.secrets, true
+	}
+	if dir == "" {
+		return nil, false
+	}
+
+	var disk diskCacheEntry
+	err := readEncryptedFile(backend, app, stage, secretsCachePath(dir, app, stage), &disk)
+	if err != nil {
+		slog.Info("could not read secrets cache", "err", err)
+		return nil, false
+	}
+	if time.Now().After(disk.Expires) {
+		return nil, false
+	}
+	state.storeSecrets(app, stage, &secretsCacheEntry{
+		secrets:  disk.Secrets,
+		expires:  disk.Expires,
+		modified: modified,
+	})
+	return disk.Secrets, true
+}
+
+func cacheSecrets(backend Home, app, stage string, secrets map[string]string) {
+	state := backend.state()
+	state.secretsMu.Lock()
+	defer state.secretsMu.Unlock()
+	if state.secretsTTL == 0 {
+		return
+	}
+	entry := &secretsCacheEntry{
+		secrets: secrets,
+		expires: time.Now().Add(state.secretsTTL),
+	}
+	if state.secretsDir != "" {
+		path := secretsCachePath(state.secretsDir, app, stage)
+		err := writeEncryptedFile(backend, app, stage, path, diskCacheEntry{
+			Secrets: secrets,
+			Expires: entry.expires,
+		})
+		if err != nil {
+			slog.Info("could not write secrets cache", "err", err)
+			return
+		}
+		if info, err := os.Stat(path); err == nil {
+			entry.modified = info.ModTime()
+		}
+	}
+	state.storeSecrets(app, stage, entry)
+}
+
+// storeSecrets is called with secretsMu held.
+func (s *homeState) storeSecrets(app, stage string, entry *secretsCacheEntry) {
+	if s.secrets == nil {
+		s.secrets = map[string]*secretsCacheEntry{}
+	}
+	s.secrets[app+stage] = entry
+}
+
+// InvalidateSecrets drops the cached secrets of a stage, both in memory and
+// on disk, so the next read goes to the home provider.
+func InvalidateSecrets(backend Home, app, stage string) {
+	state := backend.state()
+	state.secretsMu.Lock()
+	defer state.secretsMu.Unlock()
+	delete(state.secrets, app+stage)
+	if state.secretsDir != "" {
+		os.Remove(secretsCachePath(state.secretsDir, app, stage))
+	}
+}
+
+func readEncryptedFile(backend Home, app, stage, path string, out interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	plaintext, err := decryptData(backend, app, stage, data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, out)
+}
+
+func writeEncryptedFile(backend Home, app, stage, path string, data interface{}) error {
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := encryptData(backend, app, stage, plaintext)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, ciphertext, 0600)
+}
@@ -15,6 +15,7 @@ import (
 )
 
 type CloudflareProvider struct {
+	homeState
 	client     *cloudflare.API
 	identifier *cloudflare.ResourceContainer
 	env        map[string]string
@@ -101,7 +102,7 @@ func (c *CloudflareProvider) Init(app, stage string, provider map[string]interfa
 func makeRequestContext(*cloudflare.API, context.Context, string, string, interface{}) ([]byte, error)
 
 func (c *CloudflareProvider) putData(kind, app, stage string, data io.Reader) error {
-	path := filepath.Join(kind, app, stage)
+	path := namespacePath(c, filepath.Join(kind, app, stage))
 	_, err := makeRequestContext(c.client, context.Background(), http.MethodPut, "/accounts/"+c.identifier.Identifier+"/r2/buckets/"+c.bootstrap.State+"/objects/"+path, data)
 	if err != nil {
 		return err
@@ -110,7 +111,7 @@ func (c *CloudflareProvider) putData(kind, app, stage string, data io.Reader) er
 }
 
 func (c *CloudflareProvider) getData(kind, app, stage string) (io.Reader, error) {
-	path := filepath.Join(kind, app, stage)
+	path := namespacePath(c, filepath.Join(kind, app, stage))
 	data, err := makeRequestContext(c.client, context.Background(), http.MethodGet, "/accounts/"+c.identifier.Identifier+"/r2/buckets/"+c.bootstrap.State+"/objects/"+path, nil)
 	if err != nil {
 		if err.Error() == "The specified key does not exist. (10007)" {
@@ -122,7 +123,7 @@ func (c *CloudflareProvider) getData(kind, app, stage string) (io.Reader, error)
 }
 
 func (c *CloudflareProvider) removeData(kind, app, stage string) error {
-	path := filepath.Join(kind, app, stage)
+	path := namespacePath(c, filepath.Join(kind, app, stage))
 	_, err := makeRequestContext(c.client, context.Background(), http.MethodDelete, "/accounts/"+c.identifier.Identifier+"/r2/buckets/"+c.bootstrap.State+"/objects/"+path, nil)
 	if err != nil {
 		return err
@@ -135,6 +136,14 @@ func (c *CloudflareProvider) setPassphrase(app, stage string, passphrase string)
 	return c.putData("passphrase", app, stage, bytes.NewReader([]byte(passphrase)))
 }
 
+func (c *CloudflareProvider) rotatePassphrase(app, stage string, passphrase string) error {
+	return c.setPassphrase(app, stage, passphrase)
+}
+
+func (c *CloudflareProvider) removePassphrase(app, stage string) error {
+	return c.removeData("passphrase", app, stage)
+}
+
 func (c *CloudflareProvider) getPassphrase(app, stage string) (string, error) {
 	data, err := c.getData("passphrase", app, stage)
 	if err != nil {
@@ -0,0 +1,284 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+var ErrStageExists = fmt.Errorf("stage already exists")
+
+// movedKeys are copied as is when a stage is moved. The new stage shares the
+// passphrase of the old one so encrypted data stays readable.
+var movedKeys = []string{"secret", "secret-meta", "link", "metadata", "manifest", "owner"}
+
+type MoveInput struct {
+	// Rewrite is called with the state of the old stage and returns the
+	// state for the new one.
+	Rewrite func(state []byte) ([]byte, error)
+	// Validate is called with the state read back from the new stage. The
+	// old stage is only removed if it passes.
+	Validate func(state []byte) error
+	// AllowMissingState moves stages that were never deployed but have
+	// secrets set, like the fallback stage.
+	AllowMissingState bool
+}
+
+// MoveStage moves the state, secrets, links, and metadata of a stage to
+// another one and removes the old stage. Both stages are locked while
+// moving. If anything fails before the old stage is removed, whatever was
+// written to the new stage is removed again.
+func MoveStage(backend Home, from, to StageRef, input MoveInput) error {
+	slog.Info("moving stage", "from", from, "to", to)
+	err := Lock(backend, from.App, from.Stage)
+	if err != nil {
+		return err
+	}
+	defer Unlock(backend, from.App, from.Stage)
+	err = Lock(backend, to.App, to.Stage)
+	if err != nil {
+		return err
+	}
+	defer Unlock(backend, to.App, to.Stage)
+
+	// checked once both are locked so nothing deploys to the new stage in
+	// the meantime
+	for _, key := range []string{"app", "secret", "link"} {
+		reader, err := backend.getData(key, to.App, to.Stage)
+		if err != nil {
+			return err
+		}
+		if reader != nil {
+			return ErrStageExists
+		}
+	}
+
+	state, err := readData(backend, "app", from)
+	if err != nil {
+		return err
+	}
+	if state == nil && !input.AllowMissingState {
+		return ErrStateNotFound
+	}
+	audit(backend, from.App, from.Stage, AuditStatePull, "move to "+to.App+"/"+to.Stage)
+
+	// the keys the new stage already had, like tags or a passphrase left
+	// behind, are put back instead of removed if the move fails
+	previous := map[string][]byte{}
+	for _, key := range stageKeys() {
+		data, err := readData(backend, key, to)
+		if err != nil {
+			return err
+		}
+		if data != nil {
+			previous[key] = data
+		}
+	}
+	written := []string{}
+	put := func(key string, data []byte) error {
+		written = append(written, key)
+		return backend.putData(key, to.App, to.Stage, bytes.NewReader(data))
+	}
+	rollback := func(cause error) error {
+		slog.Error("removing partially moved stage", "err", cause)
+		for _, key := range written {
+			var err error
+			if data, ok := previous[key]; ok {
+				err = backend.putData(key, to.App, to.Stage, bytes.NewReader(data))
+			} else {
+				err = backend.removeData(key, to.App, to.Stage)
+			}
+			if err != nil {
+				cause = errors.Join(cause, err)
+			}
+		}
+		InvalidateSecrets(backend, to.App, to.Stage)
+		return cause
+	}
+
+	if _, ok := backend.state().getPassphraseKey(); ok {
+		written = append(written, "passphrase-key")
+	}
+	err = copyPassphrase(backend, from, to)
+	if err != nil {
+		return rollback(err)
+	}
+
+	if state != nil {
+		if input.Rewrite != nil {
+			state, err = input.Rewrite(state)
+			if err != nil {
+				return rollback(err)
+			}
+		}
+		err = put("app", state)
+		if err != nil {
+			return rollback(err)
+		}
+		audit(backend, to.App, to.Stage, AuditStatePush, "move from "+from.App+"/"+from.Stage)
+	}
+
+	for _, key := range movedKeys {
+		data, err := readData(backend, key, from)
+		if err != nil {
+			return rollback(err)
+		}
+		if data == nil {
+			continue
+		}
+		err = put(key, data)
+		if err != nil {
+			return rollback(err)
+		}
+	}
+	InvalidateSecrets(backend, to.App, to.Stage)
+
+	tags, err := GetTags(backend, from.App, from.Stage)
+	if err != nil {
+		return rollback(err)
+	}
+	if len(tags) > 0 {
+		tags["sst:app"] = to.App
+		tags["sst:stage"] = to.Stage
+		written = append(written, "tags")
+		err = PutTags(backend, to.App, to.Stage, tags)
+		if err != nil {
+			return rollback(err)
+		}
+	}
+
+	err = validateMove(backend, from, to, input.Validate)
+	if err != nil {
+		return rollback(err)
+	}
+
+	for _, key := range stageKeys() {
+		err = backend.removeData(key, from.App, from.Stage)
+		if err != nil {
+			return err
+		}
+	}
+	InvalidateSecrets(backend, from.App, from.Stage)
+	return nil
+}
+
+// validateMove reads everything back from the new stage and checks it
+// matches the old one.
+func validateMove(backend Home, from, to StageRef, validate func(state []byte) error) error {
+	state, err := readData(backend, "app", to)
+	if err != nil {
+		return err
+	}
+	if state != nil && validate != nil {
+		err = validate(state)
+		if err != nil {
+			return err
+		}
+	}
+	for _, key := range movedKeys {
+		before, err := readData(backend, key, from)
+		if err != nil {
+			return err
+		}
+		after, err := readData(backend, key, to)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(before, after) {
+			return fmt.Errorf("%v of the new stage does not match the old one", key)
+		}
+	}
+	// make sure the copied passphrase decrypts what was copied
+	_, err = readStageSecrets(backend, to.App, to.Stage)
+	return err
+}
+
+// stageKeys are every data key a stage can have that is moved with it
+func stageKeys() []string {
+	return append([]string{"app", "tags", "passphrase-key"}, movedKeys...)
+}
+
+// StageKeys returns the data keys that are set for a stage and would be
+// moved by MoveStage.
+func StageKeys(backend Home, ref StageRef) ([]string, error) {
+	result := []string{}
+	for _, key := range stageKeys() {
+		reader, err := backend.getData(key, ref.App, ref.Stage)
+		if err != nil {
+			return nil, err
+		}
+		if reader != nil {
+			result = append(result, key)
+		}
+	}
+	return result, nil
+}
+
+// ListAppStages returns every stage of an app that has state or secrets,
+// including the fallback stage.
+func ListAppStages(backend Home, app string) ([]StageRef, error) {
+	searchable, ok := backend.(SearchableHome)
+	if !ok {
+		return nil, ErrSearchNotSupported
+	}
+	seen := map[string]bool{}
+	result := []StageRef{}
+	for _, key := range []string{"app", "secret"} {
+		refs, err := searchable.listStages(key)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range refs {
+			if ref.App != app || seen[ref.Stage] {
+				continue
+			}
+			seen[ref.Stage] = true
+			result = append(result, ref)
+		}
+	}
+	return result, nil
+}
+
+// copyPassphrase gives the new stage the passphrase of the old one.
+func copyPassphrase(backend Home, from, to StageRef) error {
+	passphrase, err := Passphrase(backend, from.App, from.Stage)
+	if err != nil {
+		return err
+	}
+	if key, ok := backend.state().getPassphraseKey(); ok {
+		plaintext, err := base64.StdEncoding.DecodeString(passphrase)
+		if err != nil {
+			return err
+		}
+		ciphertext, err := key.Encrypt(to.App, to.Stage, plaintext)
+		if err != nil {
+			return err
+		}
+		err = putPassphraseKey(backend, to.App, to.Stage, ciphertext)
+		if err != nil {
+			return err
+		}
+	} else {
+		// overwrites a passphrase left behind by a stage that has no state
+		err = backend.rotatePassphrase(to.App, to.Stage, passphrase)
+		if err != nil {
+			return err
+		}
+	}
+	backend.state().cachePassphrase(to.App, to.Stage, passphrase)
+	return nil
+}
+
+func readData(backend Home, key string, ref StageRef) ([]byte, error) {
+	reader, err := backend.getData(key, ref.App, ref.Stage)
+	if err != nil {
+		return nil, err
+	}
+	if reader == nil {
+		return nil, nil
+	}
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,105 @@
+package provider
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// UseNamespace prefixes every path the backend reads and writes with the
+// namespace, so apps with the same name can share a backend.
+func UseNamespace(backend Home, namespace string) {
+	state := backend.state()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.namespace = namespace
+}
+
+func namespacePath(backend Home, p string) string {
+	if namespace := backend.state().getNamespace(); namespace != "" {
+		return path.Join(namespace, p)
+	}
+	return p
+}
+
+// trimNamespace removes the namespace from a path listed in the backend.
+func trimNamespace(backend Home, p string) (string, bool) {
+	namespace := backend.state().getNamespace()
+	if namespace == "" {
+		return p, true
+	}
+	return strings.CutPrefix(p, namespace+"/")
+}
+
+var ErrNamespaceCollision = fmt.Errorf("stage owned by another project")
+
+// Owner records which project deploys a stage so two projects that end up
+// with the same app, namespace, and stage don't overwrite each other.
+type Owner struct {
+	Repository string    `json:"repository"`
+	Claimed    time.Time `json:"claimed"`
+}
+
+// ClaimStage records the repository as the owner of a stage the first time
+// it's deployed. If another repository owns it, it's returned along with
+// ErrNamespaceCollision. Projects that are not in a repository can't be
+// told apart so they're not checked.
+func ClaimStage(backend Home, app, stage, repository string) (*Owner, error) {
+	if repository == "" {
+		return nil, nil
+	}
+	var owner *Owner
+	err := getData(backend, "owner", app, stage, false, &owner)
+	if err != nil {
+		return nil, err
+	}
+	repository = NormalizeRepository(repository)
+	if owner != nil {
+		if NormalizeRepository(owner.Repository) == repository {
+			return owner, nil
+		}
+		return owner, ErrNamespaceCollision
+	}
+	owner = &Owner{
+		Repository: repository,
+		Claimed:    time.Now().UTC(),
+	}
+	return owner, putData(backend, "owner", app, stage, false, owner)
+}
+
+// NormalizeRepository reduces a git remote to host/owner/repo so the https
+// and ssh remotes of the same repository are equal, like
+// git@github.com:sst/ion.git and https://user@github.com/sst/ion.
+func NormalizeRepository(remote string) string {
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return ""
+	}
+	host, repo := "", remote
+	if parsed, err := url.Parse(remote); err == nil && parsed.Scheme != "" && parsed.Host != "" {
+		host, repo = parsed.Hostname(), parsed.Path
+	} else if _, rest, ok := strings.Cut(remote, "://"); ok {
+		// url.Parse rejects some credentials, they're dropped all the same
+		authority, repoPath, _ := strings.Cut(rest, "/")
+		if index := strings.LastIndex(authority, "@"); index != -1 {
+			authority = authority[index+1:]
+		}
+		if index := strings.LastIndex(authority, ":"); index != -1 {
+			authority = authority[:index]
+		}
+		host, repo = authority, repoPath
+	} else if before, after, ok := strings.Cut(remote, ":"); ok && !strings.Contains(before, "/") {
+		// scp like syntax, [user@]host:owner/repo
+		if index := strings.LastIndex(before, "@"); index != -1 {
+			before = before[index+1:]
+		}
+		host, repo = before, after
+	}
+	repo = strings.TrimSuffix(strings.TrimRight(repo, "/"), ".git")
+	if host == "" {
+		return strings.ToLower(repo)
+	}
+	return strings.ToLower(path.Join(host, repo))
+}
@@ -0,0 +1,203 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmsTypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"golang.org/x/exp/slog"
+)
+
+// PassphraseKey derives the passphrase of a stage from an encrypted data key.
+// Only the encrypted data key is stored in the home provider.
+type PassphraseKey interface {
+	Generate(app, stage string) (plaintext []byte, ciphertext []byte, err error)
+	// Encrypt wraps an existing data key for a stage, used when a stage is
+	// moved and keeps its passphrase.
+	Encrypt(app, stage string, plaintext []byte) ([]byte, error)
+	Decrypt(app, stage string, ciphertext []byte) ([]byte, error)
+}
+
+// UsePassphraseKey switches the backend to derive passphrases from key
+// instead of storing them.
+func UsePassphraseKey(backend Home, key PassphraseKey) {
+	state := backend.state()
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	state.passphraseKey = key
+}
+
+func getPassphraseKey(backend Home, app, stage string) ([]byte, error) {
+	reader, err := backend.getData("passphrase-key", app, stage)
+	if err != nil {
+		return nil, err
+	}
+	if reader == nil {
+		return nil, nil
+	}
+	return io.ReadAll(reader)
+}
+
+func putPassphraseKey(backend Home, app, stage string, ciphertext []byte) error {
+	return backend.putData("passphrase-key", app, stage, bytes.NewReader(ciphertext))
+}
+
+func derivePassphrase(backend Home, key PassphraseKey, app, stage string) (string, error) {
+	ciphertext, err := getPassphraseKey(backend, app, stage)
+	if err != nil {
+		return "", err
+	}
+	if ciphertext != nil {
+		plaintext, err := key.Decrypt(app, stage, ciphertext)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(plaintext), nil
+	}
+
+	// stages created before switching keep working with their stored
+	// passphrase until it is rotated
+	legacy, err := backend.getPassphrase(app, stage)
+	if err != nil {
+		return "", err
+	}
+	if legacy != "" {
+		slog.Warn("using stored passphrase, run `sst rotate-passphrase` to derive it from the key", "app", app, "stage", stage)
+		return legacy, nil
+	}
+
+	slog.Info("passphrase key not found, generating data key", "app", app, "stage", stage)
+	plaintext, ciphertext, err := key.Generate(app, stage)
+	if err != nil {
+		return "", err
+	}
+	err = putPassphraseKey(backend, app, stage, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(plaintext), nil
+}
+
+type KmsPassphraseKey struct {
+	client *kms.Client
+	keyID  string
+}
+
+func NewKmsPassphraseKey(cfg aws.Config, keyID string) *KmsPassphraseKey {
+	return &KmsPassphraseKey{
+		client: kms.NewFromConfig(cfg),
+		keyID:  keyID,
+	}
+}
+
+func (k *KmsPassphraseKey) context(app, stage string) map[string]string {
+	return map[string]string{
+		"sst:app":   app,
+		"sst:stage": stage,
+	}
+}
+
+func (k *KmsPassphraseKey) Generate(app, stage string) ([]byte, []byte, error) {
+	result, err := k.client.GenerateDataKey(context.TODO(), &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(k.keyID),
+		KeySpec:           kmsTypes.DataKeySpecAes256,
+		EncryptionContext: k.context(app, stage),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result.Plaintext, result.CiphertextBlob, nil
+}
+
+func (k *KmsPassphraseKey) Encrypt(app, stage string, plaintext []byte) ([]byte, error) {
+	result, err := k.client.Encrypt(context.TODO(), &kms.EncryptInput{
+		KeyId:             aws.String(k.keyID),
+		Plaintext:         plaintext,
+		EncryptionContext: k.context(app, stage),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.CiphertextBlob, nil
+}
+
+func (k *KmsPassphraseKey) Decrypt(app, stage string, ciphertext []byte) ([]byte, error) {
+	result, err := k.client.Decrypt(context.TODO(), &kms.DecryptInput{
+		KeyId:             aws.String(k.keyID),
+		CiphertextBlob:    ciphertext,
+		EncryptionContext: k.context(app, stage),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Plaintext, nil
+}
+
+type AgePassphraseKey struct {
+	identities []age.Identity
+	recipient  age.Recipient
+}
+
+func NewAgePassphraseKey(identityPath string) (*AgePassphraseKey, error) {
+	file, err := os.Open(identityPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		if x25519, ok := identity.(*age.X25519Identity); ok {
+			return &AgePassphraseKey{
+				identities: identities,
+				recipient:  x25519.Recipient(),
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("no X25519 identity found in %v", identityPath)
+}
+
+func (k *AgePassphraseKey) Generate(app, stage string) ([]byte, []byte, error) {
+	plaintext := make([]byte, 32)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err := k.Encrypt(app, stage, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, ciphertext, nil
+}
+
+func (k *AgePassphraseKey) Encrypt(app, stage string, plaintext []byte) ([]byte, error) {
+	out := &bytes.Buffer{}
+	writer, err := age.Encrypt(out, k.recipient)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func (k *AgePassphraseKey) Decrypt(app, stage string, ciphertext []byte) ([]byte, error) {
+	reader, err := age.Decrypt(bytes.NewReader(ciphertext), k.identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
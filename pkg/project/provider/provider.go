@@ -7,10 +7,13 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/exp/slog"
 )
@@ -24,6 +27,38 @@ type Home interface {
 
 	setPassphrase(app, stage string, passphrase string) error
 	getPassphrase(app, stage string) (string, error)
+	rotatePassphrase(app, stage string, passphrase string) error
+	removePassphrase(app, stage string) error
+
+	state() *homeState
+}
+
+// VersionedHome is implemented by homes whose storage keeps a history of
+// every object that was written.
+type VersionedHome interface {
+	Home
+
+	listVersions(key, app, stage string) ([]Version, error)
+	getDataVersion(key, app, stage, version string) (io.Reader, error)
+}
+
+// SearchableHome is implemented by homes that can list the stages that
+// have data stored under a key.
+type SearchableHome interface {
+	Home
+
+	listStages(key string) ([]StageRef, error)
+}
+
+type StageRef struct {
+	App   string
+	Stage string
+}
+
+type Version struct {
+	ID       string
+	Modified time.Time
+	Latest   bool
 }
 
 type DevTransport struct {
@@ -57,22 +92,24 @@ const SSM_NAME_BOOTSTRAP = "/sst/bootstrap"
 
 var ErrLockExists = fmt.Errorf("Concurrent update detected, run `sst unlock` to delete lock file and retry.")
 
-var passphraseCache = map[Home]map[string]string{}
-
 func Passphrase(backend Home, app, stage string) (string, error) {
 	slog.Info("getting passphrase", "app", app, "stage", stage)
 
-	cache, ok := passphraseCache[backend]
-	if !ok {
-		cache = map[string]string{}
-		passphraseCache[backend] = cache
-	}
-
-	existingPassphrase, ok := cache[app+stage]
+	state := backend.state()
+	existingPassphrase, ok := state.cachedPassphrase(app, stage)
 	if ok {
 		return existingPassphrase, nil
 	}
 
+	if key, ok := state.getPassphraseKey(); ok {
+		passphrase, err := derivePassphrase(backend, key, app, stage)
+		if err != nil {
+			return "", err
+		}
+		state.cachePassphrase(app, stage, passphrase)
+		return passphrase, nil
+	}
+
 	passphrase, err := backend.getPassphrase(app, stage)
 	if err != nil {
 		return "", err
@@ -80,22 +117,163 @@ func Passphrase(backend Home, app, stage string) (string, error) {
 
 	if passphrase == "" {
 		slog.Info("passphrase not found, setting passphrase", "app", app, "stage", stage)
-		bytes := make([]byte, 32)
-		_, err := rand.Read(bytes)
+		passphrase, err = generatePassphrase()
 		if err != nil {
 			return "", err
 		}
-		passphrase = base64.StdEncoding.EncodeToString(bytes)
 		err = backend.setPassphrase(app, stage, passphrase)
 		if err != nil {
 			return "", err
 		}
 	}
 
-	existingPassphrase, ok = cache[app+stage]
+	state.cachePassphrase(app, stage, passphrase)
 	return passphrase, nil
 }
 
+func generatePassphrase() (string, error) {
+	bytes := make([]byte, 32)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(bytes), nil
+}
+
+// encryptedKeys are the data keys that are encrypted with the passphrase and
+// need to be re-encrypted when it is rotated.
+var encryptedKeys = []string{"secret", "secret-meta", "link", "metadata"}
+
+type RotateInput struct {
+	// Reencrypt is called with the old and new passphrase before the stored
+	// passphrase is swapped. It should re-encrypt the local state.
+	Reencrypt func(old, next string) error
+	// Commit is called after the passphrase is swapped to push the
+	// re-encrypted state. If it fails the old passphrase is restored.
+	Commit func() error
+}
+
+// RotatePassphrase generates a new passphrase for a stage and re-encrypts
+// everything that was encrypted with the old one.
+func RotatePassphrase(backend Home, app, stage string, input RotateInput) error {
+	slog.Info("rotating passphrase", "app", app, "stage", stage)
+	old, err := Passphrase(backend, app, stage)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]*json.RawMessage{}
+	for _, key := range encryptedKeys {
+		var value *json.RawMessage
+		err := getData(backend, key, app, stage, true, &value)
+		if err != nil {
+			return err
+		}
+		data[key] = value
+	}
+
+	// in derived mode only the encrypted data key is swapped, the old
+	// one is kept around so it can be restored
+	key, derived := backend.state().getPassphraseKey()
+	var oldCiphertext, nextCiphertext []byte
+	var next string
+	if derived {
+		oldCiphertext, err = getPassphraseKey(backend, app, stage)
+		if err != nil {
+			return err
+		}
+		plaintext, ciphertext, err := key.Generate(app, stage)
+		if err != nil {
+			return err
+		}
+		next = base64.StdEncoding.EncodeToString(plaintext)
+		nextCiphertext = ciphertext
+	} else {
+		next, err = generatePassphrase()
+		if err != nil {
+			return err
+		}
+	}
+
+	if input.Reencrypt != nil {
+		err = input.Reencrypt(old, next)
+		if err != nil {
+			return err
+		}
+	}
+
+	if derived {
+		err = putPassphraseKey(backend, app, stage, nextCiphertext)
+	} else {
+		err = backend.rotatePassphrase(app, stage, next)
+	}
+	if err != nil {
+		return err
+	}
+	backend.state().cachePassphrase(app, stage, next)
+
+	restore := func(cause error) error {
+		slog.Error("restoring old passphrase", "err", cause)
+		var err error
+		switch {
+		case derived && oldCiphertext != nil:
+			err = putPassphraseKey(backend, app, stage, oldCiphertext)
+		case derived:
+			err = backend.removeData("passphrase-key", app, stage)
+		default:
+			err = backend.rotatePassphrase(app, stage, old)
+		}
+		if err != nil {
+			return errors.Join(cause, err)
+		}
+		backend.state().cachePassphrase(app, stage, old)
+		for key, value := range data {
+			if value == nil {
+				continue
+			}
+			putData(backend, key, app, stage, true, value)
+		}
+		return cause
+	}
+
+	for key, value := range data {
+		if value == nil {
+			continue
+		}
+		err = putData(backend, key, app, stage, true, value)
+		if err != nil {
+			return restore(err)
+		}
+	}
+
+	if input.Commit != nil {
+		err = input.Commit()
+		if err != nil {
+			return restore(err)
+		}
+	}
+
+	// a stage that used its stored passphrase is now derived from the key,
+	// the stored one is deleted once the new data key decrypts to what the
+	// data was encrypted with
+	if derived && oldCiphertext == nil {
+		verified, err := derivePassphrase(backend, key, app, stage)
+		if err != nil {
+			return fmt.Errorf("the passphrase was migrated but could not be verified, the stored passphrase was kept: %w", err)
+		}
+		if verified != next {
+			return fmt.Errorf("the passphrase was migrated but the data key does not match, the stored passphrase was kept")
+		}
+		slog.Info("removing stored passphrase", "app", app, "stage", stage)
+		err = backend.removePassphrase(app, stage)
+		if err != nil {
+			return fmt.Errorf("the passphrase was migrated but the stored passphrase could not be removed: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func GetLinks(backend Home, app, stage string) (map[string]interface{}, error) {
 	data := map[string]interface{}{}
 	err := getData(backend, "link", app, stage, true, &data)
@@ -113,13 +291,151 @@ func PutLinks(backend Home, app, stage string, data map[string]interface{}) erro
 	return putData(backend, "link", app, stage, true, data)
 }
 
+// GetMetadata reads the metadata for a stage. Metadata is stored separately
+// from the state and is not guarded by the lock.
+func GetMetadata(backend Home, app, stage string, out interface{}) error {
+	return getData(backend, "metadata", app, stage, true, out)
+}
+
+// PutMetadata writes the metadata for a stage without taking the lock, it is
+// meant for small frequent writes in dev mode.
+func PutMetadata(backend Home, app, stage string, data interface{}) error {
+	slog.Info("putting metadata", "app", app, "stage", stage)
+	return putData(backend, "metadata", app, stage, true, data)
+}
+
+// GetSession reads the dev session running against a stage. Like the lock it
+// is not encrypted, it only identifies who is running it.
+func GetSession(backend Home, app, stage string, out interface{}) error {
+	return getData(backend, "session", app, stage, false, out)
+}
+
+// PutSession writes the heartbeat of a dev session.
+func PutSession(backend Home, app, stage string, data interface{}) error {
+	return putData(backend, "session", app, stage, false, data)
+}
+
+func RemoveSession(backend Home, app, stage string) error {
+	slog.Info("removing session", "app", app, "stage", stage)
+	return removeData(backend, "session", app, stage)
+}
+
+// GetManifest reads the manifest of the last run of a stage.
+func GetManifest(backend Home, app, stage string, out interface{}) error {
+	return getData(backend, "manifest", app, stage, false, out)
+}
+
+// PutManifest writes the manifest of a run. It only holds digests so it is
+// stored unencrypted, readable by auditors without the passphrase.
+func PutManifest(backend Home, app, stage string, data interface{}) error {
+	slog.Info("putting manifest", "app", app, "stage", stage)
+	return putData(backend, "manifest", app, stage, false, data)
+}
+
+// BinarySecretPrefix marks secret values that are not valid text and are
+// stored base64 encoded.
+const BinarySecretPrefix = "sst:base64:"
+
+// EncodeSecret turns raw bytes into a secret value. Text, including
+// multiline text, is kept as is.
+func EncodeSecret(value []byte) string {
+	if utf8.Valid(value) && !bytes.ContainsRune(value, 0) {
+		return string(value)
+	}
+	return BinarySecretPrefix + base64.StdEncoding.EncodeToString(value)
+}
+
+// FallbackStage holds secrets shared by every stage of an app.
+const FallbackStage = "_fallback"
+
+// GetSecrets returns the secrets for a stage merged on top of the secrets of
+// the fallback stage.
 func GetSecrets(backend Home, app, stage string) (map[string]string, error) {
+	secrets, _, err := GetSecretsAndExpired(backend, app, stage)
+	return secrets, err
+}
+
+// GetSecretsAndExpired returns the secrets like GetSecrets, along with the
+// names of the ones that are set but left out because they expired.
+func GetSecretsAndExpired(backend Home, app, stage string) (map[string]string, []string, error) {
+	now := time.Now()
+	stages := []string{stage}
+	if stage != FallbackStage {
+		stages = []string{FallbackStage, stage}
+	}
+	data := map[string]string{}
+	expired := map[string]bool{}
+	for _, item := range stages {
+		secrets, names, err := liveStageSecrets(backend, app, item, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		for key, value := range secrets {
+			data[key] = value
+		}
+		for _, name := range names {
+			expired[name] = true
+		}
+	}
+	result := []string{}
+	for key := range expired {
+		if _, ok := data[key]; !ok {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return data, result, nil
+}
+
+// GetStageSecrets returns only the secrets set on the stage itself. Every
+// read is audited, including the ones served from the cache.
+func GetStageSecrets(backend Home, app, stage string) (map[string]string, error) {
+	data, err := readStageSecrets(backend, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	audit(backend, app, stage, AuditSecretRead, "")
+	return data, nil
+}
+
+// readStageSecrets reads the secrets of a stage without recording it in the
+// audit log, for the checks that don't hand them to anyone.
+func readStageSecrets(backend Home, app, stage string) (map[string]string, error) {
+	if cached, ok := cachedSecrets(backend, app, stage); ok {
+		return copySecrets(cached), nil
+	}
 	data := map[string]string{}
 	err := getData(backend, "secret", app, stage, true, &data)
 	if err != nil {
 		return nil, err
 	}
-	return data, err
+	cacheSecrets(backend, app, stage, copySecrets(data))
+	return data, nil
+}
+
+func copySecrets(secrets map[string]string) map[string]string {
+	result := make(map[string]string, len(secrets))
+	for key, value := range secrets {
+		result[key] = value
+	}
+	return result
+}
+
+const RedactedValue = "********"
+
+// ListSecrets returns the secrets for a stage. Unless showValues is set the
+// values are replaced with RedactedValue so only the keys are exposed.
+func ListSecrets(backend Home, app, stage string, showValues bool) (map[string]string, error) {
+	secrets, err := GetSecrets(backend, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	if !showValues {
+		for key := range secrets {
+			secrets[key] = RedactedValue
+		}
+	}
+	return secrets, nil
 }
 
 func PutSecrets(backend Home, app, stage string, data map[string]string) error {
@@ -127,21 +443,63 @@ func PutSecrets(backend Home, app, stage string, data map[string]string) error {
 	if data == nil {
 		return nil
 	}
-	return putData(backend, "secret", app, stage, true, data)
+	InvalidateSecrets(backend, app, stage)
+	err := putData(backend, "secret", app, stage, true, data)
+	if err != nil {
+		return err
+	}
+	audit(backend, app, stage, AuditSecretWrite, "")
+	return nil
 }
 
-func PushState(backend Home, app, stage string, from string) error {
+// PushState uploads the state file at from. onProgress can be nil.
+func PushState(backend Home, app, stage string, from string, onProgress func(TransferProgress)) error {
 	slog.Info("pushing state", "app", app, "stage", stage, "from", from)
 	file, err := os.Open(from)
 	if err != nil {
 		return nil
 	}
-	return backend.putData("app", app, stage, file)
+	defer file.Close()
+	if onProgress == nil {
+		err = backend.putData("app", app, stage, file)
+	} else {
+		var info os.FileInfo
+		info, err = file.Stat()
+		if err != nil {
+			return err
+		}
+		reader := newProgressReader(file, TransferPush, info.Size(), onProgress)
+		err = backend.putData("app", app, stage, reader)
+		if err == nil {
+			reader.done()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	audit(backend, app, stage, AuditStatePush, "")
+	return nil
 }
 
 var ErrStateNotFound = fmt.Errorf("state not found")
 
-func PullState(backend Home, app, stage string, out string) error {
+// PullState downloads the state to out. onProgress can be nil.
+func PullState(backend Home, app, stage string, out string, onProgress func(TransferProgress)) error {
+	err := pullState(backend, app, stage, out, onProgress)
+	if err != nil {
+		return err
+	}
+	audit(backend, app, stage, AuditStatePull, "")
+	return nil
+}
+
+// InspectState writes the state of a stage to out without recording a pull
+// in the audit log, for commands that only look at it like a dry run.
+func InspectState(backend Home, app, stage string, out string) error {
+	return pullState(backend, app, stage, out, nil)
+}
+
+func pullState(backend Home, app, stage string, out string, onProgress func(TransferProgress)) error {
 	slog.Info("pulling state", "app", app, "stage", stage, "out", out)
 	reader, err := backend.getData("app", app, stage)
 	if err != nil {
@@ -150,16 +508,124 @@ func PullState(backend Home, app, stage string, out string) error {
 	if reader == nil {
 		return ErrStateNotFound
 	}
+	if closer, ok := reader.(io.Closer); ok {
+		defer closer.Close()
+	}
 	file, err := os.Create(out)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	_, err = io.Copy(file, reader)
+	if onProgress == nil {
+		_, err = io.Copy(file, reader)
+	} else {
+		var total int64
+		if s, ok := reader.(sized); ok {
+			total = s.Size()
+		}
+		progress := newProgressReader(reader, TransferPull, total, onProgress)
+		_, err = io.Copy(file, progress)
+		if err == nil {
+			progress.done()
+		}
+	}
+	return err
+}
+
+var ErrVersioningNotSupported = fmt.Errorf("home provider does not support versioned state")
+
+// ListStateVersions returns the versions of the state for a stage, newest first.
+func ListStateVersions(backend Home, app, stage string) ([]Version, error) {
+	slog.Info("listing state versions", "app", app, "stage", stage)
+	versioned, ok := backend.(VersionedHome)
+	if !ok {
+		return nil, ErrVersioningNotSupported
+	}
+	return versioned.listVersions("app", app, stage)
+}
+
+// PullStateVersion reads the state for a stage as it was at the given version.
+func PullStateVersion(backend Home, app, stage, version string) ([]byte, error) {
+	slog.Info("pulling state version", "app", app, "stage", stage, "version", version)
+	versioned, ok := backend.(VersionedHome)
+	if !ok {
+		return nil, ErrVersioningNotSupported
+	}
+	reader, err := versioned.getDataVersion("app", app, stage, version)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	if reader == nil {
+		return nil, ErrStateNotFound
+	}
+	audit(backend, app, stage, AuditStatePull, "version "+version)
+	return io.ReadAll(reader)
+}
+
+var ErrSearchNotSupported = fmt.Errorf("home provider does not support searching stages")
+
+// GetTags reads the tags of a stage.
+func GetTags(backend Home, app, stage string) (map[string]string, error) {
+	tags := map[string]string{}
+	err := getData(backend, "tags", app, stage, false, &tags)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// PutTags writes the tags of a stage. They are stored unencrypted so stages
+// can be searched without their passphrase.
+func PutTags(backend Home, app, stage string, tags map[string]string) error {
+	slog.Info("putting tags", "app", app, "stage", stage)
+	return putData(backend, "tags", app, stage, false, tags)
+}
+
+func RemoveTags(backend Home, app, stage string) error {
+	slog.Info("removing tags", "app", app, "stage", stage)
+	return backend.removeData("tags", app, stage)
+}
+
+type TaggedStage struct {
+	App   string            `json:"app"`
+	Stage string            `json:"stage"`
+	Tags  map[string]string `json:"tags"`
+}
+
+// SearchTags returns every stage in the home, across apps, whose tags match
+// all of filter. An empty filter value matches any value.
+func SearchTags(backend Home, filter map[string]string) ([]TaggedStage, error) {
+	slog.Info("searching tags", "filter", filter)
+	searchable, ok := backend.(SearchableHome)
+	if !ok {
+		return nil, ErrSearchNotSupported
+	}
+	refs, err := searchable.listStages("tags")
+	if err != nil {
+		return nil, err
+	}
+	result := []TaggedStage{}
+	for _, ref := range refs {
+		tags, err := GetTags(backend, ref.App, ref.Stage)
+		if err != nil {
+			return nil, err
+		}
+		if !matchTags(tags, filter) {
+			continue
+		}
+		result = append(result, TaggedStage{App: ref.App, Stage: ref.Stage, Tags: tags})
+	}
+	return result, nil
+}
+
+func matchTags(tags, filter map[string]string) bool {
+	for key, value := range filter {
+		actual, ok := tags[key]
+		if !ok || (value != "" && actual != value) {
+			return false
+		}
+	}
+	return true
 }
 
 type lockData struct {
@@ -184,11 +650,63 @@ func Lock(backend Home, app, stage string) error {
 	return nil
 }
 
+// LockedAt returns when the stage was locked, or a zero time if it isn't,
+// without taking the lock.
+func LockedAt(backend Home, app, stage string) (time.Time, error) {
+	var lockData lockData
+	err := getData(backend, "lock", app, stage, false, &lockData)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return lockData.Created, nil
+}
+
 func Unlock(backend Home, app, stage string) error {
 	slog.Info("unlocking", "app", app, "stage", stage)
 	return removeData(backend, "lock", app, stage)
 }
 
+func stageCipher(backend Home, app, stage string) (cipher.AEAD, error) {
+	passphrase, err := Passphrase(backend, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	passphraseBytes, err := base64.StdEncoding.DecodeString(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	blockCipher, err := aes.NewCipher(passphraseBytes)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(blockCipher)
+}
+
+// encryptData encrypts data with the passphrase of the stage.
+func encryptData(backend Home, app, stage string, data []byte) ([]byte, error) {
+	gcm, err := stageCipher(backend, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+func decryptData(backend Home, app, stage string, data []byte) ([]byte, error) {
+	gcm, err := stageCipher(backend, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
 func putData(backend Home, key, app, stage string, encrypt bool, data interface{}) error {
 	slog.Info("putting data", "key", key, "app", app, "stage", stage)
 	jsonBytes, err := json.Marshal(data)
@@ -196,27 +714,10 @@ func putData(backend Home, key, app, stage string, encrypt bool, data interface{
 		return err
 	}
 	if encrypt {
-		passphrase, err := Passphrase(backend, app, stage)
-		if err != nil {
-			return err
-		}
-		passphraseBytes, err := base64.StdEncoding.DecodeString(passphrase)
-		if err != nil {
-			return err
-		}
-		blockCipher, err := aes.NewCipher(passphraseBytes)
+		jsonBytes, err = encryptData(backend, app, stage, jsonBytes)
 		if err != nil {
 			return err
 		}
-		gcm, err := cipher.NewGCM(blockCipher)
-		if err != nil {
-			return err
-		}
-		nonce := make([]byte, gcm.NonceSize())
-		if _, err = rand.Read(nonce); err != nil {
-			return err
-		}
-		jsonBytes = gcm.Seal(nonce, nonce, jsonBytes, nil)
 	}
 	return backend.putData(key, app, stage, bytes.NewReader(jsonBytes))
 }
@@ -237,26 +738,7 @@ func getData(backend Home, key, app, stage string, encrypted bool, out interface
 	}
 
 	if encrypted {
-		passphrase, err := Passphrase(backend, app, stage)
-		if err != nil {
-			return err
-		}
-		passphraseBytes, err := base64.StdEncoding.DecodeString(passphrase)
-		if err != nil {
-			return err
-		}
-		blockCipher, err := aes.NewCipher(passphraseBytes)
-		if err != nil {
-			return err
-		}
-		gcm, err := cipher.NewGCM(blockCipher)
-		if err != nil {
-			return err
-		}
-
-		nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
-
-		data, err = gcm.Open(nil, nonce, ciphertext, nil)
+		data, err = decryptData(backend, app, stage, data)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"log/slog"
+	"time"
+)
+
+// SecretMetadata describes a secret. It's stored next to the secrets of a
+// stage, encrypted with the same passphrase.
+type SecretMetadata struct {
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	// RotateBy is the deadline for setting a new value
+	RotateBy *time.Time `json:"rotateBy,omitempty"`
+	// RotateEvery moves RotateBy forward every time a new value is set
+	RotateEvery time.Duration `json:"rotateEvery,omitempty"`
+	// Expires is when the secret stops being returned by GetSecrets
+	Expires *time.Time `json:"expires,omitempty"`
+	Updated time.Time  `json:"updated"`
+}
+
+// Expired returns whether the secret is past its expiry.
+func (m SecretMetadata) Expired(now time.Time) bool {
+	return m.Expires != nil && !now.Before(*m.Expires)
+}
+
+// Overdue returns whether the rotation deadline has passed.
+func (m SecretMetadata) Overdue(now time.Time) bool {
+	return m.RotateBy != nil && now.After(*m.RotateBy)
+}
+
+// Rotated records that a new value was set at now.
+func (m *SecretMetadata) Rotated(now time.Time) {
+	m.Updated = now
+	if m.RotateEvery > 0 {
+		next := now.Add(m.RotateEvery)
+		m.RotateBy = &next
+	}
+}
+
+// GetSecretMetadata returns the metadata of the secrets of a stage merged
+// on top of the metadata of the fallback stage, like GetSecrets.
+func GetSecretMetadata(backend Home, app, stage string) (map[string]SecretMetadata, error) {
+	data := map[string]SecretMetadata{}
+	if stage != FallbackStage {
+		fallback, err := GetStageSecretMetadata(backend, app, FallbackStage)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fallback {
+			data[key] = value
+		}
+	}
+	metadata, err := GetStageSecretMetadata(backend, app, stage)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range metadata {
+		data[key] = value
+	}
+	return data, nil
+}
+
+// GetStageSecretMetadata returns only the metadata set on the stage itself.
+func GetStageSecretMetadata(backend Home, app, stage string) (map[string]SecretMetadata, error) {
+	data := map[string]SecretMetadata{}
+	err := getData(backend, "secret-meta", app, stage, true, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func PutSecretMetadata(backend Home, app, stage string, data map[string]SecretMetadata) error {
+	slog.Info("putting secret metadata", "app", app, "stage", stage)
+	if data == nil {
+		return nil
+	}
+	return putData(backend, "secret-meta", app, stage, true, data)
+}
+
+// liveStageSecrets returns the secrets set on the stage itself that haven't
+// expired, and the names of the ones that have.
+func liveStageSecrets(backend Home, app, stage string, now time.Time) (map[string]string, []string, error) {
+	secrets, err := GetStageSecrets(backend, app, stage)
+	if err != nil || len(secrets) == 0 {
+		return secrets, nil, err
+	}
+	metadata, err := GetStageSecretMetadata(backend, app, stage)
+	if err != nil {
+		return nil, nil, err
+	}
+	expired := []string{}
+	for key, meta := range metadata {
+		if _, ok := secrets[key]; ok && meta.Expired(now) {
+			delete(secrets, key)
+			expired = append(expired, key)
+		}
+	}
+	return secrets, expired, nil
+}
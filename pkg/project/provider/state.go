@@ -0,0 +1,60 @@
+package provider
+
+import (
+	"sync"
+	"time"
+)
+
+// homeState is what the package keeps about a home provider between calls,
+// like the passphrases it already read. It's embedded in every provider so
+// two projects loaded in the same program don't share it.
+type homeState struct {
+	mu sync.Mutex
+	// namespace prefixes every path the home reads and writes
+	namespace string
+	// passphraseKey derives the passphrases instead of storing them
+	passphraseKey PassphraseKey
+	passphrases   map[string]string
+
+	// the secrets cache has its own lock since reading and writing it needs
+	// the passphrase
+	secretsMu sync.Mutex
+	// secretsTTL is 0 while the secrets cache is disabled
+	secretsTTL time.Duration
+	// secretsDir holds an on-disk cache, encrypted with the stage
+	// passphrase, that survives restarts and is shared with other processes
+	secretsDir string
+	secrets    map[string]*secretsCacheEntry
+}
+
+func (s *homeState) state() *homeState {
+	return s
+}
+
+func (s *homeState) cachedPassphrase(app, stage string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	passphrase, ok := s.passphrases[app+stage]
+	return passphrase, ok
+}
+
+func (s *homeState) cachePassphrase(app, stage, passphrase string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.passphrases == nil {
+		s.passphrases = map[string]string{}
+	}
+	s.passphrases[app+stage] = passphrase
+}
+
+func (s *homeState) getNamespace() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.namespace
+}
+
+func (s *homeState) getPassphraseKey() (PassphraseKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.passphraseKey, s.passphraseKey != nil
+}
@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+type TransferDirection string
+
+const (
+	TransferPull TransferDirection = "pull"
+	TransferPush TransferDirection = "push"
+)
+
+type TransferProgress struct {
+	Direction TransferDirection
+	Bytes     int64
+	// Total is 0 when the size isn't known upfront
+	Total int64
+	ETA   time.Duration
+	Done  bool
+}
+
+func (p TransferProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Bytes) / float64(p.Total) * 100
+}
+
+// sized is implemented by readers returned from getData that know the size
+// of the object upfront.
+type sized interface {
+	Size() int64
+}
+
+// progress are only reported this often so large transfers don't flood
+// the event stream
+const progressInterval = 250 * time.Millisecond
+
+type progressReader struct {
+	reader     io.Reader
+	progress   TransferProgress
+	onProgress func(TransferProgress)
+	started    time.Time
+	reported   time.Time
+}
+
+func newProgressReader(reader io.Reader, direction TransferDirection, total int64, onProgress func(TransferProgress)) *progressReader {
+	return &progressReader{
+		reader:     reader,
+		progress:   TransferProgress{Direction: direction, Total: total},
+		onProgress: onProgress,
+		started:    time.Now(),
+	}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.progress.Bytes += int64(n)
+	if time.Since(p.reported) >= progressInterval {
+		p.report()
+	}
+	return n, err
+}
+
+// Seek is passed through so uploads can still sign the body, the count
+// follows the position.
+func (p *progressReader) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := p.reader.(io.Seeker)
+	if !ok {
+		return 0, errors.New("reader is not seekable")
+	}
+	position, err := seeker.Seek(offset, whence)
+	if err == nil {
+		p.progress.Bytes = position
+	}
+	return position, err
+}
+
+func (p *progressReader) report() {
+	p.reported = time.Now()
+	elapsed := time.Since(p.started)
+	if p.progress.Total > 0 && p.progress.Bytes > 0 {
+		rate := float64(p.progress.Bytes) / elapsed.Seconds()
+		remaining := float64(p.progress.Total - p.progress.Bytes)
+		p.progress.ETA = time.Duration(remaining / rate * float64(time.Second))
+	}
+	p.onProgress(p.progress)
+}
+
+func (p *progressReader) done() {
+	p.progress.Done = true
+	p.progress.ETA = 0
+	p.onProgress(p.progress)
+}
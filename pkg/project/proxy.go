@@ -0,0 +1,95 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Proxy routes outbound traffic through an HTTP proxy. Anything not set
+// falls back to the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment
+// variables, which are respected without any config.
+type Proxy struct {
+	HTTP    string   `json:"http,omitempty"`
+	HTTPS   string   `json:"https,omitempty"`
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+func (p *Proxy) validate() error {
+	for _, value := range []string{p.HTTP, p.HTTPS} {
+		if value == "" {
+			continue
+		}
+		parsed, err := url.Parse(value)
+		if err != nil || parsed.Host == "" {
+			return fmt.Errorf("Proxy %q is not a valid URL", value)
+		}
+		switch parsed.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf(`Proxy %q must use "http", "https", or "socks5"`, value)
+		}
+	}
+	return nil
+}
+
+// proxyConfig merges the proxy from the config over the environment.
+func proxyConfig(proxy *Proxy) *httpproxy.Config {
+	config := httpproxy.FromEnvironment()
+	if proxy == nil {
+		return config
+	}
+	if proxy.HTTP != "" {
+		config.HTTPProxy = proxy.HTTP
+	}
+	if proxy.HTTPS != "" {
+		config.HTTPSProxy = proxy.HTTPS
+	}
+	if len(proxy.NoProxy) > 0 {
+		config.NoProxy = strings.Join(proxy.NoProxy, ",")
+	}
+	return config
+}
+
+// useProxy makes the requests of the CLI itself go through the proxy from
+// the config. Clients that read the environment, like the AWS SDK, pick it
+// up from the variables as long as they're set before the first request.
+func useProxy(proxy *Proxy) {
+	for key, value := range proxyEnv(proxy) {
+		os.Setenv(key, value)
+	}
+	resolve := proxyConfig(proxy).ProxyFunc()
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return resolve(req.URL)
+		}
+	}
+}
+
+// proxyEnv are the variables that point child processes, like Pulumi, the
+// providers, and node, at the proxy. They're passed even when the host
+// environment is filtered.
+func proxyEnv(proxy *Proxy) map[string]string {
+	config := proxyConfig(proxy)
+	result := map[string]string{}
+	if config.HTTPProxy == "" && config.HTTPSProxy == "" {
+		return result
+	}
+	set := func(key string, value string) {
+		if value == "" {
+			return
+		}
+		result[key] = value
+		result[strings.ToLower(key)] = value
+	}
+	set("HTTP_PROXY", config.HTTPProxy)
+	set("HTTPS_PROXY", config.HTTPSProxy)
+	set("NO_PROXY", config.NoProxy)
+	// node only uses the variables for fetch and http when asked to
+	result["NODE_USE_ENV_PROXY"] = "1"
+	return result
+}
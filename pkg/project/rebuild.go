@@ -0,0 +1,154 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/sst/ion/pkg/js"
+)
+
+// ConfigRebuildStartedEvent is sent in dev before the config is rebuilt,
+// with the files that changed since the last build, relative to the root.
+type ConfigRebuildStartedEvent struct {
+	Changed []string
+}
+
+// ConfigRebuildFinishedEvent is sent in dev once the config is rebuilt.
+type ConfigRebuildFinishedEvent struct {
+	Duration time.Duration
+	// Skipped is true if none of the inputs of the last build changed and
+	// it was reused
+	Skipped bool
+	Errors  []string
+	// NewErrors and FixedErrors compare Errors to the last build
+	NewErrors   []string
+	FixedErrors []string
+}
+
+// ReloadEvent is sent in dev when a file the config is built from changed
+// and the stage is deployed again in the same session. InFlight is true if
+// a deploy is running, the change is applied once it's done.
+type ReloadEvent struct {
+	Path     string
+	InFlight bool
+}
+
+// devBuild is the last build of the config in dev.
+type devBuild struct {
+	key     string
+	program *program
+	inputs  map[string]string
+	errors  []string
+}
+
+// rebuildProgram rebuilds the config with the incremental builder, unless
+// nothing it was built from changed since the last build.
+func (s *Stack) rebuildProgram(input *StackInput, options js.EvalOptions) (*program, error) {
+	key, err := js.OptionsKey(options, "")
+	if err != nil {
+		return nil, err
+	}
+	last := s.lastBuild
+	changed := []string{}
+	lastErrors := []string{}
+	if last != nil {
+		changed = changedInputs(last.inputs, s.hashSources(fileList(last.program)))
+		lastErrors = last.errors
+	}
+	input.OnEvent(&StackEvent{ConfigRebuildStartedEvent: &ConfigRebuildStartedEvent{Changed: changed}})
+	started := time.Now()
+
+	if last != nil && last.program != nil && last.key == key && len(changed) == 0 && len(last.errors) == 0 {
+		input.OnEvent(&StackEvent{ConfigRebuildFinishedEvent: &ConfigRebuildFinishedEvent{
+			Duration:    time.Since(started),
+			Skipped:     true,
+			Errors:      []string{},
+			NewErrors:   []string{},
+			FixedErrors: []string{},
+		}})
+		return last.program, nil
+	}
+
+	buildResult, buildErr := s.builder.Build(options)
+	errors := formatMessages(buildResult.Errors)
+	if buildErr != nil && len(errors) == 0 {
+		errors = []string{buildErr.Error()}
+	}
+	input.OnEvent(&StackEvent{ConfigRebuildFinishedEvent: &ConfigRebuildFinishedEvent{
+		Duration:    time.Since(started),
+		Errors:      errors,
+		NewErrors:   difference(errors, lastErrors),
+		FixedErrors: difference(lastErrors, errors),
+	}})
+	if buildErr != nil {
+		// the inputs stay the ones of the last good build so the next
+		// rebuild reports what changed since then
+		next := &devBuild{key: key, errors: errors}
+		if last != nil {
+			next.program = last.program
+			next.inputs = last.inputs
+		}
+		s.lastBuild = next
+		return nil, buildErr
+	}
+
+	prog, err := newProgram(buildResult)
+	if err != nil {
+		return nil, err
+	}
+	s.lastBuild = &devBuild{
+		key:     key,
+		program: prog,
+		inputs:  s.hashSources(prog.files),
+		errors:  []string{},
+	}
+	return prog, nil
+}
+
+func fileList(prog *program) []string {
+	if prog == nil {
+		return nil
+	}
+	return prog.files
+}
+
+// changedInputs returns the inputs that were changed or removed.
+func changedInputs(before map[string]string, after map[string]string) []string {
+	result := []string{}
+	for rel, hash := range before {
+		if after[rel] != hash {
+			result = append(result, rel)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func formatMessages(messages []esbuild.Message) []string {
+	result := []string{}
+	for _, message := range messages {
+		if message.Location == nil {
+			result = append(result, message.Text)
+			continue
+		}
+		result = append(result, fmt.Sprintf("%s:%d:%d: %s", message.Location.File, message.Location.Line, message.Location.Column, message.Text))
+	}
+	return result
+}
+
+// difference returns what's in a but not in b.
+func difference(a []string, b []string) []string {
+	seen := map[string]bool{}
+	for _, item := range b {
+		seen[item] = true
+	}
+	result := []string{}
+	for _, item := range a {
+		if !seen[item] {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package project
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// redactedOutput replaces the outputs that were secrets in the state.
+const redactedOutput = "[secret]"
+
+// Redacted returns a copy of the event that can leave the CLI, like through
+// --json or the exporters. The CompleteEvent has the decrypted links and
+// outputs, so they are redacted the way the UI leaves them out. Every other
+// event is returned as is.
+func (e *StackEvent) Redacted() *StackEvent {
+	if e.CompleteEvent == nil {
+		return e
+	}
+	next := *e
+	next.CompleteEvent = e.CompleteEvent.Redacted()
+	return &next
+}
+
+// Redacted returns a copy of the event with the values of the links, the
+// secret outputs, and the environment of the functions replaced. The names
+// are kept.
+func (c *CompleteEvent) Redacted() *CompleteEvent {
+	next := *c
+	next.Links = Links{}
+	for key := range c.Links {
+		next.Links[key] = provider.RedactedValue
+	}
+	next.Outputs = map[string]interface{}{}
+	for key, value := range c.Outputs {
+		if c.secretOutputs[key] {
+			value = redactedOutput
+		}
+		next.Outputs[key] = value
+	}
+	next.Warps = Warps{}
+	for key, warp := range c.Warps {
+		warp.Environment = redactEnvironment(warp.Environment)
+		next.Warps[key] = warp
+	}
+	next.Receivers = Receivers{}
+	for key, receiver := range c.Receivers {
+		receiver.Environment = redactEnvironment(receiver.Environment)
+		next.Receivers[key] = receiver
+	}
+	next.DevCommands = DevCommands{}
+	for key, command := range c.DevCommands {
+		command.Environment = redactEnvironment(command.Environment)
+		next.DevCommands[key] = command
+	}
+	// the outputs of the stack were decrypted in place, they're the links
+	// and outputs above
+	next.Resources = make([]apitype.ResourceV3, len(c.Resources))
+	for i, resource := range c.Resources {
+		if resource.Type == "pulumi:pulumi:Stack" {
+			resource.Outputs = nil
+		}
+		next.Resources[i] = resource
+	}
+	return &next
+}
+
+func redactEnvironment(env map[string]string) map[string]string {
+	if env == nil {
+		return nil
+	}
+	result := make(map[string]string, len(env))
+	for key := range env {
+		result[key] = provider.RedactedValue
+	}
+	return result
+}
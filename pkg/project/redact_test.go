@@ -0,0 +1,42 @@
+package project
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+func TestCompleteEventRedacted(t *testing.T) {
+	complete := &CompleteEvent{
+		Links: Links{"Database": map[string]interface{}{"password": "hunter2"}},
+		Outputs: map[string]interface{}{
+			"url":   "https://example.com",
+			"token": "plaintext-token",
+		},
+		Warps: Warps{"Api": Warp{Handler: "index.handler", Environment: map[string]string{"API_KEY": "warp-secret"}}},
+		Resources: []apitype.ResourceV3{
+			{Type: "pulumi:pulumi:Stack", Outputs: map[string]interface{}{"_links": "hunter2"}},
+			{Type: "aws:s3/bucket:Bucket", Outputs: map[string]interface{}{"bucket": "my-bucket"}},
+		},
+		secretOutputs: map[string]bool{"token": true},
+	}
+	data, err := json.Marshal((&StackEvent{CompleteEvent: complete}).Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leaked := range []string{"hunter2", "plaintext-token", "warp-secret"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("redacted event contains %q: %s", leaked, data)
+		}
+	}
+	for _, kept := range []string{"Database", "https://example.com", "API_KEY", "my-bucket"} {
+		if !strings.Contains(string(data), kept) {
+			t.Errorf("redacted event is missing %q: %s", kept, data)
+		}
+	}
+	if complete.Links["Database"].(map[string]interface{})["password"] != "hunter2" || complete.Resources[0].Outputs == nil {
+		t.Errorf("the original event was changed")
+	}
+}
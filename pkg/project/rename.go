@@ -0,0 +1,352 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// urnRewriter moves URNs from one app and stage to another. URNs have the
+// form urn:pulumi:<stage>::<app>::<type>::<name>, so only the prefix changes
+// except for the root stack resource which is named after both.
+type urnRewriter struct {
+	from provider.StageRef
+	to   provider.StageRef
+}
+
+func urnPrefix(ref provider.StageRef) string {
+	return "urn:pulumi:" + ref.Stage + "::" + ref.App + "::"
+}
+
+func rootStackName(ref provider.StageRef) string {
+	return "pulumi:pulumi:Stack::" + ref.App + "-" + ref.Stage
+}
+
+func (r *urnRewriter) urn(urn string) string {
+	rest, ok := strings.CutPrefix(urn, urnPrefix(r.from))
+	if !ok {
+		return urn
+	}
+	if rest == rootStackName(r.from) {
+		rest = rootStackName(r.to)
+	}
+	return urnPrefix(r.to) + rest
+}
+
+// provider references are a URN followed by ::<id>
+func (r *urnRewriter) providerRef(ref string) string {
+	index := strings.LastIndex(ref, "::")
+	if index == -1 {
+		return ref
+	}
+	return r.urn(ref[:index]) + ref[index:]
+}
+
+func (r *urnRewriter) urns(value interface{}) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return
+	}
+	for i, item := range list {
+		if urn, ok := item.(string); ok {
+			list[i] = r.urn(urn)
+		}
+	}
+}
+
+func (r *urnRewriter) resource(resource map[string]interface{}) {
+	for _, key := range []string{"urn", "parent", "deletedWith"} {
+		if urn, ok := resource[key].(string); ok {
+			resource[key] = r.urn(urn)
+		}
+	}
+	if ref, ok := resource["provider"].(string); ok {
+		resource["provider"] = r.providerRef(ref)
+	}
+	r.urns(resource["dependencies"])
+	r.urns(resource["aliases"])
+	if deps, ok := resource["propertyDependencies"].(map[string]interface{}); ok {
+		for _, urns := range deps {
+			r.urns(urns)
+		}
+	}
+}
+
+// deploymentResources returns every resource in a checkpoint, including the
+// ones in pending operations. The state is decoded generically so fields
+// this version doesn't know about are kept.
+func deploymentResources(checkpoint map[string]interface{}) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	latest, ok := checkpoint["latest"].(map[string]interface{})
+	if !ok {
+		return result
+	}
+	if resources, ok := latest["resources"].([]interface{}); ok {
+		for _, item := range resources {
+			if resource, ok := item.(map[string]interface{}); ok {
+				result = append(result, resource)
+			}
+		}
+	}
+	if operations, ok := latest["pending_operations"].([]interface{}); ok {
+		for _, item := range operations {
+			operation, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if resource, ok := operation["resource"].(map[string]interface{}); ok {
+				result = append(result, resource)
+			}
+		}
+	}
+	return result
+}
+
+type versionedState struct {
+	Version    int                    `json:"version"`
+	Checkpoint map[string]interface{} `json:"checkpoint"`
+}
+
+// rewriteState rewrites every URN in the state file to the new app and stage.
+func (r *urnRewriter) rewriteState(data []byte) ([]byte, error) {
+	var state versionedState
+	err := json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, err
+	}
+	if state.Checkpoint == nil {
+		return nil, fmt.Errorf("state has no checkpoint")
+	}
+	// the stack is named organization/<app>/<stage> by the file backend
+	if name, ok := state.Checkpoint["stack"].(string); ok {
+		parts := strings.Split(name, "/")
+		if len(parts) == 3 {
+			parts[1] = r.to.App
+		}
+		parts[len(parts)-1] = r.to.Stage
+		state.Checkpoint["stack"] = strings.Join(parts, "/")
+	}
+	for _, resource := range deploymentResources(state.Checkpoint) {
+		r.resource(resource)
+	}
+	return json.MarshalIndent(state, "", "    ")
+}
+
+// validateState checks that the moved state has every resource of the
+// original and that no URN still points at the old app or stage.
+func (r *urnRewriter) validateState(original, moved []byte) error {
+	var before, after versionedState
+	if err := json.Unmarshal(original, &before); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(moved, &after); err != nil {
+		return err
+	}
+	if len(deploymentResources(before.Checkpoint)) != len(deploymentResources(after.Checkpoint)) {
+		return fmt.Errorf("moved state has a different number of resources")
+	}
+	// rewriting again is a no-op unless a reference was missed
+	check := &urnRewriter{from: r.from, to: provider.StageRef{}}
+	for _, resource := range deploymentResources(after.Checkpoint) {
+		encoded, err := json.Marshal(resource)
+		if err != nil {
+			return err
+		}
+		check.resource(resource)
+		rewritten, err := json.Marshal(resource)
+		if err != nil {
+			return err
+		}
+		if string(encoded) != string(rewritten) {
+			return fmt.Errorf("moved state still references %v in %v", urnPrefix(r.from), resource["urn"])
+		}
+	}
+	return nil
+}
+
+// RenameStage moves a deployed stage to a new name without touching the
+// deployed resources. The URNs in the state are rewritten and the state,
+// secrets, links, and metadata are moved in the home provider.
+func (p *Project) RenameStage(ctx context.Context, old, next string) error {
+	if err := ValidateStage(next); err != nil {
+		return err
+	}
+	if old == next {
+		return fmt.Errorf("stage is already named %v", next)
+	}
+	for _, stage := range []string{old, next} {
+		if err := p.checkReservedStage(stage); err != nil {
+			return err
+		}
+	}
+	rewriter := &urnRewriter{
+		from: provider.StageRef{App: p.app.Name, Stage: old},
+		to:   provider.StageRef{App: p.app.Name, Stage: next},
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return p.moveStage(rewriter, false)
+}
+
+type URNChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// StageMove is what changes in the home provider when a stage is moved.
+type StageMove struct {
+	From provider.StageRef `json:"from"`
+	To   provider.StageRef `json:"to"`
+	// Keys are the data keys that are moved
+	Keys []string    `json:"keys"`
+	URNs []URNChange `json:"urns"`
+}
+
+// urnChanges lists every resource URN that is changed by rewriting state.
+func (r *urnRewriter) urnChanges(state []byte) ([]URNChange, error) {
+	var parsed versionedState
+	err := json.Unmarshal(state, &parsed)
+	if err != nil {
+		return nil, err
+	}
+	result := []URNChange{}
+	for _, resource := range deploymentResources(parsed.Checkpoint) {
+		urn, ok := resource["urn"].(string)
+		if !ok {
+			continue
+		}
+		if next := r.urn(urn); next != urn {
+			result = append(result, URNChange{From: urn, To: next})
+		}
+	}
+	return result, nil
+}
+
+func (p *Project) planMove(rewriter *urnRewriter) (*StageMove, error) {
+	keys, err := provider.StageKeys(p.home, rewriter.from)
+	if err != nil {
+		return nil, err
+	}
+	move := &StageMove{
+		From: rewriter.from,
+		To:   rewriter.to,
+		Keys: keys,
+		URNs: []URNChange{},
+	}
+	dir, err := os.MkdirTemp(p.PathTempDir(), "sst-rename")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	// a dry run only looks at the state so it's not audited as a pull
+	err = provider.InspectState(p.home, rewriter.from.App, rewriter.from.Stage, path)
+	if errors.Is(err, provider.ErrStateNotFound) {
+		return move, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	state, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	move.URNs, err = rewriter.urnChanges(state)
+	if err != nil {
+		return nil, err
+	}
+	return move, nil
+}
+
+func (p *Project) appRewriters(next string) ([]*urnRewriter, error) {
+	if !AppRegex.MatchString(next) {
+		return nil, ErrInvalidAppName
+	}
+	if next == p.app.Name {
+		return nil, fmt.Errorf("app is already named %v", next)
+	}
+	stages, err := provider.ListAppStages(p.home, p.app.Name)
+	if err != nil {
+		return nil, err
+	}
+	result := []*urnRewriter{}
+	for _, stage := range stages {
+		result = append(result, &urnRewriter{
+			from: stage,
+			to:   provider.StageRef{App: next, Stage: stage.Stage},
+		})
+	}
+	return result, nil
+}
+
+// PlanRenameApp returns every key and URN that RenameApp would change,
+// without changing anything.
+func (p *Project) PlanRenameApp(ctx context.Context, next string) ([]StageMove, error) {
+	rewriters, err := p.appRewriters(next)
+	if err != nil {
+		return nil, err
+	}
+	result := []StageMove{}
+	for _, rewriter := range rewriters {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		move, err := p.planMove(rewriter)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *move)
+	}
+	return result, nil
+}
+
+func (p *Project) moveStage(rewriter *urnRewriter, allowMissingState bool) error {
+	var original []byte
+	return provider.MoveStage(p.home, rewriter.from, rewriter.to, provider.MoveInput{
+		Rewrite: func(state []byte) ([]byte, error) {
+			original = state
+			return rewriter.rewriteState(state)
+		},
+		Validate: func(state []byte) error {
+			return rewriter.validateState(original, state)
+		},
+		AllowMissingState: allowMissingState,
+	})
+}
+
+// RenameApp moves every stage of the app to a new app name. Either all the
+// stages are moved or, if one fails, the ones already moved are moved back.
+// The name in the config has to be changed to match afterwards.
+func (p *Project) RenameApp(ctx context.Context, next string) error {
+	rewriters, err := p.appRewriters(next)
+	if err != nil {
+		return err
+	}
+	moved := []*urnRewriter{}
+	for _, rewriter := range rewriters {
+		err = ctx.Err()
+		if err == nil {
+			err = p.moveStage(rewriter, true)
+		}
+		if err != nil {
+			for i := len(moved) - 1; i >= 0; i-- {
+				back := &urnRewriter{from: moved[i].to, to: moved[i].from}
+				if rollbackErr := p.moveStage(back, true); rollbackErr != nil {
+					slog.Error("failed to move stage back", "stage", back.to.Stage, "err", rollbackErr)
+					err = errors.Join(err, rollbackErr)
+				}
+			}
+			return err
+		}
+		moved = append(moved, rewriter)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package project
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+func TestRewriteState(t *testing.T) {
+	state := []byte(`{
+		"version": 3,
+		"checkpoint": {
+			"stack": "organization/app/dev",
+			"latest": {
+				"resources": [
+					{"urn": "urn:pulumi:dev::app::pulumi:pulumi:Stack::app-dev", "type": "pulumi:pulumi:Stack"},
+					{"urn": "urn:pulumi:dev::app::pulumi:providers:aws::default", "type": "pulumi:providers:aws"},
+					{
+						"urn": "urn:pulumi:dev::app::sst:aws:Bucket$aws:s3/bucketV2:BucketV2::MyBucket",
+						"parent": "urn:pulumi:dev::app::pulumi:pulumi:Stack::app-dev",
+						"provider": "urn:pulumi:dev::app::pulumi:providers:aws::default::04da6b54",
+						"dependencies": ["urn:pulumi:dev::app::pulumi:providers:aws::default"],
+						"propertyDependencies": {"bucket": ["urn:pulumi:dev::app::pulumi:providers:aws::default"]},
+						"outputs": {"bucket": "app-dev-mybucket"},
+						"sourcePosition": "project:///sst.config.ts#10"
+					}
+				]
+			}
+		}
+	}`)
+	rewriter := &urnRewriter{
+		from: provider.StageRef{App: "app", Stage: "dev"},
+		to:   provider.StageRef{App: "app", Stage: "staging"},
+	}
+	moved, err := rewriter.rewriteState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rewriter.validateState(state, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	var result versionedState
+	if err := json.Unmarshal(moved, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Checkpoint["stack"] != "organization/app/staging" {
+		t.Errorf("stack = %v", result.Checkpoint["stack"])
+	}
+	resources := deploymentResources(result.Checkpoint)
+	if resources[0]["urn"] != "urn:pulumi:staging::app::pulumi:pulumi:Stack::app-staging" {
+		t.Errorf("root urn = %v", resources[0]["urn"])
+	}
+	bucket := resources[2]
+	if bucket["parent"] != "urn:pulumi:staging::app::pulumi:pulumi:Stack::app-staging" {
+		t.Errorf("parent = %v", bucket["parent"])
+	}
+	if bucket["provider"] != "urn:pulumi:staging::app::pulumi:providers:aws::default::04da6b54" {
+		t.Errorf("provider = %v", bucket["provider"])
+	}
+	// physical names and unknown fields are left alone
+	if bucket["outputs"].(map[string]interface{})["bucket"] != "app-dev-mybucket" {
+		t.Errorf("outputs were rewritten")
+	}
+	if bucket["sourcePosition"] != "project:///sst.config.ts#10" {
+		t.Errorf("unknown field was dropped")
+	}
+
+	if err := rewriter.validateState(state, state); err == nil {
+		t.Errorf("expected validation to fail for state that was not rewritten")
+	}
+}
+
+func TestRewriteURNApp(t *testing.T) {
+	rewriter := &urnRewriter{
+		from: provider.StageRef{App: "app", Stage: "dev"},
+		to:   provider.StageRef{App: "web", Stage: "dev"},
+	}
+	cases := map[string]string{
+		"urn:pulumi:dev::app::pulumi:pulumi:Stack::app-dev":  "urn:pulumi:dev::web::pulumi:pulumi:Stack::web-dev",
+		"urn:pulumi:dev::app::sst:aws:Function::app":         "urn:pulumi:dev::web::sst:aws:Function::app",
+		"urn:pulumi:prod::app::sst:aws:Function::Api":        "urn:pulumi:prod::app::sst:aws:Function::Api",
+		"urn:pulumi:dev::application::sst:aws:Function::Api": "urn:pulumi:dev::application::sst:aws:Function::Api",
+	}
+	for input, expected := range cases {
+		if actual := rewriter.urn(input); actual != expected {
+			t.Errorf("urn(%v) = %v, want %v", input, actual, expected)
+		}
+	}
+}
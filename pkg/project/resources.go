@@ -0,0 +1,113 @@
+package project
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+func parseCheckpoint(data []byte) (*apitype.CheckpointV3, error) {
+	var versioned apitype.VersionedCheckpoint
+	err := json.Unmarshal(data, &versioned)
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint apitype.CheckpointV3
+	err = json.Unmarshal(versioned.Checkpoint, &checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// Resources returns the resources in the current state of the stage. The
+// state is downloaded to a temporary file so it can be read while the stage
+// is being deployed.
+func (s *Stack) Resources() ([]apitype.ResourceV3, error) {
+	checkpoint, err := s.checkpoint()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint.Latest == nil {
+		return []apitype.ResourceV3{}, nil
+	}
+	return checkpoint.Latest.Resources, nil
+}
+
+func (s *Stack) checkpoint() (*apitype.CheckpointV3, error) {
+	dir, err := os.MkdirTemp("", "sst-state")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	err = provider.PullState(s.project.home, s.project.app.Name, s.project.app.Stage, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseCheckpoint(data)
+}
+
+// DeployedFunction is the Lambda function a function component created.
+type DeployedFunction struct {
+	Name     string
+	Arn      string
+	Region   string
+	LogGroup string
+}
+
+// Functions returns the deployed Lambda functions of the stage by the name
+// of their function component, which is also their ID in dev.
+func (s *Stack) Functions() (map[string]DeployedFunction, error) {
+	resources, err := s.Resources()
+	if err != nil {
+		return nil, err
+	}
+	return deployedFunctions(resources), nil
+}
+
+func deployedFunctions(resources []apitype.ResourceV3) map[string]DeployedFunction {
+	components := map[string]string{}
+	for _, resource := range resources {
+		if resource.Type == "sst:aws:Function" {
+			components[string(resource.URN)] = resource.URN.Name()
+		}
+	}
+	logGroups := map[string]string{}
+	for _, resource := range resources {
+		if resource.Type == "aws:cloudwatch/logGroup:LogGroup" {
+			logGroups[string(resource.Parent)], _ = resource.Outputs["name"].(string)
+		}
+	}
+	result := map[string]DeployedFunction{}
+	for _, resource := range resources {
+		if resource.Type != "aws:lambda/function:Function" {
+			continue
+		}
+		name, ok := components[string(resource.Parent)]
+		if !ok {
+			continue
+		}
+		function := DeployedFunction{}
+		function.Name, _ = resource.Outputs["name"].(string)
+		function.Arn, _ = resource.Outputs["arn"].(string)
+		function.LogGroup = logGroups[string(resource.Parent)]
+		if function.LogGroup == "" && function.Name != "" {
+			function.LogGroup = "/aws/lambda/" + function.Name
+		}
+		// arn:aws:lambda:{region}:{account}:function:{name}
+		if parts := strings.Split(function.Arn, ":"); len(parts) > 3 {
+			function.Region = parts[3]
+		}
+		result[name] = function
+	}
+	return result
+}
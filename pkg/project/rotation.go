@@ -0,0 +1,46 @@
+package project
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+type OverdueSecret struct {
+	Name     string
+	Owner    string
+	RotateBy time.Time
+}
+
+// SecretRotationEvent is a warning that the deploy used secrets that are
+// past their rotation deadline.
+type SecretRotationEvent struct {
+	Secrets []OverdueSecret
+}
+
+// overdueSecrets returns the secrets used by the resources of the stack that
+// are past their rotation deadline.
+func overdueSecrets(resources []apitype.ResourceV3, metadata map[string]provider.SecretMetadata, now time.Time) []OverdueSecret {
+	result := []OverdueSecret{}
+	for _, resource := range resources {
+		if resource.Type != "sst:sst:Secret" {
+			continue
+		}
+		name := resource.URN.Name()
+		meta, ok := metadata[name]
+		if !ok || !meta.Overdue(now) {
+			continue
+		}
+		result = append(result, OverdueSecret{
+			Name:     name,
+			Owner:    meta.Owner,
+			RotateBy: *meta.RotateBy,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,179 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/pkg/global"
+)
+
+// Runner runs the Pulumi engine, and the program and builds it starts,
+// inside a container image. The CLI still pulls and pushes the state, holds
+// the lock, and reads the events.
+type Runner struct {
+	// Image needs to have pulumi and node installed, pinned to a tag or digest
+	Image string `json:"image"`
+	// Command is the container CLI, defaults to docker
+	Command string `json:"command"`
+}
+
+func (r *Runner) validate() error {
+	if r.Image == "" {
+		return fmt.Errorf(`The "runner" config needs an image`)
+	}
+	// the tag comes after the last slash, a colon before it is a registry port
+	name := r.Image[strings.LastIndex(r.Image, "/")+1:]
+	if !strings.Contains(r.Image, "@sha256:") && (!strings.Contains(name, ":") || strings.HasSuffix(name, ":latest")) {
+		return fmt.Errorf(`The runner image %q needs to be pinned to a tag or digest, not "latest"`, r.Image)
+	}
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("The container runner is not supported on Windows")
+	}
+	return nil
+}
+
+// env that describes the host and would break the container
+var runnerExcludedEnv = map[string]bool{
+	"PATH":   true,
+	"HOME":   true,
+	"USER":   true,
+	"SHELL":  true,
+	"PWD":    true,
+	"OLDPWD": true,
+	"TMPDIR": true,
+	"TEMP":   true,
+	"TMP":    true,
+}
+
+// set by the automation API on every pulumi command
+var runnerPulumiEnv = []string{
+	"PULUMI_HOME",
+	"PULUMI_SKIP_UPDATE_CHECK",
+	"PULUMI_AUTOMATION_API",
+	"PULUMI_DEBUG_COMMANDS",
+	"PULUMI_CONFIG_PASSPHRASE",
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// runnerMounts returns the host directories the container needs, mounted
+// at the same path so every path the CLI passes in still works.
+func (p *Project) runnerMounts() []string {
+	candidates := []string{
+		p.PathRoot(),
+		p.PathWorkingDir(),
+		p.PathBuildDir(),
+		p.PathOutputDir(),
+		p.PathTempDir(),
+		// the automation API writes the event log here
+		os.TempDir(),
+	}
+	sort.Strings(candidates)
+	result := []string{}
+	for _, dir := range candidates {
+		dir = filepath.Clean(dir)
+		if len(result) > 0 {
+			last := result[len(result)-1]
+			if dir == last || strings.HasPrefix(dir, last+string(filepath.Separator)) {
+				continue
+			}
+		}
+		result = append(result, dir)
+	}
+	return result
+}
+
+// runnerScript returns a pulumi executable that forwards its arguments and
+// the named env to the runner image.
+func (p *Project) runnerScript(env map[string]string) string {
+	runner := p.app.Runner
+	command := runner.Command
+	if command == "" {
+		command = "docker"
+	}
+	// plugins are binaries for the image's platform so they get their own
+	// Pulumi home, mounted where the CLI expects it
+	pulumiHome := filepath.Join(global.ConfigDir(), "runner", strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(runner.Image))
+
+	args := []string{
+		shellQuote(command), "run", "--rm", "-i",
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"-w", `"$(pwd)"`,
+		"-e", "HOME=/tmp",
+		"-v", shellQuote(pulumiHome + ":" + global.ConfigDir()),
+	}
+	for _, dir := range p.runnerMounts() {
+		args = append(args, "-v", shellQuote(dir+":"+dir))
+	}
+
+	keys := append([]string{}, runnerPulumiEnv...)
+	for key := range env {
+		if !runnerExcludedEnv[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	seen := map[string]bool{}
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		// only the name is written, the value is read from the environment
+		// so secrets never end up in the script
+		args = append(args, "-e", shellQuote(key))
+	}
+	args = append(args, shellQuote(runner.Image), "pulumi", `"$@"`)
+
+	return strings.Join([]string{
+		"#!/bin/sh",
+		"mkdir -p " + shellQuote(pulumiHome),
+		"exec " + strings.Join(args, " "),
+		"",
+	}, "\n")
+}
+
+// PathRunnerBin is where the pulumi that runs in the runner image is
+// written. The automation API looks pulumi up on the PATH of the process, so
+// the program embedding the project has to put it first there.
+func (p *Project) PathRunnerBin() string {
+	return filepath.Join(p.PathWorkingDir(), "runner", "bin")
+}
+
+// useRunner writes the pulumi executable that runs in the runner image and
+// puts it first on the PATH of the workspace. It fails if it isn't the
+// pulumi the process finds, the PATH of the process is left alone.
+func (p *Project) useRunner(env map[string]string) error {
+	binDir := p.PathRunnerBin()
+	err := os.MkdirAll(binDir, 0755)
+	if err != nil {
+		return err
+	}
+	script := filepath.Join(binDir, "pulumi")
+	err = os.WriteFile(script, []byte(p.runnerScript(env)), 0755)
+	if err != nil {
+		return err
+	}
+	path := env["PATH"]
+	if path == "" {
+		path = os.Getenv("PATH")
+	}
+	env["PATH"] = binDir + string(os.PathListSeparator) + path
+
+	resolved, err := exec.LookPath("pulumi")
+	if err == nil {
+		expected, _ := os.Stat(script)
+		actual, statErr := os.Stat(resolved)
+		if statErr == nil && os.SameFile(expected, actual) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be first on the PATH to run pulumi in the runner image", binDir)
+}
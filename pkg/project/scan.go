@@ -0,0 +1,310 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+)
+
+type SecurityFinding struct {
+	Rule     string
+	Severity Severity
+	URN      string
+	Message  string
+}
+
+type SecurityScanEvent struct {
+	Findings []SecurityFinding
+}
+
+type securityRule struct {
+	name  string
+	types []string
+	check func(outputs map[string]interface{}) (Severity, string, bool)
+}
+
+var securityBaseline = []securityRule{
+	{
+		name:  "public-bucket",
+		types: []string{"aws:s3/bucket:Bucket", "aws:s3/bucketV2:BucketV2", "aws:s3/bucketAclV2:BucketAclV2"},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			acl, _ := outputs["acl"].(string)
+			if strings.HasPrefix(acl, "public-read") {
+				return SeverityHigh, fmt.Sprintf("Bucket ACL is set to %q", acl), true
+			}
+			return "", "", false
+		},
+	},
+	{
+		name:  "public-bucket",
+		types: []string{"aws:s3/bucketPublicAccessBlock:BucketPublicAccessBlock"},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			for _, key := range []string{"blockPublicAcls", "blockPublicPolicy", "ignorePublicAcls", "restrictPublicBuckets"} {
+				if enabled, ok := outputs[key].(bool); ok && !enabled {
+					return SeverityMedium, fmt.Sprintf("Public access block has %q disabled", key), true
+				}
+			}
+			return "", "", false
+		},
+	},
+	{
+		name:  "public-bucket",
+		types: []string{"aws:s3/bucketPolicy:BucketPolicy"},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			for _, statement := range parsePolicyStatements(outputs["policy"]) {
+				if statement.allows() && statement.publicPrincipal() && statement.Condition == nil {
+					return SeverityHigh, "Bucket policy allows access to any principal", true
+				}
+			}
+			return "", "", false
+		},
+	},
+	{
+		name: "wildcard-iam",
+		types: []string{
+			"aws:iam/policy:Policy",
+			"aws:iam/rolePolicy:RolePolicy",
+			"aws:iam/userPolicy:UserPolicy",
+			"aws:iam/groupPolicy:GroupPolicy",
+			"aws:iam/role:Role",
+		},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			documents := []interface{}{outputs["policy"]}
+			if inline, ok := outputs["inlinePolicies"].([]interface{}); ok {
+				for _, item := range inline {
+					if casted, ok := item.(map[string]interface{}); ok {
+						documents = append(documents, casted["policy"])
+					}
+				}
+			}
+			for _, document := range documents {
+				for _, statement := range parsePolicyStatements(document) {
+					if !statement.allows() {
+						continue
+					}
+					actions := toStrings(statement.Action)
+					resources := toStrings(statement.Resource)
+					if contains(actions, "*") {
+						return SeverityCritical, "Policy allows all actions (\"*\")", true
+					}
+					if contains(resources, "*") {
+						for _, action := range actions {
+							if strings.HasSuffix(action, ":*") {
+								return SeverityHigh, fmt.Sprintf("Policy allows %q on all resources", action), true
+							}
+						}
+					}
+				}
+			}
+			return "", "", false
+		},
+	},
+	{
+		name: "unencrypted-storage",
+		types: []string{
+			"aws:rds/instance:Instance",
+			"aws:rds/cluster:Cluster",
+			"aws:docdb/cluster:Cluster",
+			"aws:neptune/cluster:Cluster",
+		},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			if encrypted, ok := outputs["storageEncrypted"].(bool); ok && !encrypted {
+				return SeverityHigh, "Storage encryption is disabled", true
+			}
+			return "", "", false
+		},
+	},
+	{
+		name:  "unencrypted-storage",
+		types: []string{"aws:ebs/volume:Volume", "aws:efs/fileSystem:FileSystem"},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			if encrypted, ok := outputs["encrypted"].(bool); ok && !encrypted {
+				return SeverityHigh, "Encryption is disabled", true
+			}
+			return "", "", false
+		},
+	},
+	{
+		name:  "unencrypted-storage",
+		types: []string{"aws:sqs/queue:Queue"},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			managed, _ := outputs["sqsManagedSseEnabled"].(bool)
+			key, _ := outputs["kmsMasterKeyId"].(string)
+			if !managed && key == "" {
+				return SeverityMedium, "Queue is not encrypted at rest", true
+			}
+			return "", "", false
+		},
+	},
+	{
+		name:  "open-security-group",
+		types: []string{"aws:ec2/securityGroup:SecurityGroup"},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			ingress, _ := outputs["ingress"].([]interface{})
+			for _, item := range ingress {
+				rule, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if severity, message, ok := checkIngress(rule); ok {
+					return severity, message, true
+				}
+			}
+			return "", "", false
+		},
+	},
+	{
+		name:  "open-security-group",
+		types: []string{"aws:ec2/securityGroupRule:SecurityGroupRule"},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			if kind, _ := outputs["type"].(string); kind != "ingress" {
+				return "", "", false
+			}
+			return checkIngress(outputs)
+		},
+	},
+	{
+		name:  "open-security-group",
+		types: []string{"aws:vpc/securityGroupIngressRule:SecurityGroupIngressRule"},
+		check: func(outputs map[string]interface{}) (Severity, string, bool) {
+			return checkIngress(map[string]interface{}{
+				"fromPort":       outputs["fromPort"],
+				"toPort":         outputs["toPort"],
+				"cidrBlocks":     []interface{}{outputs["cidrIpv4"]},
+				"ipv6CidrBlocks": []interface{}{outputs["cidrIpv6"]},
+			})
+		},
+	},
+}
+
+// ScanResources checks the deployed resources against the built in security
+// baseline and returns any findings.
+func ScanResources(resources []apitype.ResourceV3) []SecurityFinding {
+	findings := []SecurityFinding{}
+	for _, resource := range resources {
+		if resource.Outputs == nil {
+			continue
+		}
+		for _, rule := range securityBaseline {
+			if !contains(rule.types, string(resource.Type)) {
+				continue
+			}
+			severity, message, ok := rule.check(resource.Outputs)
+			if !ok {
+				continue
+			}
+			findings = append(findings, SecurityFinding{
+				Rule:     rule.name,
+				Severity: severity,
+				URN:      string(resource.URN),
+				Message:  message,
+			})
+		}
+	}
+	return findings
+}
+
+func checkIngress(rule map[string]interface{}) (Severity, string, bool) {
+	open := false
+	for _, key := range []string{"cidrBlocks", "ipv6CidrBlocks"} {
+		for _, cidr := range toStrings(rule[key]) {
+			if cidr == "0.0.0.0/0" || cidr == "::/0" {
+				open = true
+			}
+		}
+	}
+	if !open {
+		return "", "", false
+	}
+	from, _ := rule["fromPort"].(float64)
+	to, _ := rule["toPort"].(float64)
+	if from == to && (from == 80 || from == 443) {
+		return "", "", false
+	}
+	if from <= 0 && (to <= 0 || to >= 65535) {
+		return SeverityCritical, "Security group allows all traffic from the internet", true
+	}
+	return SeverityHigh, fmt.Sprintf("Security group allows ports %v-%v from the internet", from, to), true
+}
+
+type policyStatement struct {
+	Effect    string      `json:"Effect"`
+	Action    interface{} `json:"Action"`
+	Resource  interface{} `json:"Resource"`
+	Principal interface{} `json:"Principal"`
+	Condition interface{} `json:"Condition"`
+}
+
+func (s policyStatement) allows() bool {
+	return s.Effect == "Allow"
+}
+
+func (s policyStatement) publicPrincipal() bool {
+	switch principal := s.Principal.(type) {
+	case string:
+		return principal == "*"
+	case map[string]interface{}:
+		return contains(toStrings(principal["AWS"]), "*")
+	}
+	return false
+}
+
+func parsePolicyStatements(input interface{}) []policyStatement {
+	raw, ok := input.(string)
+	if !ok || raw == "" {
+		return nil
+	}
+	var document struct {
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(raw), &document); err != nil {
+		return nil
+	}
+	var statements []policyStatement
+	if err := json.Unmarshal(document.Statement, &statements); err == nil {
+		return statements
+	}
+	var statement policyStatement
+	if err := json.Unmarshal(document.Statement, &statement); err == nil {
+		return []policyStatement{statement}
+	}
+	return nil
+}
+
+func toStrings(input interface{}) []string {
+	switch value := input.(type) {
+	case string:
+		return []string{value}
+	case []string:
+		return value
+	case []interface{}:
+		result := []string{}
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
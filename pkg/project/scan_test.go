@@ -0,0 +1,65 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
+)
+
+var scanExamples = map[string]struct {
+	resource apitype.ResourceV3
+	severity Severity
+}{
+	"public acl": {
+		resource: apitype.ResourceV3{
+			Type:    "aws:s3/bucketV2:BucketV2",
+			Outputs: map[string]interface{}{"acl": "public-read"},
+		},
+		severity: SeverityHigh,
+	},
+	"wildcard action": {
+		resource: apitype.ResourceV3{
+			Type: "aws:iam/rolePolicy:RolePolicy",
+			Outputs: map[string]interface{}{
+				"policy": `{"Statement":{"Effect":"Allow","Action":"*","Resource":"*"}}`,
+			},
+		},
+		severity: SeverityCritical,
+	},
+	"open ssh": {
+		resource: apitype.ResourceV3{
+			Type: "aws:ec2/securityGroup:SecurityGroup",
+			Outputs: map[string]interface{}{
+				"ingress": []interface{}{
+					map[string]interface{}{"fromPort": 22.0, "toPort": 22.0, "cidrBlocks": []interface{}{"0.0.0.0/0"}},
+				},
+			},
+		},
+		severity: SeverityHigh,
+	},
+	"open https": {
+		resource: apitype.ResourceV3{
+			Type: "aws:ec2/securityGroup:SecurityGroup",
+			Outputs: map[string]interface{}{
+				"ingress": []interface{}{
+					map[string]interface{}{"fromPort": 443.0, "toPort": 443.0, "cidrBlocks": []interface{}{"0.0.0.0/0"}},
+				},
+			},
+		},
+	},
+}
+
+func TestScanResources(t *testing.T) {
+	for name, example := range scanExamples {
+		findings := ScanResources([]apitype.ResourceV3{example.resource})
+		if example.severity == "" {
+			if len(findings) != 0 {
+				t.Errorf("%s: expected no findings, got %v", name, findings)
+			}
+			continue
+		}
+		if len(findings) != 1 || findings[0].Severity != example.severity {
+			t.Errorf("%s: expected a %s finding, got %v", name, example.severity, findings)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/internal/util"
+)
+
+var ErrInvalidAppConfig = fmt.Errorf("invalid app config")
+
+type schemaIssue struct {
+	path    []string
+	message string
+}
+
+// validateAppSchema checks the object returned by app() against App before
+// it's decoded, so unknown keys and values of the wrong type are reported
+// with where they are in sources instead of being ignored or failing later.
+func validateAppSchema(raw []byte, root string, sources []string) error {
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return err
+	}
+	issues := checkSchema(nil, value, reflect.TypeOf(App{}))
+	if providers, ok := value.(map[string]interface{})["providers"].(map[string]interface{}); ok {
+		for name, args := range providers {
+			if _, ok := args.(map[string]interface{}); ok || args == true {
+				continue
+			}
+			issues = append(issues, schemaIssue{
+				path:    []string{"providers", name},
+				message: fmt.Sprintf(`should be true or an object, got %s`, jsonKind(args)),
+			})
+		}
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		return strings.Join(issues[i].path, ".") < strings.Join(issues[j].path, ".")
+	})
+
+	contents := map[string]string{}
+	for _, source := range sources {
+		if data, err := os.ReadFile(source); err == nil {
+			contents[source] = string(data)
+		}
+	}
+	lines := []string{"Invalid app config:"}
+	for _, issue := range issues {
+		line := fmt.Sprintf(`  "%s" %s`, strings.Join(issue.path, "."), issue.message)
+		if location := locateKey(root, sources, contents, issue.path[len(issue.path)-1]); location != "" {
+			line = "  " + location + " " + strings.TrimPrefix(line, "  ")
+		}
+		lines = append(lines, line)
+	}
+	return util.NewReadableError(ErrInvalidAppConfig, strings.Join(lines, "\n"))
+}
+
+func checkSchema(path []string, value interface{}, typ reflect.Type) []schemaIssue {
+	if value == nil {
+		return nil
+	}
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	mismatch := func(expected string) []schemaIssue {
+		return []schemaIssue{{
+			path:    path,
+			message: fmt.Sprintf("should be %s, got %s", expected, jsonKind(value)),
+		}}
+	}
+
+	switch typ.Kind() {
+	case reflect.Interface:
+		return nil
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return mismatch("a string")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return mismatch("a boolean")
+		}
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		number, ok := value.(float64)
+		if !ok || number != float64(int64(number)) {
+			return mismatch("an integer")
+		}
+	case reflect.Float64, reflect.Float32:
+		if _, ok := value.(float64); !ok {
+			return mismatch("a number")
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return mismatch("an array")
+		}
+		issues := []schemaIssue{}
+		for i, item := range items {
+			issues = append(issues, checkSchema(appendPath(path, fmt.Sprintf("%d", i)), item, typ.Elem())...)
+		}
+		return issues
+	case reflect.Map:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("an object")
+		}
+		issues := []schemaIssue{}
+		for key, entry := range entries {
+			issues = append(issues, checkSchema(appendPath(path, key), entry, typ.Elem())...)
+		}
+		return issues
+	case reflect.Struct:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("an object")
+		}
+		fields := map[string]reflect.Type{}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			fields[name] = field.Type
+		}
+		issues := []schemaIssue{}
+		for key, entry := range entries {
+			fieldType, ok := fields[key]
+			if !ok {
+				message := "is not a known key"
+				if suggestion := closestKey(key, fields); suggestion != "" {
+					message += fmt.Sprintf(`, did you mean "%s"?`, suggestion)
+				}
+				issues = append(issues, schemaIssue{path: appendPath(path, key), message: message})
+				continue
+			}
+			issues = append(issues, checkSchema(appendPath(path, key), entry, fieldType)...)
+		}
+		return issues
+	}
+	return nil
+}
+
+func appendPath(path []string, key string) []string {
+	return append(append([]string{}, path...), key)
+}
+
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return "null"
+}
+
+// closestKey suggests the known key a typo was meant to be.
+func closestKey(key string, fields map[string]reflect.Type) string {
+	best := ""
+	bestDistance := 3
+	for name := range fields {
+		distance := levenshtein(strings.ToLower(key), strings.ToLower(name))
+		if distance < bestDistance || (distance == bestDistance && best != "" && name < best) {
+			best = name
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// locateKey finds where key is set in the sources, as file:line:column.
+func locateKey(root string, sources []string, contents map[string]string, key string) string {
+	pattern := regexp.MustCompile(`(?:^|[\s{,])["']?` + regexp.QuoteMeta(key) + `["']?\s*:`)
+	for _, source := range sources {
+		content, ok := contents[source]
+		if !ok {
+			continue
+		}
+		match := pattern.FindStringIndex(content)
+		if match == nil {
+			continue
+		}
+		offset := match[0] + strings.Index(content[match[0]:match[1]], key)
+		line := strings.Count(content[:offset], "\n") + 1
+		column := offset - strings.LastIndex(content[:offset], "\n")
+		rel, err := filepath.Rel(root, source)
+		if err != nil {
+			rel = source
+		}
+		return fmt.Sprintf("%s:%d:%d", rel, line, column)
+	}
+	return ""
+}
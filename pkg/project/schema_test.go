@@ -0,0 +1,38 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateAppSchema(t *testing.T) {
+	root := t.TempDir()
+	config := filepath.Join(root, "sst.config.ts")
+	source := "export default $config({\n  app(input) {\n    return {\n      name: \"app\",\n      removel: \"retain\",\n      home: 1,\n    };\n  },\n});\n"
+	if err := os.WriteFile(config, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := `{"name":"app","home":"aws","providers":{"aws":{"region":"us-east-1"},"cloudflare":true},"runner":{"image":"node"}}`
+	if err := validateAppSchema([]byte(valid), root, []string{config}); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	invalid := `{"name":"app","removel":"retain","home":1,"providers":{"aws":"us-east-1"},"runner":{"command":["x"]}}`
+	err := validateAppSchema([]byte(invalid), root, []string{config})
+	if err == nil {
+		t.Fatal("expected invalid config")
+	}
+	for _, expected := range []string{
+		`sst.config.ts:5:7 "removel" is not a known key, did you mean "removal"?`,
+		`sst.config.ts:6:7 "home" should be a string, got a number`,
+		`"providers.aws" should be true or an object, got a string`,
+		`"runner.command" should be a string, got an array`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, err.Error())
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package project
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// secretEnvLimit keeps the environment well under the smallest limit of the
+// platforms the program runs on, the whole block on Windows is 32k chars.
+const secretEnvLimit = 24 * 1024
+
+func envSize(env map[string]string) int {
+	size := 0
+	for key, value := range env {
+		size += len(key) + len(value) + 2
+	}
+	return size
+}
+
+// useSecretsFile moves the secrets out of env into a file encrypted with a
+// one-off key when they'd push it over the limit. The shim reads it back
+// into SST_SECRET_* before the program runs. The file is removed by the
+// returned function.
+func (s *Stack) useSecretsFile(env map[string]string) (func(), error) {
+	if envSize(env) <= secretEnvLimit {
+		return func() {}, nil
+	}
+	secrets := map[string]string{}
+	for key, value := range env {
+		if strings.HasPrefix(key, "SST_SECRET_") {
+			secrets[strings.TrimPrefix(key, "SST_SECRET_")] = value
+		}
+	}
+	if len(secrets) == 0 {
+		return func() {}, nil
+	}
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// the working directory is mounted by the container runner
+	file, err := os.CreateTemp(s.project.PathWorkingDir(), "secrets-*.enc")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	cleanup := func() { os.Remove(file.Name()) }
+	if _, err := file.Write(gcm.Seal(nonce, nonce, data, nil)); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	slog.Info("passing secrets through a file", "secrets", len(secrets), "env", envSize(env))
+	for name := range secrets {
+		delete(env, "SST_SECRET_"+name)
+	}
+	path, err := filepath.Abs(file.Name())
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	env["SST_SECRETS_FILE"] = path
+	env["SST_SECRETS_KEY"] = base64.StdEncoding.EncodeToString(key)
+	return cleanup, nil
+}
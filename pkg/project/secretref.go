@@ -0,0 +1,117 @@
+package project
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// secretRefPrefix marks a provider arg set with $secret("NAME") in app().
+const secretRefPrefix = "$secret:"
+
+var ErrSecretRefHome = fmt.Errorf("home provider references a secret")
+
+func hasSecretRefs(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return strings.HasPrefix(v, secretRefPrefix)
+	case map[string]interface{}:
+		for _, item := range v {
+			if hasSecretRefs(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if hasSecretRefs(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveSecretRefs returns a copy of the provider args with the secret
+// references replaced, so the resolved values never end up in $app. It also
+// returns the config paths of the values that were resolved, like
+// assumeRole.roleArn, and the names of the secrets that are not set.
+func resolveSecretRefs(args map[string]interface{}, secrets map[string]string) (map[string]interface{}, []string, []string) {
+	resolved := []string{}
+	missing := map[string]bool{}
+	var resolve func(path string, value interface{}) interface{}
+	resolve = func(path string, value interface{}) interface{} {
+		switch v := value.(type) {
+		case string:
+			if !strings.HasPrefix(v, secretRefPrefix) {
+				return v
+			}
+			name := strings.TrimPrefix(v, secretRefPrefix)
+			secret, ok := secrets[name]
+			if !ok {
+				missing[name] = true
+			}
+			resolved = append(resolved, path)
+			return secret
+		case map[string]interface{}:
+			next := make(map[string]interface{}, len(v))
+			for key, item := range v {
+				next[key] = resolve(path+"."+key, item)
+			}
+			return next
+		case []interface{}:
+			next := make([]interface{}, len(v))
+			for i, item := range v {
+				next[i] = resolve(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+			return next
+		}
+		return value
+	}
+	result := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		result[key] = resolve(key, value)
+	}
+	names := []string{}
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Strings(resolved)
+	return result, resolved, names
+}
+
+// providerConfig flattens the args of a provider into the stack config,
+// nested values use the path syntax of pulumi config, like
+// aws:assumeRole.roleArn. The values resolved from secrets are set as
+// secrets at every level.
+func providerConfig(name string, args map[string]interface{}, secret map[string]bool, skip func(key string) bool) auto.ConfigMap {
+	result := auto.ConfigMap{}
+	var flatten func(path string, value interface{})
+	flatten = func(path string, value interface{}) {
+		switch v := value.(type) {
+		case string:
+			result[name+":"+path] = auto.ConfigValue{Value: v, Secret: secret[name+":"+path]}
+		case []string:
+			for i, item := range v {
+				flatten(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		case []interface{}:
+			for i, item := range v {
+				flatten(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		case map[string]interface{}:
+			for key, item := range v {
+				flatten(path+"."+key, item)
+			}
+		}
+	}
+	for key, value := range args {
+		if skip(key) {
+			continue
+		}
+		flatten(key, value)
+	}
+	return result
+}
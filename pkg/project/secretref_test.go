@@ -0,0 +1,59 @@
+package project
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveSecretRefs(t *testing.T) {
+	args := map[string]interface{}{
+		"region": "us-east-1",
+		"token":  "$secret:Token",
+		"assumeRole": map[string]interface{}{
+			"roleArn":    "$secret:RoleArn",
+			"externalId": "$secret:Missing",
+		},
+		"headers": []interface{}{"plain", "$secret:Token"},
+	}
+	resolved, paths, missing := resolveSecretRefs(args, map[string]string{
+		"Token":   "secret-token",
+		"RoleArn": "arn:aws:iam::123:role/deploy",
+	})
+	if args["token"] != "$secret:Token" || args["assumeRole"].(map[string]interface{})["roleArn"] != "$secret:RoleArn" {
+		t.Fatalf("args were changed in place: %v", args)
+	}
+	if resolved["token"] != "secret-token" || resolved["assumeRole"].(map[string]interface{})["roleArn"] != "arn:aws:iam::123:role/deploy" {
+		t.Fatalf("unexpected resolved args: %v", resolved)
+	}
+	wantPaths := []string{"assumeRole.externalId", "assumeRole.roleArn", "headers[1]", "token"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("paths = %v, want %v", paths, wantPaths)
+	}
+	if !reflect.DeepEqual(missing, []string{"Missing"}) {
+		t.Errorf("missing = %v", missing)
+	}
+
+	secret := map[string]bool{}
+	for _, path := range paths {
+		secret["aws:"+path] = true
+	}
+	config := providerConfig("aws", resolved, secret, func(key string) bool { return key == "region" })
+	if _, ok := config["aws:region"]; ok {
+		t.Errorf("skipped key was set")
+	}
+	for key, want := range map[string]bool{
+		"aws:token":              true,
+		"aws:assumeRole.roleArn": true,
+		"aws:headers[0]":         false,
+		"aws:headers[1]":         true,
+	} {
+		value, ok := config[key]
+		if !ok {
+			t.Errorf("%s is not set", key)
+			continue
+		}
+		if value.Secret != want {
+			t.Errorf("%s secret = %v, want %v", key, value.Secret, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package project
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// SessionTimeout is how long a dev session is considered running after its
+// last heartbeat.
+const SessionTimeout = 30 * time.Second
+
+// Session identifies a dev session running against a stage, so a second one
+// can tell who else is using it.
+type Session struct {
+	ID        string    `json:"id"`
+	User      string    `json:"user"`
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	Started   time.Time `json:"started"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+func NewSession() *Session {
+	id := make([]byte, 8)
+	rand.Read(id)
+	result := &Session{
+		ID:      hex.EncodeToString(id),
+		PID:     os.Getpid(),
+		Started: time.Now(),
+	}
+	if current, err := user.Current(); err == nil {
+		result.User = current.Username
+	}
+	result.Host, _ = os.Hostname()
+	return result
+}
+
+// Live is true if the session sent a heartbeat recently.
+func (s *Session) Live(now time.Time) bool {
+	return s.ID != "" && now.Sub(s.Heartbeat) < SessionTimeout
+}
+
+// Session returns the dev session last running against the stage, it has no
+// ID if there was none.
+func (s *Stack) Session() (*Session, error) {
+	result := &Session{}
+	err := provider.GetSession(s.project.home, s.project.app.Name, s.project.app.Stage, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Heartbeat marks the session as running against the stage.
+func (s *Stack) Heartbeat(session *Session) error {
+	session.Heartbeat = time.Now()
+	return provider.PutSession(s.project.home, s.project.app.Name, s.project.app.Stage, session)
+}
+
+// EndSession removes the session, unless another one took over the stage.
+func (s *Stack) EndSession(session *Session) error {
+	existing, err := s.Session()
+	if err != nil {
+		return err
+	}
+	if existing.ID != session.ID {
+		return nil
+	}
+	return provider.RemoveSession(s.project.home, s.project.app.Name, s.project.app.Stage)
+}
@@ -0,0 +1,148 @@
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sst/ion/pkg/js"
+)
+
+// provider keys end up in generated code, so they are restricted to valid
+// npm package names
+var providerNameRegex = regexp.MustCompile(`^(@[a-z0-9][a-z0-9._~-]*/)?[a-z0-9][a-z0-9._~-]*$`)
+var identifierRegex = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*$`)
+
+// every generated line of a shim has to match one of these
+var shimLineRegexes = []*regexp.Regexp{
+	regexp.MustCompile(`^lazy\("[a-zA-Z_$][a-zA-Z0-9_$]*", "(@[a-z0-9][a-z0-9._~-]*/)?[a-z0-9][a-z0-9._~-]*"\)$`),
+}
+
+// shimHeader defines the globals as getters that load the provider the
+// first time it's used. The require is aliased so the provider isn't
+// bundled into the shim and is resolved from the platform directory.
+const shimHeader = `import { createRequire } from "node:module"
+const load = createRequire(import.meta.url)
+function lazy(name, pkg) {
+  Object.defineProperty(globalThis, name, {
+    configurable: true,
+    get() {
+      const value = load(pkg)
+      Object.defineProperty(globalThis, name, { value, writable: true, configurable: true })
+      return value
+    },
+  })
+}`
+
+var reservedWords = map[string]bool{
+	"await": true, "break": true, "case": true, "catch": true, "class": true,
+	"const": true, "continue": true, "debugger": true, "default": true,
+	"delete": true, "do": true, "else": true, "enum": true, "export": true,
+	"extends": true, "false": true, "finally": true, "for": true,
+	"function": true, "if": true, "implements": true, "import": true,
+	"in": true, "instanceof": true, "interface": true, "let": true,
+	"new": true, "null": true, "package": true, "private": true,
+	"protected": true, "public": true, "return": true, "static": true,
+	"super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true,
+	"with": true, "yield": true,
+	// globals the platform relies on
+	"globalThis": true, "process": true, "sst": true, "$app": true,
+	"$cli": true, "$dev": true, "$config": true,
+}
+
+func validateProviderName(name string) error {
+	if !providerNameRegex.MatchString(name) {
+		return fmt.Errorf("Provider %q is not a valid package name", name)
+	}
+	global := cleanProviderName(name)
+	if !identifierRegex.MatchString(global) || reservedWords[global] {
+		return fmt.Errorf("Provider %q does not map to a valid global name", name)
+	}
+	return nil
+}
+
+// providerShim generates the code that exposes every provider as a global.
+// Providers are only loaded when the program uses them, so configuring one
+// doesn't slow down every run. The generated lines are checked one by one
+// so nothing but the globals can make it into the bundle.
+func providerShim(names []string) (string, error) {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+
+	globals := map[string]string{}
+	lines := []string{}
+	for _, name := range sorted {
+		if err := validateProviderName(name); err != nil {
+			return "", err
+		}
+		global := cleanProviderName(name)
+		if existing, ok := globals[global]; ok {
+			return "", fmt.Errorf("Providers %q and %q map to the same global %q", existing, name, global)
+		}
+		globals[global] = name
+		lines = append(lines, fmt.Sprintf("lazy(%s, %s)", strconv.Quote(global), strconv.Quote(getProviderPackage(name))))
+	}
+
+	for _, line := range lines {
+		if !matchesShimLine(line) {
+			return "", fmt.Errorf("Refusing to inject unexpected provider shim: %s", line)
+		}
+	}
+	return strings.Join(append([]string{shimHeader}, lines...), "\n"), nil
+}
+
+func matchesShimLine(line string) bool {
+	for _, regex := range shimLineRegexes {
+		if regex.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// providerShimModule bundles the provider shim into a module under the
+// platform directory and returns its path. It's keyed by the shim and the
+// installed provider versions so it's only rebuilt when either changes.
+func (s *Stack) providerShimModule(names []string) (string, error) {
+	code, err := providerShim(names)
+	if err != nil {
+		return "", err
+	}
+	versions := s.providerVersions()
+	packages := make([]string, 0, len(versions))
+	for pkg := range versions {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	hash := sha256.New()
+	hash.Write([]byte(code))
+	for _, pkg := range packages {
+		hash.Write([]byte("\n" + pkg + "@" + versions[pkg]))
+	}
+	key := hex.EncodeToString(hash.Sum(nil))[:16]
+
+	// kept in the platform directory so the provider imports resolve
+	dir := filepath.Join(s.project.PathPlatformDir(), "shim")
+	outfile := filepath.Join(dir, "providers-"+key+".mjs")
+	if _, err := os.Stat(outfile); err == nil {
+		slog.Info("using cached provider shim", "path", outfile)
+		return outfile, nil
+	}
+	stale, _ := filepath.Glob(filepath.Join(dir, "providers-*.mjs"))
+	for _, file := range stale {
+		os.Remove(file)
+	}
+	err = js.BuildModule(s.project.PathPlatformDir(), code, outfile)
+	if err != nil {
+		return "", err
+	}
+	return outfile, nil
+}
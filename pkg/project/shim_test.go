@@ -0,0 +1,47 @@
+package project
+
+import (
+	"strings"
+	"testing"
+)
+
+var providerNameExamples = map[string]bool{
+	"aws":                   true,
+	"cloudflare":            true,
+	"@pulumiverse/vercel":   true,
+	"@pulumi/random":        true,
+	"aws'; process.exit(1)": false,
+	"foo\"\nimport 'evil'":  false,
+	"@scope/../../etc":      false,
+	"Upper":                 false,
+	"class":                 false,
+	"pulumi":                false,
+	"":                      false,
+}
+
+func TestValidateProviderName(t *testing.T) {
+	for name, valid := range providerNameExamples {
+		err := validateProviderName(name)
+		if valid && err != nil {
+			t.Errorf("%q: expected valid, got %v", name, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("%q: expected invalid", name)
+		}
+	}
+}
+
+func TestProviderShimIsLazy(t *testing.T) {
+	code, err := providerShim([]string{"cloudflare", "aws"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{`lazy("aws", "@pulumi/aws")`, `lazy("cloudflare", "@pulumi/cloudflare")`} {
+		if !strings.Contains(code, line+"\n") && !strings.HasSuffix(code, line) {
+			t.Errorf("expected %q in:\n%s", line, code)
+		}
+	}
+	if strings.Contains(code, "import * as") {
+		t.Errorf("expected no static provider imports in:\n%s", code)
+	}
+}
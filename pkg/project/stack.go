@@ -6,34 +6,71 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	esbuild "github.com/evanw/esbuild/pkg/api"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/debug"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/apitype"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/sst/ion/internal/util"
 	"github.com/sst/ion/pkg/global"
 	"github.com/sst/ion/pkg/js"
+	"github.com/sst/ion/pkg/project/eventlog"
 	"github.com/sst/ion/pkg/project/provider"
 )
 
-type stack struct {
+// Stack runs commands against the deployed stage of a Project.
+type Stack struct {
 	project *Project
+	// builder keeps the program build warm between runs in dev
+	builder js.Builder
+	// lastBuild is the last build of the program in dev
+	lastBuild *devBuild
+	// holdLock keeps the lock and the state between runs, locked and pulled
+	// are set while they're held
+	holdLock bool
+	locked   bool
+	pulled   bool
 }
 
 type StackEvent struct {
 	events.EngineEvent
-	StdOutEvent           *StdOutEvent
-	ConcurrentUpdateEvent *ConcurrentUpdateEvent
-	CompleteEvent         *CompleteEvent
-	StackCommandEvent     *StackCommandEvent
+	StdOutEvent                *StdOutEvent
+	ConcurrentUpdateEvent      *ConcurrentUpdateEvent
+	CompleteEvent              *CompleteEvent
+	StackCommandEvent          *StackCommandEvent
+	SecurityScanEvent          *SecurityScanEvent
+	WaitEvent                  *WaitEvent
+	DiagnosticContextEvent     *DiagnosticContextEvent
+	SecretLeakEvent            *SecretLeakEvent
+	StateTransferEvent         *StateTransferEvent
+	SecretRotationEvent        *SecretRotationEvent
+	WarningEvent               *WarningEvent
+	BuildWarningEvent          *BuildWarningEvent
+	NodeVersionEvent           *NodeVersionEvent
+	TypecheckErrorEvent        *TypecheckErrorEvent
+	LintEvent                  *LintEvent
+	ConfigRebuildStartedEvent  *ConfigRebuildStartedEvent
+	ConfigRebuildFinishedEvent *ConfigRebuildFinishedEvent
+	HotswapEvent               *HotswapEvent
+	HotswapFallbackEvent       *HotswapFallbackEvent
+	InspectorEvent             *InspectorEvent
+	ReloadEvent                *ReloadEvent
 }
 
 type StackInput struct {
@@ -41,12 +78,28 @@ type StackInput struct {
 	OnFiles func(files []string)
 	Command string
 	Dev     bool
+	// FailOnWarnings stops the run before it starts if the program was
+	// built with warnings
+	FailOnWarnings bool
+	// Typecheck runs tsc over the config before it's built
+	Typecheck bool
+	// Hotswap updates the code of the functions directly if only their
+	// code changed since the last deploy
+	Hotswap bool
 }
 
 type StdOutEvent struct {
 	Text string
 }
 
+// InspectorEvent is sent when a function running locally in sst dev --inspect
+// has a debugger listening, so editors can attach to it.
+type InspectorEvent struct {
+	FunctionID string
+	WorkerID   string
+	URL        string
+}
+
 type ConcurrentUpdateEvent struct{}
 
 type Links map[string]interface{}
@@ -65,18 +118,81 @@ type Warp struct {
 	Properties  json.RawMessage   `json:"properties"`
 	Links       []string          `json:"links"`
 	Environment map[string]string `json:"environment"`
+	// Schedules are the expressions of the crons that run the function
+	Schedules map[string]string `json:"schedules"`
+	// Queues are the queues the function is subscribed to
+	Queues map[string]WarpQueue `json:"queues"`
+	// Buses are the EventBridge buses the function is subscribed to
+	Buses map[string]WarpBus `json:"buses"`
+	// Bastion is the instance dev tunnels through to reach the linked
+	// resources in the VPC of the function
+	Bastion string `json:"bastion"`
+	// Layers are the ARNs of the layers added to the function, they're not
+	// loaded when it runs locally
+	Layers []string `json:"layers"`
 }
 type Warps map[string]Warp
 
+// DevCommand is the dev server of a frontend that dev runs with its links.
+type DevCommand struct {
+	Directory   string            `json:"directory"`
+	Command     string            `json:"command"`
+	Links       []string          `json:"links"`
+	Environment map[string]string `json:"environment"`
+	Autostart   bool              `json:"autostart"`
+}
+type DevCommands map[string]DevCommand
+
+type WarpQueue struct {
+	URL                     string   `json:"url"`
+	Arn                     string   `json:"arn"`
+	BatchSize               int      `json:"batchSize"`
+	ReportBatchItemFailures bool     `json:"reportBatchItemFailures"`
+	Filters                 []string `json:"filters"`
+}
+
+// WarpBus is the queue the events on a bus go to in dev, with the pattern
+// of the subscriber.
+type WarpBus struct {
+	URL     string `json:"url"`
+	Arn     string `json:"arn"`
+	Pattern string `json:"pattern"`
+}
+
+// WarpLog is the log group of a function, the ones that aren't live are
+// tailed in dev.
+type WarpLog struct {
+	Group  string `json:"group"`
+	Region string `json:"region"`
+	Live   bool   `json:"live"`
+}
+type WarpLogs map[string]WarpLog
+
 type CompleteEvent struct {
 	Links     Links
 	Warps     Warps
 	Receivers Receivers
-	Outputs   map[string]interface{}
-	Hints     map[string]string
-	Errors    []Error
-	Finished  bool
-	Resources []apitype.ResourceV3
+	// Logs are the log groups of all the functions, only in dev
+	Logs WarpLogs
+	// DevCommands are the dev servers of the frontends, only in dev
+	DevCommands DevCommands
+	Outputs     map[string]interface{}
+	Hints       map[string]string
+	Errors      []Error
+	Finished    bool
+	Resources   []apitype.ResourceV3
+	// secretOutputs are the outputs that are secrets in the state, they're
+	// redacted when the event leaves the CLI
+	secretOutputs map[string]bool
+}
+
+type StateTransferEvent struct {
+	Direction provider.TransferDirection
+	Bytes     int64
+	Total     int64
+	Percent   float64
+	ETA       time.Duration
+	Done      bool
 }
 
 type StackCommandEvent struct {
@@ -92,151 +208,303 @@ type StackEventStream = chan StackEvent
 
 var ErrStackRunFailed = fmt.Errorf("stack run had errors")
 var ErrStageNotFound = fmt.Errorf("stage not found")
+var ErrMissingSecrets = fmt.Errorf("missing required secrets")
+var ErrBuildWarnings = fmt.Errorf("build had warnings")
+
+// missingSecrets returns the required secrets that are not set or empty.
+func missingSecrets(required []string, secrets map[string]string) []string {
+	missing := []string{}
+	for _, key := range required {
+		if secrets[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
 
-func (s *stack) Run(ctx context.Context, input *StackInput) error {
+// Run builds the program and runs input.Command against the stage. Progress
+// is reported through input.OnEvent, nothing is printed.
+func (s *Stack) Run(ctx context.Context, input *StackInput) (err error) {
 	slog.Info("running stack command", "cmd", input.Command)
+	started := time.Now()
+	input, flush := s.export(input)
+	defer flush()
 	input.OnEvent(&StackEvent{StackCommandEvent: &StackCommandEvent{
 		Command: input.Command,
 	}})
 
-	err := s.Lock()
-	if err != nil {
-		if err == provider.ErrLockExists {
-			input.OnEvent(&StackEvent{ConcurrentUpdateEvent: &ConcurrentUpdateEvent{}})
+	if input.Command != "preview" {
+		if err := s.project.checkReservedStage(s.project.app.Stage); err != nil {
+			return err
 		}
-		return err
 	}
-	defer s.Unlock()
 
-	_, err = s.PullState()
-	if err != nil {
-		if errors.Is(err, provider.ErrStateNotFound) {
-			if input.Command != "up" {
-				return ErrStageNotFound
+	// registered before the unlock so the wait conditions are polled once the
+	// stage is unlocked, the complete event reports whether they passed
+	var complete *CompleteEvent
+	defer func() {
+		if complete == nil {
+			return
+		}
+		if input.Command == "up" && !input.Dev && err == nil && complete.Finished && len(complete.Errors) == 0 {
+			if waitErr := s.wait(ctx, input, complete); waitErr != nil {
+				err = ErrStackRunFailed
+			}
+		}
+		input.OnEvent(&StackEvent{CompleteEvent: complete})
+	}()
+
+	if !s.locked {
+		err = s.Lock()
+		if err != nil {
+			if err == provider.ErrLockExists {
+				input.OnEvent(&StackEvent{ConcurrentUpdateEvent: &ConcurrentUpdateEvent{}})
 			}
-		} else {
 			return err
 		}
+		s.locked = s.holdLock
+		if !s.locked {
+			defer s.Unlock()
+		}
+	}
+
+	if input.Command == "up" {
+		owner, err := provider.ClaimStage(s.project.home, s.project.app.Name, s.project.app.Stage, s.repository())
+		if errors.Is(err, provider.ErrNamespaceCollision) {
+			return util.NewReadableError(err, fmt.Sprintf(
+				"Stage \"%s\" of app \"%s\" is deployed from another repository, %s\nSet a different \"namespace\" in your config to share the home with it",
+				s.project.app.Stage,
+				s.project.app.Name,
+				owner.Repository,
+			))
+		}
+		if err != nil {
+			return fmt.Errorf("failed to claim stage: %w", err)
+		}
+	}
+
+	// while the lock is held the state in the workspace is the latest
+	if !s.pulled {
+		_, err = s.pullState(input.OnEvent)
+		if err != nil {
+			if errors.Is(err, provider.ErrStateNotFound) {
+				if input.Command != "up" && input.Command != "preview" {
+					return ErrStageNotFound
+				}
+				// the stage is new, it's held to the stricter rules
+				if input.Command == "up" {
+					if err := ValidateStage(s.project.app.Stage); err != nil {
+						return err
+					}
+				}
+			} else {
+				return err
+			}
+		}
+		s.pulled = s.locked
+	}
+	// a preview doesn't change the state
+	if input.Command != "preview" {
+		defer s.pushState(input.OnEvent)
 	}
-	defer s.PushState()
 
 	passphrase, err := provider.Passphrase(s.project.home, s.project.app.Name, s.project.app.Stage)
 	if err != nil {
 		return err
 	}
 
-	secrets, err := provider.GetSecrets(s.project.home, s.project.app.Name, s.project.app.Stage)
+	secrets, expired, err := provider.GetSecretsAndExpired(s.project.home, s.project.app.Name, s.project.app.Stage)
 	if err != nil {
 		return fmt.Errorf("failed to list secrets: %w", err)
 	}
+	if len(expired) > 0 {
+		input.OnEvent(&StackEvent{WarningEvent: &WarningEvent{
+			Level:   "warning",
+			Message: "These secrets expired and are not set: " + strings.Join(expired, ", "),
+		}})
+	}
+	if input.Command == "up" {
+		if missing := missingSecrets(s.project.app.RequiredSecrets, secrets); len(missing) > 0 {
+			return util.NewReadableError(ErrMissingSecrets, fmt.Sprintf(
+				"Missing required secrets for stage \"%s\": %s\nSet them with `sst secret set <name> <value>`",
+				s.project.app.Stage,
+				strings.Join(missing, ", "),
+			))
+		}
+	}
 
 	env, err := s.project.home.Env()
 	if err != nil {
 		return err
 	}
-	for _, value := range os.Environ() {
-		pair := strings.SplitN(value, "=", 2)
-		if len(pair) == 2 {
-			env[pair[0]] = pair[1]
+	// pulumi, the program, and the providers inherit the env of the process,
+	// the host variables that aren't allowed are blanked out
+	for key, value := range maskHostEnv(os.Environ(), s.project.app.EnvAllowlist, s.project.app.EnvDenylist) {
+		if _, ok := env[key]; !ok {
+			env[key] = value
 		}
 	}
+	for key, value := range filterHostEnv(os.Environ(), s.project.app.EnvAllowlist, s.project.app.EnvDenylist) {
+		env[key] = value
+	}
+	if path, ok := env["PATH"]; ok {
+		env["PATH"] = s.project.pathEnv(path)
+	}
+	for key, value := range s.project.tempEnv() {
+		env[key] = value
+	}
+	for key, value := range proxyEnv(s.project.app.Proxy) {
+		env[key] = value
+	}
 
 	// env := map[string]string{}
 	for key, value := range secrets {
 		env["SST_SECRET_"+key] = value
 	}
 	env["PULUMI_CONFIG_PASSPHRASE"] = passphrase
+	env["NODE_OPTIONS"] = nodeOptions()
+	// the plugins are installed with the providers, nothing else needs the
+	// network so deploys work offline with sst bundle-deps
+	env["PULUMI_SKIP_UPDATE_CHECK"] = "true"
+	if !input.Dev && s.remoteBuildCache() {
+		if err := s.pullBuildCache(); err != nil {
+			slog.Error("failed to pull the build cache", "err", err)
+		}
+	}
+	defer func() {
+		if input.Dev {
+			return
+		}
+		if err == nil && s.remoteBuildCache() {
+			if err := s.pushBuildCache(); err != nil {
+				slog.Error("failed to push the build cache", "err", err)
+			}
+		}
+		s.project.buildCache().prune()
+	}()
 
-	cli := map[string]interface{}{
-		"command": input.Command,
-		"dev":     input.Dev,
-		"paths": map[string]string{
-			"home":     global.ConfigDir(),
-			"root":     s.project.PathRoot(),
-			"work":     s.project.PathWorkingDir(),
-			"platform": s.project.PathPlatformDir(),
-		},
-		"env": env,
+	var prog *program
+	if s.project.definition != nil {
+		prog = s.nativeProgram(input, secrets)
+	} else {
+		if input.Typecheck || s.project.app.Typecheck {
+			diagnostics, err := s.project.typecheck(ctx)
+			if err != nil {
+				return util.NewReadableError(err, "Could not typecheck the config: "+err.Error())
+			}
+			for i := range diagnostics {
+				input.OnEvent(&StackEvent{TypecheckErrorEvent: &diagnostics[i]})
+			}
+			if len(diagnostics) > 0 {
+				return util.NewReadableError(ErrTypecheckFailed, fmt.Sprintf("The config has %d type error(s)", len(diagnostics)))
+			}
+		}
+		prog, err = s.buildProgram(input, env)
+		if err != nil {
+			return err
+		}
 	}
-	cliBytes, err := json.Marshal(cli)
+	// measured once SST_CLI is set, it's part of the env too
+	removeSecretsFile, err := s.useSecretsFile(env)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to write secrets file: %w", err)
 	}
-	appBytes, err := json.Marshal(s.project.app)
-	if err != nil {
-		return err
+	defer removeSecretsFile()
+	for i := range prog.warnings {
+		input.OnEvent(&StackEvent{BuildWarningEvent: &prog.warnings[i]})
+	}
+	if s.project.definition == nil {
+		if findings := s.project.lint(prog.files); len(findings) > 0 {
+			input.OnEvent(&StackEvent{LintEvent: &LintEvent{Findings: findings}})
+		}
 	}
+	if input.FailOnWarnings && len(prog.warnings) > 0 {
+		return util.NewReadableError(ErrBuildWarnings, fmt.Sprintf("The program was built with %d warning(s)", len(prog.warnings)))
+	}
+	if input.OnFiles != nil {
+		input.OnFiles(prog.files)
+	}
+	slog.Info("tracked files")
 
-	providerShim := []string{}
-	for name := range s.project.app.Providers {
-		pkg := getProviderPackage(name)
-		global := cleanProviderName(name)
-		providerShim = append(providerShim, fmt.Sprintf("import * as %s from '%s'", global, pkg))
-		providerShim = append(providerShim, fmt.Sprintf("globalThis.%s = %s", global, global))
-	}
-
-	buildResult, err := js.Build(js.EvalOptions{
-		Dir: s.project.PathPlatformDir(),
-		Define: map[string]string{
-			"$app": string(appBytes),
-			"$cli": string(cliBytes),
-			"$dev": fmt.Sprintf("%v", input.Dev),
-		},
-		Inject: []string{filepath.Join(s.project.PathWorkingDir(), "platform/src/shim/run.js")},
-		Code: fmt.Sprintf(`
-      import { run } from "%v";
-      %v
-      import mod from "%v/sst.config.ts";
-      const result = await run(mod.run)
-      export default result
-    `,
-			filepath.Join(s.project.PathWorkingDir(), "platform/src/auto/run.ts"),
-			strings.Join(providerShim, "\n"),
-			s.project.PathRoot(),
-		),
-	})
-	if err != nil {
-		return err
+	envKeys, envHash := hashEnv(env)
+	manifest := &Manifest{
+		App:             s.project.app.Name,
+		Stage:           s.project.app.Stage,
+		Command:         input.Command,
+		Started:         started,
+		CLIVersion:      s.project.version,
+		PlatformVersion: s.platformVersion(),
+		GitCommit:       s.git("rev-parse", "HEAD"),
+		ProgramHash:     prog.hash,
+		Sources:         s.hashSources(prog.files),
+		Providers:       s.providerVersions(),
+		EnvKeys:         envKeys,
+		EnvHash:         envHash,
+		Artifacts:       map[string]string{},
 	}
-	outfile := buildResult.OutputFiles[0].Path
 
-	if input.OnFiles != nil {
-		var meta = map[string]interface{}{}
-		err := json.Unmarshal([]byte(buildResult.Metafile), &meta)
+	if input.Hotswap && input.Command == "up" && !input.Dev {
+		swapped, err := s.hotswap(ctx, input, manifest, secrets)
 		if err != nil {
 			return err
 		}
-		files := []string{}
-		for key := range meta["inputs"].(map[string]interface{}) {
-			absPath, err := filepath.Abs(key)
-			if err != nil {
-				continue
+		if swapped {
+			return nil
+		}
+	}
+
+	if s.project.definition == nil {
+		err = s.project.useJSRuntime(env)
+		if err != nil {
+			return fmt.Errorf("failed to set up bun: %w", err)
+		}
+		// bun stands in for node, and the runner image brings its own
+		if s.project.app.JSRuntime != "bun" && s.project.app.Runner == nil {
+			s.project.useNode(env)
+			if event := s.project.checkNode(ctx, env); event != nil {
+				input.OnEvent(&StackEvent{NodeVersionEvent: event})
+				return util.NewReadableError(ErrNodeVersion, event.Error)
 			}
-			files = append(files, absPath)
 		}
-		input.OnFiles(files)
 	}
-	slog.Info("tracked files")
 
-	ws, err := auto.NewLocalWorkspace(ctx,
+	if s.project.app.Runner != nil {
+		manifest.RunnerImage = s.project.app.Runner.Image
+		err = s.project.useRunner(env)
+		if err != nil {
+			return fmt.Errorf("failed to set up the container runner: %w", err)
+		}
+	}
+
+	runtime := "nodejs"
+	if prog.run != nil {
+		runtime = "go"
+	}
+	options := []auto.LocalWorkspaceOption{
 		auto.WorkDir(s.project.PathWorkingDir()),
 		auto.PulumiHome(global.ConfigDir()),
 		auto.Project(workspace.Project{
 			Name:    tokens.PackageName(s.project.app.Name),
-			Runtime: workspace.NewProjectRuntimeInfo("nodejs", nil),
+			Runtime: workspace.NewProjectRuntimeInfo(runtime, nil),
 			Backend: &workspace.ProjectBackend{
 				URL: fmt.Sprintf("file://%v", s.project.PathWorkingDir()),
 			},
-			Main: outfile,
+			Main: prog.main,
 		}),
 		auto.EnvVars(
 			env,
 		),
-	)
+	}
+	if prog.run != nil {
+		options = append(options, auto.Program(prog.run))
+	}
+	ws, err := auto.NewLocalWorkspace(ctx, options...)
 	if err != nil {
 		return err
 	}
 	slog.Info("built workspace")
+	manifest.PulumiVersion = ws.PulumiVersion()
 
 	stack, err := auto.UpsertStack(ctx,
 		s.project.app.Stage,
@@ -248,44 +516,40 @@ func (s *stack) Run(ctx context.Context, input *StackInput) error {
 	slog.Info("built stack")
 
 	config := auto.ConfigMap{}
-	for provider, args := range s.project.app.Providers {
-		for key, value := range args.(map[string]interface{}) {
-			if provider == "cloudflare" && key == "accountId" {
-				continue
-			}
-			switch v := value.(type) {
-			case string:
-				config[fmt.Sprintf("%v:%v", provider, key)] = auto.ConfigValue{Value: v}
-			case []string:
-				for i, val := range v {
-					config[fmt.Sprintf("%v:%v[%d]", provider, key, i)] = auto.ConfigValue{Value: val}
-				}
-			}
-		}
+	for provider := range s.project.app.Providers {
+		maps.Copy(config, providerConfig(provider, s.project.providerArgs(provider), s.project.secretConfig, func(key string) bool {
+			return provider == "cloudflare" && key == "accountId"
+		}))
 	}
-	err = stack.SetAllConfig(ctx, config)
+	err = stack.SetAllConfigWithOptions(ctx, config, &auto.ConfigOptions{Path: true})
 	if err != nil {
 		return err
 	}
 	slog.Info("built config")
 
 	stream := make(chan events.EngineEvent)
-	eventlog, err := os.Create(filepath.Join(s.project.PathWorkingDir(), "event.log"))
+	log, err := eventlog.Create(s.project.PathEventLog(), eventlog.RunMarker{
+		Command: input.Command,
+		Started: started,
+	})
 	if err != nil {
 		return err
 	}
-	defer eventlog.Close()
-
-	complete := &CompleteEvent{
-		Links:     Links{},
-		Receivers: Receivers{},
-		Warps:     Warps{},
-		Hints:     map[string]string{},
-		Outputs:   map[string]interface{}{},
-		Errors:    []Error{},
-		Finished:  false,
+	defer log.Close()
+
+	complete = &CompleteEvent{
+		Links:       Links{},
+		Receivers:   Receivers{},
+		DevCommands: DevCommands{},
+		Logs:        WarpLogs{},
+		Warps:       Warps{},
+		Hints:       map[string]string{},
+		Outputs:     map[string]interface{}{},
+		Errors:      []Error{},
+		Finished:    false,
 	}
 
+	diagnostics := newDiagnosticBuffer()
 	go func() {
 		for {
 			select {
@@ -306,25 +570,54 @@ func (s *stack) Run(ctx context.Context, input *StackInput) error {
 					})
 				}
 
-				input.OnEvent(&StackEvent{EngineEvent: event})
+				detail, forward := diagnostics.handle(event)
+				if warning := parseWarning(event); warning != nil {
+					input.OnEvent(&StackEvent{WarningEvent: warning})
+					forward = false
+				}
+				if forward {
+					input.OnEvent(&StackEvent{EngineEvent: event})
+				}
+				if detail != nil {
+					input.OnEvent(&StackEvent{DiagnosticContextEvent: detail})
+				}
 
 				if event.SummaryEvent != nil {
 					complete.Finished = true
 				}
 
-				bytes, err := json.Marshal(event)
-				if err != nil {
+				if err := log.Write(event); err != nil {
 					return
 				}
-				eventlog.Write(bytes)
-				eventlog.WriteString("\n")
+			}
+		}
+	}()
+
+	defer func() {
+		if input.Command == "preview" {
+			return
+		}
+		manifest.Finished = time.Now()
+		manifest.Success = err == nil && complete.Finished && len(complete.Errors) == 0
+		manifest.Artifacts = artifactDigests(complete.Resources)
+		s.putManifest(manifest)
+		if manifest.Success {
+			s.writeTags(input.Command)
+		}
+		if input.Command == "up" && !input.Dev {
+			if manifest.Success {
+				if err := s.saveHotswap(manifest, secrets); err != nil {
+					slog.Error("failed to save hotswap state", "err", err)
+				}
+			} else {
+				s.resetHotswap()
 			}
 		}
 	}()
 
 	defer func() {
 		slog.Info("stack command complete")
-		defer input.OnEvent(&StackEvent{CompleteEvent: complete})
+		defer s.saveCompletions(complete, secrets)
 
 		rawDeploment, _ := stack.Export(context.Background())
 		var deployment apitype.DeploymentV3
@@ -333,7 +626,9 @@ func (s *stack) Run(ctx context.Context, input *StackInput) error {
 		if len(deployment.Resources) == 0 {
 			return
 		}
+		complete.secretOutputs = secretOutputs(deployment.Resources[0].Outputs)
 		outputs := decrypt(deployment.Resources[0].Outputs)
+		types := ""
 		complete.Resources = deployment.Resources
 		linksOutput, ok := outputs["_links"]
 		if ok {
@@ -341,13 +636,14 @@ func (s *stack) Run(ctx context.Context, input *StackInput) error {
 			for key, value := range links {
 				complete.Links[key] = value
 			}
-			typesFile, _ := os.Create(filepath.Join(s.project.PathWorkingDir(), "types.generated.ts"))
-			defer typesFile.Close()
+			var typesFile strings.Builder
 			typesFile.WriteString(`import "sst"` + "\n")
 			typesFile.WriteString(`declare module "sst" {` + "\n")
 			typesFile.WriteString("  export interface Resource " + inferTypes(links, "  ") + "\n")
 			typesFile.WriteString("}" + "\n")
 			typesFile.WriteString("export {}")
+			types = typesFile.String()
+			os.WriteFile(filepath.Join(s.project.PathWorkingDir(), "types.generated.ts"), []byte(types), 0644)
 			provider.PutLinks(s.project.home, s.project.app.Name, s.project.app.Stage, links)
 		}
 
@@ -384,21 +680,89 @@ func (s *stack) Run(ctx context.Context, input *StackInput) error {
 			}
 		}
 
+		devOutput, ok := outputs["_dev"]
+		if ok {
+			commands, _ := devOutput.(map[string]interface{})
+			for key, value := range commands {
+				if value == nil {
+					continue
+				}
+				data, _ := json.Marshal(value)
+				var command DevCommand
+				json.Unmarshal(data, &command)
+				complete.DevCommands[key] = command
+			}
+		}
+
+		logsOutput, ok := outputs["_logs"]
+		if ok {
+			logs, _ := logsOutput.(map[string]interface{})
+			for key, value := range logs {
+				data, _ := json.Marshal(value)
+				var entry WarpLog
+				json.Unmarshal(data, &entry)
+				complete.Logs[key] = entry
+			}
+		}
+
 		for key, value := range outputs {
 			if strings.HasPrefix(key, "_") {
 				continue
 			}
 			complete.Outputs[key] = value
 		}
+
+		if leaks := scanLeaks(secrets, complete.Outputs, types); len(leaks) > 0 {
+			input.OnEvent(&StackEvent{SecretLeakEvent: &SecretLeakEvent{
+				Leaks: leaks,
+			}})
+		}
+
+		if metadata, err := provider.GetSecretMetadata(s.project.home, s.project.app.Name, s.project.app.Stage); err != nil {
+			slog.Error("failed to get secret metadata", "err", err)
+		} else if overdue := overdueSecrets(complete.Resources, metadata, time.Now()); len(overdue) > 0 {
+			input.OnEvent(&StackEvent{SecretRotationEvent: &SecretRotationEvent{
+				Secrets: overdue,
+			}})
+		}
+
+		if input.Dev {
+			err := s.UpdateMetadata(started, func(metadata *Metadata) {
+				metadata.Links = complete.Links
+				metadata.Hints = complete.Hints
+				metadata.Receivers = complete.Receivers
+				metadata.Warps = complete.Warps
+			})
+			if err != nil {
+				slog.Error("failed to update metadata", "err", err)
+			}
+		}
+
+		if input.Command == "up" {
+			findings := ScanResources(complete.Resources)
+			if len(findings) > 0 {
+				input.OnEvent(&StackEvent{SecurityScanEvent: &SecurityScanEvent{
+					Findings: findings,
+				}})
+			}
+		}
 	}()
 
+	// debug diagnostics are always requested from the engine, they are only
+	// surfaced for resources that fail
+	debugLogging := debug.LoggingOptions{Debug: true}
+
 	slog.Info("running stack command", "cmd", input.Command)
 	switch input.Command {
 	case "up":
+		if !input.Dev {
+			s.resetHotswap()
+		}
 		_, err = stack.Up(ctx,
 			optup.ProgressStreams(),
 			optup.ErrorProgressStreams(),
 			optup.EventStreams(stream),
+			optup.DebugLogging(debugLogging),
 		)
 
 	case "destroy":
@@ -406,6 +770,7 @@ func (s *stack) Run(ctx context.Context, input *StackInput) error {
 			optdestroy.ProgressStreams(),
 			optdestroy.ErrorProgressStreams(),
 			optdestroy.EventStreams(stream),
+			optdestroy.DebugLogging(debugLogging),
 		)
 
 	case "refresh":
@@ -413,6 +778,15 @@ func (s *stack) Run(ctx context.Context, input *StackInput) error {
 			optrefresh.ProgressStreams(),
 			optrefresh.ErrorProgressStreams(),
 			optrefresh.EventStreams(stream),
+			optrefresh.DebugLogging(debugLogging),
+		)
+
+	case "preview":
+		_, err = stack.Preview(ctx,
+			optpreview.ProgressStreams(),
+			optpreview.ErrorProgressStreams(),
+			optpreview.EventStreams(stream),
+			optpreview.DebugLogging(debugLogging),
 		)
 	}
 
@@ -423,6 +797,186 @@ func (s *stack) Run(ctx context.Context, input *StackInput) error {
 	return nil
 }
 
+// program is what a stack command runs, either the built config or the run
+// function of an app defined in Go.
+type program struct {
+	main     string
+	run      pulumi.RunFunc
+	files    []string
+	hash     string
+	warnings []BuildWarningEvent
+}
+
+// programOptions returns how the config is bundled with the platform, and
+// the $cli it reads at runtime from SST_CLI.
+func (s *Stack) programOptions(input *StackInput) (js.EvalOptions, map[string]interface{}, error) {
+	cli := map[string]interface{}{
+		"command": input.Command,
+		"dev":     input.Dev,
+		"paths": map[string]string{
+			"home":      global.ConfigDir(),
+			"root":      s.project.PathRoot(),
+			"work":      s.project.PathWorkingDir(),
+			"platform":  s.project.PathPlatformDir(),
+			"artifacts": s.project.PathArtifacts(),
+			"temp":      s.project.PathTempDir(),
+		},
+	}
+	appBytes, err := json.Marshal(s.project.app)
+	if err != nil {
+		return js.EvalOptions{}, nil, err
+	}
+
+	providerNames := []string{}
+	for name := range s.project.app.Providers {
+		providerNames = append(providerNames, name)
+	}
+	shim, err := s.providerShimModule(providerNames)
+	if err != nil {
+		return js.EvalOptions{}, nil, util.NewReadableError(err, err.Error())
+	}
+
+	// the stacks and the overlay of the stage run after the config
+	imports := []string{overlayImport(s.project.PathOverlay(s.project.app.Stage))}
+	programs := []string{"mod.run"}
+	for i, stack := range s.project.PathStacks() {
+		imports = append(imports, fmt.Sprintf("import stack%d from %s;", i, strconv.Quote(stack)))
+		programs = append(programs, fmt.Sprintf("stack%d", i))
+	}
+	programs = append(programs, "overlay.run")
+
+	define := map[string]string{}
+	inject := []string{filepath.Join(s.project.PathWorkingDir(), "platform/src/shim/run.js")}
+	if build := s.project.app.Build; build != nil {
+		for key, value := range build.Define {
+			define[key] = value
+		}
+		inject = append(inject, build.Inject...)
+	}
+	define["$app"] = string(appBytes)
+	define["$dev"] = fmt.Sprintf("%v", input.Dev)
+
+	options := js.EvalOptions{
+		Dir:      s.project.PathPlatformDir(),
+		Banner:   "const $cli = JSON.parse(process.env.SST_CLI);",
+		Define:   define,
+		Inject:   inject,
+		External: []string{shim},
+		Plugins:  s.project.programPlugins(),
+		Code: fmt.Sprintf(`
+      import { run, compose } from "%v";
+      import %v;
+      import mod from "%v";
+      %v
+      const result = await run(compose(%v))
+      export default result
+    `,
+			filepath.Join(s.project.PathWorkingDir(), "platform/src/auto/run.ts"),
+			strconv.Quote(filepath.ToSlash(shim)),
+			s.project.PathConfig(),
+			strings.Join(imports, "\n      "),
+			strings.Join(programs, ", "),
+		),
+	}
+	if build := s.project.app.Build; build != nil {
+		options.Minify = build.Minify
+		options.KeepNames = build.KeepNames
+		options.Footer = build.Footer
+	}
+	return options, cli, nil
+}
+
+// buildProgram bundles the config with the platform into a single file
+// that node runs.
+func (s *Stack) buildProgram(input *StackInput, env map[string]string) (*program, error) {
+	options, cli, err := s.programOptions(input)
+	if err != nil {
+		return nil, err
+	}
+	// read at runtime instead of defined at build time so the bundle only
+	// depends on the sources and can be cached. The env is the one passed to
+	// the deployment, not the whole host env. The secrets and the passphrase
+	// are left out, the program already has them in its env and copying them
+	// would push it over the limit useSecretsFile keeps it under.
+	cliEnv := map[string]string{}
+	for key, value := range env {
+		if strings.HasPrefix(key, "SST_SECRET_") || key == "SST_SECRETS_KEY" || key == "PULUMI_CONFIG_PASSPHRASE" {
+			continue
+		}
+		cliEnv[key] = value
+	}
+	cli["env"] = cliEnv
+	cliBytes, err := json.Marshal(cli)
+	if err != nil {
+		return nil, err
+	}
+	env["SST_CLI"] = string(cliBytes)
+
+	if input.Dev {
+		return s.rebuildProgram(input, options)
+	}
+
+	cache := s.project.buildCache()
+	key, err := cache.key(options)
+	if err != nil {
+		return nil, err
+	}
+	if cached := cache.program(key, options.Dir); cached != nil {
+		slog.Info("using cached program", "key", key)
+		return cached, nil
+	}
+	buildResult, err := js.Build(options)
+	if err != nil {
+		return nil, err
+	}
+	result, err := newProgram(buildResult)
+	if err != nil {
+		return nil, err
+	}
+	if err := cache.putProgram(key, result); err != nil {
+		slog.Error("failed to cache program", "err", err)
+	}
+	return result, nil
+}
+
+func newProgram(buildResult esbuild.BuildResult) (*program, error) {
+	var meta = map[string]interface{}{}
+	err := json.Unmarshal([]byte(buildResult.Metafile), &meta)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for key := range meta["inputs"].(map[string]interface{}) {
+		absPath, err := filepath.Abs(key)
+		if err != nil {
+			continue
+		}
+		files = append(files, absPath)
+	}
+	return &program{
+		main:     buildResult.OutputFiles[0].Path,
+		files:    files,
+		hash:     digest(buildResult.OutputFiles[0].Contents),
+		warnings: buildWarnings(buildResult.Warnings),
+	}, nil
+}
+
+// nativeProgram runs the app defined in Go in-process.
+func (s *Stack) nativeProgram(input *StackInput, secrets map[string]string) *program {
+	return &program{
+		run: func(ctx *pulumi.Context) error {
+			return s.project.definition.run(&Ctx{
+				Context: ctx,
+				App:     s.project.app,
+				Dev:     input.Dev,
+				Secrets: secrets,
+			})
+		},
+		files: []string{},
+	}
+}
+
+// ImportOptions describe an existing resource to adopt into the state.
 type ImportOptions struct {
 	Type   string
 	Name   string
@@ -430,7 +984,9 @@ type ImportOptions struct {
 	Parent string
 }
 
-func (s *stack) Import(ctx context.Context, input *ImportOptions) error {
+// Import adds an existing resource to the state of the stage and refreshes
+// it so its properties are read from the cloud.
+func (s *Stack) Import(ctx context.Context, input *ImportOptions) error {
 	urnPrefix := fmt.Sprintf("urn:pulumi:%v::%v::", s.project.app.Stage, s.project.app.Name)
 	urnFinal := input.Type + "::" + input.Name
 	urn, err := resource.ParseURN(urnPrefix + urnFinal)
@@ -449,8 +1005,7 @@ func (s *stack) Import(ctx context.Context, input *ImportOptions) error {
 		parent, err = resource.ParseURN(urnPrefix + parentType + "::" + parentName)
 	}
 
-	fmt.Println(urn)
-	fmt.Println(parent)
+	slog.Info("importing", "urn", urn, "parent", parent)
 
 	err = provider.Lock(s.project.home, s.project.app.Name, s.project.app.Stage)
 	if err != nil {
@@ -495,22 +1050,12 @@ func (s *stack) Import(ctx context.Context, input *ImportOptions) error {
 	}
 
 	config := auto.ConfigMap{}
-	for provider, args := range s.project.app.Providers {
-		for key, value := range args.(map[string]interface{}) {
-			if key == "version" {
-				continue
-			}
-			switch v := value.(type) {
-			case string:
-				config[fmt.Sprintf("%v:%v", provider, key)] = auto.ConfigValue{Value: v}
-			case []string:
-				for i, val := range v {
-					config[fmt.Sprintf("%v:%v[%d]", provider, key, i)] = auto.ConfigValue{Value: val}
-				}
-			}
-		}
+	for provider := range s.project.app.Providers {
+		maps.Copy(config, providerConfig(provider, s.project.providerArgs(provider), s.project.secretConfig, func(key string) bool {
+			return key == "version"
+		}))
 	}
-	err = stack.SetAllConfig(ctx, config)
+	err = stack.SetAllConfigWithOptions(ctx, config, &auto.ConfigOptions{Path: true})
 	if err != nil {
 		return err
 	}
@@ -553,8 +1098,7 @@ func (s *stack) Import(ctx context.Context, input *ImportOptions) error {
 		return err
 	}
 
-	fmt.Println("imported")
-	fmt.Println("refreshing")
+	slog.Info("imported, refreshing", "urn", urn)
 	_, err = stack.Refresh(ctx, optrefresh.Target([]string{string(urn)}))
 	if err != nil {
 		return err
@@ -562,11 +1106,29 @@ func (s *stack) Import(ctx context.Context, input *ImportOptions) error {
 	return s.PushState()
 }
 
-func (s *stack) Lock() error {
+func (s *Stack) Lock() error {
 	return provider.Lock(s.project.home, s.project.app.Name, s.project.app.Stage)
 }
 
-func (s *stack) Unlock() error {
+// HoldLock keeps the stage locked and its state in the workspace between runs
+// until ReleaseLock, so dev doesn't lock and download the state again for
+// every deploy.
+func (s *Stack) HoldLock() {
+	s.holdLock = true
+}
+
+// ReleaseLock unlocks the stage if a run took the lock while it was held.
+func (s *Stack) ReleaseLock() error {
+	s.holdLock = false
+	s.pulled = false
+	if !s.locked {
+		return nil
+	}
+	s.locked = false
+	return s.Unlock()
+}
+
+func (s *Stack) Unlock() error {
 	dir := s.project.PathWorkingDir()
 	files, err := os.ReadDir(dir)
 	if err != nil {
@@ -585,7 +1147,12 @@ func (s *stack) Unlock() error {
 	return provider.Unlock(s.project.home, s.project.app.Name, s.project.app.Stage)
 }
 
-func (s *stack) PullState() (string, error) {
+func (s *Stack) PullState() (string, error) {
+	return s.pullState(nil)
+}
+
+// pullState downloads the state, reporting progress to onEvent if set.
+func (s *Stack) pullState(onEvent func(event *StackEvent)) (string, error) {
 	pulumiDir := filepath.Join(s.project.PathWorkingDir(), ".pulumi")
 	err := os.RemoveAll(pulumiDir)
 	if err != nil {
@@ -602,6 +1169,7 @@ func (s *stack) PullState() (string, error) {
 		s.project.app.Name,
 		s.project.app.Stage,
 		path,
+		transferProgress(onEvent),
 	)
 	if err != nil {
 		return "", err
@@ -609,17 +1177,39 @@ func (s *stack) PullState() (string, error) {
 	return path, nil
 }
 
-func (s *stack) PushState() error {
+func (s *Stack) PushState() error {
+	return s.pushState(nil)
+}
+
+// pushState uploads the state, reporting progress to onEvent if set.
+func (s *Stack) pushState(onEvent func(event *StackEvent)) error {
 	pulumiDir := filepath.Join(s.project.PathWorkingDir(), ".pulumi")
 	return provider.PushState(
 		s.project.home,
 		s.project.app.Name,
 		s.project.app.Stage,
 		filepath.Join(pulumiDir, "stacks", s.project.app.Name, fmt.Sprintf("%v.json", s.project.app.Stage)),
+		transferProgress(onEvent),
 	)
 }
 
-func (s *stack) Cancel() error {
+func transferProgress(onEvent func(event *StackEvent)) func(provider.TransferProgress) {
+	if onEvent == nil {
+		return nil
+	}
+	return func(progress provider.TransferProgress) {
+		onEvent(&StackEvent{StateTransferEvent: &StateTransferEvent{
+			Direction: progress.Direction,
+			Bytes:     progress.Bytes,
+			Total:     progress.Total,
+			Percent:   progress.Percent(),
+			ETA:       progress.ETA,
+			Done:      progress.Done,
+		}})
+	}
+}
+
+func (s *Stack) Cancel() error {
 	return provider.Unlock(
 		s.project.home,
 		s.project.app.Name,
@@ -627,6 +1217,19 @@ func (s *stack) Cancel() error {
 	)
 }
 
+// secretOutputs returns the keys of the outputs that are secrets, before
+// they're decrypted.
+func secretOutputs(input map[string]interface{}) map[string]bool {
+	result := map[string]bool{}
+	for key, value := range input {
+		casted, ok := value.(map[string]interface{})
+		if ok && casted[resource.SigKey] == resource.SecretSig {
+			result[key] = true
+		}
+	}
+	return result
+}
+
 func decrypt(input map[string]interface{}) map[string]interface{} {
 	for key, value := range input {
 		switch value := value.(type) {
@@ -644,3 +1247,74 @@ func decrypt(input map[string]interface{}) map[string]interface{} {
 	}
 	return input
 }
+
+// OutputsAt returns the outputs of the stack as they were at a previous
+// version of the state. Use StateVersions to list the available versions.
+func (s *Stack) OutputsAt(ctx context.Context, version string) (map[string]interface{}, error) {
+	data, err := provider.PullStateVersion(s.project.home, s.project.app.Name, s.project.app.Stage, version)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, err := parseCheckpoint(data)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint.Latest == nil {
+		return map[string]interface{}{}, nil
+	}
+	return stackOutputs(checkpoint.Latest.Resources), nil
+}
+
+// Outputs returns the outputs of the stack as of its last deploy.
+func (s *Stack) Outputs(ctx context.Context) (map[string]interface{}, error) {
+	resources, err := s.Resources()
+	if err != nil {
+		return nil, err
+	}
+	return stackOutputs(resources), nil
+}
+
+// stackOutputs returns the outputs the program returned, without the
+// internal ones.
+func stackOutputs(resources []apitype.ResourceV3) map[string]interface{} {
+	outputs := map[string]interface{}{}
+	for _, resource := range resources {
+		if resource.Type != "pulumi:pulumi:Stack" {
+			continue
+		}
+		for key, value := range redactSecrets(resource.Outputs) {
+			if strings.HasPrefix(key, "_") {
+				continue
+			}
+			outputs[key] = value
+		}
+	}
+	return outputs
+}
+
+func (s *Stack) StateVersions(ctx context.Context) ([]provider.Version, error) {
+	return provider.ListStateVersions(s.project.home, s.project.app.Name, s.project.app.Stage)
+}
+
+// redactSecrets replaces values that are still encrypted in the checkpoint
+// since they can only be decrypted by the engine.
+func redactSecrets(input map[string]interface{}) map[string]interface{} {
+	for key, value := range input {
+		casted, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if casted[resource.SigKey] == resource.SecretSig {
+			if plaintext, ok := casted["plaintext"].(string); ok {
+				var parsed any
+				json.Unmarshal([]byte(plaintext), &parsed)
+				input[key] = parsed
+				continue
+			}
+			input[key] = "[secret]"
+			continue
+		}
+		input[key] = redactSecrets(casted)
+	}
+	return input
+}
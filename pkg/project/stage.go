@@ -1,9 +1,19 @@
 package project
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
+
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project/provider"
 )
 
 func resolveStageFile(cfgPath string) string {
@@ -28,3 +38,213 @@ func SetPersonalStage(cfgPath string, stage string) error {
 	}
 	return nil
 }
+
+// BranchStage derives the stage from the git branch when a command isn't
+// passed a stage, so every branch gets its own stage.
+type BranchStage struct {
+	// Stages maps branches, by name or glob pattern, to a stage. A branch
+	// mapped to an empty stage uses the personal stage.
+	Stages map[string]string `json:"stages,omitempty"`
+	// Prefix is added to the stages that are derived from a branch name
+	Prefix string `json:"prefix,omitempty"`
+	// MaxLength is the longest stage derived from a branch name
+	MaxLength int `json:"maxLength,omitempty"`
+}
+
+const defaultBranchStageLength = 32
+
+var invalidStageChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+func (b *BranchStage) validate() error {
+	for branch, stage := range b.Stages {
+		if _, err := path.Match(branch, ""); err != nil {
+			return fmt.Errorf("Branch pattern %q is invalid", branch)
+		}
+		if stage != "" {
+			if err := ValidateStage(stage); err != nil {
+				return fmt.Errorf("Branch %q: %w", branch, err)
+			}
+		}
+	}
+	if b.Prefix != "" && (!StageRegex.MatchString(b.Prefix) || strings.ToLower(b.Prefix) != b.Prefix) {
+		return fmt.Errorf("Prefix %q is invalid, it can only contain lowercase alphanumeric characters and hyphens", b.Prefix)
+	}
+	if b.MaxLength < 0 || b.MaxLength > MaxStageLength {
+		return fmt.Errorf("MaxLength has to be between 1 and %d", MaxStageLength)
+	}
+	return nil
+}
+
+// Stage returns the stage of a branch, from the mapping or its sanitized
+// name. It's empty if the branch uses the personal stage.
+func (b *BranchStage) Stage(branch string) string {
+	if branch == "" {
+		return ""
+	}
+	if stage, ok := b.Stages[branch]; ok {
+		return stage
+	}
+	// the most specific pattern wins
+	patterns := make([]string, 0, len(b.Stages))
+	for pattern := range b.Stages {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return b.Stages[pattern]
+		}
+	}
+
+	maxLength := b.MaxLength
+	if maxLength == 0 {
+		maxLength = defaultBranchStageLength
+	}
+	stage := strings.Trim(invalidStageChars.ReplaceAllString(strings.ToLower(branch), "-"), "-")
+	if stage == "" {
+		return ""
+	}
+	stage = b.Prefix + stage
+	if len(stage) > maxLength {
+		stage = strings.TrimRight(stage[:maxLength], "-")
+	}
+	return stage
+}
+
+// ciBranchVariables are where CI providers put the branch, since they
+// usually check out a commit and not the branch.
+var ciBranchVariables = []string{
+	"GITHUB_HEAD_REF",
+	"GITHUB_REF_NAME",
+	"CI_COMMIT_REF_NAME",
+	"BITBUCKET_BRANCH",
+	"CIRCLE_BRANCH",
+	"BRANCH_NAME",
+}
+
+// currentBranch returns the git branch of dir, or an empty string outside of
+// a repo.
+func currentBranch(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	branch := strings.TrimSpace(string(out))
+	if err == nil && branch != "" && branch != "HEAD" {
+		return branch
+	}
+	for _, name := range ciBranchVariables {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func resolveBranchStageFile(cfgPath string) string {
+	return filepath.Join(ResolveWorkingDir(cfgPath), "branch-stage.json")
+}
+
+// LoadBranchStage returns the stage of the current branch with the rules
+// from the last time the config was loaded, or an empty string if the app
+// doesn't use branch stages.
+func LoadBranchStage(cfgPath string) string {
+	data, err := os.ReadFile(resolveBranchStageFile(cfgPath))
+	if err != nil {
+		return ""
+	}
+	var rules BranchStage
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return ""
+	}
+	return rules.Stage(currentBranch(filepath.Dir(cfgPath)))
+}
+
+// saveBranchStage keeps the rules of the config so the stage can be picked
+// before the config is loaded.
+func saveBranchStage(cfgPath string, rules *BranchStage) error {
+	path := resolveBranchStageFile(cfgPath)
+	if rules == nil {
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// BranchStage returns the stage of the current branch, or an empty string if
+// the app doesn't use branch stages or the branch uses the personal stage.
+func (p *Project) BranchStage() string {
+	if p.app.BranchStage == nil {
+		return ""
+	}
+	return p.app.BranchStage.Stage(currentBranch(p.root))
+}
+
+// MaxStageLength is the longest stage name. Resource names are prefixed
+// with the app and stage and are truncated to 64 characters, so a longer
+// stage would collide with the stages that start the same way.
+const MaxStageLength = 64
+
+// ValidateStage checks that a stage name can be used in the names of the
+// resources of every provider, the fallback stage of the secrets is allowed
+// but can't be deployed.
+func ValidateStage(stage string) error {
+	if stage == provider.FallbackStage {
+		return nil
+	}
+	invalid := func(reason string) error {
+		return util.NewReadableError(ErrInvalidStageName, fmt.Sprintf("The stage name %q is invalid, %s", stage, reason))
+	}
+	switch {
+	case stage == "":
+		return invalid("it can't be empty")
+	case len(stage) > MaxStageLength:
+		return invalid(fmt.Sprintf("it can be at most %d characters long", MaxStageLength))
+	case !StageRegex.MatchString(stage):
+		return invalid("it can only contain alphanumeric characters and hyphens")
+	case strings.HasPrefix(stage, "-") || strings.HasSuffix(stage, "-"):
+		return invalid("it can't start or end with a hyphen")
+	case strings.ToLower(stage) != stage:
+		return invalid("it has to be lowercase since it's used in the names of resources like S3 buckets")
+	}
+	return nil
+}
+
+// validateExistingStage is the looser rule stages were held to before
+// ValidateStage, so the ones already deployed, like Frank, can still be
+// changed. ValidateStage is checked when a stage is created.
+func validateExistingStage(stage string) error {
+	if stage == provider.FallbackStage || StageRegex.MatchString(stage) {
+		return nil
+	}
+	return ErrInvalidStageName
+}
+
+var ErrReservedStage = fmt.Errorf("reserved stage")
+
+// AllowReservedStages lets the reserved stages of the config be deployed.
+func (p *Project) AllowReservedStages() {
+	p.allowReserved = true
+}
+
+// checkReservedStage is called before a command changes a stage.
+func (p *Project) checkReservedStage(stage string) error {
+	if stage == provider.FallbackStage {
+		return util.NewReadableError(ErrReservedStage, fmt.Sprintf("The %s stage only holds the secrets shared by every stage, it can't be deployed", provider.FallbackStage))
+	}
+	if p.allowReserved || !slices.Contains(p.app.ReservedStages, stage) {
+		return nil
+	}
+	return util.NewReadableError(ErrReservedStage, fmt.Sprintf("The %q stage is reserved, pass --allow-reserved to change it", stage))
+}
@@ -0,0 +1,68 @@
+package project
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBranchStage(t *testing.T) {
+	rules := &BranchStage{
+		Stages: map[string]string{
+			"main":         "production",
+			"release/*":    "staging",
+			"release/v2-*": "staging-v2",
+			"dependabot/*": "",
+		},
+		Prefix:    "pr-",
+		MaxLength: 20,
+	}
+	tests := map[string]string{
+		"":                           "",
+		"main":                       "production",
+		"release/1.0":                "staging",
+		"release/v2-beta":            "staging-v2",
+		"dependabot/npm/x":           "pr-dependabot-npm-x",
+		"dependabot/foo":             "",
+		"Feature/Add_Login":          "pr-feature-add-login",
+		"fix/a-really-long-branch-x": "pr-fix-a-really-long",
+		"---":                        "",
+	}
+	for branch, expected := range tests {
+		if actual := rules.Stage(branch); actual != expected {
+			t.Errorf("%q: expected %q, got %q", branch, expected, actual)
+		}
+	}
+	if err := (&BranchStage{Stages: map[string]string{"main": "prod_1"}}).validate(); err == nil {
+		t.Error("expected an invalid stage to fail validation")
+	}
+}
+
+func TestValidateStage(t *testing.T) {
+	valid := []string{"dev", "pr-123", "_fallback", strings.Repeat("a", MaxStageLength)}
+	for _, stage := range valid {
+		if err := ValidateStage(stage); err != nil {
+			t.Errorf("%q: unexpected error %v", stage, err)
+		}
+	}
+	invalid := []string{"", "Dev", "my_stage", "-dev", "dev-", "a.b", strings.Repeat("a", MaxStageLength+1)}
+	for _, stage := range invalid {
+		if err := ValidateStage(stage); !errors.Is(err, ErrInvalidStageName) {
+			t.Errorf("%q: expected ErrInvalidStageName, got %v", stage, err)
+		}
+	}
+}
+
+func TestValidateExistingStage(t *testing.T) {
+	// deployed before the stricter rules, they can still be changed
+	for _, stage := range []string{"Frank", "-dev", "dev-", strings.Repeat("a", MaxStageLength+1), "_fallback"} {
+		if err := validateExistingStage(stage); err != nil {
+			t.Errorf("%q: unexpected error %v", stage, err)
+		}
+	}
+	for _, stage := range []string{"", "my_stage", "a.b"} {
+		if err := validateExistingStage(stage); !errors.Is(err, ErrInvalidStageName) {
+			t.Errorf("%q: expected ErrInvalidStageName, got %v", stage, err)
+		}
+	}
+}
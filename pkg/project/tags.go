@@ -0,0 +1,70 @@
+package project
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+// reservedTagPrefixes are set by the CLI and can't be overridden in config.
+var reservedTagPrefixes = []string{"sst:", "git:"}
+
+// tags returns the tags written for the stage after a deploy, the ones set
+// in config merged with the app, stage, and git metadata.
+func (s *Stack) tags() map[string]string {
+	result := map[string]string{}
+	for key, value := range s.project.app.Tags {
+		result[key] = value
+	}
+	result["sst:app"] = s.project.app.Name
+	result["sst:stage"] = s.project.app.Stage
+	result["sst:version"] = s.project.version
+	git := map[string][]string{
+		"git:branch": {"rev-parse", "--abbrev-ref", "HEAD"},
+		"git:commit": {"rev-parse", "HEAD"},
+	}
+	for key, args := range git {
+		if value := s.git(args...); value != "" {
+			result[key] = value
+		}
+	}
+	// the remote can have credentials in it, like in CI
+	if remote := s.repository(); remote != "" {
+		result["git:remote"] = remote
+	}
+	return result
+}
+
+func (s *Stack) writeTags(command string) {
+	var err error
+	switch command {
+	case "up":
+		err = provider.PutTags(s.project.home, s.project.app.Name, s.project.app.Stage, s.tags())
+	case "destroy":
+		err = provider.RemoveTags(s.project.home, s.project.app.Name, s.project.app.Stage)
+	}
+	if err != nil {
+		slog.Error("failed to write tags", "err", err)
+	}
+}
+
+// Tags returns the tags of the stage as of its last deploy.
+func (s *Stack) Tags() (map[string]string, error) {
+	return provider.GetTags(s.project.home, s.project.app.Name, s.project.app.Stage)
+}
+
+// Search returns every stage in the home, across apps, with tags matching
+// filter.
+func (p *Project) Search(filter map[string]string) ([]provider.TaggedStage, error) {
+	return provider.SearchTags(p.home, filter)
+}
+
+func validateTag(key string) bool {
+	for _, prefix := range reservedTagPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+	return key != ""
+}
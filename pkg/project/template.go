@@ -0,0 +1,198 @@
+package project
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sst/ion/pkg/platform"
+)
+
+// DefaultTemplateRegistry is the list of templates in the gallery, it's the
+// same file that's built into the CLI for when it can't be fetched.
+const DefaultTemplateRegistry = "https://raw.githubusercontent.com/sst/ion/dev/pkg/platform/templates/registry.json"
+
+// Template is a template in the registry, a directory in a GitHub repo with
+// a preset.json and the files to copy. Without a repo it's one of the
+// templates built into the CLI.
+type Template struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Repo        string `json:"repo,omitempty"`
+	Ref         string `json:"ref,omitempty"`
+	Path        string `json:"path,omitempty"`
+	// Home is the only home the template works with, if it's set
+	Home     string `json:"home,omitempty"`
+	Official bool   `json:"official,omitempty"`
+}
+
+// BuiltIn is true for the templates that ship with the CLI, they are
+// created from platform.Templates instead of being downloaded.
+func (t Template) BuiltIn() bool {
+	return t.Repo == ""
+}
+
+type templateRegistry struct {
+	Templates []Template `json:"templates"`
+}
+
+// ListTemplates reads the templates from a registry, a URL or a local file.
+// The default registry falls back to the built in list if it can't be
+// fetched.
+func ListTemplates(ctx context.Context, registry string) ([]Template, error) {
+	if registry == "" {
+		registry = os.Getenv("SST_TEMPLATE_REGISTRY")
+	}
+	if registry == "" {
+		registry = DefaultTemplateRegistry
+	}
+	data, err := readTemplateRegistry(ctx, registry)
+	if err != nil && registry == DefaultTemplateRegistry {
+		slog.Warn("could not fetch template registry, using the built in one", "err", err)
+		return BuiltInTemplates()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplateRegistry(registry, data)
+}
+
+// BuiltInTemplates is the registry that's built into the CLI.
+func BuiltInTemplates() ([]Template, error) {
+	data, err := platform.Templates.ReadFile("templates/registry.json")
+	if err != nil {
+		return nil, err
+	}
+	return parseTemplateRegistry("templates/registry.json", data)
+}
+
+func parseTemplateRegistry(registry string, data []byte) ([]Template, error) {
+	var parsed templateRegistry
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("invalid template registry %s: %w", registry, err)
+	}
+	return parsed.Templates, nil
+}
+
+func readTemplateRegistry(ctx context.Context, registry string) ([]byte, error) {
+	if !strings.HasPrefix(registry, "https://") && !strings.HasPrefix(registry, "http://") {
+		return os.ReadFile(registry)
+	}
+	slog.Info("fetching template registry", "url", registry)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, registry, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status fetching %s: %s", registry, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// FetchTemplate downloads the template to a temporary directory and returns
+// it, the caller removes it when done.
+func FetchTemplate(ctx context.Context, template Template) (string, error) {
+	ref := template.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	url := fmt.Sprintf("https://codeload.github.com/%s/tar.gz/%s", template.Repo, ref)
+	slog.Info("fetching template", "name", template.Name, "url", url, "path", template.Path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status fetching %s: %s", url, resp.Status)
+	}
+
+	dir, err := os.MkdirTemp("", "sst-template")
+	if err != nil {
+		return "", err
+	}
+	err = extractTemplate(resp.Body, strings.Trim(template.Path, "/"), dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	if _, err := os.Stat(filepath.Join(dir, "preset.json")); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("template %s has no preset.json", template.Name)
+	}
+	return dir, nil
+}
+
+// extractTemplate writes the files under prefix in the tarball of a repo to
+// target. The entries of the tarball are in a directory named after the repo
+// and ref.
+func extractTemplate(reader io.Reader, prefix string, target string) error {
+	body, err := gzip.NewReader(reader)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	archive := tar.NewReader(body)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(header.Name)
+		if index := strings.Index(name, "/"); index != -1 {
+			name = name[index+1:]
+		} else {
+			continue
+		}
+		if prefix != "" {
+			if !strings.HasPrefix(name, prefix+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(name, prefix+"/")
+		}
+		if name == "" || strings.HasPrefix(name, "../") {
+			continue
+		}
+		dest := filepath.Join(target, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			file, err := os.Create(dest)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, archive)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package project
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sst/ion/pkg/platform"
+)
+
+func TestExtractTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"ion-dev/README.md":                              "readme",
+		"ion-dev/templates/api/preset.json":              "{}",
+		"ion-dev/templates/api/files/sst.config.ts":      "config",
+		"ion-dev/templates/api-other/preset.json":        "other",
+		"ion-dev/templates/api/files/../../../escape.ts": "escape",
+	} {
+		archive.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+		archive.Write([]byte(content))
+	}
+	archive.Close()
+	gz.Close()
+
+	dir := t.TempDir()
+	if err := extractTemplate(&buf, "templates/api", dir); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{}
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if !info.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if strings.Join(files, ",") != "files/sst.config.ts,preset.json" {
+		t.Fatalf("unexpected files %v", files)
+	}
+}
+
+func TestCreateFromTemplate(t *testing.T) {
+	source, err := fs.Sub(platform.Templates, "templates/monorepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cwd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(cwd)
+
+	err = CreateFrom(source, &CreateInput{App: "my-app", Home: "aws"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := os.ReadFile(filepath.Join(dir, "sst.config.ts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(config), `name: "my-app"`) {
+		t.Errorf("app name was not set:\n%s", config)
+	}
+	api, err := os.ReadFile(filepath.Join(dir, "packages", "functions", "src", "api.ts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(api), `"@my-app/core/example"`) {
+		t.Errorf("package name was not set:\n%s", api)
+	}
+}
+
+func TestBuiltInTemplatesResolve(t *testing.T) {
+	templates, err := BuiltInTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) == 0 {
+		t.Fatal("no templates in the registry")
+	}
+	for _, template := range templates {
+		if !template.BuiltIn() {
+			if template.Path == "" {
+				t.Errorf("%s has a repo but no path", template.Name)
+			}
+			continue
+		}
+		dir := path.Join("templates", template.Name)
+		if _, err := fs.Stat(platform.Templates, path.Join(dir, "preset.json")); err != nil {
+			t.Errorf("%s has no preset.json: %v", template.Name, err)
+		}
+		if _, err := fs.Stat(platform.Templates, path.Join(dir, "files")); err != nil {
+			t.Errorf("%s has no files: %v", template.Name, err)
+		}
+	}
+}
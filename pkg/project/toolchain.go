@@ -0,0 +1,194 @@
+package project
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/sst/ion/internal/util"
+)
+
+// Toolchain is where node, pulumi, and language toolchains are looked up
+// before falling back to the PATH.
+type Toolchain struct {
+	// Source is devenv or nix
+	Source string `json:"source"`
+	// Hash of the files the toolchain was resolved from
+	Hash string   `json:"hash"`
+	Path []string `json:"path"`
+}
+
+var ErrDevenvNotBuilt = fmt.Errorf("devenv profile not built")
+
+// nixFiles are checked in order, the first one found declares the toolchain.
+var nixFiles = []string{"flake.nix", "shell.nix"}
+
+// ResolveToolchain finds the toolchain declared in the project next to the
+// config. It returns nil if the project doesn't declare one, if it's
+// disabled with SST_TOOLCHAIN=path, or if the nix shell is already active.
+func ResolveToolchain(cfgPath string) (*Toolchain, error) {
+	if runtime.GOOS == "windows" || os.Getenv("SST_TOOLCHAIN") == "path" {
+		return nil, nil
+	}
+	root := filepath.Dir(cfgPath)
+
+	if _, err := os.Stat(filepath.Join(root, "devenv.nix")); err == nil {
+		// devenv builds the profile on `devenv shell`, reading it directly
+		// avoids evaluating nix on every command
+		bin := filepath.Join(root, ".devenv", "profile", "bin")
+		if _, err := os.Stat(bin); err != nil {
+			return nil, ErrDevenvNotBuilt
+		}
+		return &Toolchain{Source: "devenv", Path: []string{bin}}, nil
+	}
+
+	if os.Getenv("IN_NIX_SHELL") != "" {
+		return nil, nil
+	}
+	for _, file := range nixFiles {
+		if _, err := os.Stat(filepath.Join(root, file)); err != nil {
+			continue
+		}
+		return resolveNix(cfgPath, file)
+	}
+	return nil, nil
+}
+
+// resolveNix reads the PATH of the nix dev shell. It's slow to evaluate so
+// the result is cached until the nix files change.
+func resolveNix(cfgPath string, file string) (*Toolchain, error) {
+	root := filepath.Dir(cfgPath)
+	hash, err := hashFiles(root, file, "flake.lock")
+	if err != nil {
+		return nil, err
+	}
+	cachePath := filepath.Join(ResolveWorkingDir(cfgPath), "toolchain.json")
+	var cached Toolchain
+	if data, err := os.ReadFile(cachePath); err == nil {
+		if json.Unmarshal(data, &cached) == nil && cached.Hash == hash {
+			return &cached, nil
+		}
+	}
+
+	args := []string{"--extra-experimental-features", "nix-command flakes", "print-dev-env", "--json"}
+	if file == "flake.nix" {
+		args = append(args, root)
+	} else {
+		args = append(args, "--file", filepath.Join(root, file))
+	}
+	slog.Info("resolving nix toolchain", "file", file)
+	cmd := exec.Command("nix", args...)
+	cmd.Dir = root
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, util.NewReadableError(err, fmt.Sprintf("Could not evaluate %s with nix, set SST_TOOLCHAIN=path to always use the PATH", file))
+	}
+	path, err := devEnvPath(output)
+	if err != nil {
+		return nil, err
+	}
+	result := &Toolchain{Source: "nix", Hash: hash, Path: path}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cachePath, data, 0644); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// devEnvPath returns the nix store entries of the PATH in the output of
+// `nix print-dev-env --json`.
+func devEnvPath(output []byte) ([]string, error) {
+	var env struct {
+		Variables map[string]struct {
+			Type  string          `json:"type"`
+			Value json.RawMessage `json:"value"`
+		} `json:"variables"`
+	}
+	if err := json.Unmarshal(output, &env); err != nil {
+		return nil, err
+	}
+	variable, ok := env.Variables["PATH"]
+	if !ok {
+		return nil, nil
+	}
+	var value string
+	if err := json.Unmarshal(variable.Value, &value); err != nil {
+		return nil, err
+	}
+	result := []string{}
+	for _, dir := range filepath.SplitList(value) {
+		if strings.HasPrefix(dir, "/nix/store/") {
+			result = append(result, dir)
+		}
+	}
+	return result, nil
+}
+
+func hashFiles(root string, files ...string) (string, error) {
+	hash := sha256.New()
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(root, file))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+		hash.Write([]byte(file))
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// pathEnv puts the Path of the project first on the given PATH.
+func (p *Project) pathEnv(path string) string {
+	if len(p.path) == 0 {
+		return path
+	}
+	return strings.Join(append(append([]string{}, p.path...), path), string(os.PathListSeparator))
+}
+
+// environ is the env of the process with the Path of the project first on
+// the PATH, for the commands the project runs.
+func (p *Project) environ() []string {
+	env := os.Environ()
+	if len(p.path) == 0 {
+		return env
+	}
+	return append(env, "PATH="+p.pathEnv(os.Getenv("PATH")))
+}
+
+// lookPath finds a command in the Path of the project before the PATH.
+func (p *Project) lookPath(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	for _, dir := range p.path {
+		if resolved, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+			return resolved
+		}
+	}
+	return name
+}
+
+// command runs name from the Path of the project, with that Path first on
+// the PATH of the command.
+func (p *Project) command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, p.lookPath(name), args...)
+	cmd.Env = p.environ()
+	return cmd
+}
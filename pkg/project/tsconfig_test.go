@@ -0,0 +1,78 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+)
+
+func TestTsconfigPaths(t *testing.T) {
+	repo := t.TempDir()
+	files := map[string]string{
+		".git/HEAD": "ref: refs/heads/main\n",
+		"tsconfig.base.json": `{
+  // shared by every package
+  "compilerOptions": {
+    "paths": { "@core/*": ["packages/core/src/*"], },
+  },
+}`,
+		"packages/core/src/greet.ts":        `export const greet = "hello"`,
+		"apps/web/sst.config.ts":            `import { greet } from "@core/greet"; console.log(greet)`,
+		"apps/web/.sst/platform/src/run.ts": `import { greet } from "@core/greet"; console.log(greet)`,
+	}
+	for name, content := range files {
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := filepath.Join(repo, "apps", "web")
+	p := &Project{root: root}
+
+	if got, want := p.PathTsconfig(), filepath.Join(repo, "tsconfig.base.json"); got != want {
+		t.Fatalf("PathTsconfig() = %q, want %q", got, want)
+	}
+
+	build := func(entry string) esbuild.BuildResult {
+		return esbuild.Build(esbuild.BuildOptions{
+			EntryPoints: []string{entry},
+			Bundle:      true,
+			Write:       false,
+			Plugins:     p.tsconfigPlugins(),
+		})
+	}
+	result := build(filepath.Join(root, "sst.config.ts"))
+	if len(result.Errors) > 0 {
+		t.Fatalf("config did not bundle: %v", result.Errors[0].Text)
+	}
+	if !strings.Contains(string(result.OutputFiles[0].Contents), "hello") {
+		t.Errorf("alias was not bundled")
+	}
+
+	// the platform keeps resolving with its own tsconfig
+	result = build(filepath.Join(root, ".sst", "platform", "src", "run.ts"))
+	if len(result.Errors) == 0 {
+		t.Errorf("alias was resolved for the platform")
+	}
+}
+
+func TestTsconfigStopsAtRepository(t *testing.T) {
+	parent := t.TempDir()
+	if err := os.WriteFile(filepath.Join(parent, "tsconfig.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(parent, "app")
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := &Project{root: root}
+	if got := p.PathTsconfig(); got != "" {
+		t.Errorf("PathTsconfig() = %q, want the tsconfig outside the repository to be ignored", got)
+	}
+}
@@ -0,0 +1,108 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TypecheckErrorEvent is an error tsc reported in the config or a file it
+// imports.
+type TypecheckErrorEvent struct {
+	File    string
+	Line    int
+	Column  int
+	Code    string
+	Message string
+}
+
+var ErrTypecheckFailed = fmt.Errorf("typecheck failed")
+
+// file(line,col): error TS1234: message, with --pretty false
+var tscDiagnosticRegex = regexp.MustCompile(`^(.+)\((\d+),(\d+)\): error (TS\d+): (.*)$`)
+
+// typecheck runs tsc over the config, its stacks and overlay, and what they
+// import, with the globals of the platform. It extends the tsconfig of the
+// project if there is one.
+func (p *Project) typecheck(ctx context.Context) ([]TypecheckErrorEvent, error) {
+	tsc := filepath.Join(p.PathPlatformDir(), "node_modules", "typescript", "bin", "tsc")
+	if _, err := os.Stat(tsc); err != nil {
+		return nil, fmt.Errorf("typescript is not installed in the platform, run sst install")
+	}
+
+	files := []string{p.PathConfig(), filepath.Join(p.PathPlatformDir(), "config.d.ts")}
+	files = append(files, p.PathStacks()...)
+	if overlay := p.PathOverlay(p.app.Stage); overlay != "" {
+		files = append(files, overlay)
+	}
+	config := map[string]interface{}{
+		"compilerOptions": map[string]interface{}{
+			"noEmit":           true,
+			"skipLibCheck":     true,
+			"module":           "ESNext",
+			"moduleResolution": "Bundler",
+			"target":           "ESNext",
+			"allowJs":          true,
+		},
+		"files": files,
+		// or the include of the extended tsconfig would check the whole project
+		"include": []string{},
+	}
+	if tsconfig := p.PathTsconfig(); tsconfig != "" {
+		config["extends"] = tsconfig
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(p.PathWorkingDir(), "tsconfig.typecheck.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, err
+	}
+
+	cmd := p.command(ctx, p.nodePath(), tsc, "--project", path, "--pretty", "false")
+	cmd.Dir = p.root
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil, nil
+	}
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) {
+		return nil, err
+	}
+	result := []TypecheckErrorEvent{}
+	for _, line := range strings.Split(string(output), "\n") {
+		match := tscDiagnosticRegex.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			// continuation of the previous message
+			if len(result) > 0 && strings.HasPrefix(line, " ") {
+				result[len(result)-1].Message += "\n" + strings.TrimRight(line, "\r")
+			}
+			continue
+		}
+		lineNumber, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		file := match[1]
+		if rel, err := filepath.Rel(p.root, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+		result = append(result, TypecheckErrorEvent{
+			File:    filepath.ToSlash(file),
+			Line:    lineNumber,
+			Column:  column,
+			Code:    match[4],
+			Message: match[5],
+		})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("tsc failed: %s", strings.TrimSpace(string(output)))
+	}
+	return result, nil
+}
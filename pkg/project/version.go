@@ -0,0 +1,36 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func resolvePinnedVersionFile(cfgPath string) string {
+	return filepath.Join(ResolveWorkingDir(cfgPath), "version")
+}
+
+// LoadPinnedVersion returns the constraint on the version of the CLI from
+// the last time the config was loaded, or an empty string if the app doesn't
+// pin one.
+func LoadPinnedVersion(cfgPath string) string {
+	data, err := os.ReadFile(resolvePinnedVersionFile(cfgPath))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// savePinnedVersion keeps the constraint of the config so the version can be
+// switched before the config is loaded.
+func savePinnedVersion(cfgPath string, constraint string) error {
+	path := resolvePinnedVersionFile(cfgPath)
+	if constraint == "" {
+		err := os.Remove(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(path, []byte(constraint+"\n"), 0644)
+}
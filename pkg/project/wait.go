@@ -0,0 +1,174 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/sst/ion/pkg/project/provider"
+)
+
+type WaitCondition struct {
+	Resource string `json:"resource"`
+	HTTP     string `json:"http"`
+	SSM      string `json:"ssm"`
+	DNS      string `json:"dns"`
+	Timeout  int    `json:"timeout"`
+}
+
+type WaitStatus string
+
+const (
+	WaitStatusWaiting WaitStatus = "waiting"
+	WaitStatusReady   WaitStatus = "ready"
+	WaitStatusFailed  WaitStatus = "failed"
+)
+
+type WaitEvent struct {
+	Resource  string
+	Condition string
+	Status    WaitStatus
+	Error     string
+}
+
+var ErrWaitTimeout = fmt.Errorf("wait condition timed out")
+
+const defaultWaitTimeout = 5 * time.Minute
+const waitInterval = 5 * time.Second
+
+func (w WaitCondition) validate() error {
+	count := 0
+	for _, target := range []string{w.HTTP, w.SSM, w.DNS} {
+		if target != "" {
+			count++
+		}
+	}
+	if count != 1 {
+		return fmt.Errorf("Wait condition for %q must set exactly one of: http, ssm, dns", w.Resource)
+	}
+	return nil
+}
+
+func (w WaitCondition) String() string {
+	switch {
+	case w.HTTP != "":
+		return "http " + w.HTTP
+	case w.SSM != "":
+		return "ssm " + w.SSM
+	default:
+		return "dns " + w.DNS
+	}
+}
+
+// interpolate replaces ${name} references with the matching stack output.
+func (w WaitCondition) interpolate(outputs map[string]interface{}) WaitCondition {
+	replace := func(input string) string {
+		for key, value := range outputs {
+			input = strings.ReplaceAll(input, "${"+key+"}", fmt.Sprint(value))
+		}
+		return input
+	}
+	w.HTTP = replace(w.HTTP)
+	w.SSM = replace(w.SSM)
+	w.DNS = replace(w.DNS)
+	return w
+}
+
+func (s *Stack) wait(ctx context.Context, input *StackInput, complete *CompleteEvent) error {
+	names := map[string]bool{}
+	for _, resource := range complete.Resources {
+		names[resource.URN.Name()] = true
+	}
+
+	var result error
+	for _, condition := range s.project.app.Wait {
+		if condition.Resource != "" && !names[condition.Resource] {
+			continue
+		}
+		condition = condition.interpolate(complete.Outputs)
+		input.OnEvent(&StackEvent{WaitEvent: &WaitEvent{
+			Resource:  condition.Resource,
+			Condition: condition.String(),
+			Status:    WaitStatusWaiting,
+		}})
+		err := s.poll(ctx, condition)
+		event := &WaitEvent{
+			Resource:  condition.Resource,
+			Condition: condition.String(),
+			Status:    WaitStatusReady,
+		}
+		if err != nil {
+			event.Status = WaitStatusFailed
+			event.Error = err.Error()
+			complete.Errors = append(complete.Errors, Error{
+				Message: fmt.Sprintf("Wait condition %q failed: %v", condition.String(), err),
+			})
+			result = errors.Join(result, err)
+		}
+		input.OnEvent(&StackEvent{WaitEvent: event})
+	}
+	return result
+}
+
+func (s *Stack) poll(ctx context.Context, condition WaitCondition) error {
+	timeout := defaultWaitTimeout
+	if condition.Timeout > 0 {
+		timeout = time.Duration(condition.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var last error
+	for {
+		last = s.check(ctx, condition)
+		if last == nil {
+			return nil
+		}
+		slog.Info("wait condition not met", "condition", condition.String(), "err", last)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", ErrWaitTimeout, last)
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+func (s *Stack) check(ctx context.Context, condition WaitCondition) error {
+	switch {
+	case condition.HTTP != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, condition.HTTP, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %v", resp.StatusCode)
+		}
+		return nil
+
+	case condition.SSM != "":
+		awsProvider, ok := s.project.Providers["aws"].(*provider.AwsProvider)
+		if !ok {
+			return fmt.Errorf("ssm wait conditions require the aws provider")
+		}
+		_, err := ssm.NewFromConfig(awsProvider.Config()).GetParameter(ctx, &ssm.GetParameterInput{
+			Name: aws.String(condition.SSM),
+		})
+		return err
+
+	default:
+		_, err := net.DefaultResolver.LookupHost(ctx, condition.DNS)
+		return err
+	}
+}
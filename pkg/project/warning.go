@@ -0,0 +1,62 @@
+package project
+
+import (
+	"encoding/json"
+	"strings"
+
+	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/events"
+)
+
+// warningPrefix marks a line the program printed with the Warning module.
+const warningPrefix = "~w"
+
+// WarningEvent is a warning or info the program emitted while it was
+// evaluated, like a misconfigured or deprecated component.
+type WarningEvent struct {
+	// Level is warning or info
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+	Component string `json:"component,omitempty"`
+}
+
+// parseWarning returns the warning printed by the program, or nil if the
+// event is anything else.
+func parseWarning(event events.EngineEvent) *WarningEvent {
+	diag := event.DiagnosticEvent
+	if diag == nil || diag.Severity != "info" {
+		return nil
+	}
+	line := strings.TrimSpace(diag.Message)
+	if !strings.HasPrefix(line, warningPrefix) {
+		return nil
+	}
+	var result WarningEvent
+	if err := json.Unmarshal([]byte(line[len(warningPrefix):]), &result); err != nil {
+		return nil
+	}
+	return &result
+}
+
+// BuildWarningEvent is a warning esbuild reported while bundling the
+// program, like an import that couldn't be resolved.
+type BuildWarningEvent struct {
+	Text   string `json:"text"`
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+func buildWarnings(messages []esbuild.Message) []BuildWarningEvent {
+	result := []BuildWarningEvent{}
+	for _, message := range messages {
+		warning := BuildWarningEvent{Text: message.Text}
+		if message.Location != nil {
+			warning.File = message.Location.File
+			warning.Line = message.Location.Line
+			warning.Column = message.Location.Column
+		}
+		result = append(result, warning)
+	}
+	return result
+}
@@ -0,0 +1,120 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WarpSettings are the memory, timeout, and architecture of a function,
+// carried in the properties of its warp.
+type WarpSettings struct {
+	// Memory is in MB
+	Memory       int
+	Timeout      time.Duration
+	Architecture string
+}
+
+var DefaultWarpSettings = WarpSettings{
+	Memory:       1024,
+	Timeout:      20 * time.Second,
+	Architecture: "x86_64",
+}
+
+// runtimes Lambda doesn't run on arm64
+var x86OnlyRuntimes = map[string]bool{
+	"go1.x":      true,
+	"java8":      true,
+	"python3.7":  true,
+	"nodejs12.x": true,
+}
+
+// Settings parses and validates the settings of the function, the ones that
+// are not set are the defaults of the Function component.
+func (w Warp) Settings() (*WarpSettings, error) {
+	var properties struct {
+		Memory       string `json:"memory"`
+		Timeout      string `json:"timeout"`
+		Architecture string `json:"architecture"`
+	}
+	if len(w.Properties) > 0 {
+		json.Unmarshal(w.Properties, &properties)
+	}
+	result := DefaultWarpSettings
+	problems := []string{}
+	if properties.Memory != "" {
+		memory, err := ParseSize(properties.Memory)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else if memory < 128 || memory > 10240 {
+			problems = append(problems, fmt.Sprintf("memory must be between 128 MB and 10240 MB, got %s", properties.Memory))
+		} else {
+			result.Memory = memory
+		}
+	}
+	if properties.Timeout != "" {
+		timeout, err := ParseDuration(properties.Timeout)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else if timeout < time.Second || timeout > 15*time.Minute {
+			problems = append(problems, fmt.Sprintf("timeout must be between 1 second and 15 minutes, got %s", properties.Timeout))
+		} else {
+			result.Timeout = timeout
+		}
+	}
+	if properties.Architecture != "" {
+		switch properties.Architecture {
+		case "x86_64", "arm64":
+			result.Architecture = properties.Architecture
+		default:
+			problems = append(problems, fmt.Sprintf("architecture must be \"x86_64\" or \"arm64\", got \"%s\"", properties.Architecture))
+		}
+	}
+	if result.Architecture == "arm64" && x86OnlyRuntimes[w.Runtime] {
+		problems = append(problems, fmt.Sprintf("runtime \"%s\" does not support arm64", w.Runtime))
+	}
+	if len(problems) > 0 {
+		return nil, errors.New("Invalid settings for function " + w.FunctionID + ": " + strings.Join(problems, ", "))
+	}
+	return &result, nil
+}
+
+// ParseSize parses a size like "1024 MB" or "1 GB" into MB.
+func ParseSize(size string) (int, error) {
+	count, unit, ok := strings.Cut(strings.TrimSpace(size), " ")
+	value, err := strconv.Atoi(count)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("invalid size \"%s\"", size)
+	}
+	switch unit {
+	case "MB":
+		return value, nil
+	case "GB":
+		return value * 1024, nil
+	}
+	return 0, fmt.Errorf("invalid size \"%s\"", size)
+}
+
+// ParseDuration parses a duration like "20 seconds" or "1 minute".
+func ParseDuration(duration string) (time.Duration, error) {
+	count, unit, ok := strings.Cut(strings.TrimSpace(duration), " ")
+	value, err := strconv.Atoi(count)
+	if !ok || err != nil {
+		return 0, fmt.Errorf("invalid duration \"%s\"", duration)
+	}
+	unit = strings.ToLower(unit)
+	switch {
+	case strings.HasPrefix(unit, "second"):
+		return time.Duration(value) * time.Second, nil
+	case strings.HasPrefix(unit, "minute"):
+		return time.Duration(value) * time.Minute, nil
+	case strings.HasPrefix(unit, "hour"):
+		return time.Duration(value) * time.Hour, nil
+	case strings.HasPrefix(unit, "day"):
+		return time.Duration(value) * 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("invalid duration \"%s\"", duration)
+}
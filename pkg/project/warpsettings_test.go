@@ -0,0 +1,42 @@
+package project
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWarpSettings(t *testing.T) {
+	warp := func(runtime string, properties map[string]string) Warp {
+		data, _ := json.Marshal(properties)
+		return Warp{FunctionID: "MyFunction", Runtime: runtime, Properties: data}
+	}
+
+	settings, err := warp("nodejs20.x", map[string]string{}).Settings()
+	if err != nil || *settings != DefaultWarpSettings {
+		t.Fatalf("expected the defaults, got %v %v", settings, err)
+	}
+
+	settings, err = warp("nodejs20.x", map[string]string{
+		"memory":       "2 GB",
+		"timeout":      "1 minute",
+		"architecture": "arm64",
+	}).Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Memory != 2048 || settings.Timeout != time.Minute || settings.Architecture != "arm64" {
+		t.Fatalf("unexpected settings %v", settings)
+	}
+
+	for _, invalid := range []Warp{
+		warp("nodejs20.x", map[string]string{"memory": "64 MB"}),
+		warp("nodejs20.x", map[string]string{"timeout": "20 minutes"}),
+		warp("nodejs20.x", map[string]string{"architecture": "arm"}),
+		warp("go1.x", map[string]string{"architecture": "arm64"}),
+	} {
+		if _, err := invalid.Settings(); err == nil {
+			t.Errorf("expected %s to be invalid", invalid.Properties)
+		}
+	}
+}
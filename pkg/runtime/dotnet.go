@@ -0,0 +1,215 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type DotnetRuntime struct {
+	lock sync.Mutex
+	// the project directories each function is built from
+	dirs map[string][]string
+	// the Amazon.Lambda.RuntimeSupport assembly each function runs with
+	bootstraps map[string]string
+}
+
+func newDotnetRuntime() *DotnetRuntime {
+	return &DotnetRuntime{
+		dirs:       map[string][]string{},
+		bootstraps: map[string]string{},
+	}
+}
+
+// DotnetProperties are set by the function from its handler, like
+// services/Api::Api.Function::Handler.
+type DotnetProperties struct {
+	ReadyToRun bool `json:"readyToRun"`
+	// Project is the directory of the project, relative to the root
+	Project string `json:"project"`
+	// Assembly is named after the directory of the project
+	Assembly string `json:"assembly"`
+	// Handler is what Lambda expects, Api::Api.Function::Handler
+	Handler string `json:"handler"`
+}
+
+var DOTNET_EXTENSIONS = []string{".cs", ".fs", ".vb", ".csproj", ".fsproj", ".vbproj", ".props", ".targets"}
+
+var projectReferenceRegex = regexp.MustCompile(`<ProjectReference\s+Include="([^"]+)"`)
+
+func (r *DotnetRuntime) Match(runtime string) bool {
+	return strings.HasPrefix(runtime, "dotnet")
+}
+
+func (r *DotnetRuntime) Build(ctx context.Context, input *BuildInput) (*BuildOutput, error) {
+	var properties DotnetProperties
+	json.Unmarshal(input.Warp.Properties, &properties)
+	if properties.Project == "" || properties.Handler == "" {
+		return nil, fmt.Errorf("Handler %v must look like {project}::{namespace}.{class}::{method}", input.Warp.Handler)
+	}
+	if _, err := exec.LookPath("dotnet"); err != nil {
+		return nil, fmt.Errorf("dotnet is not installed, it's needed to build %v", input.Warp.FunctionID)
+	}
+
+	dir := filepath.Join(input.Project.PathRoot(), properties.Project)
+	cmd := exec.CommandContext(
+		ctx,
+		"dotnet",
+		"build",
+		"--nologo",
+		"--configuration", "Debug",
+		"--output", input.Out(),
+		"-p:GenerateRuntimeConfigurationFiles=true",
+	)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		slog.Error("dotnet build failed", "output", string(output))
+		return &BuildOutput{
+			Handler: properties.Handler,
+			Errors:  msbuildErrors(string(output)),
+		}, nil
+	}
+
+	bootstrap, ok := r.findRuntimeSupport(input.Out())
+	if !ok {
+		return &BuildOutput{
+			Handler: properties.Handler,
+			Errors: []string{
+				"Amazon.Lambda.RuntimeSupport is needed to run .NET functions in dev, add it with: dotnet add package Amazon.Lambda.RuntimeSupport",
+			},
+		}, nil
+	}
+	r.lock.Lock()
+	r.bootstraps[input.Warp.FunctionID] = bootstrap
+	r.dirs[input.Warp.FunctionID] = projectDirs(dir)
+	r.lock.Unlock()
+
+	return &BuildOutput{
+		Handler: properties.Handler,
+		Errors:  []string{},
+	}, nil
+}
+
+func (r *DotnetRuntime) Run(ctx context.Context, input *RunInput) (Worker, error) {
+	r.lock.Lock()
+	bootstrap, ok := r.bootstraps[input.FunctionID]
+	r.lock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("function %v is not built", input.FunctionID)
+	}
+	assembly := strings.Split(input.Build.Handler, "::")[0]
+	// how the Lambda runtime starts a class library
+	cmd := exec.CommandContext(
+		ctx,
+		"dotnet",
+		"exec",
+		"--depsfile", filepath.Join(input.Build.Out, assembly+".deps.json"),
+		"--runtimeconfig", filepath.Join(input.Build.Out, assembly+".runtimeconfig.json"),
+		bootstrap,
+		input.Build.Handler,
+	)
+	cmd.Env = append(input.Env, "AWS_LAMBDA_RUNTIME_API="+input.Server)
+	slog.Info("starting worker", "env", cmd.Env)
+	cmd.Dir = input.Build.Out
+	return startWorker(cmd), nil
+}
+
+func (r *DotnetRuntime) ShouldRebuild(functionID string, file string) bool {
+	r.lock.Lock()
+	dirs, ok := r.dirs[functionID]
+	r.lock.Unlock()
+	if !ok {
+		return false
+	}
+	matched := false
+	for _, ext := range DOTNET_EXTENSIONS {
+		if strings.HasSuffix(file, ext) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false
+	}
+	for _, dir := range dirs {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		first := strings.Split(filepath.ToSlash(rel), "/")[0]
+		if first == "bin" || first == "obj" {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// findRuntimeSupport returns the assembly from the build output if the
+// project references it, or the latest one in the NuGet cache.
+func (r *DotnetRuntime) findRuntimeSupport(out string) (string, bool) {
+	name := "Amazon.Lambda.RuntimeSupport.dll"
+	if _, err := os.Stat(filepath.Join(out, name)); err == nil {
+		return filepath.Join(out, name), true
+	}
+	packages := os.Getenv("NUGET_PACKAGES")
+	if packages == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		packages = filepath.Join(home, ".nuget", "packages")
+	}
+	matches, _ := filepath.Glob(filepath.Join(packages, "amazon.lambda.runtimesupport", "*", "lib", "net*", name))
+	if len(matches) == 0 {
+		return "", false
+	}
+	sort.Strings(matches)
+	return matches[len(matches)-1], true
+}
+
+// projectDirs returns the directory of the project and of the projects it
+// references.
+func projectDirs(dir string) []string {
+	result := []string{dir}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.*proj"))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		for _, match := range projectReferenceRegex.FindAllStringSubmatch(string(data), -1) {
+			reference := filepath.FromSlash(strings.ReplaceAll(match[1], "\\", "/"))
+			result = append(result, filepath.Dir(filepath.Join(dir, reference)))
+		}
+	}
+	return result
+}
+
+// msbuildErrors picks the errors out of the output of dotnet build, each is
+// printed again in the summary.
+func msbuildErrors(output string) []string {
+	errors := []string{}
+	seen := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.Contains(line, ": error ") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		errors = append(errors, line)
+	}
+	if len(errors) == 0 {
+		errors = append(errors, strings.TrimSpace(output))
+	}
+	return errors
+}
@@ -0,0 +1,205 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sst/ion/pkg/project"
+)
+
+// InvokeInput runs a function once on this machine, outside of sst dev.
+type InvokeInput struct {
+	Project *project.Project
+	Warp    project.Warp
+	Links   project.Links
+	Env     []string
+	Payload []byte
+	Timeout time.Duration
+	// Build skips building the function if it's already built
+	Build *BuildOutput
+	// RequestID is generated if not set
+	RequestID string
+	// Logs receives what the function prints
+	Logs io.Writer
+	// Inspect starts the function with a debugger attached
+	Inspect bool
+}
+
+type InvokeError struct {
+	ErrorType    string   `json:"errorType"`
+	ErrorMessage string   `json:"errorMessage"`
+	Trace        []string `json:"trace"`
+}
+
+type InvokeOutput struct {
+	RequestID string
+	Response  []byte
+	Error     *InvokeError
+}
+
+var ErrBuildFailed = fmt.Errorf("function failed to build")
+
+func NewRequestID() string {
+	return hex.EncodeToString(func(b []byte) []byte { _, _ = rand.Read(b); return b }(make([]byte, 16)))
+}
+
+// LocalEnv returns the environment a function runs with on this machine,
+// in place of the one Lambda gives it, with its linked resources.
+func LocalEnv(warp project.Warp, links project.Links, receivers project.Receivers, base map[string]string) ([]string, error) {
+	env := os.Environ()
+	for key, value := range base {
+		env = append(env, key+"="+value)
+	}
+	settings, err := warp.Settings()
+	if err != nil {
+		return nil, err
+	}
+	env = append(env,
+		"AWS_LAMBDA_FUNCTION_NAME="+warp.FunctionID,
+		"AWS_LAMBDA_FUNCTION_VERSION=$LATEST",
+		fmt.Sprintf("AWS_LAMBDA_FUNCTION_MEMORY_SIZE=%d", settings.Memory),
+	)
+	for _, name := range warp.Links {
+		value, err := json.Marshal(links[name])
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, fmt.Sprintf("SST_RESOURCE_%s=%s", name, value))
+	}
+	receiver := warp.Bundle
+	if receiver == "" {
+		receiver = warp.Handler
+	}
+	for key, value := range receivers[receiver].Environment {
+		env = append(env, key+"="+value)
+	}
+	for key, value := range warp.Environment {
+		env = append(env, key+"="+value)
+	}
+	return env, nil
+}
+
+// Invoke builds the function and runs it against a Lambda runtime API that
+// serves a single invocation with the payload.
+func Invoke(ctx context.Context, input *InvokeInput) (*InvokeOutput, error) {
+	build := input.Build
+	if build == nil {
+		var err error
+		build, err = Build(ctx, &BuildInput{
+			Warp:    input.Warp,
+			Project: input.Project,
+			Links:   input.Links,
+			Dev:     true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(build.Errors) > 0 {
+			return nil, fmt.Errorf("%w:\n%s", ErrBuildFailed, strings.Join(build.Errors, "\n"))
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	requestID := input.RequestID
+	if requestID == "" {
+		requestID = NewRequestID()
+	}
+	deadline := time.Now().Add(input.Timeout)
+	result := make(chan *InvokeOutput, 1)
+	var served atomic.Bool
+	send := func(output *InvokeOutput) {
+		select {
+		case result <- output:
+		default:
+		}
+	}
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := strings.TrimPrefix(r.URL.Path, "/2018-06-01")
+			slog.Info("invoke runtime request", "method", r.Method, "path", path)
+			body, _ := io.ReadAll(r.Body)
+			switch {
+			case r.Method == http.MethodGet && path == "/runtime/invocation/next":
+				if served.Swap(true) {
+					// the function only gets the one invocation
+					<-r.Context().Done()
+					return
+				}
+				w.Header().Set("lambda-runtime-aws-request-id", requestID)
+				w.Header().Set("lambda-runtime-deadline-ms", strconv.FormatInt(deadline.UnixMilli(), 10))
+				w.Header().Set("lambda-runtime-invoked-function-arn", input.Warp.FunctionID)
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(input.Payload)
+			case r.Method == http.MethodPost && path == "/runtime/invocation/"+requestID+"/response":
+				w.WriteHeader(http.StatusAccepted)
+				send(&InvokeOutput{RequestID: requestID, Response: body})
+			case r.Method == http.MethodPost && (path == "/runtime/invocation/"+requestID+"/error" || path == "/runtime/init/error"):
+				invokeError := &InvokeError{}
+				if err := json.Unmarshal(body, invokeError); err != nil || invokeError.ErrorMessage == "" {
+					invokeError.ErrorMessage = string(body)
+				}
+				w.WriteHeader(http.StatusAccepted)
+				send(&InvokeOutput{RequestID: requestID, Error: invokeError})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	}
+	go server.Serve(listener)
+	defer server.Close()
+
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	worker, err := Run(ctx, &RunInput{
+		Project:    input.Project,
+		Server:     listener.Addr().String(),
+		FunctionID: input.Warp.FunctionID,
+		WorkerID:   requestID,
+		Runtime:    input.Warp.Runtime,
+		Build:      build,
+		Env:        input.Env,
+		Inspect:    input.Inspect,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer worker.Stop()
+	exited := make(chan struct{})
+	go func() {
+		io.Copy(input.Logs, worker.Logs())
+		close(exited)
+	}()
+
+	select {
+	case output := <-result:
+		return output, nil
+	case <-exited:
+		// the response may have been sent right before it exited
+		select {
+		case output := <-result:
+			return output, nil
+		default:
+		}
+		return nil, fmt.Errorf("function exited without a response")
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("function timed out after %v", input.Timeout)
+		}
+		return nil, ctx.Err()
+	}
+}
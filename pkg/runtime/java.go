@@ -0,0 +1,197 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sst/ion/internal/fs"
+)
+
+type JavaRuntime struct {
+	lock sync.Mutex
+	// the project directory each function is built from
+	dirs map[string]string
+}
+
+func newJavaRuntime() *JavaRuntime {
+	return &JavaRuntime{
+		dirs: map[string]string{},
+	}
+}
+
+// JavaProperties are set by the function from its handler, like
+// services/api::com.example.Handler::handleRequest.
+type JavaProperties struct {
+	// SnapStart only applies to published versions, there's nothing to
+	// restore from in dev
+	SnapStart bool `json:"snapStart"`
+	// Project is the directory of the project, relative to the root
+	Project string `json:"project"`
+	// Handler is what Lambda expects, com.example.Handler::handleRequest
+	Handler string `json:"handler"`
+}
+
+var JAVA_EXTENSIONS = []string{".java", ".kt", ".kts", ".gradle", ".xml", ".properties"}
+
+// Adds a task that copies the runtime dependencies of a project, so the
+// build of the project doesn't need to be changed. Same as the one the
+// platform uses to deploy.
+const gradleInitScript = `
+allprojects {
+  plugins.withId("java") {
+    tasks.register("sstDependencies", Copy) {
+      from configurations.runtimeClasspath
+      into System.getenv("SST_JAVA_DEPENDENCIES")
+    }
+  }
+}
+`
+
+const javaRuntimeClient = "com.amazonaws.services.lambda.runtime.api.client.AWSLambda"
+
+func (r *JavaRuntime) Match(runtime string) bool {
+	return strings.HasPrefix(runtime, "java")
+}
+
+func (r *JavaRuntime) Build(ctx context.Context, input *BuildInput) (*BuildOutput, error) {
+	var properties JavaProperties
+	json.Unmarshal(input.Warp.Properties, &properties)
+	if properties.Project == "" || properties.Handler == "" {
+		return nil, fmt.Errorf("Handler %v must look like {project}::{package}.{class}::{method}", input.Warp.Handler)
+	}
+
+	dir := filepath.Join(input.Project.PathRoot(), properties.Project)
+	lib := filepath.Join(input.Out(), "lib")
+	var cmd *exec.Cmd
+	var libs string
+	switch {
+	case fs.Exists(filepath.Join(dir, "build.gradle")) || fs.Exists(filepath.Join(dir, "build.gradle.kts")):
+		init := filepath.Join(input.Project.PathWorkingDir(), "java", "init.gradle")
+		if err := os.MkdirAll(filepath.Dir(init), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(init, []byte(gradleInitScript), 0644); err != nil {
+			return nil, err
+		}
+		cmd = exec.CommandContext(ctx, javaBuildTool(dir, "gradle", "gradlew", "gradlew.bat"), "--quiet", "--init-script", init, "jar", "sstDependencies", "-x", "test")
+		cmd.Env = append(os.Environ(), "SST_JAVA_DEPENDENCIES="+lib)
+		libs = filepath.Join(dir, "build", "libs")
+	case fs.Exists(filepath.Join(dir, "pom.xml")):
+		cmd = exec.CommandContext(ctx, javaBuildTool(dir, "mvn", "mvnw", "mvnw.cmd"), "--quiet", "-DskipTests", "package", "dependency:copy-dependencies", "-DincludeScope=runtime", "-DoutputDirectory="+lib)
+		libs = filepath.Join(dir, "target")
+	default:
+		return nil, fmt.Errorf("Could not find a build.gradle or pom.xml for handler: %v", input.Warp.Handler)
+	}
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		slog.Error("java build failed", "output", string(output))
+		return &BuildOutput{
+			Handler: properties.Handler,
+			Errors:  javaErrors(string(output)),
+		}, nil
+	}
+
+	jars, _ := filepath.Glob(filepath.Join(libs, "*.jar"))
+	copied := 0
+	for _, jar := range jars {
+		name := filepath.Base(jar)
+		if strings.HasSuffix(name, "-plain.jar") || strings.HasSuffix(name, "-sources.jar") || strings.HasSuffix(name, "-javadoc.jar") || strings.HasPrefix(name, "original-") {
+			continue
+		}
+		if err := fs.CopyFile(jar, filepath.Join(lib, name)); err != nil {
+			return nil, err
+		}
+		copied++
+	}
+	if copied == 0 {
+		return nil, fmt.Errorf("Could not find the jar built in %v", libs)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(lib, "aws-lambda-java-runtime-interface-client*.jar")); len(matches) == 0 {
+		return &BuildOutput{
+			Handler: properties.Handler,
+			Errors: []string{
+				"com.amazonaws:aws-lambda-java-runtime-interface-client is needed to run Java functions in dev, add it to the dependencies of the project",
+			},
+		}, nil
+	}
+	r.lock.Lock()
+	r.dirs[input.Warp.FunctionID] = dir
+	r.lock.Unlock()
+
+	return &BuildOutput{
+		Handler: properties.Handler,
+		Errors:  []string{},
+	}, nil
+}
+
+func (r *JavaRuntime) Run(ctx context.Context, input *RunInput) (Worker, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		"java",
+		"-cp", filepath.Join(input.Build.Out, "lib", "*"),
+		javaRuntimeClient,
+		input.Build.Handler,
+	)
+	cmd.Env = append(input.Env, "AWS_LAMBDA_RUNTIME_API="+input.Server)
+	slog.Info("starting worker", "env", cmd.Env)
+	cmd.Dir = input.Build.Out
+	return startWorker(cmd), nil
+}
+
+func (r *JavaRuntime) ShouldRebuild(functionID string, file string) bool {
+	r.lock.Lock()
+	dir, ok := r.dirs[functionID]
+	r.lock.Unlock()
+	if !ok {
+		return false
+	}
+	rel, err := filepath.Rel(dir, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	first := strings.Split(filepath.ToSlash(rel), "/")[0]
+	if first == "build" || first == "target" || first == ".gradle" {
+		return false
+	}
+	for _, ext := range JAVA_EXTENSIONS {
+		if strings.HasSuffix(file, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// javaBuildTool returns the wrapper of the project if it has one.
+func javaBuildTool(dir string, command string, wrapper string, windowsWrapper string) string {
+	if runtime.GOOS == "windows" {
+		wrapper = windowsWrapper
+	}
+	if path, err := fs.FindUp(dir, wrapper); err == nil {
+		return path
+	}
+	return command
+}
+
+// javaErrors picks the errors out of the output of gradle or maven.
+func javaErrors(output string) []string {
+	errors := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, ": error:") || strings.HasPrefix(line, "e: ") || strings.HasPrefix(line, "[ERROR]") {
+			errors = append(errors, line)
+		}
+	}
+	if len(errors) == 0 {
+		errors = append(errors, strings.TrimSpace(output))
+	}
+	return errors
+}
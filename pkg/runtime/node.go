@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"os"
 	"os/exec"
@@ -18,6 +17,8 @@ import (
 )
 
 type NodeRuntime struct {
+	// lock guards the maps, functions are built concurrently
+	lock     sync.Mutex
 	contexts map[string]esbuild.BuildContext
 	results  map[string]esbuild.BuildResult
 }
@@ -29,38 +30,6 @@ func newNodeRuntime() *NodeRuntime {
 	}
 }
 
-type NodeWorker struct {
-	stdout io.ReadCloser
-	stderr io.ReadCloser
-	cmd    *exec.Cmd
-}
-
-func (w *NodeWorker) Stop() {
-	w.cmd.Process.Signal(os.Interrupt)
-}
-
-func (w *NodeWorker) Logs() io.ReadCloser {
-	reader, writer := io.Pipe()
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() {
-		defer wg.Done()
-		_, _ = io.Copy(writer, w.stdout)
-	}()
-	go func() {
-		defer wg.Done()
-		_, _ = io.Copy(writer, w.stderr)
-	}()
-
-	go func() {
-		wg.Wait()
-		defer writer.Close()
-	}()
-
-	return reader
-}
-
 type NodeProperties struct {
 	Loader    map[string]string `json:"loader"`
 	Install   []string
@@ -70,6 +39,11 @@ type NodeProperties struct {
 	Format    string               `json:"format"`
 	SourceMap bool                 `json:"sourceMap"`
 	Splitting bool                 `json:"splitting"`
+	// KeepNames and TreeShaking default to true
+	KeepNames   *bool    `json:"keepNames"`
+	TreeShaking *bool    `json:"treeShaking"`
+	Footer      string   `json:"footer"`
+	External    []string `json:"external"`
 }
 
 var NODE_EXTENSIONS = []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs"}
@@ -126,15 +100,15 @@ func (r *NodeRuntime) Build(ctx context.Context, input *BuildInput) (*BuildOutpu
 	options := esbuild.BuildOptions{
 		EntryPoints: []string{file},
 		Platform:    esbuild.PlatformNode,
-		External: append(
+		External: append(append(
 			[]string{
 				"sharp", "pg-native",
 			},
 			properties.Install...,
-		),
+		), properties.External...),
 		Sourcemap:         esbuild.SourceMapLinked,
 		Loader:            loader,
-		KeepNames:         true,
+		KeepNames:         properties.KeepNames == nil || *properties.KeepNames,
 		Bundle:            true,
 		Splitting:         properties.Splitting,
 		Metafile:          true,
@@ -144,6 +118,12 @@ func (r *NodeRuntime) Build(ctx context.Context, input *BuildInput) (*BuildOutpu
 		MinifySyntax:      properties.Minify,
 		MinifyIdentifiers: properties.Minify,
 	}
+	if properties.TreeShaking != nil && !*properties.TreeShaking {
+		options.TreeShaking = esbuild.TreeShakingFalse
+	}
+	if properties.Footer != "" {
+		options.Footer = map[string]string{"js": properties.Footer}
+	}
 
 	links, _ := json.Marshal(input.Links)
 
@@ -166,14 +146,28 @@ func (r *NodeRuntime) Build(ctx context.Context, input *BuildInput) (*BuildOutpu
 		options.Target = esbuild.ESNext
 	}
 
+	// the first build of a function in a session can come from the cache,
+	// after that the context rebuilds incrementally
+	cacheKey, _ := input.Project.BuildCacheKey("warp", options)
+	r.lock.Lock()
 	buildContext, ok := r.contexts[input.Warp.FunctionID]
+	r.lock.Unlock()
+	if !ok && cacheKey != "" {
+		if output, ok := r.restore(input, cacheKey, file); ok {
+			return output, nil
+		}
+	}
 	if !ok {
 		buildContext, _ = esbuild.Context(options)
+		r.lock.Lock()
 		r.contexts[input.Warp.FunctionID] = buildContext
+		r.lock.Unlock()
 	}
 
 	result := buildContext.Rebuild()
+	r.lock.Lock()
 	r.results[input.Warp.FunctionID] = result
+	r.lock.Unlock()
 	errors := []string{}
 	for _, error := range result.Errors {
 		errors = append(errors, error.Text)
@@ -186,6 +180,10 @@ func (r *NodeRuntime) Build(ctx context.Context, input *BuildInput) (*BuildOutpu
 		slog.Error("esbuild error", "error", warning)
 	}
 
+	if len(errors) == 0 && cacheKey != "" {
+		r.store(input, cacheKey, result)
+	}
+
 	nodeModules, err := fs.FindUp(file, "node_modules")
 	if err == nil {
 		os.Symlink(nodeModules, filepath.Join(input.Out(), "node_modules"))
@@ -197,11 +195,71 @@ func (r *NodeRuntime) Build(ctx context.Context, input *BuildInput) (*BuildOutpu
 	}, nil
 }
 
+// restore copies the bundle of the function out of the build cache, with
+// the metafile so file changes still trigger a rebuild.
+func (r *NodeRuntime) restore(input *BuildInput, key string, file string) (*BuildOutput, bool) {
+	dir := input.Project.LookupBuild(key)
+	if dir == "" {
+		return nil, false
+	}
+	metafile, err := os.ReadFile(filepath.Join(dir, "metafile.json"))
+	if err != nil {
+		return nil, false
+	}
+	if err := fs.CopyDir(filepath.Join(dir, "out"), input.Out()); err != nil {
+		slog.Error("failed to restore cached bundle", "functionID", input.Warp.FunctionID, "err", err)
+		return nil, false
+	}
+	r.lock.Lock()
+	r.results[input.Warp.FunctionID] = esbuild.BuildResult{Metafile: string(metafile)}
+	r.lock.Unlock()
+	slog.Info("restored cached bundle", "functionID", input.Warp.FunctionID)
+
+	nodeModules, err := fs.FindUp(file, "node_modules")
+	if err == nil {
+		os.Symlink(nodeModules, filepath.Join(input.Out(), "node_modules"))
+	}
+	return &BuildOutput{
+		Handler: input.Warp.Handler,
+		Errors:  []string{},
+		Cached:  true,
+	}, true
+}
+
+// store caches the bundle, keyed by the files esbuild read to make it.
+func (r *NodeRuntime) store(input *BuildInput, key string, result esbuild.BuildResult) {
+	var meta struct {
+		Inputs map[string]interface{} `json:"inputs"`
+	}
+	if err := json.Unmarshal([]byte(result.Metafile), &meta); err != nil {
+		return
+	}
+	files := []string{}
+	for path := range meta.Inputs {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, abs)
+	}
+	err := input.Project.StoreBuild(key, files, func(dir string) error {
+		if err := fs.CopyDir(input.Out(), filepath.Join(dir, "out")); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(dir, "metafile.json"), []byte(result.Metafile), 0644)
+	})
+	if err != nil {
+		slog.Error("failed to cache bundle", "functionID", input.Warp.FunctionID, "err", err)
+	}
+}
+
 func (r *NodeRuntime) Run(ctx context.Context, input *RunInput) (Worker, error) {
-	cmd := exec.CommandContext(
-		ctx,
-		"node",
-		"--enable-source-maps",
+	args := []string{"--enable-source-maps"}
+	if input.Inspect {
+		// each worker gets its own port, the url is read back from the logs
+		args = append(args, "--inspect=127.0.0.1:0")
+	}
+	args = append(args,
 		filepath.Join(
 			input.Project.PathPlatformDir(),
 			"/dist/nodejs-runtime/index.js",
@@ -209,17 +267,21 @@ func (r *NodeRuntime) Run(ctx context.Context, input *RunInput) (Worker, error)
 		filepath.Join(input.Build.Out, input.Build.Handler),
 		input.WorkerID,
 	)
+	cmd := exec.CommandContext(ctx, "node", args...)
 	cmd.Env = append(input.Env, "AWS_LAMBDA_RUNTIME_API="+input.Server)
 	slog.Info("starting worker", "env", cmd.Env)
 	cmd.Dir = input.Build.Out
-	stdout, _ := cmd.StdoutPipe()
-	stderr, _ := cmd.StderrPipe()
-	cmd.Start()
-	return &NodeWorker{
-		stdout,
-		stderr,
-		cmd,
-	}, nil
+	return startWorker(cmd), nil
+}
+
+// InspectorURL returns the url of the debugger if the line is the one node
+// prints when it starts with --inspect.
+func InspectorURL(line string) (string, bool) {
+	const prefix = "Debugger listening on "
+	if !strings.HasPrefix(line, prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, prefix)), true
 }
 
 func (r *NodeRuntime) Match(runtime string) bool {
@@ -239,7 +301,9 @@ func (r *NodeRuntime) getFile(input *BuildInput) (string, bool) {
 }
 
 func (r *NodeRuntime) ShouldRebuild(functionID string, file string) bool {
+	r.lock.Lock()
 	result, ok := r.results[functionID]
+	r.lock.Unlock()
 	if !ok {
 		return false
 	}
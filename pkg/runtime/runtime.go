@@ -6,7 +6,10 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
+	"sync"
 
 	"github.com/sst/ion/pkg/project"
 )
@@ -23,6 +26,50 @@ type Worker interface {
 	Logs() io.ReadCloser
 }
 
+// ProcessWorker is a function running as a local process.
+type ProcessWorker struct {
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+	cmd    *exec.Cmd
+}
+
+func startWorker(cmd *exec.Cmd) *ProcessWorker {
+	stdout, _ := cmd.StdoutPipe()
+	stderr, _ := cmd.StderrPipe()
+	cmd.Start()
+	return &ProcessWorker{
+		stdout,
+		stderr,
+		cmd,
+	}
+}
+
+func (w *ProcessWorker) Stop() {
+	w.cmd.Process.Signal(os.Interrupt)
+}
+
+func (w *ProcessWorker) Logs() io.ReadCloser {
+	reader, writer := io.Pipe()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(writer, w.stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(writer, w.stderr)
+	}()
+
+	go func() {
+		wg.Wait()
+		defer writer.Close()
+	}()
+
+	return reader
+}
+
 type BuildInput struct {
 	Warp    project.Warp
 	Project *project.Project
@@ -31,13 +78,15 @@ type BuildInput struct {
 }
 
 func (input *BuildInput) Out() string {
-	return filepath.Join(input.Project.PathWorkingDir(), "artifacts", input.Warp.FunctionID)
+	return filepath.Join(input.Project.PathArtifacts(), input.Warp.FunctionID)
 }
 
 type BuildOutput struct {
 	Out     string
 	Handler string
 	Errors  []string
+	// Cached is true if the bundle was restored from the build cache
+	Cached bool
 }
 
 type RunInput struct {
@@ -48,10 +97,16 @@ type RunInput struct {
 	Runtime    string
 	Build      *BuildOutput
 	Env        []string
+	// Inspect starts the worker with a debugger attached, for the runtimes
+	// that support it
+	Inspect bool
 }
 
 var runtimes = []Runtime{
 	newNodeRuntime(),
+	newRustRuntime(),
+	newDotnetRuntime(),
+	newJavaRuntime(),
 }
 
 func GetRuntime(input string) (Runtime, bool) {
@@ -70,6 +125,11 @@ func Build(ctx context.Context, input *BuildInput) (*BuildOutput, error) {
 	if !ok {
 		return nil, fmt.Errorf("Runtime not found: %v", input.Warp.Runtime)
 	}
+	// invalid settings would fail the deploy, they're reported as a build
+	// error before the function runs
+	if _, err := input.Warp.Settings(); err != nil {
+		return nil, err
+	}
 	out := input.Out()
 	if err := os.RemoveAll(out); err != nil {
 		return nil, err
@@ -93,6 +153,57 @@ func Build(ctx context.Context, input *BuildInput) (*BuildOutput, error) {
 	return result, nil
 }
 
+// BuildResult is the outcome of building one function with BuildAll.
+type BuildResult struct {
+	FunctionID string
+	Output     *BuildOutput
+	Err        error
+}
+
+// Failed is true if the function couldn't be built.
+func (r *BuildResult) Failed() bool {
+	return r.Err != nil || len(r.Output.Errors) > 0
+}
+
+// BuildAll builds the functions with at most concurrency builds running at
+// once. onResult is called as each one finishes, one at a time, and the
+// results are returned sorted by function ID so failures are reported the
+// same way every time.
+func BuildAll(ctx context.Context, inputs []*BuildInput, concurrency int, onResult func(result *BuildResult)) []*BuildResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make([]*BuildResult, len(inputs))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	for index, input := range inputs {
+		wg.Add(1)
+		go func(index int, input *BuildInput) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			result := &BuildResult{FunctionID: input.Warp.FunctionID}
+			if err := ctx.Err(); err != nil {
+				result.Err = err
+			} else {
+				result.Output, result.Err = Build(ctx, input)
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			results[index] = result
+			if onResult != nil {
+				onResult(result)
+			}
+		}(index, input)
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].FunctionID < results[j].FunctionID
+	})
+	return results
+}
+
 func Run(ctx context.Context, input *RunInput) (Worker, error) {
 	slog.Info("running function", "runtime", input.Runtime, "functionID", input.FunctionID)
 	runtime, ok := GetRuntime(input.Runtime)
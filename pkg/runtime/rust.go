@@ -0,0 +1,189 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/sst/ion/internal/fs"
+)
+
+type RustRuntime struct {
+	lock sync.Mutex
+	// the files each function was built from, from the dep-info of cargo
+	sources map[string]map[string]bool
+}
+
+func newRustRuntime() *RustRuntime {
+	return &RustRuntime{
+		sources: map[string]map[string]bool{},
+	}
+}
+
+type RustProperties struct {
+	Binary   string   `json:"binary"`
+	Features []string `json:"features"`
+}
+
+var cargoPackageNameRegex = regexp.MustCompile(`(?m)^\s*name\s*=\s*["']([^"']+)["']`)
+
+// the section headers of a Cargo.toml, like [package] or [dependencies]
+var cargoSectionRegex = regexp.MustCompile(`(?m)^\s*\[`)
+
+func (r *RustRuntime) Match(runtime string) bool {
+	return runtime == "rust"
+}
+
+// targetDir is shared by all the functions and deploys, so cargo only
+// rebuilds the crates that changed.
+func (r *RustRuntime) targetDir(input *BuildInput) string {
+	return filepath.Join(input.Project.PathWorkingDir(), "rust", "target")
+}
+
+func (r *RustRuntime) Build(ctx context.Context, input *BuildInput) (*BuildOutput, error) {
+	var properties RustProperties
+	json.Unmarshal(input.Warp.Properties, &properties)
+
+	dir := filepath.Join(input.Project.PathRoot(), input.Warp.Handler)
+	manifest, err := os.ReadFile(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		return nil, fmt.Errorf("Could not find Cargo.toml for handler: %v", input.Warp.Handler)
+	}
+	binary := properties.Binary
+	if binary == "" {
+		binary = cargoPackageName(string(manifest))
+	}
+	if binary == "" {
+		return nil, fmt.Errorf("Could not find the package name in %v", filepath.Join(input.Warp.Handler, "Cargo.toml"))
+	}
+	if _, err := exec.LookPath("cargo"); err != nil {
+		return nil, fmt.Errorf("cargo is not installed, it's needed to build %v", input.Warp.FunctionID)
+	}
+
+	// built for the host in dev, it runs locally
+	args := []string{"build", "--bin", binary, "--message-format", "short"}
+	if len(properties.Features) > 0 {
+		args = append(args, "--features", strings.Join(properties.Features, ","))
+	}
+	target := r.targetDir(input)
+	cmd := exec.CommandContext(ctx, "cargo", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "CARGO_TARGET_DIR="+target)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		slog.Error("cargo build failed", "output", string(output))
+		return &BuildOutput{
+			Handler: "bootstrap",
+			Errors:  cargoErrors(string(output)),
+		}, nil
+	}
+
+	name := binary
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	// copied so the next build can't change the binary under a running
+	// worker
+	err = fs.CopyFile(filepath.Join(target, "debug", name), filepath.Join(input.Out(), "bootstrap"))
+	if err != nil {
+		return nil, err
+	}
+
+	sources := map[string]bool{
+		filepath.Join(dir, "Cargo.toml"): true,
+	}
+	if lock, err := fs.FindUp(dir, "Cargo.lock"); err == nil {
+		sources[lock] = true
+	}
+	if info, err := os.ReadFile(filepath.Join(target, "debug", binary+".d")); err == nil {
+		for _, file := range parseDepInfo(string(info)) {
+			sources[file] = true
+		}
+	}
+	r.lock.Lock()
+	r.sources[input.Warp.FunctionID] = sources
+	r.lock.Unlock()
+
+	return &BuildOutput{
+		Handler: "bootstrap",
+		Errors:  []string{},
+	}, nil
+}
+
+func (r *RustRuntime) Run(ctx context.Context, input *RunInput) (Worker, error) {
+	cmd := exec.CommandContext(ctx, filepath.Join(input.Build.Out, input.Build.Handler))
+	cmd.Env = append(input.Env, "AWS_LAMBDA_RUNTIME_API="+input.Server)
+	slog.Info("starting worker", "env", cmd.Env)
+	cmd.Dir = input.Build.Out
+	return startWorker(cmd), nil
+}
+
+func (r *RustRuntime) ShouldRebuild(functionID string, file string) bool {
+	r.lock.Lock()
+	sources, ok := r.sources[functionID]
+	r.lock.Unlock()
+	if !ok {
+		return false
+	}
+	return sources[file]
+}
+
+// cargoPackageName returns the name from the [package] section.
+func cargoPackageName(manifest string) string {
+	sections := cargoSectionRegex.Split(manifest, -1)
+	for _, section := range sections {
+		if !strings.HasPrefix(section, "package]") {
+			continue
+		}
+		match := cargoPackageNameRegex.FindStringSubmatch(section)
+		if match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+// cargoErrors picks the errors out of the output of cargo with
+// --message-format short.
+func cargoErrors(output string) []string {
+	errors := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, "error") && !strings.HasPrefix(line, "warning") {
+			errors = append(errors, line)
+		}
+	}
+	if len(errors) == 0 {
+		errors = append(errors, strings.TrimSpace(output))
+	}
+	return errors
+}
+
+// parseDepInfo returns the files of a Makefile-style dep-info file written
+// by cargo next to the binary, "target: file file\ with\ spaces".
+func parseDepInfo(info string) []string {
+	result := []string{}
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimRight(line, "\r")
+		index := strings.Index(line, ": ")
+		if index == -1 {
+			continue
+		}
+		// only escaped spaces, windows paths keep their backslashes
+		files := strings.Split(strings.ReplaceAll(line[index+2:], "\\ ", "\x00"), " ")
+		for _, file := range files {
+			if file != "" {
+				result = append(result, strings.ReplaceAll(file, "\x00", " "))
+			}
+		}
+	}
+	return result
+}
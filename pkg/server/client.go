@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"time"
@@ -15,6 +17,17 @@ type ConnectInput struct {
 	CfgPath string
 	Stage   string
 	OnEvent func(event Event)
+	// Inspect is passed on to the server if one has to be started
+	Inspect bool
+	// Attach is passed on to the server if one has to be started
+	Attach bool
+	// AllowReserved is passed on to the server if one has to be started
+	AllowReserved bool
+	// LogLevel, LogFormat, and LogFile are passed on to the server if one
+	// has to be started
+	LogLevel  string
+	LogFormat string
+	LogFile   string
 }
 
 func Connect(ctx context.Context, input ConnectInput) error {
@@ -32,6 +45,24 @@ func Connect(ctx context.Context, input ConnectInput) error {
 		cmd := exec.Command(currentExecutable)
 		cmd.Env = os.Environ()
 		cmd.Args = append(cmd.Args, "--stage="+input.Stage, "server")
+		if input.Inspect {
+			cmd.Args = append(cmd.Args, "--inspect")
+		}
+		if input.Attach {
+			cmd.Args = append(cmd.Args, "--attach")
+		}
+		if input.AllowReserved {
+			cmd.Args = append(cmd.Args, "--allow-reserved")
+		}
+		if input.LogLevel != "" {
+			cmd.Args = append(cmd.Args, "--log-level="+input.LogLevel)
+		}
+		if input.LogFormat != "" {
+			cmd.Args = append(cmd.Args, "--log-format="+input.LogFormat)
+		}
+		if input.LogFile != "" {
+			cmd.Args = append(cmd.Args, "--log-file="+input.LogFile)
+		}
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 		if err := cmd.Start(); err != nil {
@@ -103,3 +134,25 @@ func Connect(ctx context.Context, input ConnectInput) error {
 		}
 	}
 }
+
+var ErrServerNotRunning = fmt.Errorf("sst dev is not running")
+
+// Trigger asks the running dev server to fire something now, like a cron.
+func Trigger(cfgPath string, stage string, kind string, name string) error {
+	addr, err := findExisting(cfgPath, stage)
+	if err != nil {
+		return err
+	}
+	if addr == "" {
+		return ErrServerNotRunning
+	}
+	resp, err := http.Post("http://"+addr+"/trigger/"+kind+"/"+url.PathEscape(name), "application/json", nil)
+	if err != nil {
+		return ErrServerNotRunning
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("could not trigger %s %s: %s", kind, name, resp.Status)
+	}
+	return nil
+}
@@ -3,32 +3,57 @@ package server
 import (
 	"context"
 	"log/slog"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sst/ion/internal/util"
 	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/project/provider"
 	"github.com/sst/ion/pkg/server/bus"
 	"github.com/sst/ion/pkg/server/dev/watcher"
 )
 
 func startDeployer(ctx context.Context, p *project.Project) (util.CleanupFunc, error) {
-	trigger := make(chan any, 10000)
+	// changes made while deploying are applied together once it's done
+	trigger := make(chan any, 1)
 	mutex := sync.RWMutex{}
 	watchedFiles := make(map[string]bool)
+	var running atomic.Bool
 
 	bus.Subscribe(ctx, func(event *watcher.FileChangedEvent) {
 		mutex.RLock()
 		defer mutex.RUnlock()
 		if _, ok := watchedFiles[event.Path]; ok {
-			trigger <- true
+			rel, err := filepath.Rel(p.PathRoot(), event.Path)
+			if err != nil {
+				rel = event.Path
+			}
+			bus.Publish(&project.StackEvent{ReloadEvent: &project.ReloadEvent{
+				Path:     rel,
+				InFlight: running.Load(),
+			}})
+			select {
+			case trigger <- true:
+			default:
+			}
 		}
 	})
 
+	// the session keeps the lock and the state between deploys
+	p.Stack.HoldLock()
+
+	// every redeploy reads the same secrets, `sst secret set` invalidates
+	// the cache
+	provider.EnableSecretsCache(p.Backend(), 5*time.Minute)
+
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		for {
+			running.Store(true)
 			p.Stack.Run(ctx, &project.StackInput{
 				Command: "up",
 				Dev:     true,
@@ -44,6 +69,7 @@ func startDeployer(ctx context.Context, p *project.Project) (util.CleanupFunc, e
 					}
 				},
 			})
+			running.Store(false)
 
 			slog.Info("waiting for file changes")
 			select {
@@ -58,6 +84,6 @@ func startDeployer(ctx context.Context, p *project.Project) (util.CleanupFunc, e
 	return func() error {
 		slog.Info("cleaning up deployer")
 		wg.Wait()
-		return nil
+		return p.Stack.ReleaseLock()
 	}, nil
 }
@@ -13,6 +13,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -42,6 +43,8 @@ type FunctionInvokedEvent struct {
 	WorkerID   string
 	RequestID  string
 	Input      []byte
+	// Cold is true for the first invocation of a worker
+	Cold bool
 }
 
 type FunctionResponseEvent struct {
@@ -65,11 +68,26 @@ type FunctionBuildEvent struct {
 	Errors     []string
 }
 
+// FunctionBuildProgressEvent is sent as each function of a batch built
+// concurrently finishes, the errors are sent after as FunctionBuildEvent.
+type FunctionBuildProgressEvent struct {
+	FunctionID string
+	Completed  int
+	Total      int
+	Failed     bool
+	Cached     bool
+}
+
 type FunctionLogEvent struct {
 	FunctionID string
 	WorkerID   string
 	RequestID  string
 	Line       string
+	// Level is set for the lines read from CloudWatch, the others are
+	// guessed with LogLevel
+	Level string
+	// Remote is true for the logs of a function that isn't live
+	Remote bool
 }
 
 func Start(
@@ -78,6 +96,7 @@ func Start(
 	aws *provider.AwsProvider,
 	p *project.Project,
 	port int,
+	inspect bool,
 ) (util.CleanupFunc, error) {
 	expire := time.Hour * 24
 	from := time.Now()
@@ -179,6 +198,7 @@ func Start(
 		Worker           runtime.Worker
 		CurrentRequestID string
 		Env              []string
+		Invoked          bool
 	}
 
 	completeChan := make(chan *project.CompleteEvent, 1000)
@@ -205,6 +225,12 @@ func Start(
 		fileChan <- event
 	})
 
+	queueBatchChan := make(chan *queueBatch, 1000)
+	cronTriggerChan := make(chan *CronTriggerEvent, 1000)
+	bus.Subscribe(ctx, func(event *CronTriggerEvent) {
+		cronTriggerChan <- event
+	})
+
 	if token := mqttClient.Subscribe(prefix+"/+/init", 1, func(c MQTT.Client, m MQTT.Message) {
 		slog.Info("iot", "topic", m.Topic())
 		initChan <- m
@@ -258,6 +284,112 @@ func Start(
 			return build
 		}
 
+		// buildAll builds the functions concurrently, reporting the ones that
+		// failed once they're all done, in order
+		buildAll := func(functionIDs []string) {
+			sort.Strings(functionIDs)
+			inputs := []*runtime.BuildInput{}
+			for _, functionID := range functionIDs {
+				inputs = append(inputs, &runtime.BuildInput{
+					Warp:    complete.Warps[functionID],
+					Project: p,
+					Dev:     true,
+					Links:   complete.Links,
+				})
+			}
+			completed := 0
+			results := runtime.BuildAll(ctx, inputs, p.BuildConcurrency(), func(result *runtime.BuildResult) {
+				completed++
+				bus.Publish(&FunctionBuildProgressEvent{
+					FunctionID: result.FunctionID,
+					Completed:  completed,
+					Total:      len(inputs),
+					Failed:     result.Failed(),
+					Cached:     !result.Failed() && result.Output.Cached,
+				})
+			})
+			for _, result := range results {
+				if !result.Failed() {
+					builds[result.FunctionID] = result.Output
+					continue
+				}
+				delete(builds, result.FunctionID)
+				errors := []string{}
+				if result.Err != nil {
+					errors = append(errors, result.Err.Error())
+				} else {
+					errors = result.Output.Errors
+				}
+				bus.Publish(&FunctionBuildEvent{
+					FunctionID: result.FunctionID,
+					Errors:     errors,
+				})
+			}
+		}
+		// unbuilt returns the functions that haven't been built yet
+		unbuilt := func() []string {
+			result := []string{}
+			for functionID := range complete.Warps {
+				if builds[functionID] == nil {
+					result = append(result, functionID)
+				}
+			}
+			return result
+		}
+		buildAll(unbuilt())
+
+		crons := updateCrons(nil, complete.Warps, time.Now())
+		cronTimer := nextCron(crons, time.Now())
+		tunnels := updateTunnels(ctx, config, nil, complete)
+		// localInput prepares a function to run on this machine, outside of
+		// the bridge
+		localInput := func(functionID string) (*runtime.InvokeInput, error) {
+			build := getBuildOutput(functionID)
+			if build == nil {
+				return nil, runtime.ErrBuildFailed
+			}
+			warp := complete.Warps[functionID]
+			providerEnv, err := aws.Env()
+			if err != nil {
+				return nil, err
+			}
+			env, err := runtime.LocalEnv(warp, complete.Links, complete.Receivers, providerEnv)
+			if err != nil {
+				return nil, err
+			}
+			settings, _ := warp.Settings()
+			return &runtime.InvokeInput{
+				Project:   p,
+				Warp:      warp,
+				Links:     complete.Links,
+				Env:       tunnelEnv(env, tunnels),
+				Timeout:   settings.Timeout,
+				Build:     build,
+				RequestID: runtime.NewRequestID(),
+				Inspect:   inspect,
+			}, nil
+		}
+
+		fireCron := func(name string) {
+			entry, ok := crons[name]
+			if !ok {
+				bus.Publish(&CronEvent{Name: name, Error: "No cron named " + name + " in dev"})
+				return
+			}
+			input, err := localInput(entry.functionID)
+			if err != nil {
+				bus.Publish(&CronEvent{Name: name, FunctionID: entry.functionID, Error: err.Error()})
+				return
+			}
+			input.Payload = scheduledEvent(config.Region, time.Now())
+			bus.Publish(&CronEvent{Name: name, FunctionID: entry.functionID})
+			go invokeLocal(ctx, "cron-"+name, input)
+		}
+
+		pollers := updateQueuePollers(ctx, config, nil, complete.Warps, queueBatchChan)
+		busPollers := updateBusPollers(ctx, config, nil, complete.Warps, queueBatchChan)
+		logTails := updateLogTails(ctx, config, nil, complete.Logs)
+
 		run := func(functionID string, workerID string) bool {
 			build := getBuildOutput(functionID)
 			if build == nil {
@@ -271,7 +403,8 @@ func Start(
 				Runtime:    warp.Runtime,
 				FunctionID: functionID,
 				Build:      build,
-				Env:        workerEnv[workerID],
+				Env:        tunnelEnv(workerEnv[workerID], tunnels),
+				Inspect:    inspect,
 			})
 			info := &WorkerInfo{
 				FunctionID: functionID,
@@ -283,6 +416,7 @@ func Start(
 				scanner := bufio.NewScanner(logs)
 				for scanner.Scan() {
 					line := scanner.Text()
+					publishInspector(functionID, workerID, line)
 					bus.Publish(&FunctionLogEvent{
 						FunctionID: functionID,
 						WorkerID:   workerID,
@@ -318,7 +452,9 @@ func Start(
 						WorkerID:   info.WorkerID,
 						RequestID:  info.CurrentRequestID,
 						Input:      responseBody,
+						Cold:       !info.Invoked,
 					})
+					info.Invoked = true
 				}
 				if evt.path[len(evt.path)-1] == "response" {
 					bus.Publish(&FunctionResponseEvent{
@@ -348,8 +484,67 @@ func Start(
 					delete(workers, info.WorkerID)
 				}
 				break
-			case complete = <-completeChan:
+			case next := <-completeChan:
+				previous := complete
+				complete = next
+				rebuild, reload := warpChanges(previous, complete)
+				pollers = updateQueuePollers(ctx, config, pollers, complete.Warps, queueBatchChan)
+				busPollers = updateBusPollers(ctx, config, busPollers, complete.Warps, queueBatchChan)
+				logTails = updateLogTails(ctx, config, logTails, complete.Logs)
+				tunnels = updateTunnels(ctx, config, tunnels, complete)
+				crons = updateCrons(crons, complete.Warps, time.Now())
+				cronTimer = nextCron(crons, time.Now())
+				toBuild := map[string]bool{}
+				for _, functionID := range rebuild {
+					delete(builds, functionID)
+					toBuild[functionID] = true
+				}
+				buildAll(unbuilt())
+				for workerID, info := range workers {
+					if toBuild[info.FunctionID] {
+						info.Worker.Stop()
+						run(info.FunctionID, workerID)
+					}
+				}
+				// the build is reused, only the env of the workers changes
+				for _, functionID := range reload {
+					count := 0
+					for workerID, info := range workers {
+						if info.FunctionID != functionID {
+							continue
+						}
+						workerEnv[workerID] = reloadEnv(workerEnv[workerID], previous.Warps[functionID], complete.Warps[functionID], complete.Links)
+						info.Worker.Stop()
+						run(functionID, workerID)
+						count++
+					}
+					if count > 0 {
+						bus.Publish(&FunctionReloadEvent{FunctionID: functionID, Workers: count})
+					}
+				}
 				break
+			case now := <-cronTimer:
+				for name, entry := range crons {
+					if entry.next.IsZero() || entry.next.After(now) {
+						continue
+					}
+					entry.next = entry.schedule.Next(now)
+					fireCron(name)
+				}
+				cronTimer = nextCron(crons, time.Now())
+			case event := <-cronTriggerChan:
+				fireCron(event.Name)
+			case batch := <-queueBatchChan:
+				input, err := localInput(batch.functionID)
+				if err != nil {
+					batch.result <- queueResult{err: err}
+					continue
+				}
+				input.Payload = batch.payload
+				go func() {
+					output, err := invokeLocal(ctx, batch.workerID, input)
+					batch.result <- queueResult{output: output, err: err}
+				}()
 			case m := <-initChan:
 				bytes := m.Payload()
 				workerID := strings.Split(m.Topic(), "/")[3]
@@ -413,9 +608,13 @@ func Start(
 					}
 				}
 
+				rebuild := []string{}
 				for functionID := range toBuild {
-					output := getBuildOutput(functionID)
-					if output == nil {
+					rebuild = append(rebuild, functionID)
+				}
+				buildAll(rebuild)
+				for functionID := range toBuild {
+					if builds[functionID] == nil {
 						delete(toBuild, functionID)
 					}
 				}
@@ -430,6 +629,8 @@ func Start(
 
 	}()
 
+	startMetrics(ctx, mux)
+
 	mux.HandleFunc(`/lambda/`, func(w http.ResponseWriter, r *http.Request) {
 		path := strings.Split(r.URL.Path, "/")
 		slog.Info("lambda request", "path", path)
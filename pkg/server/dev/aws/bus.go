@@ -0,0 +1,165 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/server/bus"
+)
+
+// BusPollEvent is sent when dev starts forwarding the events of a bus, or
+// fails to read them.
+type BusPollEvent struct {
+	Name        string
+	Subscribers []string
+	Error       string
+}
+
+type busSubscriber struct {
+	functionID string
+	pattern    string
+}
+
+type busPoller struct {
+	url         string
+	arn         string
+	subscribers []busSubscriber
+	cancel      context.CancelFunc
+}
+
+// updateBusPollers starts a poller for the dev queue of each bus, shared by
+// all its subscribers, and restarts the ones whose subscribers changed.
+func updateBusPollers(ctx context.Context, config aws.Config, existing map[string]*busPoller, warps project.Warps, batches chan<- *queueBatch) map[string]*busPoller {
+	wanted := map[string]*busPoller{}
+	for functionID, warp := range warps {
+		for name, item := range warp.Buses {
+			poller, ok := wanted[name]
+			if !ok {
+				poller = &busPoller{url: item.URL, arn: item.Arn}
+				wanted[name] = poller
+			}
+			poller.subscribers = append(poller.subscribers, busSubscriber{
+				functionID: functionID,
+				pattern:    item.Pattern,
+			})
+		}
+	}
+	result := map[string]*busPoller{}
+	for name, poller := range wanted {
+		sort.Slice(poller.subscribers, func(i, j int) bool {
+			return poller.subscribers[i].functionID < poller.subscribers[j].functionID
+		})
+		if current, ok := existing[name]; ok && current.url == poller.url && reflect.DeepEqual(current.subscribers, poller.subscribers) {
+			result[name] = current
+			continue
+		}
+		pollCtx, cancel := context.WithCancel(ctx)
+		poller.cancel = cancel
+		result[name] = poller
+		go pollBus(pollCtx, config, name, poller, batches)
+	}
+	for name, poller := range existing {
+		if result[name] != poller {
+			poller.cancel()
+		}
+	}
+	return result
+}
+
+func pollBus(ctx context.Context, config aws.Config, name string, poller *busPoller, batches chan<- *queueBatch) {
+	config = config.Copy()
+	if parts := strings.Split(poller.arn, ":"); len(parts) > 3 {
+		config.Region = parts[3]
+	}
+	client := sqs.NewFromConfig(config)
+	patterns := map[string]map[string]interface{}{}
+	functionIDs := []string{}
+	for _, subscriber := range poller.subscribers {
+		functionIDs = append(functionIDs, subscriber.functionID)
+		pattern, err := parsePattern(subscriber.pattern)
+		if err != nil {
+			bus.Publish(&BusPollEvent{Name: name, Subscribers: functionIDs, Error: "invalid pattern for " + subscriber.functionID + ": " + err.Error()})
+			continue
+		}
+		patterns[subscriber.functionID] = pattern
+	}
+	slog.Info("polling bus", "name", name, "url", poller.url)
+	bus.Publish(&BusPollEvent{Name: name, Subscribers: functionIDs})
+
+	failing := false
+	for ctx.Err() == nil {
+		received, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(poller.url),
+			MaxNumberOfMessages: 10,
+			WaitTimeSeconds:     20,
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !failing {
+				bus.Publish(&BusPollEvent{Name: name, Subscribers: functionIDs, Error: err.Error()})
+			}
+			failing = true
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		failing = false
+
+		delivered := []types.Message{}
+		for _, message := range received.Messages {
+			var event map[string]interface{}
+			if err := json.Unmarshal([]byte(aws.ToString(message.Body)), &event); err != nil {
+				slog.Error("invalid bus event", "name", name, "err", err)
+				continue
+			}
+			// every subscriber whose pattern matches gets its own invocation,
+			// like separate rules would
+			pending := []*queueBatch{}
+			for _, subscriber := range poller.subscribers {
+				pattern, ok := patterns[subscriber.functionID]
+				if !ok || !matchesPattern(pattern, event) {
+					continue
+				}
+				batch := &queueBatch{
+					name:       name,
+					workerID:   "bus-" + subscriber.functionID,
+					functionID: subscriber.functionID,
+					payload:    []byte(aws.ToString(message.Body)),
+					result:     make(chan queueResult, 1),
+				}
+				select {
+				case batches <- batch:
+				case <-ctx.Done():
+					return
+				}
+				pending = append(pending, batch)
+			}
+			for _, batch := range pending {
+				select {
+				case <-batch.result:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if len(pending) > 0 {
+				delivered = append(delivered, message)
+			}
+		}
+		// events are delivered once, failures aren't retried locally. The
+		// ones no subscriber matched become visible again.
+		deleteMessages(ctx, client, poller.url, delivered)
+	}
+}
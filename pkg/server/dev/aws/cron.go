@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/runtime"
+	"github.com/sst/ion/pkg/server/bus"
+	"github.com/sst/ion/pkg/server/dev/cron"
+)
+
+// CronTriggerEvent asks dev to fire a cron now, sst dev --trigger cron
+// publishes it through the server.
+type CronTriggerEvent struct {
+	Name string
+}
+
+// CronEvent is sent when a cron is fired locally.
+type CronEvent struct {
+	Name       string
+	FunctionID string
+	Error      string
+}
+
+type cronEntry struct {
+	functionID string
+	expression string
+	schedule   cron.Schedule
+	next       time.Time
+}
+
+// updateCrons picks up the schedules of the warps, keeping when the ones
+// that didn't change fire next.
+func updateCrons(existing map[string]*cronEntry, warps project.Warps, now time.Time) map[string]*cronEntry {
+	result := map[string]*cronEntry{}
+	for functionID, warp := range warps {
+		for name, expression := range warp.Schedules {
+			if entry, ok := existing[name]; ok && entry.expression == expression && entry.functionID == functionID {
+				result[name] = entry
+				continue
+			}
+			schedule, err := cron.Parse(expression)
+			if err != nil {
+				bus.Publish(&CronEvent{Name: name, FunctionID: functionID, Error: "Will not run locally: " + err.Error()})
+				continue
+			}
+			result[name] = &cronEntry{
+				functionID: functionID,
+				expression: expression,
+				schedule:   schedule,
+				next:       schedule.Next(now),
+			}
+		}
+	}
+	return result
+}
+
+// nextCron returns a channel that fires when the earliest cron is due.
+func nextCron(entries map[string]*cronEntry, now time.Time) <-chan time.Time {
+	var earliest time.Time
+	for _, entry := range entries {
+		if entry.next.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || entry.next.Before(earliest) {
+			earliest = entry.next
+		}
+	}
+	if earliest.IsZero() {
+		return nil
+	}
+	return time.After(earliest.Sub(now))
+}
+
+// scheduledEvent is the event EventBridge sends for a schedule.
+func scheduledEvent(region string, now time.Time) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"version":     "0",
+		"id":          runtime.NewRequestID(),
+		"detail-type": "Scheduled Event",
+		"source":      "aws.events",
+		"account":     "",
+		"time":        now.UTC().Format(time.RFC3339),
+		"region":      region,
+		"resources":   []string{},
+		"detail":      map[string]interface{}{},
+	})
+	return data
+}
@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"io"
+
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/runtime"
+	"github.com/sst/ion/pkg/server/bus"
+)
+
+// invokeLocal runs a function on this machine, reporting it like an
+// invocation through the bridge.
+func invokeLocal(ctx context.Context, workerID string, input *runtime.InvokeInput) (*runtime.InvokeOutput, error) {
+	functionID := input.Warp.FunctionID
+	bus.Publish(&FunctionInvokedEvent{
+		FunctionID: functionID,
+		WorkerID:   workerID,
+		RequestID:  input.RequestID,
+		Input:      input.Payload,
+		Cold:       true,
+	})
+
+	reader, writer := io.Pipe()
+	go func() {
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			publishInspector(functionID, workerID, scanner.Text())
+			bus.Publish(&FunctionLogEvent{
+				FunctionID: functionID,
+				WorkerID:   workerID,
+				RequestID:  input.RequestID,
+				Line:       scanner.Text(),
+			})
+		}
+	}()
+	input.Logs = writer
+	output, err := runtime.Invoke(ctx, input)
+	writer.Close()
+	if err != nil {
+		bus.Publish(&FunctionErrorEvent{
+			FunctionID:   functionID,
+			WorkerID:     workerID,
+			RequestID:    input.RequestID,
+			ErrorType:    "Error",
+			ErrorMessage: err.Error(),
+		})
+		return nil, err
+	}
+	if output.Error != nil {
+		bus.Publish(&FunctionErrorEvent{
+			FunctionID:   functionID,
+			WorkerID:     workerID,
+			RequestID:    input.RequestID,
+			ErrorType:    output.Error.ErrorType,
+			ErrorMessage: output.Error.ErrorMessage,
+			Trace:        output.Error.Trace,
+		})
+		return output, nil
+	}
+	bus.Publish(&FunctionResponseEvent{
+		FunctionID: functionID,
+		WorkerID:   workerID,
+		RequestID:  input.RequestID,
+		Output:     output.Response,
+	})
+	return output, nil
+}
+
+// publishInspector lets the clients know where to attach a debugger when a
+// worker started with --inspect prints its url.
+func publishInspector(functionID string, workerID string, line string) {
+	url, ok := runtime.InspectorURL(line)
+	if !ok {
+		return
+	}
+	bus.Publish(&project.StackEvent{InspectorEvent: &project.InspectorEvent{
+		FunctionID: functionID,
+		WorkerID:   workerID,
+		URL:        url,
+	}})
+}
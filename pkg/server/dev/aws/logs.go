@@ -0,0 +1,366 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/server/bus"
+)
+
+// LogTailEvent is sent when dev starts tailing the CloudWatch logs of a
+// function that doesn't run locally, or fails to read them.
+type LogTailEvent struct {
+	FunctionID string
+	Group      string
+	Error      string
+}
+
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+var logLevels = map[string]int{
+	LogLevelDebug: 0,
+	LogLevelInfo:  1,
+	LogLevelWarn:  2,
+	LogLevelError: 3,
+}
+
+// LogFilter picks the function logs to show, sst dev --filter and --level.
+type LogFilter struct {
+	// Pattern the line or the function has to match
+	Pattern *regexp.Regexp
+	// Level is the lowest level shown
+	Level string
+}
+
+func NewLogFilter(pattern string, level string) (*LogFilter, error) {
+	result := &LogFilter{}
+	if pattern != "" {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", pattern, err)
+		}
+		result.Pattern = compiled
+	}
+	if level != "" {
+		level = strings.ToLower(level)
+		if _, ok := logLevels[level]; !ok {
+			return nil, fmt.Errorf("invalid level %q, it must be one of debug, info, warn, or error", level)
+		}
+		result.Level = level
+	}
+	return result, nil
+}
+
+func (f *LogFilter) Match(event *FunctionLogEvent) bool {
+	if f == nil {
+		return true
+	}
+	if f.Level != "" {
+		level := event.Level
+		if level == "" {
+			level = LogLevel(event.Line)
+		}
+		if logLevels[level] < logLevels[f.Level] {
+			return false
+		}
+	}
+	if f.Pattern != nil && !f.Pattern.MatchString(event.Line) && !f.Pattern.MatchString(event.FunctionID) {
+		return false
+	}
+	return true
+}
+
+var levelAliases = map[string]string{
+	"trace":   LogLevelDebug,
+	"debug":   LogLevelDebug,
+	"info":    LogLevelInfo,
+	"log":     LogLevelInfo,
+	"warn":    LogLevelWarn,
+	"warning": LogLevelWarn,
+	"error":   LogLevelError,
+	"fatal":   LogLevelError,
+}
+
+// LogLevel guesses the level of a line from a leading level, like `ERROR`
+// or `[warn]`, or the level field of a JSON line. It's info otherwise.
+func LogLevel(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") {
+		var parsed struct {
+			Level interface{} `json:"level"`
+		}
+		if json.Unmarshal([]byte(trimmed), &parsed) == nil {
+			if level, ok := levelAliases[strings.ToLower(fmt.Sprint(parsed.Level))]; ok {
+				return level
+			}
+		}
+		return LogLevelInfo
+	}
+	fields := strings.Fields(trimmed)
+	if len(fields) == 0 {
+		return LogLevelInfo
+	}
+	first := strings.ToLower(strings.Trim(fields[0], "[]():"))
+	if level, ok := levelAliases[first]; ok {
+		return level
+	}
+	return LogLevelInfo
+}
+
+type logTail struct {
+	log    project.WarpLog
+	cancel context.CancelFunc
+}
+
+// updateLogTails tails the log groups of the functions that aren't live,
+// the logs of the live ones come from the workers.
+func updateLogTails(ctx context.Context, config aws.Config, existing map[string]*logTail, logs project.WarpLogs) map[string]*logTail {
+	result := map[string]*logTail{}
+	for functionID, log := range logs {
+		if log.Live || log.Group == "" {
+			continue
+		}
+		if tail, ok := existing[functionID]; ok && tail.log == log {
+			result[functionID] = tail
+			continue
+		}
+		tailCtx, cancel := context.WithCancel(ctx)
+		result[functionID] = &logTail{
+			log:    log,
+			cancel: cancel,
+		}
+		go tailLogs(tailCtx, config, functionID, log)
+	}
+	for functionID, tail := range existing {
+		if result[functionID] != tail {
+			tail.cancel()
+		}
+	}
+	return result
+}
+
+func tailLogs(ctx context.Context, config aws.Config, functionID string, log project.WarpLog) {
+	config = config.Copy()
+	if log.Region != "" {
+		config.Region = log.Region
+	}
+	slog.Info("tailing logs", "functionID", functionID, "group", log.Group)
+	bus.Publish(&LogTailEvent{FunctionID: functionID, Group: log.Group})
+
+	reader := NewLogReader(config, log.Group, "", time.Now())
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(2 * time.Second):
+		}
+		entries, err := reader.Read(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// the group doesn't exist until the function logs something
+			if !failing && !errors.Is(err, ErrLogGroupNotFound) {
+				bus.Publish(&LogTailEvent{FunctionID: functionID, Group: log.Group, Error: err.Error()})
+			}
+			failing = true
+			continue
+		}
+		failing = false
+		for _, entry := range entries {
+			for _, line := range strings.Split(entry.Message, "\n") {
+				bus.Publish(&FunctionLogEvent{
+					FunctionID: functionID,
+					WorkerID:   "remote-" + functionID,
+					RequestID:  entry.RequestID,
+					Line:       line,
+					Level:      entry.Level,
+					Remote:     true,
+				})
+			}
+		}
+	}
+}
+
+// LogEntry is a message a function logged to CloudWatch.
+type LogEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Stream    string    `json:"stream"`
+	RequestID string    `json:"requestId,omitempty"`
+	Level     string    `json:"level,omitempty"`
+	Message   string    `json:"message"`
+}
+
+var ErrLogGroupNotFound = fmt.Errorf("log group not found")
+
+// how far back each read looks, since the events can show up late
+const logLookback = 10 * time.Second
+
+// LogReader reads the events of a log group as they come in, each one once.
+type LogReader struct {
+	config  aws.Config
+	group   string
+	pattern string
+	start   int64
+	seen    map[string]int64
+}
+
+// NewLogReader reads the events of the group since a time, matching the
+// CloudWatch filter pattern if there is one.
+func NewLogReader(config aws.Config, group string, pattern string, since time.Time) *LogReader {
+	return &LogReader{
+		config:  config,
+		group:   group,
+		pattern: pattern,
+		start:   since.UnixMilli(),
+		seen:    map[string]int64{},
+	}
+}
+
+// Read returns the events since the last read, without the START, END, and
+// REPORT lines Lambda adds for each request.
+func (r *LogReader) Read(ctx context.Context) ([]LogEntry, error) {
+	result := []LogEntry{}
+	latest := r.start
+	token := ""
+	for {
+		output, err := filterLogEvents(ctx, r.config, r.group, r.pattern, r.start-logLookback.Milliseconds(), token)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range output.Events {
+			if _, ok := r.seen[event.EventID]; ok || event.Timestamp < r.start {
+				continue
+			}
+			r.seen[event.EventID] = event.Timestamp
+			latest = max(latest, event.Timestamp)
+			if entry, ok := parseLogEvent(event); ok {
+				result = append(result, entry)
+			}
+		}
+		if output.NextToken == "" {
+			break
+		}
+		token = output.NextToken
+	}
+	r.start = latest
+	for id, timestamp := range r.seen {
+		if timestamp < r.start-logLookback.Milliseconds() {
+			delete(r.seen, id)
+		}
+	}
+	return result, nil
+}
+
+var remoteLogFormat = regexp.MustCompile(`^\S+\t([0-9a-f-]{36})\t([A-Z]+)\t([\s\S]*)$`)
+
+func parseLogEvent(event logEvent) (LogEntry, bool) {
+	message := strings.TrimRight(event.Message, "\n")
+	for _, prefix := range []string{"START RequestId:", "END RequestId:", "REPORT RequestId:", "INIT_START "} {
+		if strings.HasPrefix(message, prefix) {
+			return LogEntry{}, false
+		}
+	}
+	entry := LogEntry{
+		Timestamp: time.UnixMilli(event.Timestamp),
+		Stream:    event.LogStreamName,
+		Message:   message,
+	}
+	if match := remoteLogFormat.FindStringSubmatch(message); match != nil {
+		entry.RequestID = match[1]
+		if alias, ok := levelAliases[strings.ToLower(match[2])]; ok {
+			entry.Level = alias
+		}
+		entry.Message = match[3]
+	}
+	return entry, true
+}
+
+type logEvent struct {
+	EventID       string `json:"eventId"`
+	Timestamp     int64  `json:"timestamp"`
+	Message       string `json:"message"`
+	LogStreamName string `json:"logStreamName"`
+}
+
+type filterLogEventsOutput struct {
+	Events    []logEvent `json:"events"`
+	NextToken string     `json:"nextToken"`
+}
+
+// filterLogEvents calls the CloudWatch Logs API directly, it's a single
+// JSON request and not worth pulling in the client for.
+func filterLogEvents(ctx context.Context, config aws.Config, group string, pattern string, start int64, token string) (*filterLogEventsOutput, error) {
+	input := map[string]interface{}{
+		"logGroupName": group,
+		"startTime":    start,
+		"interleaved":  true,
+	}
+	if pattern != "" {
+		input["filterPattern"] = pattern
+	}
+	if token != "" {
+		input["nextToken"] = token
+	}
+	body, _ := json.Marshal(input)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://logs.%s.amazonaws.com/", config.Region), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328.FilterLogEvents")
+	creds, err := config.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(body)
+	err = v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "logs", config.Region, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &failure)
+		if strings.HasSuffix(failure.Type, "ResourceNotFoundException") {
+			return nil, ErrLogGroupNotFound
+		}
+		return nil, fmt.Errorf("%s: %s", failure.Type, failure.Message)
+	}
+	var output filterLogEventsOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
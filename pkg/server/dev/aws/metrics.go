@@ -0,0 +1,151 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sst/ion/pkg/server/bus"
+)
+
+// FunctionMetrics are the invocations of a function in this dev session.
+type FunctionMetrics struct {
+	FunctionID  string
+	Invocations int
+	Errors      int
+	ColdStarts  int
+	// Duration is the total time spent in the function
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+func (m FunctionMetrics) AverageDuration() time.Duration {
+	if m.Invocations == 0 {
+		return 0
+	}
+	return m.Duration / time.Duration(m.Invocations)
+}
+
+func (m FunctionMetrics) ErrorRate() float64 {
+	if m.Invocations == 0 {
+		return 0
+	}
+	return float64(m.Errors) / float64(m.Invocations)
+}
+
+// MetricsEvent is sent periodically with the functions that were invoked
+// since the last one, the busiest first.
+type MetricsEvent struct {
+	Functions []FunctionMetrics
+}
+
+// metricsInterval is how often the metrics of the functions that were
+// invoked are sent, so a burst of invocations doesn't flood the output.
+const metricsInterval = 30 * time.Second
+
+type metrics struct {
+	mutex     sync.Mutex
+	functions map[string]*FunctionMetrics
+	started   map[string]time.Time
+	changed   map[string]bool
+}
+
+// startMetrics tracks the invocations of the functions and serves them at
+// /metrics.
+func startMetrics(ctx context.Context, mux *http.ServeMux) {
+	m := &metrics{
+		functions: map[string]*FunctionMetrics{},
+		started:   map[string]time.Time{},
+		changed:   map[string]bool{},
+	}
+	bus.Subscribe(ctx, func(event *FunctionInvokedEvent) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		m.started[event.WorkerID+event.RequestID] = time.Now()
+		if event.Cold {
+			m.function(event.FunctionID).ColdStarts++
+		}
+	})
+	bus.Subscribe(ctx, func(event *FunctionResponseEvent) {
+		m.finish(event.FunctionID, event.WorkerID, event.RequestID, false)
+	})
+	bus.Subscribe(ctx, func(event *FunctionErrorEvent) {
+		m.finish(event.FunctionID, event.WorkerID, event.RequestID, true)
+	})
+
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m.list(nil))
+	})
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(metricsInterval):
+			}
+			m.mutex.Lock()
+			changed := m.changed
+			m.changed = map[string]bool{}
+			m.mutex.Unlock()
+			if len(changed) == 0 {
+				continue
+			}
+			bus.Publish(&MetricsEvent{Functions: m.list(changed)})
+		}
+	}()
+}
+
+func (m *metrics) function(functionID string) *FunctionMetrics {
+	result, ok := m.functions[functionID]
+	if !ok {
+		result = &FunctionMetrics{FunctionID: functionID}
+		m.functions[functionID] = result
+	}
+	return result
+}
+
+func (m *metrics) finish(functionID string, workerID string, requestID string, failed bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	key := workerID + requestID
+	started, ok := m.started[key]
+	if !ok {
+		return
+	}
+	delete(m.started, key)
+	duration := time.Since(started)
+	function := m.function(functionID)
+	function.Invocations++
+	function.Duration += duration
+	function.MaxDuration = max(function.MaxDuration, duration)
+	if failed {
+		function.Errors++
+	}
+	m.changed[functionID] = true
+}
+
+// list returns the metrics of the functions, or only the ones given, sorted
+// by the number of invocations.
+func (m *metrics) list(only map[string]bool) []FunctionMetrics {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	result := []FunctionMetrics{}
+	for functionID, function := range m.functions {
+		if only != nil && !only[functionID] {
+			continue
+		}
+		result = append(result, *function)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Invocations != result[j].Invocations {
+			return result[i].Invocations > result[j].Invocations
+		}
+		return result[i].FunctionID < result[j].FunctionID
+	})
+	return result
+}
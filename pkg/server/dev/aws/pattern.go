@@ -0,0 +1,280 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parsePattern reads an EventBridge pattern, or the filter of an event
+// source mapping, and checks that it only uses the operators that are
+// matched locally: exact values, prefix, suffix, equals-ignore-case,
+// wildcard, anything-but, numeric, exists, and $or.
+func parsePattern(raw string) (map[string]interface{}, error) {
+	var pattern map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &pattern); err != nil {
+		return nil, err
+	}
+	if err := validatePattern(pattern); err != nil {
+		return nil, err
+	}
+	return pattern, nil
+}
+
+func validatePattern(pattern map[string]interface{}) error {
+	for key, expected := range pattern {
+		if key == "$or" {
+			list, ok := expected.([]interface{})
+			if !ok || len(list) == 0 {
+				return fmt.Errorf("$or must be a list of patterns")
+			}
+			for _, item := range list {
+				nested, ok := item.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("$or must be a list of patterns")
+				}
+				if err := validatePattern(nested); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		switch expected := expected.(type) {
+		case map[string]interface{}:
+			if err := validatePattern(expected); err != nil {
+				return err
+			}
+		case []interface{}:
+			for _, candidate := range expected {
+				switch candidate := candidate.(type) {
+				case map[string]interface{}:
+					if err := validateRule(key, candidate); err != nil {
+						return err
+					}
+				case string, float64, bool, nil:
+				default:
+					return fmt.Errorf("the values of %q must be strings, numbers, booleans, null, or rules", key)
+				}
+			}
+		default:
+			return fmt.Errorf("the value of %q must be a list or an object", key)
+		}
+	}
+	return nil
+}
+
+func validateRule(key string, rule map[string]interface{}) error {
+	if len(rule) != 1 {
+		return fmt.Errorf("a rule for %q must have one operator", key)
+	}
+	for operator, operand := range rule {
+		var ok bool
+		switch operator {
+		case "exists":
+			_, ok = operand.(bool)
+		case "prefix", "suffix", "equals-ignore-case", "wildcard":
+			_, ok = operand.(string)
+		case "numeric":
+			_, err := numericConditions(operand)
+			if err != nil {
+				return fmt.Errorf("numeric rule for %q: %w", key, err)
+			}
+			ok = true
+		case "anything-but":
+			if nested, isRule := operand.(map[string]interface{}); isRule {
+				for nestedOperator := range nested {
+					if nestedOperator != "prefix" && nestedOperator != "suffix" && nestedOperator != "equals-ignore-case" {
+						return fmt.Errorf("unsupported anything-but operator %q for %q", nestedOperator, key)
+					}
+				}
+				return validateRule(key, nested)
+			}
+			ok = true
+		default:
+			return fmt.Errorf("unsupported operator %q for %q", operator, key)
+		}
+		if !ok {
+			return fmt.Errorf("invalid value for %q of %q", operator, key)
+		}
+	}
+	return nil
+}
+
+type numericCondition struct {
+	operator string
+	value    float64
+}
+
+// numericConditions reads the operand of numeric, like [">", 0, "<=", 5].
+func numericConditions(operand interface{}) ([]numericCondition, error) {
+	list, ok := operand.([]interface{})
+	if !ok || len(list) == 0 || len(list)%2 != 0 {
+		return nil, fmt.Errorf("must be pairs of an operator and a number")
+	}
+	result := []numericCondition{}
+	for index := 0; index < len(list); index += 2 {
+		operator, ok := list[index].(string)
+		if !ok {
+			return nil, fmt.Errorf("must be pairs of an operator and a number")
+		}
+		switch operator {
+		case "<", "<=", "=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("unsupported operator %q", operator)
+		}
+		value, ok := list[index+1].(float64)
+		if !ok {
+			return nil, fmt.Errorf("must be pairs of an operator and a number")
+		}
+		result = append(result, numericCondition{operator, value})
+	}
+	return result, nil
+}
+
+// matchesPattern checks a value against a pattern that passed
+// validatePattern. Fields that are lists match if any of their items do.
+func matchesPattern(pattern map[string]interface{}, value map[string]interface{}) bool {
+	for key, expected := range pattern {
+		if key == "$or" {
+			matched := false
+			for _, item := range expected.([]interface{}) {
+				if matchesPattern(item.(map[string]interface{}), value) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+			continue
+		}
+		actual, exists := value[key]
+		switch expected := expected.(type) {
+		case map[string]interface{}:
+			if !matchesNested(expected, actual) {
+				return false
+			}
+		case []interface{}:
+			if !matchesValues(expected, actual, exists) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func matchesNested(pattern map[string]interface{}, actual interface{}) bool {
+	switch actual := actual.(type) {
+	case map[string]interface{}:
+		return matchesPattern(pattern, actual)
+	case []interface{}:
+		for _, item := range actual {
+			if matchesNested(pattern, item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesValues(expected []interface{}, actual interface{}, exists bool) bool {
+	for _, candidate := range expected {
+		rule, ok := candidate.(map[string]interface{})
+		if ok {
+			if want, ok := rule["exists"].(bool); ok {
+				if want == exists {
+					return true
+				}
+				continue
+			}
+		}
+		if !exists {
+			continue
+		}
+		// a list matches if any of its items do
+		items, ok := actual.([]interface{})
+		if !ok {
+			items = []interface{}{actual}
+		}
+		for _, item := range items {
+			if rule != nil && matchesRule(rule, item) {
+				return true
+			}
+			if rule == nil && candidate == item {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesRule(rule map[string]interface{}, actual interface{}) bool {
+	str, isString := actual.(string)
+	for operator, operand := range rule {
+		switch operator {
+		case "prefix":
+			return isString && strings.HasPrefix(str, operand.(string))
+		case "suffix":
+			return isString && strings.HasSuffix(str, operand.(string))
+		case "equals-ignore-case":
+			return isString && strings.EqualFold(str, operand.(string))
+		case "wildcard":
+			return isString && wildcardRegex(operand.(string)).MatchString(str)
+		case "numeric":
+			number, ok := actual.(float64)
+			if !ok {
+				return false
+			}
+			conditions, _ := numericConditions(operand)
+			for _, condition := range conditions {
+				if !compareNumber(number, condition) {
+					return false
+				}
+			}
+			return true
+		case "anything-but":
+			switch operand := operand.(type) {
+			case map[string]interface{}:
+				return isString && !matchesRule(operand, actual)
+			case []interface{}:
+				for _, item := range operand {
+					if item == actual {
+						return false
+					}
+				}
+				return true
+			default:
+				return operand != actual
+			}
+		}
+	}
+	return false
+}
+
+func compareNumber(number float64, condition numericCondition) bool {
+	switch condition.operator {
+	case "<":
+		return number < condition.value
+	case "<=":
+		return number <= condition.value
+	case "=":
+		return number == condition.value
+	case ">":
+		return number > condition.value
+	case ">=":
+		return number >= condition.value
+	}
+	return false
+}
+
+// wildcardRegex turns a wildcard, where * matches anything, into a regex.
+func wildcardRegex(wildcard string) *regexp.Regexp {
+	parts := strings.Split(wildcard, "*")
+	for index, part := range parts {
+		parts[index] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
@@ -0,0 +1,79 @@
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMatchesPattern(t *testing.T) {
+	event := map[string]interface{}{}
+	json.Unmarshal([]byte(`{
+		"source": "app.orders",
+		"detail-type": "OrderPlaced",
+		"detail": {
+			"id": "order-123",
+			"total": 42.5,
+			"tags": ["priority", "gift"],
+			"items": [{"sku": "abc"}, {"sku": "def"}],
+			"email": "Someone@Example.com",
+			"coupon": null
+		}
+	}`), &event)
+	cases := []struct {
+		pattern string
+		matches bool
+	}{
+		{`{"source": ["app.orders"]}`, true},
+		{`{"source": ["app.users"]}`, false},
+		{`{"detail": {"id": [{"prefix": "order-"}]}}`, true},
+		{`{"detail": {"id": [{"suffix": "-123"}]}}`, true},
+		{`{"detail": {"id": [{"suffix": "-124"}]}}`, false},
+		{`{"detail": {"email": [{"equals-ignore-case": "someone@example.com"}]}}`, true},
+		{`{"detail": {"id": [{"wildcard": "order-*3"}]}}`, true},
+		{`{"detail": {"id": [{"wildcard": "order.*"}]}}`, false},
+		{`{"detail": {"total": [{"numeric": [">", 40, "<=", 50]}]}}`, true},
+		{`{"detail": {"total": [{"numeric": ["<", 40]}]}}`, false},
+		{`{"detail": {"total": [42.5]}}`, true},
+		{`{"detail": {"total": ["42.5"]}}`, false},
+		{`{"detail": {"tags": ["gift"]}}`, true},
+		{`{"detail": {"tags": ["sale"]}}`, false},
+		{`{"detail": {"tags": [{"prefix": "pri"}]}}`, true},
+		{`{"detail": {"items": {"sku": ["def"]}}}`, true},
+		{`{"detail": {"items": {"sku": ["xyz"]}}}`, false},
+		{`{"detail": {"coupon": [null]}}`, true},
+		{`{"detail": {"missing": [{"exists": false}]}}`, true},
+		{`{"detail": {"id": [{"exists": false}]}}`, false},
+		{`{"detail": {"id": [{"anything-but": ["order-1", "order-2"]}]}}`, true},
+		{`{"detail": {"id": [{"anything-but": {"prefix": "order-"}}]}}`, false},
+		{`{"detail": {"missing": [{"anything-but": "x"}]}}`, false},
+		{`{"$or": [{"source": ["app.users"]}, {"detail-type": ["OrderPlaced"]}]}`, true},
+		{`{"$or": [{"source": ["app.users"]}, {"detail-type": ["OrderShipped"]}]}`, false},
+	}
+	for _, c := range cases {
+		pattern, err := parsePattern(c.pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", c.pattern, err)
+		}
+		if matches := matchesPattern(pattern, event); matches != c.matches {
+			t.Errorf("%s: expected %v, got %v", c.pattern, c.matches, matches)
+		}
+	}
+}
+
+func TestParsePatternInvalid(t *testing.T) {
+	for _, pattern := range []string{
+		`{"source": "app.orders"}`,
+		`{"detail": {"ip": [{"cidr": "10.0.0.0/24"}]}}`,
+		`{"detail": {"total": [{"numeric": [">"]}]}}`,
+		`{"detail": {"total": [{"numeric": ["!=", 1]}]}}`,
+		`{"detail": {"id": [{"prefix": 1}]}}`,
+		`{"detail": {"id": [{"prefix": "a", "suffix": "b"}]}}`,
+		`{"detail": {"id": [{"anything-but": {"wildcard": "a*"}}]}}`,
+		`{"detail": {"id": [["nested"]]}}`,
+		`{"$or": {"source": ["app.orders"]}}`,
+	} {
+		if _, err := parsePattern(pattern); err == nil {
+			t.Errorf("%s: expected an error", pattern)
+		}
+	}
+}
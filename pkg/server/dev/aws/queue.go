@@ -0,0 +1,285 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/runtime"
+	"github.com/sst/ion/pkg/server/bus"
+)
+
+// QueuePollEvent is sent when a poller starts, or fails to read from its
+// queue.
+type QueuePollEvent struct {
+	Name       string
+	FunctionID string
+	Error      string
+}
+
+// queueBatch is a batch of messages for the event loop to run the function
+// with, since only the loop can build it.
+type queueBatch struct {
+	name       string
+	workerID   string
+	functionID string
+	payload    []byte
+	result     chan queueResult
+}
+
+type queueResult struct {
+	output *runtime.InvokeOutput
+	err    error
+}
+
+type queuePoller struct {
+	functionID string
+	queue      project.WarpQueue
+	cancel     context.CancelFunc
+}
+
+// updateQueuePollers starts a poller for each queue a function is
+// subscribed to and stops the ones that are gone or changed.
+func updateQueuePollers(ctx context.Context, config aws.Config, existing map[string]*queuePoller, warps project.Warps, batches chan<- *queueBatch) map[string]*queuePoller {
+	result := map[string]*queuePoller{}
+	for functionID, warp := range warps {
+		for name, queue := range warp.Queues {
+			if poller, ok := existing[name]; ok && poller.functionID == functionID && reflect.DeepEqual(poller.queue, queue) {
+				result[name] = poller
+				continue
+			}
+			pollCtx, cancel := context.WithCancel(ctx)
+			result[name] = &queuePoller{
+				functionID: functionID,
+				queue:      queue,
+				cancel:     cancel,
+			}
+			go pollQueue(pollCtx, config, name, functionID, queue, batches)
+		}
+	}
+	for name, poller := range existing {
+		if result[name] != poller {
+			poller.cancel()
+		}
+	}
+	return result
+}
+
+func pollQueue(ctx context.Context, config aws.Config, name string, functionID string, queue project.WarpQueue, batches chan<- *queueBatch) {
+	config = config.Copy()
+	// arn:aws:sqs:{region}:{account}:{name}
+	if parts := strings.Split(queue.Arn, ":"); len(parts) > 3 {
+		config.Region = parts[3]
+	}
+	client := sqs.NewFromConfig(config)
+	batchSize := queue.BatchSize
+	if batchSize < 1 || batchSize > 10 {
+		batchSize = 10
+	}
+	// an invalid filter would drop every message, so nothing is polled
+	filters := []map[string]interface{}{}
+	for _, filter := range queue.Filters {
+		pattern, err := parsePattern(filter)
+		if err != nil {
+			bus.Publish(&QueuePollEvent{Name: name, FunctionID: functionID, Error: "invalid filter: " + err.Error()})
+			return
+		}
+		filters = append(filters, pattern)
+	}
+	slog.Info("polling queue", "name", name, "url", queue.URL)
+	bus.Publish(&QueuePollEvent{Name: name, FunctionID: functionID})
+
+	failing := false
+	for ctx.Err() == nil {
+		received, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(queue.URL),
+			MaxNumberOfMessages:   int32(batchSize),
+			WaitTimeSeconds:       20,
+			AttributeNames:        []types.QueueAttributeName{types.QueueAttributeNameAll},
+			MessageAttributeNames: []string{"All"},
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !failing {
+				bus.Publish(&QueuePollEvent{Name: name, FunctionID: functionID, Error: err.Error()})
+			}
+			failing = true
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		failing = false
+		if len(received.Messages) == 0 {
+			continue
+		}
+
+		// only the messages that are delivered are deleted, the ones that
+		// don't match the filters become visible again
+		messages := []types.Message{}
+		for _, message := range received.Messages {
+			if matchesFilters(filters, message) {
+				messages = append(messages, message)
+			}
+		}
+		if len(messages) == 0 {
+			continue
+		}
+
+		batch := &queueBatch{
+			name:       name,
+			workerID:   "queue-" + name,
+			functionID: functionID,
+			payload:    sqsEvent(queue, config.Region, messages),
+			result:     make(chan queueResult, 1),
+		}
+		select {
+		case batches <- batch:
+		case <-ctx.Done():
+			return
+		}
+		var result queueResult
+		select {
+		case result = <-batch.result:
+		case <-ctx.Done():
+			return
+		}
+		// failed messages become visible again and are retried
+		if result.err != nil || result.output.Error != nil {
+			continue
+		}
+		deleteMessages(ctx, client, queue.URL, succeeded(queue, messages, result.output.Response))
+	}
+}
+
+// succeeded returns the messages to delete, all of them unless the function
+// reports the ones that failed.
+func succeeded(queue project.WarpQueue, messages []types.Message, response []byte) []types.Message {
+	if !queue.ReportBatchItemFailures {
+		return messages
+	}
+	var parsed struct {
+		BatchItemFailures []struct {
+			ItemIdentifier string `json:"itemIdentifier"`
+		} `json:"batchItemFailures"`
+	}
+	if err := json.Unmarshal(response, &parsed); err != nil {
+		return messages
+	}
+	failed := map[string]bool{}
+	for _, failure := range parsed.BatchItemFailures {
+		// an empty or unknown identifier fails the whole batch
+		if failure.ItemIdentifier == "" {
+			return []types.Message{}
+		}
+		failed[failure.ItemIdentifier] = true
+	}
+	result := []types.Message{}
+	for _, message := range messages {
+		if !failed[aws.ToString(message.MessageId)] {
+			result = append(result, message)
+		}
+	}
+	if len(messages)-len(result) != len(failed) {
+		return []types.Message{}
+	}
+	return result
+}
+
+func deleteMessages(ctx context.Context, client *sqs.Client, url string, messages []types.Message) {
+	for len(messages) > 0 {
+		count := min(len(messages), 10)
+		entries := []types.DeleteMessageBatchRequestEntry{}
+		for index, message := range messages[:count] {
+			entries = append(entries, types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(index)),
+				ReceiptHandle: message.ReceiptHandle,
+			})
+		}
+		_, err := client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(url),
+			Entries:  entries,
+		})
+		if err != nil {
+			slog.Error("failed to delete messages", "url", url, "err", err)
+		}
+		messages = messages[count:]
+	}
+}
+
+// sqsEvent is the event Lambda sends for a batch of messages.
+func sqsEvent(queue project.WarpQueue, region string, messages []types.Message) []byte {
+	records := []map[string]interface{}{}
+	for _, message := range messages {
+		attributes := map[string]interface{}{}
+		for key, value := range message.MessageAttributes {
+			attribute := map[string]interface{}{
+				"dataType":         aws.ToString(value.DataType),
+				"stringListValues": []string{},
+				"binaryListValues": []string{},
+			}
+			if value.StringValue != nil {
+				attribute["stringValue"] = *value.StringValue
+			}
+			if value.BinaryValue != nil {
+				attribute["binaryValue"] = base64.StdEncoding.EncodeToString(value.BinaryValue)
+			}
+			attributes[key] = attribute
+		}
+		records = append(records, map[string]interface{}{
+			"messageId":         aws.ToString(message.MessageId),
+			"receiptHandle":     aws.ToString(message.ReceiptHandle),
+			"body":              aws.ToString(message.Body),
+			"attributes":        message.Attributes,
+			"messageAttributes": attributes,
+			"md5OfBody":         aws.ToString(message.MD5OfBody),
+			"eventSource":       "aws:sqs",
+			"eventSourceARN":    queue.Arn,
+			"awsRegion":         region,
+		})
+	}
+	data, _ := json.Marshal(map[string]interface{}{"Records": records})
+	return data
+}
+
+// matchesFilters checks a message against the filter patterns of the event
+// source mapping.
+func matchesFilters(filters []map[string]interface{}, message types.Message) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	var body interface{} = aws.ToString(message.Body)
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(aws.ToString(message.Body)), &parsed); err == nil {
+		if _, ok := parsed.(map[string]interface{}); ok {
+			body = parsed
+		}
+	}
+	attributes := map[string]interface{}{}
+	for key, value := range message.Attributes {
+		attributes[key] = value
+	}
+	record := map[string]interface{}{
+		"body":       body,
+		"attributes": attributes,
+		"messageId":  aws.ToString(message.MessageId),
+	}
+	for _, filter := range filters {
+		if matchesPattern(filter, record) {
+			return true
+		}
+	}
+	return false
+}
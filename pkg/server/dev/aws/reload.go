@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sst/ion/pkg/project"
+)
+
+// FunctionReloadEvent is sent when only the links or the environment of a
+// function changed, its workers are restarted with them without building it
+// again.
+type FunctionReloadEvent struct {
+	FunctionID string
+	Workers    int
+}
+
+// warpChanges compares the functions of two deploys. The ones whose code or
+// build options changed are rebuilt, the ones where only the links or the
+// environment changed are reloaded.
+func warpChanges(before *project.CompleteEvent, after *project.CompleteEvent) (rebuild []string, reload []string) {
+	rebuild = []string{}
+	reload = []string{}
+	for functionID, next := range after.Warps {
+		previous, ok := before.Warps[functionID]
+		if !ok {
+			continue
+		}
+		if previous.Runtime != next.Runtime ||
+			previous.Handler != next.Handler ||
+			previous.Bundle != next.Bundle ||
+			!bytes.Equal(previous.Properties, next.Properties) {
+			rebuild = append(rebuild, functionID)
+			continue
+		}
+		if !reflect.DeepEqual(previous.Links, next.Links) ||
+			!reflect.DeepEqual(previous.Environment, next.Environment) ||
+			!reflect.DeepEqual(linkValues(next.Links, before.Links), linkValues(next.Links, after.Links)) {
+			reload = append(reload, functionID)
+		}
+	}
+	sort.Strings(rebuild)
+	sort.Strings(reload)
+	return rebuild, reload
+}
+
+func linkValues(names []string, links project.Links) []interface{} {
+	result := []interface{}{}
+	for _, name := range names {
+		result = append(result, links[name])
+	}
+	return result
+}
+
+// reloadEnv replaces the links and the environment of a function in the env
+// of one of its workers.
+func reloadEnv(env []string, before project.Warp, after project.Warp, links project.Links) []string {
+	removed := map[string]bool{}
+	for _, name := range before.Links {
+		removed["SST_RESOURCE_"+name] = true
+	}
+	for key := range before.Environment {
+		removed[key] = true
+	}
+	for _, name := range after.Links {
+		removed["SST_RESOURCE_"+name] = true
+	}
+	for key := range after.Environment {
+		removed[key] = true
+	}
+	result := []string{}
+	for _, item := range env {
+		key, _, _ := strings.Cut(item, "=")
+		if !removed[key] {
+			result = append(result, item)
+		}
+	}
+	for _, name := range after.Links {
+		value, _ := json.Marshal(links[name])
+		result = append(result, fmt.Sprintf("SST_RESOURCE_%s=%s", name, value))
+	}
+	for key, value := range after.Environment {
+		result = append(result, key+"="+value)
+	}
+	return result
+}
@@ -0,0 +1,217 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/server/bus"
+)
+
+// TunnelEvent is sent when a tunnel to a linked resource in a VPC opens, or
+// fails to.
+type TunnelEvent struct {
+	Name   string
+	Remote string
+	Local  string
+	Error  string
+}
+
+type tunnel struct {
+	bastion string
+	host    string
+	port    int
+	local   int
+	cancel  context.CancelFunc
+}
+
+// updateTunnels opens a tunnel for each link with a host and a port that's
+// used by a function with a bastion, and closes the ones that are gone or
+// changed.
+func updateTunnels(ctx context.Context, config aws.Config, existing map[string]*tunnel, complete *project.CompleteEvent) map[string]*tunnel {
+	result := map[string]*tunnel{}
+	for _, warp := range complete.Warps {
+		if warp.Bastion == "" {
+			continue
+		}
+		for _, name := range warp.Links {
+			if _, ok := result[name]; ok {
+				continue
+			}
+			host, port, ok := linkEndpoint(complete.Links[name])
+			if !ok {
+				continue
+			}
+			if current, ok := existing[name]; ok && current.bastion == warp.Bastion && current.host == host && current.port == port {
+				result[name] = current
+				continue
+			}
+			local, err := freePort()
+			if err != nil {
+				bus.Publish(&TunnelEvent{Name: name, Error: err.Error()})
+				continue
+			}
+			tunnelCtx, cancel := context.WithCancel(ctx)
+			created := &tunnel{
+				bastion: warp.Bastion,
+				host:    host,
+				port:    port,
+				local:   local,
+				cancel:  cancel,
+			}
+			result[name] = created
+			go runTunnel(tunnelCtx, config, name, created)
+		}
+	}
+	for name, existing := range existing {
+		if result[name] != existing {
+			existing.cancel()
+		}
+	}
+	return result
+}
+
+// linkEndpoint reads the host and the port out of the properties of a link.
+func linkEndpoint(link interface{}) (string, int, bool) {
+	properties, ok := link.(map[string]interface{})
+	if !ok {
+		return "", 0, false
+	}
+	host, ok := properties["host"].(string)
+	if !ok || host == "" {
+		return "", 0, false
+	}
+	switch port := properties["port"].(type) {
+	case float64:
+		return host, int(port), true
+	case string:
+		parsed, err := strconv.Atoi(port)
+		return host, parsed, err == nil
+	}
+	return "", 0, false
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// runTunnel keeps a port forwarding session through the bastion open until
+// the context is done, restarting it when the plugin exits.
+func runTunnel(ctx context.Context, config aws.Config, name string, t *tunnel) {
+	remote := net.JoinHostPort(t.host, strconv.Itoa(t.port))
+	local := "127.0.0.1:" + strconv.Itoa(t.local)
+	plugin, err := exec.LookPath("session-manager-plugin")
+	if err != nil {
+		bus.Publish(&TunnelEvent{Name: name, Remote: remote, Error: "The Session Manager plugin is needed to reach " + remote + ", install it from https://docs.aws.amazon.com/systems-manager/latest/userguide/session-manager-working-with-install-plugin.html"})
+		return
+	}
+	client := ssm.NewFromConfig(config)
+	failing := false
+	for ctx.Err() == nil {
+		err := startSession(ctx, client, config.Region, plugin, t, func() {
+			failing = false
+			bus.Publish(&TunnelEvent{Name: name, Remote: remote, Local: local})
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && !failing {
+			failing = true
+			bus.Publish(&TunnelEvent{Name: name, Remote: remote, Error: err.Error()})
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func startSession(ctx context.Context, client *ssm.Client, region string, plugin string, t *tunnel, started func()) error {
+	request := &ssm.StartSessionInput{
+		Target:       aws.String(t.bastion),
+		DocumentName: aws.String("AWS-StartPortForwardingSessionToRemoteHost"),
+		Parameters: map[string][]string{
+			"host":            {t.host},
+			"portNumber":      {strconv.Itoa(t.port)},
+			"localPortNumber": {strconv.Itoa(t.local)},
+		},
+	}
+	session, err := client.StartSession(ctx, request)
+	if err != nil {
+		return err
+	}
+	defer client.TerminateSession(context.Background(), &ssm.TerminateSessionInput{
+		SessionId: session.SessionId,
+	})
+	sessionJSON, _ := json.Marshal(map[string]string{
+		"SessionId":  aws.ToString(session.SessionId),
+		"StreamUrl":  aws.ToString(session.StreamUrl),
+		"TokenValue": aws.ToString(session.TokenValue),
+	})
+	requestJSON, _ := json.Marshal(map[string]interface{}{
+		"Target":       t.bastion,
+		"DocumentName": aws.ToString(request.DocumentName),
+		"Parameters":   request.Parameters,
+	})
+	cmd := exec.CommandContext(ctx, plugin,
+		string(sessionJSON),
+		region,
+		"StartSession",
+		"",
+		string(requestJSON),
+		fmt.Sprintf("https://ssm.%s.amazonaws.com", region),
+	)
+	output := &strings.Builder{}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	started()
+	slog.Info("tunnel started", "host", t.host, "port", t.port, "local", t.local)
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
+
+// tunnelEnv points the links in the environment of a local function at the
+// tunnels, so it reaches them the way the deployed function does.
+func tunnelEnv(env []string, tunnels map[string]*tunnel) []string {
+	if len(tunnels) == 0 {
+		return env
+	}
+	result := make([]string, 0, len(env))
+	for _, item := range env {
+		key, value, _ := strings.Cut(item, "=")
+		t, ok := tunnels[strings.TrimPrefix(key, "SST_RESOURCE_")]
+		if !ok || !strings.HasPrefix(key, "SST_RESOURCE_") {
+			result = append(result, item)
+			continue
+		}
+		var properties map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &properties); err != nil {
+			result = append(result, item)
+			continue
+		}
+		properties["host"] = "127.0.0.1"
+		properties["port"] = t.local
+		data, _ := json.Marshal(properties)
+		result = append(result, key+"="+string(data))
+	}
+	return result
+}
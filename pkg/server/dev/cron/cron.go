@@ -0,0 +1,217 @@
+// Package cron evaluates EventBridge schedule expressions, so scheduled
+// functions can be fired locally in dev.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Schedule interface {
+	// Next returns the first time after the given time the schedule fires.
+	Next(after time.Time) time.Time
+}
+
+// Parse parses rate(value unit) and cron(minutes hours day-of-month month
+// day-of-week year) expressions. The L, W, and # wildcards are not
+// supported.
+func Parse(expression string) (Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if inner, ok := unwrap(expression, "rate"); ok {
+		return parseRate(inner)
+	}
+	if inner, ok := unwrap(expression, "cron"); ok {
+		return parseCron(inner)
+	}
+	return nil, fmt.Errorf("invalid schedule %q, must be rate(...) or cron(...)", expression)
+}
+
+func unwrap(expression string, name string) (string, bool) {
+	if !strings.HasPrefix(expression, name+"(") || !strings.HasSuffix(expression, ")") {
+		return "", false
+	}
+	return strings.TrimSpace(expression[len(name)+1 : len(expression)-1]), true
+}
+
+type rate time.Duration
+
+func parseRate(input string) (Schedule, error) {
+	fields := strings.Fields(input)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid rate %q, must look like rate(5 minutes)", input)
+	}
+	value, err := strconv.Atoi(fields[0])
+	if err != nil || value < 1 {
+		return nil, fmt.Errorf("invalid rate %q, the value must be a positive number", input)
+	}
+	units := map[string]time.Duration{
+		"minute": time.Minute,
+		"hour":   time.Hour,
+		"day":    24 * time.Hour,
+	}
+	unit, ok := units[strings.TrimSuffix(fields[1], "s")]
+	if !ok {
+		return nil, fmt.Errorf("invalid rate %q, the unit must be minutes, hours, or days", input)
+	}
+	return rate(time.Duration(value) * unit), nil
+}
+
+func (r rate) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(r))
+}
+
+type cron struct {
+	minutes map[int]bool
+	hours   map[int]bool
+	// nil when the field is ?
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+	// nil when the field is *
+	years map[int]bool
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+// day-of-week is 1-7 starting on Sunday
+var weekdayNames = map[string]int{
+	"SUN": 1, "MON": 2, "TUE": 3, "WED": 4, "THU": 5, "FRI": 6, "SAT": 7,
+}
+
+func parseCron(input string) (Schedule, error) {
+	fields := strings.Fields(input)
+	if len(fields) != 6 {
+		return nil, fmt.Errorf("invalid cron %q, must have 6 fields", input)
+	}
+	if (fields[2] == "?") == (fields[4] == "?") {
+		return nil, fmt.Errorf("invalid cron %q, one of day-of-month or day-of-week must be ?", input)
+	}
+	result := &cron{}
+	var err error
+	if result.minutes, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if result.hours, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if fields[2] != "?" {
+		if result.days, err = parseField(fields[2], 1, 31, nil); err != nil {
+			return nil, err
+		}
+	}
+	if result.months, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if fields[4] != "?" {
+		if result.weekdays, err = parseField(fields[4], 1, 7, weekdayNames); err != nil {
+			return nil, err
+		}
+	}
+	if fields[5] != "*" {
+		if result.years, err = parseField(fields[5], 1970, 2199, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func parseField(field string, min int, max int, names map[string]int) (map[int]bool, error) {
+	result := map[int]bool{}
+	value := func(input string) (int, error) {
+		if number, ok := names[strings.ToUpper(input)]; ok {
+			return number, nil
+		}
+		number, err := strconv.Atoi(input)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q in cron field %q", input, field)
+		}
+		if number < min || number > max {
+			return 0, fmt.Errorf("value %d in cron field %q must be between %d and %d", number, field, min, max)
+		}
+		return number, nil
+	}
+	for _, part := range strings.Split(field, ",") {
+		_, named := names[strings.ToUpper(part)]
+		wildcard := strings.HasSuffix(strings.ToUpper(part), "L") || strings.HasSuffix(strings.ToUpper(part), "W")
+		if strings.Contains(part, "#") || wildcard && !named {
+			return nil, fmt.Errorf("cron field %q uses wildcards that aren't supported locally", field)
+		}
+		step := 1
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			parsed, err := strconv.Atoi(after)
+			if err != nil || parsed < 1 {
+				return nil, fmt.Errorf("invalid step in cron field %q", field)
+			}
+			step = parsed
+			part = before
+		}
+		start, end := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			from, to, _ := strings.Cut(part, "-")
+			var err error
+			if start, err = value(from); err != nil {
+				return nil, err
+			}
+			if end, err = value(to); err != nil {
+				return nil, err
+			}
+		default:
+			var err error
+			if start, err = value(part); err != nil {
+				return nil, err
+			}
+			end = start
+			if step > 1 {
+				end = max
+			}
+		}
+		for i := start; i <= end; i += step {
+			result[i] = true
+		}
+	}
+	return result, nil
+}
+
+func (c *cron) Next(after time.Time) time.Time {
+	next := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(5, 0, 0)
+	for next.Before(limit) {
+		if c.years != nil && !c.years[next.Year()] {
+			next = time.Date(next.Year()+1, 1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.months[int(next.Month())] {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.hours[next.Hour()] {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !c.minutes[next.Minute()] {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+	// never fires
+	return time.Time{}
+}
+
+func (c *cron) matchesDay(t time.Time) bool {
+	if c.days != nil {
+		return c.days[t.Day()]
+	}
+	return c.weekdays[int(t.Weekday())+1]
+}
@@ -0,0 +1,46 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	cases := []struct {
+		expression string
+		next       time.Time
+	}{
+		{"rate(5 minutes)", from.Add(5 * time.Minute)},
+		{"rate(1 day)", from.Add(24 * time.Hour)},
+		{"cron(0 12 * * ? *)", time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)},
+		{"cron(0/15 * * * ? *)", time.Date(2024, 3, 15, 10, 45, 0, 0, time.UTC)},
+		{"cron(0 9 ? * MON-FRI *)", time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC)},
+		{"cron(0 0 1 JAN ? *)", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"cron(0 0 ? * 1 2024)", time.Date(2024, 3, 17, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		schedule, err := Parse(c.expression)
+		if err != nil {
+			t.Fatalf("%s: %v", c.expression, err)
+		}
+		if next := schedule.Next(from); !next.Equal(c.next) {
+			t.Errorf("%s: expected %v, got %v", c.expression, c.next, next)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expression := range []string{
+		"every 5 minutes",
+		"rate(5 weeks)",
+		"cron(0 12 * * * *)",
+		"cron(0 12 L * ? *)",
+		"cron(0 12 ? * 2#1 *)",
+		"cron(61 12 * * ? *)",
+	} {
+		if _, err := Parse(expression); err == nil {
+			t.Errorf("%s: expected an error", expression)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package frontend runs the dev servers of the frontends in the app, like
+// next dev, with their links in the environment.
+package frontend
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/server/bus"
+)
+
+// LogEvent is a line printed by a dev server.
+type LogEvent struct {
+	Name string
+	Line string
+}
+
+// StatusEvent is sent when a dev server starts, stops, or crashes.
+type StatusEvent struct {
+	Name    string
+	Command string
+	Status  string
+	Error   string
+}
+
+const (
+	StatusStarted    = "started"
+	StatusRestarting = "restarting"
+	StatusExited     = "exited"
+)
+
+type process struct {
+	hash   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start runs the dev servers after every deploy, restarting the ones whose
+// command or links changed.
+func Start(ctx context.Context, root string) (util.CleanupFunc, error) {
+	completed := make(chan *project.CompleteEvent, 100)
+	bus.Subscribe(ctx, func(event *project.StackEvent) {
+		if event.CompleteEvent != nil && event.CompleteEvent.Finished && len(event.CompleteEvent.Errors) == 0 {
+			completed <- event.CompleteEvent
+		}
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		processes := map[string]*process{}
+		defer func() {
+			for _, existing := range processes {
+				existing.cancel()
+				<-existing.done
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case complete := <-completed:
+				next := map[string]*process{}
+				for name, command := range complete.DevCommands {
+					if !command.Autostart {
+						continue
+					}
+					env := commandEnv(command, complete.Links)
+					hash := commandHash(command, env)
+					if existing, ok := processes[name]; ok && existing.hash == hash {
+						next[name] = existing
+						continue
+					}
+					if existing, ok := processes[name]; ok {
+						bus.Publish(&StatusEvent{Name: name, Command: command.Command, Status: StatusRestarting})
+						existing.cancel()
+						<-existing.done
+					}
+					processCtx, cancel := context.WithCancel(ctx)
+					started := &process{
+						hash:   hash,
+						cancel: cancel,
+						done:   make(chan struct{}),
+					}
+					next[name] = started
+					go supervise(processCtx, root, name, command, env, started.done)
+				}
+				for name, existing := range processes {
+					if next[name] != existing {
+						existing.cancel()
+						<-existing.done
+					}
+				}
+				processes = next
+			}
+		}
+	}()
+
+	return func() error {
+		slog.Info("cleaning up dev servers")
+		wg.Wait()
+		return nil
+	}, nil
+}
+
+// commandEnv is the environment of the host with the links and the
+// environment of the site, like sst dev passes to a command.
+func commandEnv(command project.DevCommand, links project.Links) []string {
+	env := os.Environ()
+	for key, value := range command.Environment {
+		env = append(env, key+"="+value)
+	}
+	for _, name := range command.Links {
+		value, _ := json.Marshal(links[name])
+		env = append(env, fmt.Sprintf("SST_RESOURCE_%s=%s", name, value))
+	}
+	return env
+}
+
+func commandHash(command project.DevCommand, env []string) string {
+	hash := sha256.New()
+	data, _ := json.Marshal(command)
+	hash.Write(data)
+	for _, item := range env {
+		hash.Write([]byte("\n" + item))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// supervise runs the command until the context is done, restarting it if
+// it crashes.
+func supervise(ctx context.Context, root string, name string, command project.DevCommand, env []string, done chan struct{}) {
+	defer close(done)
+	for {
+		err := run(ctx, root, name, command, env)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			bus.Publish(&StatusEvent{Name: name, Command: command.Command, Status: StatusExited})
+			return
+		}
+		bus.Publish(&StatusEvent{Name: name, Command: command.Command, Status: StatusExited, Error: err.Error()})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+		bus.Publish(&StatusEvent{Name: name, Command: command.Command, Status: StatusRestarting})
+	}
+}
+
+func run(ctx context.Context, root string, name string, command project.DevCommand, env []string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command.Command)
+	}
+	cmd.Dir = filepath.Join(root, command.Directory)
+	cmd.Env = env
+	configure(cmd)
+	cmd.WaitDelay = 10 * time.Second
+
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	scanned := make(chan struct{})
+	go func() {
+		defer close(scanned)
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+		for scanner.Scan() {
+			bus.Publish(&LogEvent{Name: name, Line: scanner.Text()})
+		}
+		io.Copy(io.Discard, reader)
+	}()
+
+	slog.Info("starting dev server", "name", name, "command", command.Command, "dir", cmd.Dir)
+	if err := cmd.Start(); err != nil {
+		writer.Close()
+		<-scanned
+		return err
+	}
+	bus.Publish(&StatusEvent{Name: name, Command: command.Command, Status: StatusStarted})
+	err := cmd.Wait()
+	writer.Close()
+	<-scanned
+	return err
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package frontend
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// configure starts the command in its own process group so the processes
+// it starts, like the node of npm run dev, are stopped with it.
+func configure(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/sst/ion/internal/fs"
 	"github.com/sst/ion/internal/util"
 	"github.com/sst/ion/pkg/server/bus"
 )
@@ -16,35 +20,67 @@ type FileChangedEvent struct {
 	Path string
 }
 
-func Start(ctx context.Context, root string) (util.CleanupFunc, error) {
-	watcher, err := fsnotify.NewWatcher()
+type Options struct {
+	// Ignore are glob patterns of the files and directories not watched, on
+	// top of the defaults
+	Ignore []string
+	// Debounce is how long to wait for the changes to settle before they're
+	// published
+	Debounce time.Duration
+	// Poll checks the files every PollInterval instead of using events
+	Poll         bool
+	PollInterval time.Duration
+}
+
+var defaultIgnore = []string{".sst", "node_modules", ".git"}
+
+func Start(ctx context.Context, root string, options Options) (util.CleanupFunc, error) {
+	matcher, err := newMatcher(append(append([]string{}, defaultIgnore...), options.Ignore...))
 	if err != nil {
 		return nil, err
 	}
-	err = watcher.AddWith(root)
+	if options.PollInterval == 0 {
+		options.PollInterval = time.Second
+	}
+	changes := debounce(ctx, options.Debounce)
+
+	if !options.Poll {
+		cleanup, err := watch(ctx, root, matcher, changes)
+		if err == nil {
+			return cleanup, nil
+		}
+		// too many directories, or a file system without events
+		slog.Warn("falling back to polling", "err", err)
+	}
+	go poll(ctx, root, matcher, options.PollInterval, changes)
+	return func() error {
+		slog.Info("cleaning up file watcher")
+		return nil
+	}, nil
+}
+
+func watch(ctx context.Context, root string, matcher *matcher, changes chan<- string) (util.CleanupFunc, error) {
+	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
 	}
-	ignoreSubstrings := []string{".sst", "node_modules"}
-
 	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
-			for _, substring := range ignoreSubstrings {
-				if strings.Contains(path, substring) {
-					return nil
-				}
-			}
-			slog.Info("watching", "path", path)
-			err = watcher.Add(path)
-			if err != nil {
-				return err
-			}
+		if !info.IsDir() {
+			return nil
 		}
-		return nil
+		if path != root && (matcher.ignored(root, path) || isBuildOutput(path)) {
+			return filepath.SkipDir
+		}
+		slog.Info("watching", "path", path)
+		return watcher.Add(path)
 	})
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
 
 	go func() {
 		for {
@@ -56,8 +92,10 @@ func Start(ctx context.Context, root string) (util.CleanupFunc, error) {
 				if event.Op&fsnotify.Write != fsnotify.Write {
 					continue
 				}
-				slog.Info("file changed", "path", event.Name)
-				bus.Publish(&FileChangedEvent{Path: event.Name})
+				if matcher.ignored(root, event.Name) {
+					continue
+				}
+				changes <- event.Name
 			case <-ctx.Done():
 				return
 			}
@@ -69,3 +107,180 @@ func Start(ctx context.Context, root string) (util.CleanupFunc, error) {
 		return watcher.Close()
 	}, nil
 }
+
+// poll compares the modification times of the files on an interval.
+func poll(ctx context.Context, root string, matcher *matcher, interval time.Duration, changes chan<- string) {
+	scan := func() map[string]time.Time {
+		result := map[string]time.Time{}
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if path != root && matcher.ignored(root, path) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if path != root && isBuildOutput(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			result[path] = info.ModTime()
+			return nil
+		})
+		return result
+	}
+	slog.Info("polling for changes", "root", root, "interval", interval)
+	previous := scan()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		current := scan()
+		for path, modified := range current {
+			if before, ok := previous[path]; ok && !before.Equal(modified) {
+				changes <- path
+			}
+		}
+		previous = current
+	}
+}
+
+// debounce publishes the changed files once none changed for the interval,
+// each file once.
+func debounce(ctx context.Context, interval time.Duration) chan<- string {
+	changes := make(chan string, 1000)
+	go func() {
+		pending := map[string]bool{}
+		var timer <-chan time.Time
+		flush := func() {
+			paths := make([]string, 0, len(pending))
+			for path := range pending {
+				paths = append(paths, path)
+			}
+			pending = map[string]bool{}
+			sort.Strings(paths)
+			for _, path := range paths {
+				slog.Info("file changed", "path", path)
+				bus.Publish(&FileChangedEvent{Path: path})
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case path := <-changes:
+				if interval <= 0 {
+					slog.Info("file changed", "path", path)
+					bus.Publish(&FileChangedEvent{Path: path})
+					continue
+				}
+				pending[path] = true
+				timer = time.After(interval)
+			case <-timer:
+				timer = nil
+				flush()
+			}
+		}
+	}()
+	return changes
+}
+
+// matcher checks paths against glob patterns. A pattern without a slash
+// matches a name anywhere in the path, the others match the path from the
+// root, where ** matches any number of directories.
+type matcher struct {
+	names []*regexp.Regexp
+	paths []*regexp.Regexp
+}
+
+func newMatcher(patterns []string) (*matcher, error) {
+	result := &matcher{}
+	for _, pattern := range patterns {
+		pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+		compiled, err := regexp.Compile(globToRegexp(pattern))
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(pattern, "/") {
+			result.paths = append(result.paths, compiled)
+			continue
+		}
+		result.names = append(result.names, compiled)
+	}
+	return result, nil
+}
+
+// ignored is true if the path, or a directory it's in, matches a pattern.
+func (m *matcher) ignored(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for index, part := range parts {
+		for _, name := range m.names {
+			if name.MatchString(part) {
+				return true
+			}
+		}
+		prefix := strings.Join(parts[:index+1], "/")
+		for _, path := range m.paths {
+			if path.MatchString(prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func globToRegexp(pattern string) string {
+	var result strings.Builder
+	result.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			result.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			result.WriteString(".*")
+			i++
+		case c == '*':
+			result.WriteString("[^/]*")
+		case c == '?':
+			result.WriteString("[^/]")
+		default:
+			result.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	result.WriteString("$")
+	return result.String()
+}
+
+// isBuildOutput is true for the target directory of a rust crate or maven
+// project, the build directory of a gradle project, and the bin and obj
+// directories of a .NET project.
+func isBuildOutput(path string) bool {
+	parent := filepath.Dir(path)
+	switch filepath.Base(path) {
+	case "target":
+		return fs.Exists(filepath.Join(parent, "Cargo.toml")) || fs.Exists(filepath.Join(parent, "pom.xml"))
+	case "build":
+		return fs.Exists(filepath.Join(parent, "build.gradle")) || fs.Exists(filepath.Join(parent, "build.gradle.kts"))
+	case ".gradle":
+		return true
+	case "bin", "obj":
+		projects, _ := filepath.Glob(filepath.Join(parent, "*.*proj"))
+		return len(projects) > 0
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package watcher
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	matcher, err := newMatcher([]string{".git", "*.test.ts", "src/generated", "**/fixtures/**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.FromSlash("/app")
+	cases := map[string]bool{
+		"/app/.git/HEAD":                   true,
+		"/app/packages/web/.git":           true,
+		"/app/src/index.ts":                false,
+		"/app/src/index.test.ts":           true,
+		"/app/src/generated":               true,
+		"/app/src/generated/schema.ts":     true,
+		"/app/lib/src/generated/schema.ts": false,
+		"/app/test/fixtures/data.json":     true,
+		"/app/fixtures.ts":                 false,
+		"/other/src/index.test.ts":         false,
+	}
+	for path, expected := range cases {
+		if got := matcher.ignored(root, filepath.FromSlash(path)); got != expected {
+			t.Errorf("%s: expected %v, got %v", path, expected, got)
+		}
+	}
+}
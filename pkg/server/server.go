@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/sst/ion/pkg/project/provider"
 	"github.com/sst/ion/pkg/server/bus"
 	"github.com/sst/ion/pkg/server/dev/aws"
+	"github.com/sst/ion/pkg/server/dev/frontend"
 	"github.com/sst/ion/pkg/server/dev/watcher"
 	"github.com/sst/ion/pkg/server/socket"
 )
@@ -27,6 +29,12 @@ type Server struct {
 	subscribers  []chan *Event
 	state        *State
 	lastEvent    *Event
+	// Inspect runs the functions with a debugger attached
+	Inspect bool
+	// Attach connects to a stage another session is running without
+	// deploying or running its functions
+	Attach   bool
+	conflict *SessionConflictEvent
 }
 
 type State struct {
@@ -37,12 +45,23 @@ type State struct {
 
 type Event struct {
 	project.StackEvent
-	StateEvent            *StateEvent
-	FunctionInvokedEvent  *aws.FunctionInvokedEvent
-	FunctionResponseEvent *aws.FunctionResponseEvent
-	FunctionErrorEvent    *aws.FunctionErrorEvent
-	FunctionLogEvent      *aws.FunctionLogEvent
-	FunctionBuildEvent    *aws.FunctionBuildEvent
+	StateEvent                 *StateEvent
+	FunctionInvokedEvent       *aws.FunctionInvokedEvent
+	FunctionResponseEvent      *aws.FunctionResponseEvent
+	FunctionErrorEvent         *aws.FunctionErrorEvent
+	FunctionLogEvent           *aws.FunctionLogEvent
+	FunctionBuildEvent         *aws.FunctionBuildEvent
+	FunctionBuildProgressEvent *aws.FunctionBuildProgressEvent
+	FunctionReloadEvent        *aws.FunctionReloadEvent
+	CronEvent                  *aws.CronEvent
+	QueuePollEvent             *aws.QueuePollEvent
+	LogTailEvent               *aws.LogTailEvent
+	MetricsEvent               *aws.MetricsEvent
+	BusPollEvent               *aws.BusPollEvent
+	TunnelEvent                *aws.TunnelEvent
+	FrontendLogEvent           *frontend.LogEvent
+	FrontendStatusEvent        *frontend.StatusEvent
+	SessionConflictEvent       *SessionConflictEvent
 }
 
 type StateEvent struct {
@@ -65,6 +84,25 @@ func findExisting(cfgPath, stage string) (string, error) {
 	return string(contents), nil
 }
 
+// Running reports if the dev server of any stage of the app is running,
+// the server files of the ones that exited are left behind so it checks
+// that they still accept connections.
+func Running(cfgPath string) bool {
+	files, _ := filepath.Glob(filepath.Join(project.ResolveWorkingDir(cfgPath), "*.server"))
+	for _, file := range files {
+		addr, err := os.ReadFile(file)
+		if err != nil || len(addr) == 0 {
+			continue
+		}
+		conn, err := net.DialTimeout("tcp", string(addr), 200*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
+}
+
 func cleanupExisting(cfgPath, stage string) error {
 	return os.Remove(resolveServerFile(cfgPath, stage))
 }
@@ -117,6 +155,11 @@ func (s *Server) Start(parentContext context.Context) error {
 			},
 		})
 		publish(s.lastEvent)
+		if s.conflict != nil {
+			publish(&Event{
+				SessionConflictEvent: s.conflict,
+			})
+		}
 		bus.Subscribe(ctx, func(event *aws.FunctionInvokedEvent) {
 			publish(&Event{
 				FunctionInvokedEvent: event,
@@ -150,6 +193,72 @@ func (s *Server) Start(parentContext context.Context) error {
 				FunctionBuildEvent: event,
 			})
 		})
+
+		bus.Subscribe(ctx, func(event *aws.FunctionReloadEvent) {
+			publish(&Event{
+				FunctionReloadEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *aws.FunctionBuildProgressEvent) {
+			publish(&Event{
+				FunctionBuildProgressEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *aws.CronEvent) {
+			publish(&Event{
+				CronEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *aws.QueuePollEvent) {
+			publish(&Event{
+				QueuePollEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *aws.LogTailEvent) {
+			publish(&Event{
+				LogTailEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *aws.MetricsEvent) {
+			publish(&Event{
+				MetricsEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *aws.BusPollEvent) {
+			publish(&Event{
+				BusPollEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *aws.TunnelEvent) {
+			publish(&Event{
+				TunnelEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *SessionConflictEvent) {
+			publish(&Event{
+				SessionConflictEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *frontend.LogEvent) {
+			publish(&Event{
+				FrontendLogEvent: event,
+			})
+		})
+
+		bus.Subscribe(ctx, func(event *frontend.StatusEvent) {
+			publish(&Event{
+				FrontendStatusEvent: event,
+			})
+		})
 		<-ctx.Done()
 		slog.Info("done", "addr", r.RemoteAddr)
 		if atomic.LoadInt64(&count) == 1 {
@@ -157,6 +266,17 @@ func (s *Server) Start(parentContext context.Context) error {
 		}
 	})
 
+	mux.HandleFunc("/trigger/cron/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/trigger/cron/")
+		slog.Info("triggering cron", "name", name)
+		bus.Publish(&aws.CronTriggerEvent{Name: name})
+		w.WriteHeader(http.StatusAccepted)
+	})
+
 	s.server = &http.Server{
 		Handler: mux,
 	}
@@ -169,17 +289,21 @@ func (s *Server) Start(parentContext context.Context) error {
 	slog.Info("server", "addr", s.server.Addr)
 
 	socket.Start(ctx, s.project, mux)
-	for _, p := range s.project.Providers {
-		switch casted := p.(type) {
-		case *provider.AwsProvider:
-			cleanup, err := aws.Start(ctx, mux, casted,
-				s.project,
-				port,
-			)
-			if err != nil {
-				return err
+	// an attached session leaves the functions to the one it attached to
+	if !s.Attach {
+		for _, p := range s.project.Providers {
+			switch casted := p.(type) {
+			case *provider.AwsProvider:
+				cleanup, err := aws.Start(ctx, mux, casted,
+					s.project,
+					port,
+					s.Inspect,
+				)
+				if err != nil {
+					return err
+				}
+				defer cleanup()
 			}
-			defer cleanup()
 		}
 	}
 
@@ -196,17 +320,57 @@ func (s *Server) Start(parentContext context.Context) error {
 	}
 	defer os.Remove(serverFile)
 
-	fileWatcher, err := watcher.Start(ctx, s.project.PathRoot())
+	bus.Subscribe(ctx, func(event *SessionConflictEvent) {
+		s.conflict = event
+	})
+	session, err := startSession(ctx, s.project, s.Attach)
 	if err != nil {
 		return err
 	}
-	defer fileWatcher()
+	defer session()
 
-	deployer, _ := startDeployer(ctx, s.project)
-	if err != nil {
-		return err
+	if s.Attach {
+		complete, err := attachedEvent(s.project)
+		if err != nil {
+			return err
+		}
+		s.lastEvent = &Event{
+			StackEvent: project.StackEvent{CompleteEvent: complete},
+		}
+	} else {
+		watchOptions := watcher.Options{Debounce: 100 * time.Millisecond}
+		if watch := s.project.App().Watch; watch != nil {
+			watchOptions.Ignore = append(watchOptions.Ignore, watch.Ignore...)
+			watchOptions.Poll = watch.Poll
+			if watch.Debounce > 0 {
+				watchOptions.Debounce = time.Duration(watch.Debounce) * time.Millisecond
+			}
+		}
+		// what the CLI writes can be moved anywhere in the project
+		for _, dir := range []string{s.project.PathWorkingDir(), s.project.PathBuildDir(), s.project.PathOutputDir()} {
+			rel, err := filepath.Rel(s.project.PathRoot(), dir)
+			if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				watchOptions.Ignore = append(watchOptions.Ignore, filepath.ToSlash(rel))
+			}
+		}
+		fileWatcher, err := watcher.Start(ctx, s.project.PathRoot(), watchOptions)
+		if err != nil {
+			return err
+		}
+		defer fileWatcher()
+
+		deployer, _ := startDeployer(ctx, s.project)
+		if err != nil {
+			return err
+		}
+		defer deployer()
+
+		frontends, err := frontend.Start(ctx, s.project.PathRoot())
+		if err != nil {
+			return err
+		}
+		defer frontends()
 	}
-	defer deployer()
 
 	bus.Subscribe(ctx, func(event *project.StackEvent) {
 		if event.CompleteEvent != nil {
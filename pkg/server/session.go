@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sst/ion/internal/util"
+	"github.com/sst/ion/pkg/project"
+	"github.com/sst/ion/pkg/server/bus"
+)
+
+// SessionConflictEvent is sent when another dev session is running against
+// the same stage.
+type SessionConflictEvent struct {
+	Session project.Session
+	// Attached is true if this session only reads from the stage
+	Attached bool
+}
+
+// startSession sends a heartbeat for this session and reports the other
+// sessions it finds on the stage. An attached session doesn't send one, it
+// doesn't own the stage.
+func startSession(ctx context.Context, p *project.Project, attach bool) (util.CleanupFunc, error) {
+	session := project.NewSession()
+	reported := ""
+	check := func() {
+		existing, err := p.Stack.Session()
+		if err != nil {
+			slog.Error("failed to read session", "err", err)
+			return
+		}
+		if existing.ID != session.ID && existing.Live(time.Now()) {
+			if existing.ID != reported {
+				reported = existing.ID
+				slog.Info("session conflict", "id", existing.ID, "user", existing.User, "host", existing.Host)
+				bus.Publish(&SessionConflictEvent{Session: *existing, Attached: attach})
+			}
+		}
+		if attach {
+			return
+		}
+		if err := p.Stack.Heartbeat(session); err != nil {
+			slog.Error("failed to write session", "err", err)
+		}
+	}
+	check()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(project.SessionTimeout / 3):
+				check()
+			}
+		}
+	}()
+
+	return func() error {
+		wg.Wait()
+		if attach {
+			return nil
+		}
+		slog.Info("ending session", "id", session.ID)
+		return p.Stack.EndSession(session)
+	}, nil
+}
+
+// attachedEvent is the last deploy of the stage, read from its metadata, so
+// an attached session can show the links without deploying.
+func attachedEvent(p *project.Project) (*project.CompleteEvent, error) {
+	metadata, err := p.Stack.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	return &project.CompleteEvent{
+		Links:     metadata.Links,
+		Hints:     metadata.Hints,
+		Receivers: metadata.Receivers,
+		Warps:     metadata.Warps,
+		Finished:  true,
+		Errors:    []project.Error{},
+	}, nil
+}
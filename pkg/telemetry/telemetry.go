@@ -35,19 +35,17 @@ func Enable() error {
 	return nil
 }
 
+// IsEnabled is false if telemetry was disabled or it's turned off in the
+// user config.
 func IsEnabled() bool {
+	if config, err := global.LoadUserConfig(); err == nil && config.Telemetry != nil && !*config.Telemetry {
+		return false
+	}
 	path := filepath.Join(global.ConfigDir(), TELEMETRY_DISABLED_KEY)
 	_, err := os.Stat(path)
 	return os.IsNotExist(err)
 }
 
-// detectCI attempts to detect the CI environment and returns its name if detected, empty string otherwise.
-func detectCI() (ciName string) {
-	// You may need to add more CI detection logic here
-
-	return ""
-}
-
 var telemetryEnvironment = sync.OnceValue((func() map[string]interface{} {
 	telemetryIDPath := filepath.Join(global.ConfigDir(), TELEMETRY_ID_KEY)
 	var userID string
@@ -75,19 +73,6 @@ var telemetryEnvironment = sync.OnceValue((func() map[string]interface{} {
 
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
-	ciEnvVars := map[string]string{
-		"GITHUB_ACTIONS": "GitHub Actions",
-		"GITLAB_CI":      "GitLab CI",
-		"CIRCLECI":       "CircleCI",
-		"JENKINS_URL":    "Jenkins",
-		"TRAVIS":         "Travis CI",
-	}
-	ci := ""
-	for envVar, name := range ciEnvVars {
-		if _, present := os.LookupEnv(envVar); present {
-			ci = name
-		}
-	}
 	return map[string]interface{}{
 		"user_id":             userID,
 		"project_id":          projectID,
@@ -96,7 +81,7 @@ var telemetryEnvironment = sync.OnceValue((func() map[string]interface{} {
 		"system_architecture": runtime.GOARCH,
 		"cpu_count":           runtime.NumCPU(),
 		"memory_total":        int(memStats.TotalAlloc),
-		"ci_name":             ci,
+		"ci_name":             global.CI(),
 		"sst_version":         version,
 	}
 }))